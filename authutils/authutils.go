@@ -93,6 +93,42 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
+// HasAnyRole inaangalia kama claims zina angalau role moja kati ya roles
+func (c *Claims) HasAnyRole(roles ...string) bool {
+	for _, userRole := range c.Roles {
+		for _, role := range roles {
+			if userRole == role {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// HasPermission inaangalia kama claims zina permission husika
+func (c *Claims) HasPermission(permission string) bool {
+	for _, userPerm := range c.Permissions {
+		if userPerm == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// claimsContextKey ni key ya claims ndani ya request context
+type claimsContextKey struct{}
+
+// ContextWithClaims inaweka claims zilizovalidiwa ndani ya context
+func ContextWithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsContextKey{}, claims)
+}
+
+// ClaimsFromContext inarudisha claims zilizowekwa na ContextWithClaims
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsContextKey{}).(*Claims)
+	return claims, ok && claims != nil
+}
+
 // AuthError ni custom error ya authentication
 type AuthError struct {
 	Code    string
@@ -757,8 +793,7 @@ func (au *AuthUtils) AuthMiddleware(next http.Handler) http.Handler {
 		}
 
 		// Add claims to context
-		ctx := context.WithValue(r.Context(), "userClaims", claims)
-		next.ServeHTTP(w, r.WithContext(ctx))
+		next.ServeHTTP(w, r.WithContext(ContextWithClaims(r.Context(), claims)))
 	})
 }
 
@@ -766,27 +801,14 @@ func (au *AuthUtils) AuthMiddleware(next http.Handler) http.Handler {
 func (au *AuthUtils) RoleBasedMiddleware(allowedRoles []string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			claims, ok := r.Context().Value("userClaims").(*Claims)
-			if !ok || claims == nil {
+			claims, ok := ClaimsFromContext(r.Context())
+			if !ok {
 				au.writeError(w, http.StatusUnauthorized, "User claims not found")
 				return
 			}
 
 			// Check if user has any of the allowed roles
-			hasRole := false
-			for _, userRole := range claims.Roles {
-				for _, allowedRole := range allowedRoles {
-					if userRole == allowedRole {
-						hasRole = true
-						break
-					}
-				}
-				if hasRole {
-					break
-				}
-			}
-
-			if !hasRole {
+			if !claims.HasAnyRole(allowedRoles...) {
 				au.writeError(w, http.StatusForbidden, "Insufficient permissions")
 				return
 			}
@@ -800,22 +822,15 @@ func (au *AuthUtils) RoleBasedMiddleware(allowedRoles []string) func(http.Handle
 func (au *AuthUtils) PermissionBasedMiddleware(requiredPermissions []string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			claims, ok := r.Context().Value("userClaims").(*Claims)
-			if !ok || claims == nil {
+			claims, ok := ClaimsFromContext(r.Context())
+			if !ok {
 				au.writeError(w, http.StatusUnauthorized, "User claims not found")
 				return
 			}
 
 			// Check if user has all required permissions
 			for _, requiredPerm := range requiredPermissions {
-				hasPerm := false
-				for _, userPerm := range claims.Permissions {
-					if userPerm == requiredPerm {
-						hasPerm = true
-						break
-					}
-				}
-				if !hasPerm {
+				if !claims.HasPermission(requiredPerm) {
 					au.writeError(w, http.StatusForbidden,
 						fmt.Sprintf("Missing permission: %s", requiredPerm))
 					return
@@ -842,48 +857,6 @@ func (au *AuthUtils) writeError(w http.ResponseWriter, statusCode int, message s
 	json.NewEncoder(w).Encode(errorResponse)
 }
 
-// Rate limiting utilities
-type RateLimiter struct {
-	store  TokenStore
-	limit  int
-	window time.Duration
-}
-
-func NewRateLimiter(store TokenStore, limit int, window time.Duration) *RateLimiter {
-	return &RateLimiter{
-		store:  store,
-		limit:  limit,
-		window: window,
-	}
-}
-
-// CheckRateLimit ina check rate limit for a key
-func (rl *RateLimiter) CheckRateLimit(ctx context.Context, key string) (bool, int, error) {
-	currentKey := fmt.Sprintf("ratelimit:%s", key)
-
-	// Get current count
-	countStr, err := rl.store.GetToken(ctx, currentKey)
-	if err != nil {
-		// Key doesn't exist, create it
-		err = rl.store.SetToken(ctx, currentKey, "1", rl.window)
-		return true, 1, err
-	}
-
-	count, err := strconv.Atoi(countStr)
-	if err != nil {
-		count = 0
-	}
-
-	if count >= rl.limit {
-		return false, count, nil
-	}
-
-	// Increment count
-	count++
-	err = rl.store.SetToken(ctx, currentKey, strconv.Itoa(count), rl.window)
-	return true, count, err
-}
-
 // Password expiration utilities
 func (au *AuthUtils) ShouldChangePassword(lastChanged time.Time, maxAgeDays int) bool {
 	if maxAgeDays <= 0 {
@@ -0,0 +1,432 @@
+package authutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrRateLimitContention inarudishwa pale CompareAndSwap inaposhindwa mara nyingi mfululizo
+var ErrRateLimitContention = errors.New("rate limit state is under contention")
+
+// RateLimitResult ni matokeo ya ukaguzi mmoja wa rate limit
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	ResetAfter time.Duration // muda hadi limit ijae tena kikamilifu
+	RetryAfter time.Duration // muda wa kusubiri kabla request inayofuata ikubaliwe
+}
+
+// RateLimitAlgorithm ni algorithm ya rate limiting inayofanya kazi juu ya state ya string,
+// ili state iweze kuhifadhiwa kwenye backend yoyote
+type RateLimitAlgorithm interface {
+	// Take inatumia request moja kwenye state na kurudisha state mpya
+	Take(state string, now time.Time) (string, RateLimitResult)
+	// TTL ni muda mrefu zaidi ambao state inahitaji kuhifadhiwa
+	TTL() time.Duration
+	// Policy inaelezea limit kwa RateLimit-Policy header, mfano "100;w=60"
+	Policy() string
+}
+
+// RateLimitBackend inahifadhi state ya rate limiting
+type RateLimitBackend interface {
+	// Update inabadilisha state ya key kwa atomic; fn inapokea "" kama key haipo.
+	// fn inaweza kuitwa zaidi ya mara moja kama backend inajaribu tena.
+	Update(ctx context.Context, key string, ttl time.Duration, fn func(state string) string) error
+}
+
+// AtomicTokenStore ni TokenStore inayoweza compare-and-swap (kwa mfano Redis kwa Lua script).
+// TokenStoreRateLimitBackend inaihitaji ili replicas nyingi zisipite limit kwa pamoja.
+type AtomicTokenStore interface {
+	TokenStore
+	// CompareAndSwap inaweka value kama value ya sasa ni old; old == "" inamaanisha key haipo
+	CompareAndSwap(ctx context.Context, key, old, value string, expiration time.Duration) (bool, error)
+}
+
+// ========== ALGORITHMS ==========
+
+// tokenBucket inajaza token moja kila interval hadi capacity
+type tokenBucket struct {
+	capacity int
+	interval time.Duration
+	window   time.Duration
+}
+
+// TokenBucket inaruhusu rate requests kwa kila per, na burst ya juu zaidi ya burst
+func TokenBucket(rate int, per time.Duration, burst int) RateLimitAlgorithm {
+	rate, per = normalizeRate(rate, per)
+	if burst <= 0 {
+		burst = rate
+	}
+	return &tokenBucket{capacity: burst, interval: per / time.Duration(rate), window: per}
+}
+
+func (tb *tokenBucket) Take(state string, now time.Time) (string, RateLimitResult) {
+	tokens := float64(tb.capacity)
+	if tokensPart, lastPart, ok := strings.Cut(state, ":"); ok {
+		stored, err1 := strconv.ParseFloat(tokensPart, 64)
+		last, err2 := strconv.ParseInt(lastPart, 10, 64)
+		if err1 == nil && err2 == nil {
+			elapsed := now.Sub(time.Unix(0, last))
+			if elapsed < 0 {
+				elapsed = 0
+			}
+			tokens = math.Min(float64(tb.capacity), stored+float64(elapsed)/float64(tb.interval))
+		}
+	}
+
+	result := RateLimitResult{Limit: tb.capacity}
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - tokens) * float64(tb.interval))
+	}
+	result.Remaining = int(tokens)
+	result.ResetAfter = time.Duration((float64(tb.capacity) - tokens) * float64(tb.interval))
+
+	return strconv.FormatFloat(tokens, 'f', -1, 64) + ":" + strconv.FormatInt(now.UnixNano(), 10), result
+}
+
+func (tb *tokenBucket) TTL() time.Duration {
+	return time.Duration(tb.capacity) * tb.interval
+}
+
+func (tb *tokenBucket) Policy() string {
+	return formatPolicy(tb.capacity, tb.window)
+}
+
+// slidingWindowLog inahifadhi muda wa kila request iliyokubaliwa ndani ya window
+type slidingWindowLog struct {
+	limit  int
+	window time.Duration
+}
+
+// SlidingWindowLog inaruhusu limit requests ndani ya window yoyote inayoteleza.
+// Ni sahihi kabisa lakini state yake inakua hadi entries limit kwa kila key.
+func SlidingWindowLog(limit int, window time.Duration) RateLimitAlgorithm {
+	limit, window = normalizeRate(limit, window)
+	return &slidingWindowLog{limit: limit, window: window}
+}
+
+func (sw *slidingWindowLog) Take(state string, now time.Time) (string, RateLimitResult) {
+	cutoff := now.Add(-sw.window).UnixNano()
+
+	entries := make([]int64, 0, sw.limit)
+	if state != "" {
+		for _, part := range strings.Split(state, ",") {
+			ts, err := strconv.ParseInt(part, 10, 64)
+			if err == nil && ts > cutoff {
+				entries = append(entries, ts)
+			}
+		}
+	}
+
+	result := RateLimitResult{Limit: sw.limit}
+	if len(entries) < sw.limit {
+		entries = append(entries, now.UnixNano())
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Unix(0, entries[0]).Add(sw.window).Sub(now)
+	}
+	result.Remaining = sw.limit - len(entries)
+	if len(entries) > 0 {
+		result.ResetAfter = time.Unix(0, entries[len(entries)-1]).Add(sw.window).Sub(now)
+	}
+
+	parts := make([]string, len(entries))
+	for i, ts := range entries {
+		parts[i] = strconv.FormatInt(ts, 10)
+	}
+	return strings.Join(parts, ","), result
+}
+
+func (sw *slidingWindowLog) TTL() time.Duration {
+	return sw.window
+}
+
+func (sw *slidingWindowLog) Policy() string {
+	return formatPolicy(sw.limit, sw.window)
+}
+
+// gcra ni Generic Cell Rate Algorithm; state yake ni theoretical arrival time (TAT) pekee
+type gcra struct {
+	burst    int
+	interval time.Duration
+	window   time.Duration
+}
+
+// GCRA inaruhusu rate requests kwa kila per na burst ya juu zaidi ya burst,
+// kwa state ndogo ya namba moja kwa kila key
+func GCRA(rate int, per time.Duration, burst int) RateLimitAlgorithm {
+	rate, per = normalizeRate(rate, per)
+	if burst <= 0 {
+		burst = rate
+	}
+	return &gcra{burst: burst, interval: per / time.Duration(rate), window: per}
+}
+
+func (g *gcra) Take(state string, now time.Time) (string, RateLimitResult) {
+	tat := now
+	if ns, err := strconv.ParseInt(state, 10, 64); err == nil {
+		if stored := time.Unix(0, ns); stored.After(now) {
+			tat = stored
+		}
+	}
+
+	burstOffset := time.Duration(g.burst) * g.interval
+	newTAT := tat.Add(g.interval)
+	allowAt := newTAT.Add(-burstOffset)
+
+	result := RateLimitResult{Limit: g.burst}
+	if now.Before(allowAt) {
+		result.RetryAfter = allowAt.Sub(now)
+		result.ResetAfter = tat.Sub(now)
+		return strconv.FormatInt(tat.UnixNano(), 10), result
+	}
+
+	result.Allowed = true
+	result.Remaining = int((burstOffset - newTAT.Sub(now)) / g.interval)
+	result.ResetAfter = newTAT.Sub(now)
+	return strconv.FormatInt(newTAT.UnixNano(), 10), result
+}
+
+func (g *gcra) TTL() time.Duration {
+	return time.Duration(g.burst) * g.interval
+}
+
+func (g *gcra) Policy() string {
+	return formatPolicy(g.burst, g.window)
+}
+
+func normalizeRate(limit int, window time.Duration) (int, time.Duration) {
+	if limit <= 0 {
+		limit = 1
+	}
+	if window <= 0 {
+		window = time.Second
+	}
+	return limit, window
+}
+
+func formatPolicy(limit int, window time.Duration) string {
+	return fmt.Sprintf("%d;w=%d", limit, int64(math.Ceil(window.Seconds())))
+}
+
+// ========== BACKENDS ==========
+
+// MemoryRateLimitBackend ni backend ya process moja iliyogawanywa kwa shards
+// ili requests za keys tofauti zisishindane kwenye lock moja
+type MemoryRateLimitBackend struct {
+	shards []*rateLimitShard
+}
+
+type rateLimitShard struct {
+	mu      sync.Mutex
+	entries map[string]rateLimitEntry
+	ops     int
+}
+
+type rateLimitEntry struct {
+	state   string
+	expires time.Time
+}
+
+// sweepEvery ni idadi ya operations kwenye shard kabla ya kuondoa entries zilizoisha
+const sweepEvery = 1024
+
+// NewMemoryRateLimitBackend inaunda in-memory backend; shards <= 0 inatumia 32
+func NewMemoryRateLimitBackend(shards int) *MemoryRateLimitBackend {
+	if shards <= 0 {
+		shards = 32
+	}
+	b := &MemoryRateLimitBackend{shards: make([]*rateLimitShard, shards)}
+	for i := range b.shards {
+		b.shards[i] = &rateLimitShard{entries: make(map[string]rateLimitEntry)}
+	}
+	return b
+}
+
+// Update inatekeleza RateLimitBackend
+func (b *MemoryRateLimitBackend) Update(ctx context.Context, key string, ttl time.Duration, fn func(state string) string) error {
+	shard := b.shard(key)
+	now := time.Now()
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	shard.ops++
+	if shard.ops >= sweepEvery {
+		shard.ops = 0
+		for k, entry := range shard.entries {
+			if now.After(entry.expires) {
+				delete(shard.entries, k)
+			}
+		}
+	}
+
+	entry, ok := shard.entries[key]
+	if !ok || now.After(entry.expires) {
+		entry = rateLimitEntry{}
+	}
+
+	shard.entries[key] = rateLimitEntry{state: fn(entry.state), expires: now.Add(ttl)}
+	return nil
+}
+
+// Len inarudisha idadi ya keys zinazohifadhiwa sasa
+func (b *MemoryRateLimitBackend) Len() int {
+	total := 0
+	for _, shard := range b.shards {
+		shard.mu.Lock()
+		total += len(shard.entries)
+		shard.mu.Unlock()
+	}
+	return total
+}
+
+func (b *MemoryRateLimitBackend) shard(key string) *rateLimitShard {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return b.shards[h.Sum32()%uint32(len(b.shards))]
+}
+
+// TokenStoreRateLimitBackend inahifadhi state kwenye AtomicTokenStore ili replicas
+// zote zishiriki limit moja. Kila update ni compare-and-swap, hivyo requests
+// zinazoshindana hazipiti limit.
+type TokenStoreRateLimitBackend struct {
+	store      AtomicTokenStore
+	maxRetries int
+}
+
+// NewTokenStoreRateLimitBackend inaunda backend juu ya AtomicTokenStore. TokenStore
+// ya kawaida haitoshi: kusoma na kuandika kando kando kunaruhusu replicas kupita limit.
+func NewTokenStoreRateLimitBackend(store AtomicTokenStore) *TokenStoreRateLimitBackend {
+	return &TokenStoreRateLimitBackend{store: store, maxRetries: 10}
+}
+
+// Update inatekeleza RateLimitBackend
+func (b *TokenStoreRateLimitBackend) Update(ctx context.Context, key string, ttl time.Duration, fn func(state string) string) error {
+	for attempt := 0; attempt < b.maxRetries; attempt++ {
+		current, err := b.get(ctx, key)
+		if err != nil {
+			return err
+		}
+
+		next := fn(current)
+
+		swapped, err := b.store.CompareAndSwap(ctx, key, current, next, ttl)
+		if err != nil {
+			return fmt.Errorf("failed to store rate limit state: %w", err)
+		}
+		if swapped {
+			return nil
+		}
+	}
+
+	return ErrRateLimitContention
+}
+
+// get inasoma state; GetToken haitofautishi key isiyokuwepo na error, hivyo Exists inatumika
+func (b *TokenStoreRateLimitBackend) get(ctx context.Context, key string) (string, error) {
+	value, err := b.store.GetToken(ctx, key)
+	if err == nil {
+		return value, nil
+	}
+
+	exists, existsErr := b.store.Exists(ctx, key)
+	if existsErr != nil {
+		return "", fmt.Errorf("failed to read rate limit state: %w", existsErr)
+	}
+	if exists {
+		return "", fmt.Errorf("failed to read rate limit state: %w", err)
+	}
+	return "", nil
+}
+
+// ========== RATE LIMITER ==========
+
+// RateLimiterOptions inabadilisha default settings za RateLimiter
+type RateLimiterOptions struct {
+	Prefix string           // default: "ratelimit:"
+	Clock  func() time.Time // default: time.Now
+}
+
+// RateLimiter inaunganisha algorithm na backend
+type RateLimiter struct {
+	backend   RateLimitBackend
+	algorithm RateLimitAlgorithm
+	prefix    string
+	now       func() time.Time
+}
+
+// NewRateLimiter inaruhusu limit requests kwa kila window kwa kutumia GCRA.
+// Kama store ni nil, in-memory backend inatumika.
+func NewRateLimiter(store AtomicTokenStore, limit int, window time.Duration) *RateLimiter {
+	var backend RateLimitBackend
+	if store != nil {
+		backend = NewTokenStoreRateLimitBackend(store)
+	} else {
+		backend = NewMemoryRateLimitBackend(0)
+	}
+	return NewRateLimiterWithBackend(backend, GCRA(limit, window, limit))
+}
+
+// NewRateLimiterWithBackend inaunda RateLimiter kwa backend na algorithm yoyote
+func NewRateLimiterWithBackend(backend RateLimitBackend, algorithm RateLimitAlgorithm, options ...RateLimiterOptions) *RateLimiter {
+	var opts RateLimiterOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if opts.Prefix == "" {
+		opts.Prefix = "ratelimit:"
+	}
+	if opts.Clock == nil {
+		opts.Clock = time.Now
+	}
+
+	return &RateLimiter{
+		backend:   backend,
+		algorithm: algorithm,
+		prefix:    opts.Prefix,
+		now:       opts.Clock,
+	}
+}
+
+// Allow inatumia request moja kwa key na kurudisha matokeo
+func (rl *RateLimiter) Allow(ctx context.Context, key string) (RateLimitResult, error) {
+	var result RateLimitResult
+	now := rl.now()
+
+	err := rl.backend.Update(ctx, rl.prefix+key, rl.algorithm.TTL(), func(state string) string {
+		var next string
+		next, result = rl.algorithm.Take(state, now)
+		return next
+	})
+	if err != nil {
+		return RateLimitResult{}, err
+	}
+
+	return result, nil
+}
+
+// CheckRateLimit ina check rate limit for a key; inarudisha kama imekubaliwa na idadi iliyotumika
+func (rl *RateLimiter) CheckRateLimit(ctx context.Context, key string) (bool, int, error) {
+	result, err := rl.Allow(ctx, key)
+	if err != nil {
+		return false, 0, err
+	}
+	return result.Allowed, result.Limit - result.Remaining, nil
+}
+
+// Policy inarudisha maelezo ya limit kwa RateLimit-Policy header
+func (rl *RateLimiter) Policy() string {
+	return rl.algorithm.Policy()
+}
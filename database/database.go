@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
@@ -15,6 +16,7 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq" // PostgreSQL driver
+	"github.com/selanim/sego/tracing"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	_ "modernc.org/sqlite" // SQLite driver
@@ -30,6 +32,14 @@ const (
 	MongoDB    DBType = "mongodb"
 )
 
+// System inarudisha jina la database kwa OpenTelemetry db.system, mfano "postgresql"
+func (t DBType) System() string {
+	if t == PostgreSQL {
+		return "postgresql"
+	}
+	return string(t)
+}
+
 // DB ni interface ya generic database
 type DB struct {
 	Type         DBType
@@ -388,7 +398,10 @@ func (db *DB) Stats() string {
 }
 
 // ExecuteQuery inafanya query rahisi kwa SQL databases
-func (db *DB) ExecuteQuery(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+func (db *DB) ExecuteQuery(ctx context.Context, query string, args ...interface{}) (rows *sql.Rows, err error) {
+	ctx, span := db.startSpan(ctx, "db.query", query)
+	defer tracing.End(span, &err)
+
 	switch db.Type {
 	case PostgreSQL:
 		if db.PostgresPool != nil {
@@ -405,7 +418,10 @@ func (db *DB) ExecuteQuery(ctx context.Context, query string, args ...interface{
 }
 
 // ExecuteQueryRows inafanya query na kureturn pgx.Rows kwa PostgreSQL
-func (db *DB) ExecuteQueryRows(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error) {
+func (db *DB) ExecuteQueryRows(ctx context.Context, query string, args ...interface{}) (rows pgx.Rows, err error) {
+	ctx, span := db.startSpan(ctx, "db.query", query)
+	defer tracing.End(span, &err)
+
 	if db.Type == PostgreSQL && db.PostgresPool != nil {
 		return db.PostgresPool.Query(ctx, query, args...)
 	}
@@ -446,7 +462,13 @@ func (db *DB) ScanSQLRows(rows *sql.Rows, dest ...interface{}) error {
 }
 
 // ExecuteExec inafanya exec command kwa SQL databases (INSERT, UPDATE, DELETE)
-func (db *DB) ExecuteExec(ctx context.Context, query string, args ...interface{}) (int64, error) {
+func (db *DB) ExecuteExec(ctx context.Context, query string, args ...interface{}) (rowsAffected int64, err error) {
+	ctx, span := db.startSpan(ctx, "db.exec", query)
+	defer func() {
+		span.SetAttribute("db.rows_affected", rowsAffected)
+		tracing.End(span, &err)
+	}()
+
 	switch db.Type {
 	case PostgreSQL:
 		if db.PostgresPool != nil {
@@ -474,6 +496,32 @@ func (db *DB) ExecuteExec(ctx context.Context, query string, args ...interface{}
 	return 0, fmt.Errorf("ExecuteExec not supported for database type: %s", db.Type)
 }
 
+// startSpan inaanzisha client span ya query; inarudisha nil span kama tracing haijawekwa
+func (db *DB) startSpan(ctx context.Context, fallback, query string) (context.Context, *tracing.Span) {
+	operation := sqlOperation(query)
+	name := fallback
+	if operation != "" {
+		name = operation
+	}
+
+	ctx, span := tracing.Start(ctx, name, tracing.SpanKindClient)
+	span.SetAttributes(map[string]interface{}{
+		"db.system":    db.Type.System(),
+		"db.statement": query,
+		"db.operation": operation,
+	})
+	return ctx, span
+}
+
+// sqlOperation inarudisha keyword ya kwanza ya query, mfano SELECT
+func sqlOperation(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToUpper(fields[0])
+}
+
 // WithTransaction inafanya transaction safely kwa SQL databases
 func (db *DB) WithTransaction(ctx context.Context, fn func(tx interface{}) error) error {
 	switch db.Type {
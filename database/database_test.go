@@ -3,11 +3,14 @@ package database
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 	"sync"
 	"testing"
+	"testing/fstest"
 	"time"
 )
 
@@ -633,6 +636,174 @@ TEST_VAR=test_value
 	}
 }
 
+// newMigrationTestDB inaunda SQLite database ya memory kwa migration tests
+func newMigrationTestDB(t *testing.T) *DB {
+	t.Helper()
+
+	sqlDB, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open SQLite: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	return &DB{Type: SQLite, SQLDB: sqlDB}
+}
+
+// TestMigrations inatest Migrate, Status na Rollback kwa SQLite
+func TestMigrations(t *testing.T) {
+	db := newMigrationTestDB(t)
+	ctx := context.Background()
+
+	source := fstest.MapFS{
+		"0001_create_users.up.sql":   {Data: []byte("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);")},
+		"0001_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+		"0002_add_email.up.sql":      {Data: []byte("ALTER TABLE users ADD COLUMN email TEXT;\nCREATE INDEX idx_users_email ON users(email);")},
+		"0002_add_email.down.sql":    {Data: []byte("DROP INDEX idx_users_email;\nALTER TABLE users DROP COLUMN email;")},
+		"README.md":                  {Data: []byte("ignored")},
+	}
+
+	migrator, err := NewMigrator(db, source, MigratorOptions{DisableLogging: true})
+	if err != nil {
+		t.Fatalf("NewMigrator failed: %v", err)
+	}
+	if len(migrator.Migrations()) != 2 {
+		t.Fatalf("Expected 2 migrations, got %d", len(migrator.Migrations()))
+	}
+
+	status, err := migrator.Status()
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	for _, s := range status {
+		if s.Applied {
+			t.Errorf("Expected migration %d to be pending", s.Version)
+		}
+	}
+
+	if err := migrator.Migrate(ctx); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	// Kuendesha tena hakufanyi kitu
+	if err := migrator.Migrate(ctx); err != nil {
+		t.Fatalf("Second Migrate failed: %v", err)
+	}
+
+	if _, err := db.SQLDB.ExecContext(ctx, "INSERT INTO users (name, email) VALUES (?, ?)", "Asha", "asha@example.com"); err != nil {
+		t.Fatalf("Expected migrated schema, insert failed: %v", err)
+	}
+
+	status, err = migrator.Status()
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	for _, s := range status {
+		if !s.Applied || s.AppliedAt == nil || s.AppliedAt.IsZero() {
+			t.Errorf("Expected migration %d to be applied, got %+v", s.Version, s)
+		}
+	}
+
+	if err := migrator.Rollback(ctx, 1); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	if _, err := db.SQLDB.ExecContext(ctx, "INSERT INTO users (name, email) VALUES (?, ?)", "Juma", "juma@example.com"); err == nil {
+		t.Error("Expected email column to be dropped after rollback")
+	}
+
+	status, _ = migrator.Status()
+	if !status[0].Applied || status[1].Applied {
+		t.Errorf("Expected only first migration applied, got %+v", status)
+	}
+
+	if err := migrator.Rollback(ctx, 5); err != nil {
+		t.Fatalf("Rollback of remaining migrations failed: %v", err)
+	}
+	if err := migrator.Rollback(ctx, 0); err == nil {
+		t.Error("Expected error for zero steps")
+	}
+}
+
+// TestMigrationChecksum inatest kwamba migrations zilizobadilishwa zinakataliwa
+func TestMigrationChecksum(t *testing.T) {
+	db := newMigrationTestDB(t)
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	upFile := filepath.Join(dir, "1_create_items.up.sql")
+	if err := os.WriteFile(upFile, []byte("CREATE TABLE items (id INTEGER PRIMARY KEY);"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	migrator, err := NewMigratorFromDir(db, dir, MigratorOptions{DisableLogging: true})
+	if err != nil {
+		t.Fatalf("NewMigratorFromDir failed: %v", err)
+	}
+	if err := migrator.Migrate(ctx); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+
+	// Badilisha file baada ya kutumika
+	if err := os.WriteFile(upFile, []byte("CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT);"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	migrator, err = NewMigratorFromDir(db, dir, MigratorOptions{DisableLogging: true})
+	if err != nil {
+		t.Fatalf("NewMigratorFromDir failed: %v", err)
+	}
+	if err := migrator.Migrate(ctx); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Expected ErrChecksumMismatch, got %v", err)
+	}
+
+	status, err := migrator.Status()
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if len(status) != 1 || !status[0].Modified {
+		t.Errorf("Expected modified migration in status, got %+v", status)
+	}
+
+	// Bila down SQL, rollback inashindwa
+	if err := migrator.Rollback(ctx, 1); err == nil {
+		t.Error("Expected rollback error")
+	}
+
+	migrator, _ = NewMigratorFromDir(db, dir, MigratorOptions{DisableLogging: true, SkipChecksum: true})
+	if err := migrator.Rollback(ctx, 1); !errors.Is(err, ErrNoDownMigration) {
+		t.Errorf("Expected ErrNoDownMigration, got %v", err)
+	}
+}
+
+// TestMigrationHelpers inatest parsing ya filenames na kugawanya statements
+func TestMigrationHelpers(t *testing.T) {
+	version, name, direction, ok := parseMigrationFilename("20240101120000_add_orders.down.sql")
+	if !ok || version != 20240101120000 || name != "add_orders" || direction != "down" {
+		t.Errorf("Unexpected parse result: %d %q %q %v", version, name, direction, ok)
+	}
+	for _, invalid := range []string{"notes.sql", "abc_init.up.sql", "1_init.sql", "1_init.up.txt"} {
+		if _, _, _, ok := parseMigrationFilename(invalid); ok {
+			t.Errorf("Expected %q to be rejected", invalid)
+		}
+	}
+
+	script := "CREATE TABLE a (v TEXT DEFAULT 'x;y'); -- comment; here\nINSERT INTO a VALUES (\"q;\");\n/* block; */ DROP TABLE b;"
+	statements := splitStatements(script)
+	if len(statements) != 3 {
+		t.Fatalf("Expected 3 statements, got %d: %q", len(statements), statements)
+	}
+	if statements[0] != "CREATE TABLE a (v TEXT DEFAULT 'x;y')" {
+		t.Errorf("Unexpected first statement: %q", statements[0])
+	}
+
+	if _, err := LoadMigrations(fstest.MapFS{"1_x.down.sql": {Data: []byte("DROP TABLE x;")}}); err == nil {
+		t.Error("Expected error for migration without up SQL")
+	}
+
+	if _, err := NewMigrator(&DB{Type: MongoDB}, fstest.MapFS{}); err == nil {
+		t.Error("Expected error for MongoDB")
+	}
+}
+
 // TestErrorHandling inatest error handling
 func TestErrorHandling(t *testing.T) {
 	// Test with nil database instance
@@ -0,0 +1,724 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Migration errors
+var (
+	ErrChecksumMismatch = errors.New("migration checksum mismatch")
+	ErrNoDownMigration  = errors.New("migration has no down SQL")
+	ErrLockTimeout      = errors.New("timed out waiting for migration lock")
+)
+
+// Migration ni migration moja yenye up na down SQL
+type Migration struct {
+	Version  int64
+	Name     string
+	UpSQL    string
+	DownSQL  string
+	Checksum string
+}
+
+// MigrationStatus inaonyesha hali ya migration moja
+type MigrationStatus struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+	Missing   bool       `json:"missing"`  // applied lakini file haipo tena
+	Modified  bool       `json:"modified"` // checksum imebadilika tangu ilipotumika
+}
+
+// MigratorOptions inabadilisha default settings za Migrator
+type MigratorOptions struct {
+	TableName      string        // default: schema_migrations
+	LockKey        int64         // advisory lock key; default inatokana na TableName
+	LockTimeout    time.Duration // MySQL GET_LOCK timeout; default 60s
+	SkipChecksum   bool          // usiangalie checksum za migrations zilizotumika
+	DisableLogging bool
+}
+
+// Migrator inaendesha schema migrations kwenye *DB
+type Migrator struct {
+	db         *DB
+	migrations []Migration
+	options    MigratorOptions
+}
+
+// appliedMigration ni row moja ya tracking table
+type appliedMigration struct {
+	version   int64
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+// NewMigrator huunda Migrator kutoka kwa fs.FS (kwa mfano embed.FS).
+// Files zinatakiwa kuitwa <version>_<name>.up.sql na <version>_<name>.down.sql.
+func NewMigrator(db *DB, source fs.FS, options ...MigratorOptions) (*Migrator, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database not connected")
+	}
+	switch db.Type {
+	case PostgreSQL, MySQL, SQLite:
+	default:
+		return nil, fmt.Errorf("migrations not supported for database type: %s", db.Type)
+	}
+
+	var opts MigratorOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if opts.TableName == "" {
+		opts.TableName = "schema_migrations"
+	}
+	if opts.LockKey == 0 {
+		opts.LockKey = int64(crc32.ChecksumIEEE([]byte("sego:" + opts.TableName)))
+	}
+	if opts.LockTimeout <= 0 {
+		opts.LockTimeout = 60 * time.Second
+	}
+
+	migrations, err := LoadMigrations(source)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+		options:    opts,
+	}, nil
+}
+
+// NewMigratorFromDir huunda Migrator kutoka kwa directory ya migrations
+func NewMigratorFromDir(db *DB, dir string, options ...MigratorOptions) (*Migrator, error) {
+	return NewMigrator(db, os.DirFS(dir), options...)
+}
+
+// LoadMigrations inasoma migrations zote kutoka kwa fs.FS na kuzipanga kwa version
+func LoadMigrations(source fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(source, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		version, name, direction, ok := parseMigrationFilename(entry.Name())
+		if !ok {
+			continue
+		}
+
+		content, err := fs.ReadFile(source, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		m, exists := byVersion[version]
+		if !exists {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("migration version %d has conflicting names: %s and %s", version, m.Name, name)
+		}
+
+		if direction == "up" {
+			if m.UpSQL != "" {
+				return nil, fmt.Errorf("duplicate up migration for version %d", version)
+			}
+			m.UpSQL = string(content)
+		} else {
+			if m.DownSQL != "" {
+				return nil, fmt.Errorf("duplicate down migration for version %d", version)
+			}
+			m.DownSQL = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.UpSQL == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up SQL", m.Version, m.Name)
+		}
+		m.Checksum = checksum(m.UpSQL)
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Migrations inarudisha migrations zilizopakiwa
+func (m *Migrator) Migrations() []Migration {
+	return append([]Migration(nil), m.migrations...)
+}
+
+// Migrate inaendesha migrations zote ambazo hazijatumika
+func (m *Migrator) Migrate(ctx context.Context) error {
+	return m.withLock(ctx, func(conn migrationConn) error {
+		applied, err := m.loadApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		if err := m.verifyChecksums(applied); err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, done := applied[migration.Version]; done {
+				continue
+			}
+
+			start := time.Now()
+			err := conn.inTx(ctx, func(exec execFunc) error {
+				if err := m.runSQL(ctx, exec, migration.UpSQL); err != nil {
+					return err
+				}
+				insert := fmt.Sprintf(
+					"INSERT INTO %s (version, name, checksum, applied_at) VALUES (%s, %s, %s, %s)",
+					m.options.TableName, m.placeholder(1), m.placeholder(2), m.placeholder(3), m.placeholder(4),
+				)
+				return exec(ctx, insert, migration.Version, migration.Name, migration.Checksum, time.Now().UTC())
+			})
+			if err != nil {
+				return fmt.Errorf("failed to apply migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+
+			m.logf("✅ Applied migration %d_%s (%s)", migration.Version, migration.Name, time.Since(start).Round(time.Millisecond))
+		}
+
+		return nil
+	})
+}
+
+// Rollback inarudisha nyuma migrations za mwisho kwa idadi ya steps
+func (m *Migrator) Rollback(ctx context.Context, steps int) error {
+	if steps < 1 {
+		return fmt.Errorf("steps must be greater than 0")
+	}
+
+	return m.withLock(ctx, func(conn migrationConn) error {
+		applied, err := m.loadApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		if err := m.verifyChecksums(applied); err != nil {
+			return err
+		}
+
+		versions := make([]int64, 0, len(applied))
+		for version := range applied {
+			versions = append(versions, version)
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+
+		if steps > len(versions) {
+			steps = len(versions)
+		}
+
+		for _, version := range versions[:steps] {
+			migration, ok := m.find(version)
+			if !ok {
+				return fmt.Errorf("cannot roll back migration %d: file not found", version)
+			}
+			if strings.TrimSpace(migration.DownSQL) == "" {
+				return fmt.Errorf("cannot roll back migration %d_%s: %w", version, migration.Name, ErrNoDownMigration)
+			}
+
+			err := conn.inTx(ctx, func(exec execFunc) error {
+				if err := m.runSQL(ctx, exec, migration.DownSQL); err != nil {
+					return err
+				}
+				del := fmt.Sprintf("DELETE FROM %s WHERE version = %s", m.options.TableName, m.placeholder(1))
+				return exec(ctx, del, version)
+			})
+			if err != nil {
+				return fmt.Errorf("failed to roll back migration %d_%s: %w", version, migration.Name, err)
+			}
+
+			m.logf("↩️  Rolled back migration %d_%s", version, migration.Name)
+		}
+
+		return nil
+	})
+}
+
+// Status inarudisha hali ya kila migration, iliyopangwa kwa version
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	conn, err := m.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.close()
+
+	if err := m.ensureTable(ctx, conn); err != nil {
+		return nil, err
+	}
+
+	applied, err := m.loadApplied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	seen := make(map[int64]bool, len(m.migrations))
+	for _, migration := range m.migrations {
+		seen[migration.Version] = true
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if row, ok := applied[migration.Version]; ok {
+			appliedAt := row.appliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+			status.Modified = row.checksum != migration.Checksum
+		}
+		statuses = append(statuses, status)
+	}
+
+	for version, row := range applied {
+		if seen[version] {
+			continue
+		}
+		appliedAt := row.appliedAt
+		statuses = append(statuses, MigrationStatus{
+			Version:   version,
+			Name:      row.name,
+			Applied:   true,
+			AppliedAt: &appliedAt,
+			Missing:   true,
+		})
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+
+	return statuses, nil
+}
+
+// ========== INTERNAL HELPERS ==========
+
+// withLock inashika connection moja na advisory lock wakati fn inaendelea
+func (m *Migrator) withLock(ctx context.Context, fn func(conn migrationConn) error) error {
+	conn, err := m.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.close()
+
+	switch m.db.Type {
+	case PostgreSQL:
+		if err := conn.exec(ctx, "SELECT pg_advisory_lock($1)", m.options.LockKey); err != nil {
+			return fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+		defer conn.exec(context.Background(), "SELECT pg_advisory_unlock($1)", m.options.LockKey)
+
+	case MySQL:
+		name := fmt.Sprintf("sego_migrations_%d", m.options.LockKey)
+		var locked sql.NullInt64
+		if err := conn.queryRow(ctx, "SELECT GET_LOCK(?, ?)", []interface{}{name, int(m.options.LockTimeout.Seconds())}, &locked); err != nil {
+			return fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+		if !locked.Valid || locked.Int64 != 1 {
+			return ErrLockTimeout
+		}
+		defer conn.exec(context.Background(), "SELECT RELEASE_LOCK(?)", name)
+	}
+
+	if err := m.ensureTable(ctx, conn); err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+// acquire inachukua connection moja kutoka kwa pool
+func (m *Migrator) acquire(ctx context.Context) (migrationConn, error) {
+	switch m.db.Type {
+	case PostgreSQL:
+		if m.db.PostgresPool == nil {
+			return nil, fmt.Errorf("PostgreSQL not connected")
+		}
+		conn, err := m.db.PostgresPool.Acquire(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to acquire connection: %w", err)
+		}
+		return &pgMigrationConn{conn: conn}, nil
+
+	case MySQL, SQLite:
+		if m.db.SQLDB == nil {
+			return nil, fmt.Errorf("SQL database not connected")
+		}
+		conn, err := m.db.SQLDB.Conn(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to acquire connection: %w", err)
+		}
+		return &sqlMigrationConn{conn: conn}, nil
+	}
+	return nil, fmt.Errorf("migrations not supported for database type: %s", m.db.Type)
+}
+
+// ensureTable inaunda tracking table kama haipo
+func (m *Migrator) ensureTable(ctx context.Context, conn migrationConn) error {
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	version BIGINT NOT NULL PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	checksum VARCHAR(64) NOT NULL,
+	applied_at TIMESTAMP NOT NULL
+)`, m.options.TableName)
+
+	if err := conn.exec(ctx, query); err != nil {
+		return fmt.Errorf("failed to create %s table: %w", m.options.TableName, err)
+	}
+	return nil
+}
+
+// loadApplied inasoma migrations zilizotumika kutoka kwa tracking table
+func (m *Migrator) loadApplied(ctx context.Context, conn migrationConn) (map[int64]appliedMigration, error) {
+	query := fmt.Sprintf("SELECT version, name, checksum, applied_at FROM %s", m.options.TableName)
+
+	applied := make(map[int64]appliedMigration)
+	err := conn.query(ctx, query, func(scan func(dest ...interface{}) error) error {
+		var row appliedMigration
+		var appliedAt interface{}
+		if err := scan(&row.version, &row.name, &row.checksum, &appliedAt); err != nil {
+			return err
+		}
+		t, err := toTime(appliedAt)
+		if err != nil {
+			return err
+		}
+		row.appliedAt = t
+		applied[row.version] = row
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+
+	return applied, nil
+}
+
+// verifyChecksums inahakikisha migrations zilizotumika hazijabadilishwa
+func (m *Migrator) verifyChecksums(applied map[int64]appliedMigration) error {
+	if m.options.SkipChecksum {
+		return nil
+	}
+
+	for _, migration := range m.migrations {
+		row, ok := applied[migration.Version]
+		if ok && row.checksum != migration.Checksum {
+			return fmt.Errorf("%w: %d_%s", ErrChecksumMismatch, migration.Version, migration.Name)
+		}
+	}
+	return nil
+}
+
+// runSQL inaendesha SQL ya migration. MySQL haikubali statements nyingi kwa Exec moja.
+func (m *Migrator) runSQL(ctx context.Context, exec execFunc, script string) error {
+	if m.db.Type != MySQL {
+		return exec(ctx, script)
+	}
+
+	for _, stmt := range splitStatements(script) {
+		if err := exec(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// find inatafuta migration kwa version
+func (m *Migrator) find(version int64) (Migration, bool) {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration, true
+		}
+	}
+	return Migration{}, false
+}
+
+// placeholder inarudisha bind parameter kulingana na database type
+func (m *Migrator) placeholder(n int) string {
+	if m.db.Type == PostgreSQL {
+		return fmt.Sprintf("$%d", n)
+	}
+	return "?"
+}
+
+func (m *Migrator) logf(format string, args ...interface{}) {
+	if !m.options.DisableLogging {
+		log.Printf(format, args...)
+	}
+}
+
+// parseMigrationFilename inasoma version, name na direction kutoka kwa jina la file
+func parseMigrationFilename(filename string) (int64, string, string, bool) {
+	if path.Ext(filename) != ".sql" {
+		return 0, "", "", false
+	}
+	base := strings.TrimSuffix(filename, ".sql")
+
+	var direction string
+	switch {
+	case strings.HasSuffix(base, ".up"):
+		direction = "up"
+	case strings.HasSuffix(base, ".down"):
+		direction = "down"
+	default:
+		return 0, "", "", false
+	}
+	base = strings.TrimSuffix(base, "."+direction)
+
+	versionPart, name, _ := strings.Cut(base, "_")
+	version, err := strconv.ParseInt(versionPart, 10, 64)
+	if err != nil || version < 0 {
+		return 0, "", "", false
+	}
+
+	return version, name, direction, true
+}
+
+// splitStatements inagawanya script kwa ';' bila kuvunja strings au comments
+func splitStatements(script string) []string {
+	var statements []string
+	var current strings.Builder
+	var quote rune
+	lineComment, blockComment := false, false
+
+	runes := []rune(script)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		next := rune(0)
+		if i+1 < len(runes) {
+			next = runes[i+1]
+		}
+
+		switch {
+		case lineComment:
+			if r == '\n' {
+				lineComment = false
+			}
+		case blockComment:
+			if r == '*' && next == '/' {
+				blockComment = false
+				current.WriteRune(r)
+				r = next
+				i++
+			}
+		case quote != 0:
+			if r == '\\' && next != 0 {
+				current.WriteRune(r)
+				r = next
+				i++
+			} else if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == '-' && next == '-', r == '#':
+			lineComment = true
+			continue
+		case r == '/' && next == '*':
+			blockComment = true
+		case r == ';':
+			if stmt := strings.TrimSpace(current.String()); stmt != "" {
+				statements = append(statements, stmt)
+			}
+			current.Reset()
+			continue
+		}
+
+		if !lineComment {
+			current.WriteRune(r)
+		}
+	}
+
+	if stmt := strings.TrimSpace(current.String()); stmt != "" {
+		statements = append(statements, stmt)
+	}
+
+	return statements
+}
+
+// checksum inarudisha SHA-256 ya SQL
+func checksum(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// toTime inabadilisha applied_at kuwa time.Time kwa drivers zote
+func toTime(value interface{}) (time.Time, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case []byte:
+		return parseTimeString(string(v))
+	case string:
+		return parseTimeString(v)
+	case nil:
+		return time.Time{}, nil
+	}
+	return time.Time{}, fmt.Errorf("unsupported applied_at type %T", value)
+}
+
+func parseTimeString(s string) (time.Time, error) {
+	layouts := []string{
+		time.RFC3339Nano,
+		"2006-01-02 15:04:05.999999999-07:00",
+		"2006-01-02 15:04:05.999999999 -0700 MST",
+		"2006-01-02 15:04:05.999999999",
+		"2006-01-02 15:04:05",
+	}
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("cannot parse applied_at %q", s)
+}
+
+// ========== CONNECTIONS ==========
+
+// execFunc inaendesha statement moja
+type execFunc func(ctx context.Context, query string, args ...interface{}) error
+
+// migrationConn ni connection moja inayotumika na Migrator
+type migrationConn interface {
+	exec(ctx context.Context, query string, args ...interface{}) error
+	query(ctx context.Context, query string, fn func(scan func(dest ...interface{}) error) error) error
+	queryRow(ctx context.Context, query string, args []interface{}, dest ...interface{}) error
+	inTx(ctx context.Context, fn func(exec execFunc) error) error
+	close()
+}
+
+// pgMigrationConn inatumia connection ya pgxpool
+type pgMigrationConn struct {
+	conn *pgxpool.Conn
+}
+
+func (c *pgMigrationConn) exec(ctx context.Context, query string, args ...interface{}) error {
+	_, err := c.conn.Exec(ctx, query, args...)
+	return err
+}
+
+func (c *pgMigrationConn) query(ctx context.Context, query string, fn func(scan func(dest ...interface{}) error) error) error {
+	rows, err := c.conn.Query(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := fn(rows.Scan); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (c *pgMigrationConn) queryRow(ctx context.Context, query string, args []interface{}, dest ...interface{}) error {
+	return c.conn.QueryRow(ctx, query, args...).Scan(dest...)
+}
+
+func (c *pgMigrationConn) inTx(ctx context.Context, fn func(exec execFunc) error) error {
+	tx, err := c.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = fn(func(ctx context.Context, query string, args ...interface{}) error {
+		_, err := tx.Exec(ctx, query, args...)
+		return err
+	})
+	if err != nil {
+		tx.Rollback(ctx)
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (c *pgMigrationConn) close() {
+	c.conn.Release()
+}
+
+// sqlMigrationConn inatumia *sql.Conn kwa MySQL na SQLite
+type sqlMigrationConn struct {
+	conn *sql.Conn
+}
+
+func (c *sqlMigrationConn) exec(ctx context.Context, query string, args ...interface{}) error {
+	_, err := c.conn.ExecContext(ctx, query, args...)
+	return err
+}
+
+func (c *sqlMigrationConn) query(ctx context.Context, query string, fn func(scan func(dest ...interface{}) error) error) error {
+	rows, err := c.conn.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := fn(rows.Scan); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (c *sqlMigrationConn) queryRow(ctx context.Context, query string, args []interface{}, dest ...interface{}) error {
+	return c.conn.QueryRowContext(ctx, query, args...).Scan(dest...)
+}
+
+func (c *sqlMigrationConn) inTx(ctx context.Context, fn func(exec execFunc) error) error {
+	tx, err := c.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	err = fn(func(ctx context.Context, query string, args ...interface{}) error {
+		_, err := tx.ExecContext(ctx, query, args...)
+		return err
+	})
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (c *sqlMigrationConn) close() {
+	c.conn.Close()
+}
@@ -16,6 +16,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/selanim/sego/redact"
 )
 
 // ErrorLevel defines the severity level of an error
@@ -98,6 +100,10 @@ type ErrorOptions struct {
 	Cause       error
 }
 
+// Redactor masks secrets in messages and metadata when errors are serialized;
+// set to nil to disable redaction
+var Redactor = redact.Default
+
 // DefaultOptions provides default error options
 var DefaultOptions = ErrorOptions{
 	Level:      Error,
@@ -162,26 +168,28 @@ func (e *EnhancedError) String() string {
 	e.mu.RLock()
 	defer e.mu.RUnlock()
 
+	info := redactedInfo(e.info)
+
 	var buf bytes.Buffer
-	buf.WriteString(fmt.Sprintf("[%s] %s", e.info.Level, e.info.Message))
+	buf.WriteString(fmt.Sprintf("[%s] %s", info.Level, info.Message))
 
-	if e.info.Code != "" {
-		buf.WriteString(fmt.Sprintf(" (code: %s)", e.info.Code))
+	if info.Code != "" {
+		buf.WriteString(fmt.Sprintf(" (code: %s)", info.Code))
 	}
 
-	if e.info.Type != Unknown {
-		buf.WriteString(fmt.Sprintf(" type: %s", e.info.Type))
+	if info.Type != Unknown {
+		buf.WriteString(fmt.Sprintf(" type: %s", info.Type))
 	}
 
-	if len(e.info.Metadata) > 0 {
+	if len(info.Metadata) > 0 {
 		buf.WriteString(" metadata:")
-		for k, v := range e.info.Metadata {
+		for k, v := range info.Metadata {
 			buf.WriteString(fmt.Sprintf(" %s=%v", k, v))
 		}
 	}
 
-	if e.info.Cause != nil {
-		buf.WriteString(fmt.Sprintf("\nCaused by: %v", e.info.Cause))
+	if info.Cause != nil {
+		buf.WriteString(fmt.Sprintf("\nCaused by: %v", info.Cause))
 	}
 
 	return buf.String()
@@ -196,7 +204,20 @@ func (e *EnhancedError) JSON() ([]byte, error) {
 	e.mu.RLock()
 	defer e.mu.RUnlock()
 
-	return json.Marshal(e.info)
+	return json.Marshal(redactedInfo(e.info))
+}
+
+// redactedInfo returns a copy of info with secrets masked by Redactor
+func redactedInfo(info *ErrorInfo) *ErrorInfo {
+	if Redactor == nil || info == nil {
+		return info
+	}
+
+	redacted := *info
+	redacted.Message = Redactor.String(info.Message)
+	redacted.Metadata = Redactor.Map(info.Metadata)
+	redacted.Cause = redactedInfo(info.Cause)
+	return &redacted
 }
 
 // PrettyJSON returns indented JSON representation
@@ -208,7 +229,7 @@ func (e *EnhancedError) PrettyJSON() ([]byte, error) {
 	e.mu.RLock()
 	defer e.mu.RUnlock()
 
-	return json.MarshalIndent(e.info, "", "  ")
+	return json.MarshalIndent(redactedInfo(e.info), "", "  ")
 }
 
 // HasMetadata checks if the error has specific metadata
@@ -909,7 +930,8 @@ func PrettyPrint(err error, w io.Writer) {
 		return
 	}
 
-	info := ErrorInfoFromError(err)
+	errInfo := ErrorInfoFromError(err)
+	info := redactedInfo(&errInfo)
 	fmt.Fprintf(w, "Error: %s\n", info.Message)
 
 	if info.Code != "" {
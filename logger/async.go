@@ -0,0 +1,426 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// OverflowPolicy decides what AsyncLogger does when its buffer is full
+type OverflowPolicy int
+
+const (
+	// OverflowBlock waits for space in the buffer
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest discards the entry being logged
+	OverflowDropNewest
+	// OverflowDropOldest discards the oldest buffered entry to make room
+	OverflowDropOldest
+	// OverflowSync writes the entry synchronously, bypassing the buffer
+	OverflowSync
+)
+
+// String returns string representation of the policy
+func (p OverflowPolicy) String() string {
+	switch p {
+	case OverflowBlock:
+		return "block"
+	case OverflowDropNewest:
+		return "drop-newest"
+	case OverflowDropOldest:
+		return "drop-oldest"
+	case OverflowSync:
+		return "sync"
+	default:
+		return "unknown"
+	}
+}
+
+// AsyncOptions configures an AsyncLogger
+type AsyncOptions struct {
+	// Policy applied when the buffer is full; defaults to OverflowBlock
+	Policy OverflowPolicy
+	// BatchSize is the maximum number of entries per write to the output; defaults to 64
+	BatchSize int
+}
+
+// AsyncStats counts entries handled by an AsyncLogger
+type AsyncStats struct {
+	Enqueued    uint64
+	Written     uint64
+	Dropped     uint64
+	Synchronous uint64
+}
+
+// AsyncLogger provides asynchronous logging. Entries are formatted by a single
+// worker and written to the output in batches.
+type AsyncLogger struct {
+	logger   *Logger
+	pipeline *asyncPipeline
+}
+
+// asyncPipeline is shared by an AsyncLogger and the loggers derived with WithField
+type asyncPipeline struct {
+	queue     chan *asyncEntry
+	policy    OverflowPolicy
+	batchSize int
+	done      chan struct{}
+
+	sendMu sync.Mutex    // orders sequence numbers with queue sends
+	closed bool          // guarded by sendMu
+	seq    atomic.Uint64 // sequence number of the last queued entry
+
+	mu       sync.Mutex
+	written  uint64        // sequence number of the last handled entry
+	progress chan struct{} // closed and replaced whenever written advances
+
+	enqueued    atomic.Uint64
+	writes      atomic.Uint64
+	dropped     atomic.Uint64
+	synchronous atomic.Uint64
+}
+
+// asyncEntry is a buffered log entry with the logger that produced it
+type asyncEntry struct {
+	seq    uint64
+	logger *Logger
+	entry  Entry
+}
+
+// NewAsyncLogger creates a new asynchronous logger with a buffer of bufferSize entries
+func NewAsyncLogger(logger *Logger, bufferSize int, opts ...AsyncOptions) *AsyncLogger {
+	var opt AsyncOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	if bufferSize <= 0 {
+		bufferSize = 1
+	}
+	if opt.BatchSize <= 0 {
+		opt.BatchSize = 64
+	}
+
+	p := &asyncPipeline{
+		queue:     make(chan *asyncEntry, bufferSize),
+		policy:    opt.Policy,
+		batchSize: opt.BatchSize,
+		done:      make(chan struct{}),
+		progress:  make(chan struct{}),
+	}
+	go p.worker()
+
+	return &AsyncLogger{logger: logger, pipeline: p}
+}
+
+// Log logs asynchronously
+func (al *AsyncLogger) Log(level Level, args ...interface{}) {
+	if !al.logger.Enabled(level) {
+		return
+	}
+	al.enqueue(level, fmt.Sprint(args...))
+}
+
+// Logf logs formatted message asynchronously
+func (al *AsyncLogger) Logf(level Level, format string, args ...interface{}) {
+	if !al.logger.Enabled(level) {
+		return
+	}
+	al.enqueue(level, fmt.Sprintf(format, args...))
+}
+
+// Debug logs a debug message asynchronously
+func (al *AsyncLogger) Debug(args ...interface{}) {
+	al.Log(DEBUG, args...)
+}
+
+// Debugf logs a formatted debug message asynchronously
+func (al *AsyncLogger) Debugf(format string, args ...interface{}) {
+	al.Logf(DEBUG, format, args...)
+}
+
+// Info logs an info message asynchronously
+func (al *AsyncLogger) Info(args ...interface{}) {
+	al.Log(INFO, args...)
+}
+
+// Infof logs a formatted info message asynchronously
+func (al *AsyncLogger) Infof(format string, args ...interface{}) {
+	al.Logf(INFO, format, args...)
+}
+
+// Warn logs a warning message asynchronously
+func (al *AsyncLogger) Warn(args ...interface{}) {
+	al.Log(WARN, args...)
+}
+
+// Warnf logs a formatted warning message asynchronously
+func (al *AsyncLogger) Warnf(format string, args ...interface{}) {
+	al.Logf(WARN, format, args...)
+}
+
+// Error logs an error message asynchronously
+func (al *AsyncLogger) Error(args ...interface{}) {
+	al.Log(ERROR, args...)
+}
+
+// Errorf logs a formatted error message asynchronously
+func (al *AsyncLogger) Errorf(format string, args ...interface{}) {
+	al.Logf(ERROR, format, args...)
+}
+
+// WithField adds a field for async logging
+func (al *AsyncLogger) WithField(key string, value interface{}) *AsyncLogger {
+	return &AsyncLogger{logger: al.logger.WithField(key, value), pipeline: al.pipeline}
+}
+
+// WithFields adds multiple fields for async logging
+func (al *AsyncLogger) WithFields(fields map[string]interface{}) *AsyncLogger {
+	return &AsyncLogger{logger: al.logger.WithFields(fields), pipeline: al.pipeline}
+}
+
+// enqueue builds the entry and hands it to the worker according to the overflow policy
+func (al *AsyncLogger) enqueue(level Level, msg string) {
+	l := al.logger
+	if l.throttle != nil && !l.throttle.allow(l, level, msg) {
+		return
+	}
+
+	e := &asyncEntry{
+		logger: l,
+		entry:  Entry{Time: time.Now(), Level: level, Message: msg, Prefix: l.prefix},
+	}
+	if l.config.WithCaller {
+		e.entry.Caller = getCaller()
+	}
+
+	p := al.pipeline
+	p.sendMu.Lock()
+	if p.closed {
+		p.sendMu.Unlock()
+		p.writeSync(e)
+		return
+	}
+
+	// Sequence numbers are only used by queued entries, in queue order
+	e.seq = p.seq.Load() + 1
+
+	select {
+	case p.queue <- e:
+		p.seq.Store(e.seq)
+		p.sendMu.Unlock()
+		p.enqueued.Add(1)
+		return
+	default:
+	}
+
+	switch p.policy {
+	case OverflowDropNewest:
+		p.sendMu.Unlock()
+		p.dropped.Add(1)
+	case OverflowSync:
+		p.sendMu.Unlock()
+		p.writeSync(e)
+	case OverflowDropOldest:
+		select {
+		case <-p.queue:
+			p.dropped.Add(1)
+		default:
+		}
+		p.queue <- e
+		p.seq.Store(e.seq)
+		p.sendMu.Unlock()
+		p.enqueued.Add(1)
+	default:
+		p.queue <- e
+		p.seq.Store(e.seq)
+		p.sendMu.Unlock()
+		p.enqueued.Add(1)
+	}
+}
+
+// Flush waits until every entry logged before the call has been written
+func (al *AsyncLogger) Flush(ctx context.Context) error {
+	p := al.pipeline
+	target := p.seq.Load()
+	for {
+		p.mu.Lock()
+		written, progress := p.written, p.progress
+		p.mu.Unlock()
+		if written >= target {
+			return nil
+		}
+
+		select {
+		case <-progress:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Close stops accepting entries and waits until buffered entries are written.
+// Entries logged after Close are written synchronously.
+func (al *AsyncLogger) Close(ctx context.Context) error {
+	p := al.pipeline
+	p.sendMu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.queue)
+	}
+	p.sendMu.Unlock()
+
+	select {
+	case <-p.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown waits for all logs to be processed
+func (al *AsyncLogger) Shutdown() {
+	al.Close(context.Background())
+}
+
+// Dropped returns the number of entries discarded because the buffer was full
+func (al *AsyncLogger) Dropped() uint64 {
+	return al.pipeline.dropped.Load()
+}
+
+// Stats returns counters for the async pipeline
+func (al *AsyncLogger) Stats() AsyncStats {
+	p := al.pipeline
+	return AsyncStats{
+		Enqueued:    p.enqueued.Load(),
+		Written:     p.writes.Load(),
+		Dropped:     p.dropped.Load(),
+		Synchronous: p.synchronous.Load(),
+	}
+}
+
+// ========== WORKER ==========
+
+// worker formats queued entries and writes them in batches
+func (p *asyncPipeline) worker() {
+	defer close(p.done)
+
+	var buf bytes.Buffer
+	batch := make([]*asyncEntry, 0, p.batchSize)
+
+	for e := range p.queue {
+		batch = append(batch[:0], e)
+		// Take whatever is already buffered, up to the batch size
+	fill:
+		for len(batch) < p.batchSize {
+			select {
+			case next, ok := <-p.queue:
+				if !ok {
+					break fill
+				}
+				batch = append(batch, next)
+			default:
+				break fill
+			}
+		}
+
+		p.writeBatch(&buf, batch)
+		p.markHandled(batch[len(batch)-1].seq)
+	}
+}
+
+// writeBatch formats the entries and writes consecutive entries with the same
+// output in a single Write call. LevelWriters get one WriteLevel call per entry.
+func (p *asyncPipeline) writeBatch(buf *bytes.Buffer, batch []*asyncEntry) {
+	var out io.Writer
+	var owner *Logger
+
+	flush := func() {
+		if buf.Len() == 0 {
+			return
+		}
+		owner.mu.Lock()
+		if _, err := out.Write(buf.Bytes()); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write log: %v\n", err)
+		}
+		owner.mu.Unlock()
+		buf.Reset()
+	}
+
+	for _, e := range batch {
+		l := e.logger
+		if l.handler != nil {
+			flush()
+			l.handleEntry(&e.entry)
+			p.writes.Add(1)
+			continue
+		}
+
+		if out != l.output {
+			flush()
+			out, owner = l.output, l
+		}
+
+		entry := e.entry
+		entry.Fields = l.fields
+		l.redactEntry(&entry)
+		formatted, err := l.formatter.Format(&entry)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to format log entry: %v\n", err)
+			continue
+		}
+		if _, ok := out.(LevelWriter); ok {
+			l.mu.Lock()
+			if _, err := writeOutput(out, entry.Level, formatted); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to write log: %v\n", err)
+			}
+			l.mu.Unlock()
+		} else {
+			buf.Write(formatted)
+		}
+		p.writes.Add(1)
+	}
+	flush()
+}
+
+// writeSync writes an entry on the caller's goroutine
+func (p *asyncPipeline) writeSync(e *asyncEntry) {
+	p.synchronous.Add(1)
+	if e.logger.handler != nil {
+		e.logger.handleEntry(&e.entry)
+		return
+	}
+	entry := e.entry
+	entry.Fields = e.logger.fields
+	e.logger.write(&entry)
+}
+
+// markHandled advances the written sequence number and wakes Flush callers
+func (p *asyncPipeline) markHandled(seq uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if seq <= p.written {
+		return
+	}
+	p.written = seq
+	close(p.progress)
+	p.progress = make(chan struct{})
+}
+
+// handleEntry sends a prepared entry to l.handler
+func (l *Logger) handleEntry(entry *Entry) {
+	ctx := l.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	level := entry.Level.SlogLevel()
+	if !l.handler.Enabled(ctx, level) {
+		return
+	}
+	l.handleRecord(ctx, slog.NewRecord(entry.Time, level, entry.Message, 0))
+}
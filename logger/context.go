@@ -2,8 +2,8 @@ package logger
 
 import (
 	"context"
-	"fmt"
-	"sync"
+
+	"github.com/selanim/sego/tracing"
 )
 
 // contextKey is a custom type for context keys
@@ -61,15 +61,50 @@ func getFieldsFromContext(ctx context.Context) map[string]interface{} {
 	return make(map[string]interface{})
 }
 
-// ContextLogger creates a logger with context fields
+// ContextLogger creates a logger with context fields and the trace_id and
+// span_id of the current span, if any
 func ContextLogger(ctx context.Context) *Logger {
 	logger := FromContext(ctx)
 	fields := getFieldsFromContext(ctx)
+	addTraceFields(ctx, fields)
+	return logger.withContextFields(ctx, fields)
+}
+
+// WithContext returns a logger with the trace_id and span_id of the current span in ctx.
+// Loggers built with NewFromSlog also pass ctx to their handler.
+func (l *Logger) WithContext(ctx context.Context) *Logger {
+	fields := make(map[string]interface{}, 2)
+	addTraceFields(ctx, fields)
+	return l.withContextFields(ctx, fields)
+}
+
+// withContextFields returns a logger with fields added and ctx kept for slog handlers
+func (l *Logger) withContextFields(ctx context.Context, fields map[string]interface{}) *Logger {
+	if len(fields) == 0 && l.handler == nil {
+		return l
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	newLogger := l.clone()
+	for k, v := range fields {
+		newLogger.fields[k] = v
+	}
+	if l.handler != nil {
+		newLogger.ctx = ctx
+	}
+	return newLogger
+}
 
-	if len(fields) > 0 {
-		return logger.WithFields(fields)
+// addTraceFields adds trace correlation fields from ctx
+func addTraceFields(ctx context.Context, fields map[string]interface{}) {
+	sc := tracing.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return
 	}
-	return logger
+	fields["trace_id"] = sc.TraceID.String()
+	fields["span_id"] = sc.SpanID.String()
 }
 
 // CtxDebug logs debug message with context
@@ -117,81 +152,3 @@ func RequestLogger(ctx context.Context, requestID string) context.Context {
 	logger := New().WithField("request_id", requestID)
 	return WithLogger(ctx, logger)
 }
-
-// AsyncLogger provides asynchronous logging
-type AsyncLogger struct {
-	logger *Logger
-	queue  chan *logEntry
-	wg     *sync.WaitGroup
-}
-
-func (al *AsyncLogger) Infof(s string, i int) {
-	panic("unimplemented")
-}
-
-type logEntry struct {
-	level   Level
-	message string
-	fields  map[string]interface{}
-}
-
-// NewAsyncLogger creates a new asynchronous logger
-func NewAsyncLogger(logger *Logger, bufferSize int) *AsyncLogger {
-	asyncLogger := &AsyncLogger{
-		logger: logger,
-		queue:  make(chan *logEntry, bufferSize),
-	}
-
-	// Start worker
-	asyncLogger.wg.Add(1)
-	go asyncLogger.worker()
-
-	return asyncLogger
-}
-
-// worker processes log entries asynchronously
-func (al *AsyncLogger) worker() {
-	defer al.wg.Done()
-
-	for entry := range al.queue {
-		logger := al.logger
-		if len(entry.fields) > 0 {
-			logger = logger.WithFields(entry.fields)
-		}
-		logger.log(entry.level, entry.message)
-	}
-}
-
-// Log logs asynchronously
-func (al *AsyncLogger) Log(level Level, args ...interface{}) {
-	al.queue <- &logEntry{
-		level:   level,
-		message: fmt.Sprint(args...),
-		fields:  make(map[string]interface{}),
-	}
-}
-
-// Logf logs formatted message asynchronously
-func (al *AsyncLogger) Logf(level Level, format string, args ...interface{}) {
-	al.queue <- &logEntry{
-		level:   level,
-		message: fmt.Sprintf(format, args...),
-		fields:  make(map[string]interface{}),
-	}
-}
-
-// WithField adds a field for async logging
-func (al *AsyncLogger) WithField(key string, value interface{}) *AsyncLogger {
-	// This creates a wrapper that adds fields to each log entry
-	return &AsyncLogger{
-		logger: al.logger.WithField(key, value),
-		queue:  al.queue,
-		wg:     al.wg,
-	}
-}
-
-// Shutdown waits for all logs to be processed
-func (al *AsyncLogger) Shutdown() {
-	close(al.queue)
-	al.wg.Wait()
-}
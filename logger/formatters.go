@@ -3,8 +3,15 @@ package logger
 import (
 	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/selanim/sego/tracing"
 )
 
 // TextFormatter formats logs as plain text
@@ -79,20 +86,64 @@ func (f *TextFormatter) colorizeLevel(level string, lvl Level) string {
 	}
 }
 
+// FieldKeys names the standard keys written by JSONFormatter and LogfmtFormatter.
+// Empty names fall back to the formatter's defaults.
+type FieldKeys struct {
+	Time    string
+	Level   string
+	Message string
+	Prefix  string
+	Caller  string
+}
+
+// withDefaults fills empty keys from defaults
+func (k FieldKeys) withDefaults(defaults FieldKeys) FieldKeys {
+	if k.Time == "" {
+		k.Time = defaults.Time
+	}
+	if k.Level == "" {
+		k.Level = defaults.Level
+	}
+	if k.Message == "" {
+		k.Message = defaults.Message
+	}
+	if k.Prefix == "" {
+		k.Prefix = defaults.Prefix
+	}
+	if k.Caller == "" {
+		k.Caller = defaults.Caller
+	}
+	return k
+}
+
 // JSONFormatter formats logs as JSON
-type JSONFormatter struct{}
+type JSONFormatter struct {
+	// Keys overrides the names of the standard keys
+	Keys FieldKeys
+	// TimeFormat defaults to time.RFC3339
+	TimeFormat string
+}
+
+// defaultJSONKeys are the keys written by JSONFormatter
+var defaultJSONKeys = FieldKeys{Time: "timestamp", Level: "level", Message: "message", Prefix: "prefix", Caller: "caller"}
 
 // Format formats a log entry as JSON
 func (f *JSONFormatter) Format(entry *Entry) ([]byte, error) {
+	keys := f.Keys.withDefaults(defaultJSONKeys)
+	timeFormat := f.TimeFormat
+	if timeFormat == "" {
+		timeFormat = time.RFC3339
+	}
+
 	logData := map[string]interface{}{
-		"timestamp": entry.Time.Format(time.RFC3339),
-		"level":     entry.Level.String(),
-		"message":   entry.Message,
+		keys.Time:    entry.Time.Format(timeFormat),
+		keys.Level:   entry.Level.String(),
+		keys.Message: entry.Message,
 	}
 
 	// Add prefix if exists
 	if entry.Prefix != "" {
-		logData["prefix"] = entry.Prefix
+		logData[keys.Prefix] = entry.Prefix
 	}
 
 	// Add fields
@@ -102,7 +153,7 @@ func (f *JSONFormatter) Format(entry *Entry) ([]byte, error) {
 
 	// Add caller info if available
 	if entry.Caller != nil {
-		logData["caller"] = map[string]interface{}{
+		logData[keys.Caller] = map[string]interface{}{
 			"file":     entry.Caller.File,
 			"line":     entry.Caller.Line,
 			"function": entry.Caller.Function,
@@ -111,3 +162,348 @@ func (f *JSONFormatter) Format(entry *Entry) ([]byte, error) {
 
 	return json.Marshal(logData)
 }
+
+// ========== LOGFMT ==========
+
+// LogfmtFormatter formats logs as logfmt key=value pairs, e.g.
+// time=2024-01-02T15:04:05Z level=info msg="user created" user_id=42
+type LogfmtFormatter struct {
+	// Keys overrides the names of the standard keys
+	Keys FieldKeys
+	// TimeFormat defaults to time.RFC3339
+	TimeFormat string
+	// UppercaseLevel writes INFO instead of info
+	UppercaseLevel bool
+}
+
+// defaultLogfmtKeys are the keys written by LogfmtFormatter
+var defaultLogfmtKeys = FieldKeys{Time: "time", Level: "level", Message: "msg", Prefix: "prefix", Caller: "caller"}
+
+// Format formats a log entry as a logfmt line
+func (f *LogfmtFormatter) Format(entry *Entry) ([]byte, error) {
+	keys := f.Keys.withDefaults(defaultLogfmtKeys)
+	timeFormat := f.TimeFormat
+	if timeFormat == "" {
+		timeFormat = time.RFC3339
+	}
+
+	level := entry.Level.String()
+	if !f.UppercaseLevel {
+		level = strings.ToLower(level)
+	}
+
+	var buf strings.Builder
+	writeLogfmtPair(&buf, keys.Time, entry.Time.Format(timeFormat))
+	writeLogfmtPair(&buf, keys.Level, level)
+	if entry.Prefix != "" {
+		writeLogfmtPair(&buf, keys.Prefix, entry.Prefix)
+	}
+	writeLogfmtPair(&buf, keys.Message, entry.Message)
+	if entry.Caller != nil {
+		writeLogfmtPair(&buf, keys.Caller, fmt.Sprintf("%s:%d", filepath.Base(entry.Caller.File), entry.Caller.Line))
+	}
+	for _, k := range sortedFieldKeys(entry.Fields) {
+		writeLogfmtPair(&buf, k, formatFieldValue(entry.Fields[k]))
+	}
+
+	buf.WriteString("\n")
+	return []byte(buf.String()), nil
+}
+
+// writeLogfmtPair writes key=value, quoting the value when needed
+func writeLogfmtPair(buf *strings.Builder, key, value string) {
+	if buf.Len() > 0 {
+		buf.WriteByte(' ')
+	}
+	buf.WriteString(logfmtKey(key))
+	buf.WriteByte('=')
+	if value == "" || strings.ContainsAny(value, " =\"\\") || strings.IndexFunc(value, func(r rune) bool { return r < ' ' || r == 0x7f }) >= 0 {
+		buf.WriteString(strconv.Quote(value))
+		return
+	}
+	buf.WriteString(value)
+}
+
+// logfmtKey replaces characters that are not allowed in logfmt keys
+func logfmtKey(key string) string {
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' || r == 0x7f {
+			return '_'
+		}
+		return r
+	}, key)
+}
+
+// ========== ECS ==========
+
+// ecsVersion is the Elastic Common Schema version the ECSFormatter follows
+const ecsVersion = "8.11.0"
+
+// ECSFormatter formats logs as Elastic Common Schema JSON
+type ECSFormatter struct {
+	// ServiceName sets service.name
+	ServiceName string
+	// TimeFormat defaults to RFC 3339 with milliseconds in UTC
+	TimeFormat string
+}
+
+// Format formats a log entry as an ECS document
+func (f *ECSFormatter) Format(entry *Entry) ([]byte, error) {
+	timeFormat := f.TimeFormat
+	if timeFormat == "" {
+		timeFormat = "2006-01-02T15:04:05.000Z07:00"
+	}
+
+	doc := make(map[string]interface{}, len(entry.Fields)+8)
+	for k, v := range entry.Fields {
+		doc[k] = v
+	}
+
+	doc["@timestamp"] = entry.Time.UTC().Format(timeFormat)
+	doc["log.level"] = strings.ToLower(entry.Level.String())
+	doc["message"] = entry.Message
+	doc["ecs.version"] = ecsVersion
+	if entry.Prefix != "" {
+		doc["log.logger"] = entry.Prefix
+	}
+	if f.ServiceName != "" {
+		doc["service.name"] = f.ServiceName
+	}
+	if entry.Caller != nil {
+		doc["log.origin.file.name"] = entry.Caller.File
+		doc["log.origin.file.line"] = entry.Caller.Line
+		doc["log.origin.function"] = entry.Caller.Function
+	}
+
+	// Map well-known fields to their ECS names
+	renameField(doc, "error", "error.message")
+	renameField(doc, "trace_id", "trace.id")
+	renameField(doc, "span_id", "span.id")
+	if err, ok := doc["error.message"].(error); ok {
+		doc["error.message"] = err.Error()
+	}
+
+	return appendNewline(json.Marshal(doc))
+}
+
+// ========== GELF ==========
+
+// GELFFormatter formats logs as GELF 1.1 JSON for Graylog
+type GELFFormatter struct {
+	// Host sets the host field; defaults to the machine's hostname
+	Host string
+}
+
+// hostname is the machine's hostname, looked up once
+var hostname = sync.OnceValue(func() string {
+	host, _ := os.Hostname()
+	return host
+})
+
+// Format formats a log entry as a GELF message
+func (f *GELFFormatter) Format(entry *Entry) ([]byte, error) {
+	host := f.Host
+	if host == "" {
+		host = hostname()
+	}
+
+	msg := make(map[string]interface{}, len(entry.Fields)+8)
+	for k, v := range entry.Fields {
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		msg[gelfKey(k)] = v
+	}
+
+	msg["version"] = "1.1"
+	msg["host"] = host
+	msg["short_message"] = entry.Message
+	msg["timestamp"] = float64(entry.Time.UnixNano()/int64(time.Millisecond)) / 1000
+	msg["level"] = syslogSeverity(entry.Level)
+	msg["_level_name"] = entry.Level.String()
+	if entry.Prefix != "" {
+		msg["_logger"] = entry.Prefix
+	}
+	if entry.Caller != nil {
+		msg["_file"] = entry.Caller.File
+		msg["_line"] = entry.Caller.Line
+		msg["_function"] = entry.Caller.Function
+	}
+
+	return appendNewline(json.Marshal(msg))
+}
+
+// gelfKey prefixes an additional field with an underscore and replaces
+// characters GELF does not allow; _id is reserved
+func gelfKey(key string) string {
+	key = strings.Map(func(r rune) rune {
+		if r == '_' || r == '.' || r == '-' || (r >= '0' && r <= '9') || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') {
+			return r
+		}
+		return '_'
+	}, key)
+	if key == "id" {
+		key = "id_"
+	}
+	return "_" + key
+}
+
+// syslogSeverity maps a level to its syslog severity
+func syslogSeverity(level Level) int {
+	switch level {
+	case DEBUG:
+		return 7
+	case INFO:
+		return 6
+	case WARN:
+		return 4
+	case ERROR:
+		return 3
+	case FATAL:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// ========== OTLP ==========
+
+// OTLPFormatter formats logs as OTLP/JSON log records, as sent to an
+// OpenTelemetry collector's /v1/logs endpoint
+type OTLPFormatter struct {
+	// ServiceName sets the service.name resource attribute in the envelope
+	ServiceName string
+	// Envelope wraps each record in an ExportLogsServiceRequest
+	Envelope bool
+}
+
+// Format formats a log entry as an OTLP log record
+func (f *OTLPFormatter) Format(entry *Entry) ([]byte, error) {
+	record := otlpLogRecord(entry)
+	if !f.Envelope {
+		return appendNewline(json.Marshal(record))
+	}
+
+	var resourceAttrs []interface{}
+	if f.ServiceName != "" {
+		resourceAttrs = tracing.OTLPAttributes(map[string]interface{}{"service.name": f.ServiceName})
+	}
+	request := map[string]interface{}{
+		"resourceLogs": []interface{}{map[string]interface{}{
+			"resource": map[string]interface{}{"attributes": resourceAttrs},
+			"scopeLogs": []interface{}{map[string]interface{}{
+				"scope":      map[string]interface{}{"name": "github.com/selanim/sego/logger"},
+				"logRecords": []interface{}{record},
+			}},
+		}},
+	}
+	return appendNewline(json.Marshal(request))
+}
+
+// otlpLogRecord builds an OTLP LogRecord; trace_id and span_id fields become
+// the record's trace context
+func otlpLogRecord(entry *Entry) map[string]interface{} {
+	attrs := make(map[string]interface{}, len(entry.Fields)+4)
+	for k, v := range entry.Fields {
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		attrs[k] = v
+	}
+
+	record := map[string]interface{}{
+		"timeUnixNano":         strconv.FormatInt(entry.Time.UnixNano(), 10),
+		"observedTimeUnixNano": strconv.FormatInt(time.Now().UnixNano(), 10),
+		"severityNumber":       otlpSeverity(entry.Level),
+		"severityText":         entry.Level.String(),
+		"body":                 map[string]interface{}{"stringValue": entry.Message},
+	}
+
+	if traceID, ok := attrs["trace_id"].(string); ok {
+		record["traceId"] = traceID
+		delete(attrs, "trace_id")
+	}
+	if spanID, ok := attrs["span_id"].(string); ok {
+		record["spanId"] = spanID
+		delete(attrs, "span_id")
+	}
+
+	if entry.Prefix != "" {
+		attrs["log.logger"] = entry.Prefix
+	}
+	if entry.Caller != nil {
+		attrs["code.file.path"] = entry.Caller.File
+		attrs["code.line.number"] = entry.Caller.Line
+		attrs["code.function.name"] = entry.Caller.Function
+	}
+	if len(attrs) > 0 {
+		record["attributes"] = tracing.OTLPAttributes(attrs)
+	}
+
+	return record
+}
+
+// otlpSeverity maps a level to the OTLP SeverityNumber at the start of its range
+func otlpSeverity(level Level) int {
+	switch level {
+	case DEBUG:
+		return 5
+	case INFO:
+		return 9
+	case WARN:
+		return 13
+	case ERROR:
+		return 17
+	case FATAL:
+		return 21
+	default:
+		return 24
+	}
+}
+
+// ========== HELPERS ==========
+
+// sortedFieldKeys returns the field names in order
+func sortedFieldKeys(fields map[string]interface{}) []string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// formatFieldValue formats a field value for text output
+func formatFieldValue(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case error:
+		return val.Error()
+	case time.Time:
+		return val.Format(time.RFC3339)
+	case fmt.Stringer:
+		return val.String()
+	case map[string]interface{}, []interface{}:
+		if b, err := json.Marshal(val); err == nil {
+			return string(b)
+		}
+	}
+	return fmt.Sprint(v)
+}
+
+// renameField moves doc[from] to doc[to] when present
+func renameField(doc map[string]interface{}, from, to string) {
+	if v, ok := doc[from]; ok {
+		delete(doc, from)
+		doc[to] = v
+	}
+}
+
+// appendNewline terminates encoded JSON with a newline
+func appendNewline(b []byte, err error) ([]byte, error) {
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
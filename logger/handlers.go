@@ -1,10 +1,7 @@
 package logger
 
 import (
-	"fmt"
 	"io"
-	"os"
-	"path/filepath"
 	"sync"
 	"time"
 )
@@ -34,6 +31,21 @@ func (mw *MultiWriter) Write(p []byte) (n int, err error) {
 	return len(p), nil
 }
 
+// WriteLevel writes to all writers, passing the level to LevelWriters such as
+// LevelFilter and network sinks so each target can have its own minimum level
+func (mw *MultiWriter) WriteLevel(level Level, p []byte) (n int, err error) {
+	mw.mu.Lock()
+	defer mw.mu.Unlock()
+
+	for _, w := range mw.writers {
+		n, err = writeOutput(w, level, p)
+		if err != nil {
+			return n, err
+		}
+	}
+	return len(p), nil
+}
+
 // AddWriter adds a writer to MultiWriter
 func (mw *MultiWriter) AddWriter(w io.Writer) {
 	mw.mu.Lock()
@@ -41,163 +53,36 @@ func (mw *MultiWriter) AddWriter(w io.Writer) {
 	mw.writers = append(mw.writers, w)
 }
 
-// FileHandler handles file-based logging
+// FileHandler handles file-based logging. It is a RotatingFileWriter that
+// rotates by size and keeps backups named filename.1 (newest) to filename.N.
 type FileHandler struct {
-	file        *os.File
-	filename    string
-	maxSize     int64 // in bytes
-	maxFiles    int
-	currentSize int64
-	mu          sync.Mutex
+	*RotatingFileWriter
 }
 
-// NewFileHandler creates a new file handler
+// NewFileHandler creates a file handler that rotates before the file grows
+// past maxSize bytes and keeps maxFiles backups, at least one
 func NewFileHandler(filename string, maxSize int64, maxFiles int) (*FileHandler, error) {
-	// Create directory if it doesn't exist
-	dir := filepath.Dir(filename)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create log directory: %w", err)
-	}
-
-	file, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open log file: %w", err)
-	}
-
-	info, err := file.Stat()
-	if err != nil {
-		file.Close()
-		return nil, fmt.Errorf("failed to get file info: %w", err)
-	}
-
-	return &FileHandler{
-		file:        file,
-		filename:    filename,
-		maxSize:     maxSize,
-		maxFiles:    maxFiles,
-		currentSize: info.Size(),
-	}, nil
-}
-
-// Write writes data to file with rotation
-func (h *FileHandler) Write(p []byte) (n int, err error) {
-	h.mu.Lock()
-	defer h.mu.Unlock()
-
-	// Check if we need to rotate
-	if h.maxSize > 0 && h.currentSize+int64(len(p)) > h.maxSize {
-		if err := h.rotate(); err != nil {
-			return 0, err
-		}
-	}
-
-	n, err = h.file.Write(p)
-	if err == nil {
-		h.currentSize += int64(n)
-	}
-	return n, err
-}
-
-// rotate rotates the log file
-func (h *FileHandler) rotate() error {
-	// Close current file
-	if err := h.file.Close(); err != nil {
-		return err
-	}
-
-	// Rotate old files
-	for i := h.maxFiles - 1; i > 0; i-- {
-		oldName := fmt.Sprintf("%s.%d", h.filename, i)
-		newName := fmt.Sprintf("%s.%d", h.filename, i+1)
-
-		if _, err := os.Stat(oldName); err == nil {
-			os.Rename(oldName, newName)
-		}
-	}
-
-	// Rename current file
-	backupName := fmt.Sprintf("%s.1", h.filename)
-	if err := os.Rename(h.filename, backupName); err != nil {
-		return err
-	}
-
-	// Create new file
-	file, err := os.OpenFile(h.filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	w, err := NewRotatingFileWriter(filename, RotateOptions{MaxSize: maxSize})
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	h.file = file
-	h.currentSize = 0
-	return nil
+	w.numbered = max(maxFiles, 1)
+	return &FileHandler{RotatingFileWriter: w}, nil
 }
 
-// Close closes the file handler
-func (h *FileHandler) Close() error {
-	h.mu.Lock()
-	defer h.mu.Unlock()
-	return h.file.Close()
-}
-
-// DailyFileHandler rotates logs daily
+// DailyFileHandler writes one file per day, named baseName-YYYY-MM-DD.log.
+// It is a RotatingFileWriter that switches files at local midnight; old
+// files are kept.
 type DailyFileHandler struct {
-	file       *os.File
-	baseName   string
-	currentDay int
-	mu         sync.Mutex
+	*RotatingFileWriter
 }
 
-// NewDailyFileHandler creates a new daily file handler
+// NewDailyFileHandler creates a daily file handler writing baseName-YYYY-MM-DD.log
 func NewDailyFileHandler(baseName string) (*DailyFileHandler, error) {
-	today := time.Now().Day()
-	filename := fmt.Sprintf("%s-%s.log", baseName, time.Now().Format("2006-01-02"))
-
-	// Create directory if it doesn't exist
-	dir := filepath.Dir(filename)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create log directory: %w", err)
-	}
-
-	file, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	w, err := NewRotatingFileWriter(dailyName(baseName, time.Now()), RotateOptions{Interval: 24 * time.Hour})
 	if err != nil {
-		return nil, fmt.Errorf("failed to open log file: %w", err)
+		return nil, err
 	}
-
-	return &DailyFileHandler{
-		file:       file,
-		baseName:   baseName,
-		currentDay: today,
-	}, nil
-}
-
-// Write writes data to file with daily rotation
-func (h *DailyFileHandler) Write(p []byte) (n int, err error) {
-	h.mu.Lock()
-	defer h.mu.Unlock()
-
-	// Check if day has changed
-	today := time.Now().Day()
-	if today != h.currentDay {
-		// Close current file
-		h.file.Close()
-
-		// Open new file for today
-		filename := fmt.Sprintf("%s-%s.log", h.baseName, time.Now().Format("2006-01-02"))
-		file, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-		if err != nil {
-			return 0, err
-		}
-
-		h.file = file
-		h.currentDay = today
-	}
-
-	return h.file.Write(p)
-}
-
-// Close closes the daily file handler
-func (h *DailyFileHandler) Close() error {
-	h.mu.Lock()
-	defer h.mu.Unlock()
-	return h.file.Close()
+	w.dailyBase = baseName
+	return &DailyFileHandler{RotatingFileWriter: w}, nil
 }
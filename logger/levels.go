@@ -0,0 +1,271 @@
+package logger
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Named loggers form a hierarchy by dotted name, e.g. "server.middleware" is a
+// child of "server". A name without its own level inherits the level of its
+// closest ancestor; when no ancestor has one, the level the logger was created
+// with applies. The empty name is the root: its level applies to every named
+// logger without a closer level.
+
+// NamedLevel describes the level of a named logger
+type NamedLevel struct {
+	Name string `json:"name"`
+	// Level is the effective level, empty when the logger's configured level applies
+	Level string `json:"level,omitempty"`
+	// Explicit is set when the level was set for this name rather than inherited
+	Explicit bool `json:"explicit"`
+	// InheritedFrom is the ancestor the level comes from
+	InheritedFrom string `json:"inherited_from,omitempty"`
+	// ExpiresAt is when a temporary level reverts
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// levelNode holds the level state of one name
+type levelNode struct {
+	name string
+
+	// effective is the resolved level plus one; 0 means no level in the hierarchy
+	effective atomic.Int32
+
+	explicit *Level
+	// revertTo is the explicit level restored when a temporary level expires
+	revertTo  *Level
+	revert    *time.Timer
+	expiresAt time.Time
+	// generation identifies the latest temporary change
+	generation uint64
+}
+
+// level returns the effective level and whether one is set in the hierarchy
+func (n *levelNode) level() (Level, bool) {
+	v := n.effective.Load()
+	return Level(v - 1), v != 0
+}
+
+// levelRegistry tracks named loggers and their levels
+type levelRegistry struct {
+	mu    sync.Mutex
+	nodes map[string]*levelNode
+}
+
+// levels is the process-wide registry used by Named and the level functions
+var levels = &levelRegistry{nodes: make(map[string]*levelNode)}
+
+// node returns the node for name, creating it and resolving its level; r.mu must be held
+func (r *levelRegistry) node(name string) *levelNode {
+	n, ok := r.nodes[name]
+	if !ok {
+		n = &levelNode{name: name}
+		r.nodes[name] = n
+		r.resolve(n)
+	}
+	return n
+}
+
+// register returns the node for name
+func (r *levelRegistry) register(name string) *levelNode {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.node(name)
+}
+
+// source returns the closest node at or above name with an explicit level; r.mu must be held
+func (r *levelRegistry) source(name string) *levelNode {
+	for {
+		if n, ok := r.nodes[name]; ok && n.explicit != nil {
+			return n
+		}
+		if name == "" {
+			return nil
+		}
+		if i := strings.LastIndexByte(name, '.'); i >= 0 {
+			name = name[:i]
+		} else {
+			name = ""
+		}
+	}
+}
+
+// resolve updates the effective level of n; r.mu must be held
+func (r *levelRegistry) resolve(n *levelNode) {
+	if src := r.source(n.name); src != nil {
+		n.effective.Store(int32(*src.explicit) + 1)
+	} else {
+		n.effective.Store(0)
+	}
+}
+
+// resolveAll updates every node after a level change; r.mu must be held
+func (r *levelRegistry) resolveAll() {
+	for _, n := range r.nodes {
+		r.resolve(n)
+	}
+}
+
+// set sets or clears the explicit level of name. With a ttl the previous state
+// is restored when it expires.
+func (r *levelRegistry) set(name string, level *Level, ttl time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := r.node(name)
+	if ttl > 0 {
+		// Keep the state from before the first temporary change
+		if n.revert == nil {
+			n.revertTo = n.explicit
+		} else {
+			n.revert.Stop()
+		}
+		n.generation++
+		generation := n.generation
+		n.revert = time.AfterFunc(ttl, func() { r.expire(n, generation) })
+		n.expiresAt = time.Now().Add(ttl)
+	} else {
+		n.stopRevert()
+	}
+
+	n.explicit = level
+	r.resolveAll()
+}
+
+// expire restores the level saved by a temporary change
+func (r *levelRegistry) expire(n *levelNode, generation uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// The level was changed again after this timer was scheduled
+	if n.revert == nil || n.generation != generation {
+		return
+	}
+	n.explicit = n.revertTo
+	n.stopRevert()
+	r.resolveAll()
+}
+
+// stopRevert cancels a pending revert; r.mu must be held
+func (n *levelNode) stopRevert() {
+	if n.revert != nil {
+		n.revert.Stop()
+	}
+	n.revert = nil
+	n.revertTo = nil
+	n.expiresAt = time.Time{}
+}
+
+// list describes every registered name, sorted
+func (r *levelRegistry) list() []NamedLevel {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make([]NamedLevel, 0, len(r.nodes))
+	for _, n := range r.nodes {
+		out = append(out, r.describe(n))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// describe builds the NamedLevel of n; r.mu must be held
+func (r *levelRegistry) describe(n *levelNode) NamedLevel {
+	info := NamedLevel{Name: n.name, Explicit: n.explicit != nil}
+	if level, ok := n.level(); ok {
+		info.Level = level.String()
+	}
+	if !info.Explicit {
+		if src := r.source(n.name); src != nil {
+			info.InheritedFrom = src.name
+		}
+	}
+	if !n.expiresAt.IsZero() {
+		expiresAt := n.expiresAt
+		info.ExpiresAt = &expiresAt
+	}
+	return info
+}
+
+// ========== PUBLIC API ==========
+
+// Named returns a child logger registered under name. Names nest with dots:
+// logger.Named("server").Named("middleware") is "server.middleware". The child
+// logs a "logger" field with its full name, and its level can be changed at
+// runtime with SetNamedLevel.
+func (l *Logger) Named(name string) *Logger {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	newLogger := l.clone()
+	if l.name != "" {
+		name = l.name + "." + name
+	}
+	newLogger.name = name
+	newLogger.level = levels.register(name)
+	newLogger.fields["logger"] = name
+	return newLogger
+}
+
+// Name returns the name given by Named, or "" for unnamed loggers
+func (l *Logger) Name() string {
+	return l.name
+}
+
+// SetNamedLevel sets the level of name and the names below it that have no
+// level of their own. A positive ttl reverts the change after that duration.
+func SetNamedLevel(name string, level Level, ttl time.Duration) error {
+	if level < DEBUG || level > PANIC {
+		return fmt.Errorf("invalid log level %d", level)
+	}
+	levels.set(name, &level, ttl)
+	return nil
+}
+
+// ResetNamedLevel removes the level set for name so it inherits again
+func ResetNamedLevel(name string) {
+	levels.set(name, nil, 0)
+}
+
+// NamedLevels lists the named loggers and their levels
+func NamedLevels() []NamedLevel {
+	return levels.list()
+}
+
+// NamedLevelOf describes the level of name
+func NamedLevelOf(name string) NamedLevel {
+	levels.mu.Lock()
+	defer levels.mu.Unlock()
+
+	n, ok := levels.nodes[name]
+	if !ok {
+		// Describe without registering, so lookups cannot grow the registry
+		n = &levelNode{name: name}
+		levels.resolve(n)
+	}
+	return levels.describe(n)
+}
+
+// LookupLevel parses a level name, reporting whether it is valid
+func LookupLevel(level string) (Level, bool) {
+	switch strings.ToUpper(level) {
+	case "DEBUG", "INFO", "WARN", "WARNING", "ERROR", "FATAL", "PANIC":
+		return ParseLevel(level), true
+	}
+	return INFO, false
+}
+
+// Enabled reports whether entries at level are logged, taking the level of
+// named loggers into account
+func (l *Logger) Enabled(level Level) bool {
+	if l.level != nil {
+		if named, ok := l.level.level(); ok {
+			return level >= named
+		}
+	}
+	return level >= l.config.Level
+}
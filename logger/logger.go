@@ -1,13 +1,17 @@
 package logger
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"runtime"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/selanim/sego/redact"
 )
 
 // Level represents log level
@@ -78,6 +82,19 @@ type Config struct {
 	TimeFormat string
 	Color      bool
 	JSON       bool
+
+	// Sampling limits repeated entries per message and level
+	Sampling *SamplingConfig
+	// DedupWindow collapses identical entries within the window into one
+	// line with a "repeated" count; 0 disables deduplication
+	DedupWindow time.Duration
+	// LevelCaps limits entries per second for each level; FATAL and PANIC are never dropped
+	LevelCaps map[Level]int
+
+	// Redactor masks secrets in fields and messages; defaults to redact.Default
+	Redactor *redact.Redactor
+	// DisableRedaction turns off secret redaction
+	DisableRedaction bool
 }
 
 // DefaultConfig returns default logger configuration
@@ -102,6 +119,12 @@ type Logger struct {
 	prefix    string
 	output    io.Writer
 	formatter Formatter
+	handler   slog.Handler    // set for loggers built on an slog.Handler
+	ctx       context.Context // passed to handler, set by WithContext
+	throttle  *throttle       // sampling, dedup and caps, shared with derived loggers
+	redactor  *redact.Redactor
+	name      string     // set by Named
+	level     *levelNode // runtime level of a named logger
 }
 
 // Formatter defines interface for log formatting
@@ -135,9 +158,17 @@ func New() *Logger {
 // NewWithConfig creates a new logger with custom configuration
 func NewWithConfig(config Config) *Logger {
 	logger := &Logger{
-		config: config,
-		fields: make(map[string]interface{}),
-		output: config.Output,
+		config:   config,
+		fields:   make(map[string]interface{}),
+		output:   config.Output,
+		throttle: newThrottle(config),
+	}
+
+	if !config.DisableRedaction {
+		logger.redactor = config.Redactor
+		if logger.redactor == nil {
+			logger.redactor = redact.Default
+		}
 	}
 
 	// Set formatter based on config
@@ -198,12 +229,18 @@ func (l *Logger) clone() *Logger {
 		prefix:    l.prefix,
 		output:    l.output,
 		formatter: l.formatter,
+		handler:   l.handler,
+		ctx:       l.ctx,
+		throttle:  l.throttle,
+		redactor:  l.redactor,
+		name:      l.name,
+		level:     l.level,
 	}
 }
 
 // Log logs a message at specified level
 func (l *Logger) Log(level Level, args ...interface{}) {
-	if level < l.config.Level {
+	if !l.Enabled(level) {
 		return
 	}
 
@@ -212,7 +249,7 @@ func (l *Logger) Log(level Level, args ...interface{}) {
 
 // Logf logs a formatted message at specified level
 func (l *Logger) Logf(level Level, format string, args ...interface{}) {
-	if level < l.config.Level {
+	if !l.Enabled(level) {
 		return
 	}
 
@@ -285,6 +322,15 @@ func (l *Logger) Panicf(format string, args ...interface{}) {
 
 // log writes the log entry
 func (l *Logger) log(level Level, msg string) {
+	if l.throttle != nil && !l.throttle.allow(l, level, msg) {
+		return
+	}
+
+	if l.handler != nil {
+		l.logToHandler(level, msg)
+		return
+	}
+
 	entry := &Entry{
 		Time:    time.Now(),
 		Level:   level,
@@ -298,7 +344,12 @@ func (l *Logger) log(level Level, msg string) {
 		entry.Caller = getCaller()
 	}
 
-	// Format and write the entry
+	l.write(entry)
+}
+
+// write formats the entry and writes it to the output
+func (l *Logger) write(entry *Entry) {
+	l.redactEntry(entry)
 	formatted, err := l.formatter.Format(entry)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to format log entry: %v\n", err)
@@ -308,11 +359,20 @@ func (l *Logger) log(level Level, msg string) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
-	if _, err := l.output.Write(formatted); err != nil {
+	if _, err := writeOutput(l.output, entry.Level, formatted); err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to write log: %v\n", err)
 	}
 }
 
+// redactEntry masks secrets in the entry's message and fields
+func (l *Logger) redactEntry(entry *Entry) {
+	if l.redactor == nil {
+		return
+	}
+	entry.Message = l.redactor.String(entry.Message)
+	entry.Fields = l.redactor.Map(entry.Fields)
+}
+
 // getCaller retrieves caller information
 func getCaller() *CallerInfo {
 	// Skip 4 callers: getCaller -> log -> Log/Logf -> actual caller
@@ -334,11 +394,15 @@ func getCaller() *CallerInfo {
 	}
 }
 
-// SetLevel sets the log level
+// SetLevel sets the log level. For named loggers it sets the level of the
+// name, which applies to every logger with that name and its children.
 func (l *Logger) SetLevel(level Level) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 	l.config.Level = level
+	if l.level != nil {
+		levels.set(l.name, &level, 0)
+	}
 }
 
 // SetOutput sets the output writer
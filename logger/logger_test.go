@@ -1,13 +1,28 @@
 package logger
 
 import (
+	"bufio"
 	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/selanim/sego/redact"
+	"github.com/selanim/sego/tracing"
 )
 
 func TestLoggerLevels(t *testing.T) {
@@ -133,6 +148,211 @@ func TestContextLogger(t *testing.T) {
 	}
 }
 
+func TestContextLoggerTraceFields(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewWithConfig(Config{Level: INFO, Output: &buf, JSON: true})
+
+	tracer := tracing.NewTracer()
+	defer tracer.Shutdown(context.Background())
+	ctx, span := tracer.Start(WithLogger(context.Background(), logger), "op", tracing.SpanKindInternal)
+	sc := span.SpanContext()
+
+	CtxInfo(ctx, "inside span")
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Failed to decode log entry: %v", err)
+	}
+	if entry["trace_id"] != sc.TraceID.String() || entry["span_id"] != sc.SpanID.String() {
+		t.Errorf("Expected trace_id and span_id in entry, got %v", entry)
+	}
+
+	buf.Reset()
+	logger.WithContext(ctx).Info("explicit")
+	if !strings.Contains(buf.String(), sc.TraceID.String()) {
+		t.Error("Expected WithContext to add trace_id")
+	}
+
+	buf.Reset()
+	CtxInfo(WithLogger(context.Background(), logger), "no span")
+	if strings.Contains(buf.String(), "trace_id") {
+		t.Error("Expected no trace fields without a span")
+	}
+}
+
+func TestSlogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewWithConfig(Config{Level: INFO, Output: &buf, JSON: true}).WithField("service", "api")
+
+	sl := slog.New(NewSlogHandler(l))
+	sl.Debug("hidden")
+	if buf.Len() != 0 {
+		t.Errorf("Expected debug record to be filtered, got %s", buf.String())
+	}
+
+	sl.With("user", 7).WithGroup("req").Warn("slow", "ms", 120, slog.Group("db", "rows", 3))
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Failed to parse JSON output: %v", err)
+	}
+	if entry["level"] != "WARN" || entry["message"] != "slow" {
+		t.Errorf("Expected WARN slow, got %v %v", entry["level"], entry["message"])
+	}
+	for key, want := range map[string]interface{}{"service": "api", "user": 7.0, "req.ms": 120.0, "req.db.rows": 3.0} {
+		if entry[key] != want {
+			t.Errorf("Expected field %s=%v, got %v", key, want, entry[key])
+		}
+	}
+
+	// Context fields are honored
+	buf.Reset()
+	ctx := WithField(context.Background(), "request_id", "abc")
+	sl.InfoContext(ctx, "hello")
+	if !strings.Contains(buf.String(), `"request_id":"abc"`) {
+		t.Errorf("Expected context field in output, got %s", buf.String())
+	}
+}
+
+func TestNewFromSlog(t *testing.T) {
+	var buf bytes.Buffer
+	h := slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo})
+	l := NewFromSlog(h)
+
+	l.Debug("hidden")
+	if buf.Len() != 0 {
+		t.Errorf("Expected debug entry to be filtered by handler, got %s", buf.String())
+	}
+
+	l.WithPrefix("db").WithField("table", "users").Warn("slow query")
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("Failed to parse JSON output: %v", err)
+	}
+	if record["level"] != "WARN" || record["msg"] != "slow query" {
+		t.Errorf("Expected WARN slow query, got %v %v", record["level"], record["msg"])
+	}
+	group, _ := record["db"].(map[string]interface{})
+	if group["table"] != "users" {
+		t.Errorf("Expected field in db group, got %v", record)
+	}
+
+	// The context logger receives ctx and context fields
+	buf.Reset()
+	ctx := WithField(WithLogger(context.Background(), l), "request_id", "abc")
+	ContextLogger(ctx).Info("handled")
+	if !strings.Contains(buf.String(), `"request_id":"abc"`) {
+		t.Errorf("Expected context field in output, got %s", buf.String())
+	}
+
+	// Round trip through Slog keeps fields
+	buf.Reset()
+	l.WithField("k", "v").Slog().Error("failed")
+	if !strings.Contains(buf.String(), `"k":"v"`) || !strings.Contains(buf.String(), `"level":"ERROR"`) {
+		t.Errorf("Expected field and level in output, got %s", buf.String())
+	}
+}
+
+func TestSlogLevelMapping(t *testing.T) {
+	for _, level := range []Level{DEBUG, INFO, WARN, ERROR, FATAL, PANIC} {
+		if got := LevelFromSlog(level.SlogLevel()); got != level {
+			t.Errorf("Expected %s after round trip, got %s", level, got)
+		}
+	}
+	if LevelFromSlog(slog.LevelInfo+2) != INFO {
+		t.Error("Expected levels between INFO and WARN to map to INFO")
+	}
+}
+
+func TestLoggerSampling(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewWithConfig(Config{
+		Level:    INFO,
+		Output:   &buf,
+		JSON:     true,
+		Sampling: &SamplingConfig{Initial: 2, Thereafter: 3, Interval: time.Hour},
+	})
+
+	for i := 0; i < 10; i++ {
+		logger.Error("db down")
+	}
+	logger.Error("other")
+
+	// Entries 1, 2, 5 and 8 of "db down" are kept, plus "other"
+	if got := strings.Count(buf.String(), "db down"); got != 4 {
+		t.Errorf("Expected 4 sampled entries, got %d", got)
+	}
+	if !strings.Contains(buf.String(), "other") {
+		t.Error("Expected a different message to be sampled separately")
+	}
+	if stats := logger.Suppressed(); stats.Sampled != 6 {
+		t.Errorf("Expected 6 sampled out, got %d", stats.Sampled)
+	}
+}
+
+func TestLoggerDedup(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewWithConfig(Config{
+		Level:       INFO,
+		Output:      &buf,
+		JSON:        true,
+		DedupWindow: time.Hour,
+	})
+
+	for i := 0; i < 5; i++ {
+		logger.WithField("attempt", i).Error("connection refused")
+	}
+	if got := strings.Count(buf.String(), "connection refused"); got != 1 {
+		t.Fatalf("Expected 1 entry before the window closes, got %d", got)
+	}
+
+	buf.Reset()
+	logger.FlushSuppressed()
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Failed to parse summary entry: %v", err)
+	}
+	if entry["repeated"] != 4.0 {
+		t.Errorf("Expected repeated=4, got %v", entry["repeated"])
+	}
+	if entry["attempt"] != 4.0 {
+		t.Errorf("Expected fields of the last repeat, got %v", entry["attempt"])
+	}
+
+	// A new window starts after the flush
+	buf.Reset()
+	logger.Error("connection refused")
+	if buf.Len() == 0 {
+		t.Error("Expected entry after the window closed")
+	}
+}
+
+func TestLoggerLevelCaps(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewWithConfig(Config{
+		Level:     INFO,
+		Output:    &buf,
+		LevelCaps: map[Level]int{ERROR: 3},
+	})
+
+	for i := 0; i < 10; i++ {
+		logger.Errorf("error %d", i)
+		logger.Infof("info %d", i)
+	}
+
+	if got := strings.Count(buf.String(), "[ERROR]"); got != 3 {
+		t.Errorf("Expected 3 error entries, got %d", got)
+	}
+	if got := strings.Count(buf.String(), "[INFO]"); got != 10 {
+		t.Errorf("Expected uncapped info entries, got %d", got)
+	}
+	if stats := logger.Suppressed(); stats.Capped != 7 {
+		t.Errorf("Expected 7 capped, got %d", stats.Capped)
+	}
+}
+
 func TestParseLevel(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -232,6 +452,386 @@ func TestFileHandler(t *testing.T) {
 	}
 }
 
+func TestRotatingFileWriterSize(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "app.log")
+	link := filepath.Join(dir, "current.log")
+
+	w, err := NewRotatingFileWriter(filename, RotateOptions{
+		MaxSize:    100,
+		MaxBackups: 2,
+		Compress:   CompressGzip,
+		Symlink:    link,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create rotating writer: %v", err)
+	}
+
+	clock := time.Date(2024, 1, 2, 15, 4, 5, 0, time.Local)
+	w.now = func() time.Time { clock = clock.Add(time.Second); return clock }
+
+	line := strings.Repeat("x", 59) + "\n"
+	for i := 0; i < 5; i++ {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	// 5 writes of 60 bytes rotate 4 times; only 2 compressed backups are kept
+	backups, _ := filepath.Glob(filepath.Join(dir, "app-*.log.gz"))
+	if len(backups) != 2 {
+		t.Fatalf("Expected 2 compressed backups, got %v", backups)
+	}
+	if plain, _ := filepath.Glob(filepath.Join(dir, "app-*.log")); len(plain) != 0 {
+		t.Errorf("Expected rotated files to be compressed, got %v", plain)
+	}
+
+	f, err := os.Open(backups[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("Backup is not gzip: %v", err)
+	}
+	data, _ := io.ReadAll(gz)
+	if string(data) != line {
+		t.Errorf("Expected backup to hold one line, got %q", data)
+	}
+
+	if target, err := os.Readlink(link); err != nil || filepath.Base(target) != "app.log" {
+		t.Errorf("Expected symlink to the active file, got %q (%v)", target, err)
+	}
+}
+
+func TestRotatingFileWriterInterval(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "app.log")
+
+	// A backup older than MaxAge is pruned after the next rotation
+	old := filepath.Join(dir, "app-2023-12-01T00-00-00.000.log.zst")
+	if err := os.WriteFile(old, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := NewRotatingFileWriter(filename, RotateOptions{
+		Interval: 24 * time.Hour,
+		MaxAge:   7 * 24 * time.Hour,
+		Compress: CompressZstd,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create rotating writer: %v", err)
+	}
+
+	clock := time.Date(2024, 1, 2, 23, 0, 0, 0, time.Local)
+	w.now = func() time.Time { return clock }
+	w.nextRotation = w.nextBoundary(clock)
+
+	w.Write([]byte("day one\n"))
+	clock = clock.Add(2 * time.Hour)
+	w.Write([]byte("day two\n"))
+	w.Close()
+
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Error("Expected expired backup to be removed")
+	}
+	backups, _ := filepath.Glob(filepath.Join(dir, "app-2024-01-03T01-00-00.000.log.zst"))
+	if len(backups) != 1 {
+		t.Fatalf("Expected one zstd backup from the rotation, got %v", backups)
+	}
+	data, _ := os.ReadFile(filename)
+	if string(data) != "day two\n" {
+		t.Errorf("Expected active file to hold the new day, got %q", data)
+	}
+}
+
+func TestRotatingFileWriterReopen(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "app.log")
+
+	w, err := NewRotatingFileWriter(filename)
+	if err != nil {
+		t.Fatalf("Failed to create rotating writer: %v", err)
+	}
+	defer w.Close()
+
+	w.Write([]byte("before\n"))
+
+	// Simulate logrotate moving the file away
+	moved := filepath.Join(dir, "app.log.1")
+	if err := os.Rename(filename, moved); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Reopen(); err != nil {
+		t.Fatalf("Reopen failed: %v", err)
+	}
+	w.Write([]byte("after\n"))
+
+	if data, _ := os.ReadFile(moved); string(data) != "before\n" {
+		t.Errorf("Expected moved file to keep old entries, got %q", data)
+	}
+	if data, _ := os.ReadFile(filename); string(data) != "after\n" {
+		t.Errorf("Expected reopened file to get new entries, got %q", data)
+	}
+}
+
+func TestRotatingFileWriterFailedRotation(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "app.log")
+
+	w, err := NewRotatingFileWriter(filename, RotateOptions{MaxSize: 10})
+	if err != nil {
+		t.Fatalf("Failed to create rotating writer: %v", err)
+	}
+	defer w.Close()
+
+	w.Write([]byte("first\n"))
+	w.rename = func(string, string) error { return os.ErrPermission }
+	if _, err := w.Write([]byte("second\n")); err == nil {
+		t.Fatal("Expected failed rotation to be reported")
+	}
+
+	// The active file stays open, so the rotation is retried on the next write
+	w.rename = os.Rename
+	if _, err := w.Write([]byte("third\n")); err != nil {
+		t.Fatalf("Expected write after failed rotation to succeed, got %v", err)
+	}
+	if data, _ := os.ReadFile(filename); string(data) != "third\n" {
+		t.Errorf("Expected active file to hold the latest entry, got %q", data)
+	}
+	if backups, _ := filepath.Glob(filepath.Join(dir, "app-*.log")); len(backups) != 1 {
+		t.Errorf("Expected one backup after the retried rotation, got %v", backups)
+	}
+}
+
+func TestDailyFileHandler(t *testing.T) {
+	base := filepath.Join(t.TempDir(), "app")
+	handler, err := NewDailyFileHandler(base)
+	if err != nil {
+		t.Fatalf("Failed to create daily handler: %v", err)
+	}
+	defer handler.Close()
+
+	today := time.Now()
+	handler.Write([]byte("today\n"))
+
+	// The next day writes its own file and keeps the previous one
+	tomorrow := today.AddDate(0, 0, 1)
+	handler.now = func() time.Time { return tomorrow }
+	handler.Write([]byte("tomorrow\n"))
+
+	for day, want := range map[time.Time]string{today: "today\n", tomorrow: "tomorrow\n"} {
+		name := base + "-" + day.Format("2006-01-02") + ".log"
+		if data, err := os.ReadFile(name); err != nil || string(data) != want {
+			t.Errorf("Expected %q in %s, got %q (%v)", want, name, data, err)
+		}
+	}
+	if files, _ := filepath.Glob(base + "*"); len(files) != 2 {
+		t.Errorf("Expected only the two daily files, got %v", files)
+	}
+}
+
+func TestFileHandlerNumberedBackups(t *testing.T) {
+	for _, tc := range []struct {
+		maxFiles int
+		backups  []string
+	}{
+		{maxFiles: 2, backups: []string{"app.log.1", "app.log.2"}},
+		// Zero still keeps the last backup
+		{maxFiles: 0, backups: []string{"app.log.1"}},
+	} {
+		dir := t.TempDir()
+		handler, err := NewFileHandler(filepath.Join(dir, "app.log"), 6, tc.maxFiles)
+		if err != nil {
+			t.Fatalf("Failed to create file handler: %v", err)
+		}
+		for _, entry := range []string{"one\n", "two\n", "three\n", "four\n"} {
+			handler.Write([]byte(entry))
+		}
+		handler.Close()
+
+		var got []string
+		entries, _ := os.ReadDir(dir)
+		for _, entry := range entries {
+			if entry.Name() != "app.log" {
+				got = append(got, entry.Name())
+			}
+		}
+		if strings.Join(got, ",") != strings.Join(tc.backups, ",") {
+			t.Errorf("Expected backups %v with maxFiles %d, got %v", tc.backups, tc.maxFiles, got)
+		}
+		if data, _ := os.ReadFile(filepath.Join(dir, "app.log.1")); string(data) != "three\n" {
+			t.Errorf("Expected newest backup in app.log.1, got %q", data)
+		}
+	}
+}
+
+func TestLoggerRedaction(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewWithConfig(Config{Level: INFO, Output: &buf, JSON: true})
+
+	logger.WithFields(map[string]interface{}{
+		"password": "hunter2",
+		"user":     "bob",
+	}).Info("login with Bearer abc123")
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Failed to parse JSON output: %v", err)
+	}
+	if entry["password"] != redact.Placeholder || entry["user"] != "bob" {
+		t.Errorf("Expected password to be redacted, got %v", entry)
+	}
+	if entry["message"] != "login with Bearer [REDACTED]" {
+		t.Errorf("Expected token in message to be redacted, got %v", entry["message"])
+	}
+
+	// Redaction can be turned off
+	buf.Reset()
+	plain := NewWithConfig(Config{Level: INFO, Output: &buf, DisableRedaction: true})
+	plain.WithField("password", "hunter2").Info("login")
+	if !strings.Contains(buf.String(), "hunter2") {
+		t.Errorf("Expected unredacted output, got %s", buf.String())
+	}
+}
+
+func TestLogfmtFormatter(t *testing.T) {
+	f := &LogfmtFormatter{Keys: FieldKeys{Message: "message"}}
+	entry := &Entry{
+		Time:    time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC),
+		Level:   WARN,
+		Message: "disk almost full",
+		Prefix:  "storage",
+		Fields:  map[string]interface{}{"used": 0.93, "path": "/var/lib", "note": `say "hi"`},
+		Caller:  &CallerInfo{File: "/src/app/disk.go", Line: 42},
+	}
+
+	out, err := f.Format(entry)
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+
+	want := `time=2024-01-02T15:04:05Z level=warn prefix=storage message="disk almost full" caller=disk.go:42 note="say \"hi\"" path=/var/lib used=0.93` + "\n"
+	if string(out) != want {
+		t.Errorf("Expected %q, got %q", want, out)
+	}
+}
+
+func TestECSFormatter(t *testing.T) {
+	f := &ECSFormatter{ServiceName: "api"}
+	out, err := f.Format(&Entry{
+		Time:    time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC),
+		Level:   ERROR,
+		Message: "payment failed",
+		Prefix:  "billing",
+		Fields:  map[string]interface{}{"error": fmt.Errorf("card declined"), "trace_id": "abc", "order": 7},
+	})
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(out, &doc); err != nil {
+		t.Fatalf("Failed to parse ECS output: %v", err)
+	}
+	want := map[string]interface{}{
+		"@timestamp":    "2024-01-02T15:04:05.000Z",
+		"log.level":     "error",
+		"message":       "payment failed",
+		"log.logger":    "billing",
+		"service.name":  "api",
+		"error.message": "card declined",
+		"trace.id":      "abc",
+		"order":         7.0,
+	}
+	for k, v := range want {
+		if doc[k] != v {
+			t.Errorf("Expected %s=%v, got %v", k, v, doc[k])
+		}
+	}
+}
+
+func TestGELFFormatter(t *testing.T) {
+	f := &GELFFormatter{Host: "web-1"}
+	out, err := f.Format(&Entry{
+		Time:    time.Unix(1700000000, 500*int64(time.Millisecond)),
+		Level:   WARN,
+		Message: "slow request",
+		Fields:  map[string]interface{}{"id": 9, "duration ms": 1200},
+	})
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+
+	var msg map[string]interface{}
+	if err := json.Unmarshal(out, &msg); err != nil {
+		t.Fatalf("Failed to parse GELF output: %v", err)
+	}
+	if msg["version"] != "1.1" || msg["host"] != "web-1" || msg["short_message"] != "slow request" {
+		t.Errorf("Unexpected GELF envelope: %v", msg)
+	}
+	if msg["level"] != 4.0 || msg["timestamp"] != 1700000000.5 {
+		t.Errorf("Expected level 4 and fractional timestamp, got %v %v", msg["level"], msg["timestamp"])
+	}
+	if msg["_id_"] != 9.0 || msg["_duration_ms"] != 1200.0 {
+		t.Errorf("Expected sanitized additional fields, got %v", msg)
+	}
+}
+
+func TestOTLPFormatter(t *testing.T) {
+	f := &OTLPFormatter{ServiceName: "api", Envelope: true}
+	out, err := f.Format(&Entry{
+		Time:    time.Unix(0, 1700000000000000000),
+		Level:   INFO,
+		Message: "started",
+		Fields: map[string]interface{}{
+			"trace_id": "4bf92f3577b34da6a3ce929d0e0e4736",
+			"span_id":  "00f067aa0ba902b7",
+			"port":     8080,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Format failed: %v", err)
+	}
+
+	var req struct {
+		ResourceLogs []struct {
+			ScopeLogs []struct {
+				LogRecords []map[string]interface{} `json:"logRecords"`
+			} `json:"scopeLogs"`
+		} `json:"resourceLogs"`
+	}
+	if err := json.Unmarshal(out, &req); err != nil {
+		t.Fatalf("Failed to parse OTLP output: %v", err)
+	}
+	record := req.ResourceLogs[0].ScopeLogs[0].LogRecords[0]
+	if record["timeUnixNano"] != "1700000000000000000" || record["severityNumber"] != 9.0 || record["severityText"] != "INFO" {
+		t.Errorf("Unexpected record header: %v", record)
+	}
+	if record["traceId"] != "4bf92f3577b34da6a3ce929d0e0e4736" || record["spanId"] != "00f067aa0ba902b7" {
+		t.Errorf("Expected trace context on the record, got %v", record)
+	}
+	attrs := record["attributes"].([]interface{})
+	if len(attrs) != 1 || !strings.Contains(fmt.Sprint(attrs[0]), "8080") {
+		t.Errorf("Expected only the port attribute, got %v", attrs)
+	}
+}
+
+func TestJSONFormatterKeys(t *testing.T) {
+	f := &JSONFormatter{Keys: FieldKeys{Time: "ts", Message: "msg"}, TimeFormat: time.RFC3339Nano}
+	out, _ := f.Format(&Entry{Time: time.Date(2024, 1, 2, 0, 0, 0, 5, time.UTC), Level: INFO, Message: "hi"})
+
+	var data map[string]interface{}
+	json.Unmarshal(out, &data)
+	if data["ts"] != "2024-01-02T00:00:00.000000005Z" || data["msg"] != "hi" || data["level"] != "INFO" {
+		t.Errorf("Expected renamed keys, got %v", data)
+	}
+}
+
 func TestAsyncLogger(t *testing.T) {
 	var buf bytes.Buffer
 
@@ -261,6 +861,529 @@ func TestAsyncLogger(t *testing.T) {
 	}
 }
 
+// blockingWriter blocks writes until release is closed and counts Write calls
+type blockingWriter struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	writes  int
+	release chan struct{}
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	<-w.release
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writes++
+	return w.buf.Write(p)
+}
+
+func (w *blockingWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+func TestAsyncLoggerFlushAndBatching(t *testing.T) {
+	w := &blockingWriter{release: make(chan struct{})}
+	asyncLogger := NewAsyncLogger(NewWithConfig(Config{Level: INFO, Output: w}), 100)
+
+	// The first entry blocks the worker, so the rest are written as one batch
+	for i := 0; i < 20; i++ {
+		asyncLogger.WithField("n", i).Infof("Message %d", i)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	if err := asyncLogger.Flush(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected Flush to time out while the writer is blocked, got %v", err)
+	}
+	cancel()
+
+	close(w.release)
+	if err := asyncLogger.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	output := w.String()
+	for i := 0; i < 20; i++ {
+		if !strings.Contains(output, fmt.Sprintf("Message %d", i)) {
+			t.Errorf("Message %d not found in output", i)
+		}
+	}
+	if w.writes > 2 {
+		t.Errorf("Expected entries to be batched into at most 2 writes, got %d", w.writes)
+	}
+	if stats := asyncLogger.Stats(); stats.Written != 20 || stats.Dropped != 0 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+
+	asyncLogger.Shutdown()
+}
+
+func TestAsyncLoggerOverflowPolicies(t *testing.T) {
+	tests := []struct {
+		policy      OverflowPolicy
+		wantDropped uint64
+		wantSync    uint64
+		wantLast    bool
+	}{
+		{OverflowDropNewest, 7, 0, false},
+		{OverflowDropOldest, 7, 0, true},
+		{OverflowSync, 0, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy.String(), func(t *testing.T) {
+			w := &blockingWriter{release: make(chan struct{})}
+			asyncLogger := NewAsyncLogger(NewWithConfig(Config{Level: INFO, Output: w}), 2, AsyncOptions{Policy: tt.policy})
+
+			// Wait until the worker holds the first entry, so the buffer holds exactly 2
+			asyncLogger.Info("first")
+			for asyncLogger.Stats().Enqueued == 0 || len(asyncLogger.pipeline.queue) > 0 {
+				time.Sleep(time.Millisecond)
+			}
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				for i := 0; i < 9; i++ {
+					asyncLogger.Infof("entry %d", i)
+				}
+			}()
+			if tt.policy == OverflowSync {
+				// Synchronous writes also wait on the blocked writer
+				time.Sleep(10 * time.Millisecond)
+				close(w.release)
+				<-done
+			} else {
+				<-done
+				close(w.release)
+			}
+
+			if err := asyncLogger.Close(context.Background()); err != nil {
+				t.Fatalf("Close failed: %v", err)
+			}
+
+			stats := asyncLogger.Stats()
+			if stats.Dropped != tt.wantDropped || asyncLogger.Dropped() != tt.wantDropped {
+				t.Errorf("Expected %d dropped, got %d", tt.wantDropped, stats.Dropped)
+			}
+			// Once the writer is released the worker frees space, so only a minimum is known
+			if stats.Synchronous < tt.wantSync || (tt.wantSync == 0 && stats.Synchronous != 0) {
+				t.Errorf("Expected at least %d synchronous writes, got %d", tt.wantSync, stats.Synchronous)
+			}
+			if got := strings.Contains(w.String(), "entry 8"); got != tt.wantLast {
+				t.Errorf("Expected newest entry written=%v, got %v", tt.wantLast, got)
+			}
+		})
+	}
+}
+
+func TestAsyncLoggerAfterClose(t *testing.T) {
+	var buf bytes.Buffer
+	asyncLogger := NewAsyncLogger(NewWithConfig(Config{Level: INFO, Output: &buf}), 10)
+	asyncLogger.Close(context.Background())
+
+	asyncLogger.Info("late entry")
+	if !strings.Contains(buf.String(), "late entry") {
+		t.Error("Expected entries after Close to be written synchronously")
+	}
+	if err := asyncLogger.Flush(context.Background()); err != nil {
+		t.Errorf("Expected Flush after Close to succeed, got %v", err)
+	}
+}
+
+func TestLevelFilterMultiWriter(t *testing.T) {
+	var all, errorsOnly bytes.Buffer
+	logger := NewWithConfig(Config{
+		Level:  DEBUG,
+		Output: NewMultiWriter(&all, NewLevelFilter(&errorsOnly, ERROR)),
+	})
+
+	logger.Debug("debug message")
+	logger.Error("error message")
+
+	if !strings.Contains(all.String(), "debug message") || !strings.Contains(all.String(), "error message") {
+		t.Errorf("Expected all entries in unfiltered writer, got %q", all.String())
+	}
+	if strings.Contains(errorsOnly.String(), "debug message") {
+		t.Error("Expected DEBUG entry to be filtered out")
+	}
+	if !strings.Contains(errorsOnly.String(), "error message") {
+		t.Errorf("Expected ERROR entry in filtered writer, got %q", errorsOnly.String())
+	}
+}
+
+func TestSyslogSinkUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer conn.Close()
+
+	sink, err := NewSyslogSink("udp", conn.LocalAddr().String(), SyslogOptions{
+		AppName:     "sego-test",
+		Hostname:    "host1",
+		SinkOptions: SinkOptions{MinLevel: WARN},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create sink: %v", err)
+	}
+	defer sink.Close()
+
+	logger := NewWithConfig(Config{Level: DEBUG, Output: sink, JSON: true})
+	logger.Info("below minimum")
+	logger.Error("disk full")
+	sink.Flush()
+
+	buf := make([]byte, 2048)
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("Failed to read datagram: %v", err)
+	}
+	msg := string(buf[:n])
+
+	// Facility user (1) * 8 + severity error (3)
+	if !strings.HasPrefix(msg, "<11>1 ") {
+		t.Errorf("Expected PRI <11> and version 1, got %q", msg)
+	}
+	if !strings.Contains(msg, " host1 sego-test ") {
+		t.Errorf("Expected hostname and app name in header, got %q", msg)
+	}
+	if !strings.Contains(msg, "disk full") || strings.HasSuffix(msg, "\n") {
+		t.Errorf("Expected message without trailing newline, got %q", msg)
+	}
+	if strings.Contains(msg, "below minimum") {
+		t.Error("Expected INFO entry to be filtered by MinLevel")
+	}
+}
+
+func TestSyslogSinkTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer ln.Close()
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		var frames []string
+		for len(frames) < 2 {
+			length, err := r.ReadString(' ')
+			if err != nil {
+				return
+			}
+			size, _ := strconv.Atoi(strings.TrimSpace(length))
+			frame := make([]byte, size)
+			if _, err := io.ReadFull(r, frame); err != nil {
+				return
+			}
+			frames = append(frames, string(frame))
+		}
+		received <- strings.Join(frames, "|")
+	}()
+
+	sink, err := NewSyslogSink("tcp", ln.Addr().String(), SyslogOptions{Facility: 16})
+	if err != nil {
+		t.Fatalf("Failed to create sink: %v", err)
+	}
+	defer sink.Close()
+
+	logger := NewWithConfig(Config{Level: INFO, Output: sink})
+	logger.Info("first")
+	logger.Warn("second")
+	sink.Flush()
+
+	select {
+	case got := <-received:
+		frames := strings.Split(got, "|")
+		// local0 (16) * 8 + info (6), local0 * 8 + warning (4)
+		if !strings.HasPrefix(frames[0], "<134>1 ") || !strings.HasSuffix(frames[0], "first") {
+			t.Errorf("Unexpected first frame %q", frames[0])
+		}
+		if !strings.HasPrefix(frames[1], "<132>1 ") || !strings.HasSuffix(frames[1], "second") {
+			t.Errorf("Unexpected second frame %q", frames[1])
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for syslog frames")
+	}
+}
+
+func TestTCPSink(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer ln.Close()
+
+	lines := make(chan string, 10)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+
+	sink, err := NewTCPSink(ln.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to create sink: %v", err)
+	}
+	defer sink.Close()
+
+	logger := NewWithConfig(Config{Level: INFO, Output: sink, JSON: true})
+	for i := 0; i < 3; i++ {
+		logger.WithField("n", i).Info("line")
+	}
+	sink.Flush()
+
+	for i := 0; i < 3; i++ {
+		select {
+		case line := <-lines:
+			var entry map[string]interface{}
+			if err := json.Unmarshal([]byte(line), &entry); err != nil {
+				t.Fatalf("Expected one JSON entry per line, got %q", line)
+			}
+			if entry["n"] != float64(i) {
+				t.Errorf("Expected n=%d, got %v", i, entry["n"])
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timed out waiting for line %d", i)
+		}
+	}
+	if stats := sink.Stats(); stats.Sent != 3 {
+		t.Errorf("Expected 3 sent entries, got %d", stats.Sent)
+	}
+}
+
+func TestHTTPSink(t *testing.T) {
+	var mu sync.Mutex
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/x-ndjson" {
+			t.Errorf("Expected NDJSON content type, got %q", r.Header.Get("Content-Type"))
+		}
+		if r.Header.Get("Authorization") != "Bearer key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, string(body))
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	sink, err := NewHTTPSink(server.URL, HTTPSinkOptions{Headers: map[string]string{"Authorization": "Bearer key"}})
+	if err != nil {
+		t.Fatalf("Failed to create sink: %v", err)
+	}
+	defer sink.Close()
+
+	logger := NewWithConfig(Config{Level: INFO, Output: sink, JSON: true})
+	logger.Info("one")
+	logger.Info("two")
+	sink.Flush()
+
+	mu.Lock()
+	body := strings.Join(bodies, "")
+	mu.Unlock()
+	lines := strings.Split(strings.TrimSuffix(body, "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 NDJSON lines, got %q", body)
+	}
+	for _, line := range lines {
+		if !json.Valid([]byte(line)) {
+			t.Errorf("Expected valid JSON line, got %q", line)
+		}
+	}
+
+	// Client errors are not retried
+	unauthorized, err := NewHTTPSink(server.URL, HTTPSinkOptions{SinkOptions: SinkOptions{RetryBackoff: time.Millisecond}})
+	if err != nil {
+		t.Fatalf("Failed to create sink: %v", err)
+	}
+	defer unauthorized.Close()
+	unauthorized.Write([]byte(`{"msg":"denied"}`))
+	unauthorized.Flush()
+	if stats := unauthorized.Stats(); stats.Dropped != 1 || stats.Retries != 0 {
+		t.Errorf("Expected 1 dropped entry without retries, got %+v", stats)
+	}
+}
+
+func TestSinkSpillAndReplay(t *testing.T) {
+	var up atomic.Bool
+	var mu sync.Mutex
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !up.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received = append(received, strings.Split(strings.TrimSuffix(string(body), "\n"), "\n")...)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	spillPath := filepath.Join(t.TempDir(), "spill", "http.log")
+	opts := SinkOptions{
+		SpillPath:     spillPath,
+		FlushInterval: 20 * time.Millisecond,
+		MaxRetries:    1,
+		RetryBackoff:  time.Millisecond,
+		MaxBackoff:    10 * time.Millisecond,
+	}
+	sink, err := NewHTTPSink(server.URL, HTTPSinkOptions{SinkOptions: opts})
+	if err != nil {
+		t.Fatalf("Failed to create sink: %v", err)
+	}
+
+	sink.Write([]byte("first\n"))
+	sink.Write([]byte("second\n"))
+	sink.Flush()
+
+	if stats := sink.Stats(); stats.Spilled != 2 || stats.Sent != 0 {
+		t.Fatalf("Expected 2 spilled entries while the remote is down, got %+v", stats)
+	}
+	if info, err := os.Stat(spillPath); err != nil || info.Size() == 0 {
+		t.Fatalf("Expected non-empty spill file, got %v", err)
+	}
+
+	// Entries left on disk survive a restart
+	sink.Close()
+	sink, err = NewHTTPSink(server.URL, HTTPSinkOptions{SinkOptions: opts})
+	if err != nil {
+		t.Fatalf("Failed to reopen sink: %v", err)
+	}
+	defer sink.Close()
+
+	up.Store(true)
+	sink.Write([]byte("third\n"))
+	sink.Flush()
+
+	mu.Lock()
+	got := strings.Join(received, ",")
+	mu.Unlock()
+	if got != "first,second,third" {
+		t.Errorf("Expected spilled entries replayed in order before new ones, got %q", got)
+	}
+	if info, err := os.Stat(spillPath); err != nil || info.Size() != 0 {
+		t.Errorf("Expected empty spill file after replay, got %v", err)
+	}
+}
+
+func TestSpillFileKeepsTimeAndConcurrentAppends(t *testing.T) {
+	spill, err := openSpillFile(filepath.Join(t.TempDir(), "spill.log"), 1<<20)
+	if err != nil {
+		t.Fatalf("Failed to open spill file: %v", err)
+	}
+	defer spill.close()
+
+	written := time.Date(2024, 3, 1, 12, 30, 0, 123456000, time.UTC)
+	spill.append([]sinkItem{
+		{level: ERROR, time: written, data: []byte("first")},
+		{level: INFO, time: written, data: []byte("second")},
+	})
+
+	items, offset, err := spill.readAll()
+	if err != nil || len(items) != 2 {
+		t.Fatalf("Expected 2 items, got %d (%v)", len(items), err)
+	}
+	if !items[0].time.Equal(written) || items[0].level != ERROR {
+		t.Errorf("Expected time %v and level ERROR, got %v and %v", written, items[0].time, items[0].level)
+	}
+
+	// A writer spills while the read items are being sent
+	spill.append([]sinkItem{{level: INFO, time: written, data: []byte("third")}})
+	if err := spill.consume(offset, items[1:]); err != nil {
+		t.Fatalf("Failed to consume: %v", err)
+	}
+
+	items, _, _ = spill.readAll()
+	var got []string
+	for _, item := range items {
+		got = append(got, string(item.data))
+	}
+	if strings.Join(got, ",") != "second,third" {
+		t.Errorf("Expected unsent entry followed by the new one, got %v", got)
+	}
+
+	// Syslog stamps entries with the time they were written, not sent
+	st := &syslogTransport{opts: SyslogOptions{Hostname: "host1", AppName: "app"}, pid: "1"}
+	if msg := string(st.format(items[0])); !strings.Contains(msg, " 2024-03-01T12:30:00.123456Z ") {
+		t.Errorf("Expected entry time in syslog header, got %q", msg)
+	}
+}
+
+func TestNamedLoggerLevels(t *testing.T) {
+	var buf bytes.Buffer
+	base := NewWithConfig(Config{Level: INFO, Output: &buf, JSON: true})
+	db := base.Named("named-test").Named("db")
+	cache := base.Named("named-test").Named("cache")
+
+	if db.Name() != "named-test.db" {
+		t.Errorf("Expected name named-test.db, got %s", db.Name())
+	}
+	db.Info("with name")
+	if !strings.Contains(buf.String(), `"logger":"named-test.db"`) {
+		t.Errorf("Expected logger field, got %s", buf.String())
+	}
+
+	// Children inherit from the closest ancestor with a level
+	if err := SetNamedLevel("named-test", DEBUG, 0); err != nil {
+		t.Fatalf("Failed to set level: %v", err)
+	}
+	defer ResetNamedLevel("named-test")
+	if !db.Enabled(DEBUG) || !cache.Enabled(DEBUG) {
+		t.Error("Expected children to inherit DEBUG")
+	}
+
+	SetNamedLevel("named-test.cache", ERROR, 0)
+	defer ResetNamedLevel("named-test.cache")
+	if cache.Enabled(WARN) || !db.Enabled(DEBUG) {
+		t.Error("Expected only cache to use ERROR")
+	}
+	if info := NamedLevelOf("named-test.db"); info.Explicit || info.InheritedFrom != "named-test" {
+		t.Errorf("Expected db to inherit from named-test, got %+v", info)
+	}
+
+	// Unnamed loggers keep their configured level
+	if base.Enabled(DEBUG) {
+		t.Error("Expected unnamed logger to stay at INFO")
+	}
+
+	// Temporary levels revert to the previous state
+	SetNamedLevel("named-test.db", ERROR, 20*time.Millisecond)
+	if db.Enabled(WARN) {
+		t.Error("Expected temporary ERROR level")
+	}
+	time.Sleep(60 * time.Millisecond)
+	if !db.Enabled(DEBUG) {
+		t.Error("Expected db to inherit DEBUG again after the TTL")
+	}
+
+	ResetNamedLevel("named-test")
+	if db.Enabled(DEBUG) {
+		t.Error("Expected configured INFO level after reset")
+	}
+
+	if err := SetNamedLevel("named-test", Level(42), 0); err == nil {
+		t.Error("Expected error for invalid level")
+	}
+}
+
 func TestPrefix(t *testing.T) {
 	var buf bytes.Buffer
 
@@ -0,0 +1,311 @@
+package logger
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// ========== SYSLOG ==========
+
+// SyslogOptions configures a SyslogSink
+type SyslogOptions struct {
+	SinkOptions
+	// Facility is the syslog facility code; defaults to 1 (user-level)
+	Facility int
+	// AppName defaults to the program name
+	AppName string
+	// Hostname defaults to the machine's hostname
+	Hostname string
+	// TLSConfig is used for the "tls" network
+	TLSConfig *tls.Config
+	// DialTimeout defaults to 5s
+	DialTimeout time.Duration
+}
+
+// SyslogSink sends entries as RFC 5424 syslog messages over UDP, TCP or TLS.
+// TCP and TLS use octet-counting framing (RFC 6587).
+type SyslogSink struct {
+	*sink
+}
+
+// NewSyslogSink creates a sink for network "udp", "tcp" or "tls" and addr, e.g. "localhost:514"
+func NewSyslogSink(network, addr string, opts ...SyslogOptions) (*SyslogSink, error) {
+	var opt SyslogOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	if network != "udp" && network != "tcp" && network != "tls" {
+		return nil, fmt.Errorf("unsupported syslog network %q", network)
+	}
+	if opt.Facility <= 0 {
+		opt.Facility = 1
+	}
+	if opt.AppName == "" {
+		opt.AppName = filepath.Base(os.Args[0])
+	}
+	if opt.Hostname == "" {
+		opt.Hostname, _ = os.Hostname()
+	}
+
+	t := &syslogTransport{
+		conn: connTransport{network: network, addr: addr, tlsConfig: opt.TLSConfig, timeout: opt.DialTimeout},
+		opts: opt,
+		pid:  strconv.Itoa(os.Getpid()),
+	}
+	s, err := newSink(t, opt.SinkOptions)
+	if err != nil {
+		return nil, err
+	}
+	return &SyslogSink{s}, nil
+}
+
+// syslogTransport formats and sends syslog messages
+type syslogTransport struct {
+	conn connTransport
+	opts SyslogOptions
+	pid  string
+}
+
+func (t *syslogTransport) send(batch []sinkItem) error {
+	var buf bytes.Buffer
+	for _, item := range batch {
+		msg := t.format(item)
+		if t.conn.network == "udp" {
+			// One datagram per message
+			if err := t.conn.write(msg); err != nil {
+				return err
+			}
+			continue
+		}
+		buf.WriteString(strconv.Itoa(len(msg)))
+		buf.WriteByte(' ')
+		buf.Write(msg)
+	}
+	if buf.Len() == 0 {
+		return nil
+	}
+	return t.conn.write(buf.Bytes())
+}
+
+func (t *syslogTransport) close() error {
+	return t.conn.close()
+}
+
+// format builds an RFC 5424 message: <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID SD MSG
+func (t *syslogTransport) format(item sinkItem) []byte {
+	pri := t.opts.Facility*8 + syslogSeverity(item.level)
+	header := fmt.Sprintf("<%d>1 %s %s %s %s - - ",
+		pri,
+		item.time.Format("2006-01-02T15:04:05.000000Z07:00"),
+		syslogField(t.opts.Hostname, 255),
+		syslogField(t.opts.AppName, 48),
+		t.pid,
+	)
+	return append([]byte(header), bytes.TrimRight(item.data, "\r\n")...)
+}
+
+// syslogField returns "-" for empty values and truncates to max printable ASCII characters
+func syslogField(value string, max int) string {
+	out := make([]byte, 0, len(value))
+	for i := 0; i < len(value) && len(out) < max; i++ {
+		if c := value[i]; c > ' ' && c < 0x7f {
+			out = append(out, c)
+		}
+	}
+	if len(out) == 0 {
+		return "-"
+	}
+	return string(out)
+}
+
+// ========== TCP ==========
+
+// TCPSink sends entries as newline-terminated lines over TCP
+type TCPSink struct {
+	*sink
+}
+
+// NewTCPSink creates a line sink for addr, e.g. "localhost:5170"
+func NewTCPSink(addr string, opts ...SinkOptions) (*TCPSink, error) {
+	var opt SinkOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	s, err := newSink(&tcpTransport{conn: connTransport{network: "tcp", addr: addr}}, opt)
+	if err != nil {
+		return nil, err
+	}
+	return &TCPSink{s}, nil
+}
+
+// tcpTransport writes batches as lines
+type tcpTransport struct {
+	conn connTransport
+}
+
+func (t *tcpTransport) send(batch []sinkItem) error {
+	return t.conn.write(joinLines(batch))
+}
+
+func (t *tcpTransport) close() error {
+	return t.conn.close()
+}
+
+// connTransport is a lazily dialed connection that redials after errors
+type connTransport struct {
+	network   string
+	addr      string
+	tlsConfig *tls.Config
+	timeout   time.Duration
+	conn      net.Conn
+}
+
+// write sends p, dropping the connection on error so the next write redials
+func (c *connTransport) write(p []byte) error {
+	if c.conn == nil {
+		conn, err := c.dial()
+		if err != nil {
+			return err
+		}
+		c.conn = conn
+	}
+
+	c.conn.SetWriteDeadline(time.Now().Add(c.dialTimeout()))
+	if _, err := c.conn.Write(p); err != nil {
+		c.conn.Close()
+		c.conn = nil
+		return fmt.Errorf("failed to write to %s: %w", c.addr, err)
+	}
+	return nil
+}
+
+func (c *connTransport) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: c.dialTimeout()}
+	var conn net.Conn
+	var err error
+	if c.network == "tls" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", c.addr, c.tlsConfig)
+	} else {
+		conn, err = dialer.Dial(c.network, c.addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", c.addr, err)
+	}
+	return conn, nil
+}
+
+func (c *connTransport) dialTimeout() time.Duration {
+	if c.timeout > 0 {
+		return c.timeout
+	}
+	return 5 * time.Second
+}
+
+func (c *connTransport) close() error {
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+// ========== HTTP ==========
+
+// HTTPSinkOptions configures an HTTPSink
+type HTTPSinkOptions struct {
+	SinkOptions
+	// Headers are added to every request, e.g. for authentication
+	Headers map[string]string
+	// Timeout per request; defaults to 10s
+	Timeout time.Duration
+	// Client overrides the HTTP client
+	Client *http.Client
+}
+
+// HTTPSink posts batches of entries as newline-delimited JSON. Use it with a
+// JSON formatter so each entry is one JSON object.
+type HTTPSink struct {
+	*sink
+}
+
+// NewHTTPSink creates a sink posting to url
+func NewHTTPSink(url string, opts ...HTTPSinkOptions) (*HTTPSink, error) {
+	var opt HTTPSinkOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	client := opt.Client
+	if client == nil {
+		timeout := opt.Timeout
+		if timeout <= 0 {
+			timeout = 10 * time.Second
+		}
+		client = &http.Client{Timeout: timeout}
+	}
+
+	s, err := newSink(&httpTransport{url: url, headers: opt.Headers, client: client}, opt.SinkOptions)
+	if err != nil {
+		return nil, err
+	}
+	return &HTTPSink{s}, nil
+}
+
+// httpTransport posts NDJSON batches
+type httpTransport struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+func (t *httpTransport) send(batch []sinkItem) error {
+	req, err := http.NewRequest(http.MethodPost, t.url, bytes.NewReader(joinLines(batch)))
+	if err != nil {
+		return fmt.Errorf("%w: failed to create request: %v", errPermanent, err)
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send logs: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fmt.Errorf("log endpoint returned status %d", resp.StatusCode)
+	default:
+		// Other client errors will not succeed on retry
+		return fmt.Errorf("%w: log endpoint returned status %d", errPermanent, resp.StatusCode)
+	}
+}
+
+func (t *httpTransport) close() error {
+	t.client.CloseIdleConnections()
+	return nil
+}
+
+// joinLines concatenates entries, terminating each with a newline
+func joinLines(batch []sinkItem) []byte {
+	var buf bytes.Buffer
+	for _, item := range batch {
+		buf.Write(bytes.TrimRight(item.data, "\r\n"))
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,500 @@
+package logger
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression selects how rotated log files are compressed
+type Compression int
+
+const (
+	// CompressNone keeps rotated files as plain text
+	CompressNone Compression = iota
+	// CompressGzip compresses rotated files to .gz
+	CompressGzip
+	// CompressZstd compresses rotated files to .zst
+	CompressZstd
+)
+
+// extension returns the file extension added by the compression
+func (c Compression) extension() string {
+	switch c {
+	case CompressGzip:
+		return ".gz"
+	case CompressZstd:
+		return ".zst"
+	default:
+		return ""
+	}
+}
+
+// backupTimeFormat is the timestamp in rotated file names, e.g. app-2024-01-02T15-04-05.000.log
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// RotateOptions configures a RotatingFileWriter
+type RotateOptions struct {
+	// MaxSize rotates the file before it grows past this many bytes; 0 disables size rotation
+	MaxSize int64
+	// Interval rotates the file at each interval boundary, e.g. 24*time.Hour for
+	// daily files at local midnight; 0 disables time rotation
+	Interval time.Duration
+	// MaxBackups is the number of rotated files to keep; 0 keeps all
+	MaxBackups int
+	// MaxAge removes rotated files older than this; 0 keeps all
+	MaxAge time.Duration
+	// Compress compresses rotated files in the background
+	Compress Compression
+	// Symlink, if set, is kept pointing at the active log file
+	Symlink string
+	// ReopenOnSIGHUP reopens the file on SIGHUP, after an external logrotate moved it
+	ReopenOnSIGHUP bool
+}
+
+// RotatingFileWriter is an io.Writer that rotates a log file by size and time.
+// The active file keeps its name; rotated files get a timestamp suffix and are
+// compressed and pruned by a background goroutine.
+type RotatingFileWriter struct {
+	filename string
+	opts     RotateOptions
+
+	mu           sync.Mutex
+	file         *os.File
+	size         int64
+	nextRotation time.Time
+	now          func() time.Time
+	rename       func(oldpath, newpath string) error
+
+	// Set by FileHandler and DailyFileHandler to keep their file names
+	numbered  int    // backups are filename.1 to filename.N instead of timestamped
+	dailyBase string // the active file is dailyBase-YYYY-MM-DD.log and is never renamed
+
+	millCh  chan struct{}
+	millWg  sync.WaitGroup
+	signals chan os.Signal
+	closed  bool
+}
+
+// NewRotatingFileWriter opens filename for appending, creating its directory if needed
+func NewRotatingFileWriter(filename string, opts ...RotateOptions) (*RotatingFileWriter, error) {
+	var opt RotateOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	w := &RotatingFileWriter{
+		filename: filename,
+		opts:     opt,
+		now:      time.Now,
+		rename:   os.Rename,
+		millCh:   make(chan struct{}, 1),
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+	if err := w.openExisting(); err != nil {
+		return nil, err
+	}
+
+	w.millWg.Add(1)
+	go w.millRun()
+
+	if opt.ReopenOnSIGHUP {
+		w.signals = make(chan os.Signal, 1)
+		signal.Notify(w.signals, syscall.SIGHUP)
+		go w.handleSignals()
+	}
+
+	return w, nil
+}
+
+// Write writes p to the active file, rotating first when a trigger is due
+func (w *RotatingFileWriter) Write(p []byte) (n int, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+
+	if w.rotationDue(int64(len(p))) {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err = w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Rotate closes the active file, renames it with a timestamp and opens a new one
+func (w *RotatingFileWriter) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return os.ErrClosed
+	}
+	return w.rotate()
+}
+
+// Reopen closes and reopens the file by name, for use after an external tool moved it
+func (w *RotatingFileWriter) Reopen() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return os.ErrClosed
+	}
+	if err := w.file.Close(); err != nil {
+		return fmt.Errorf("failed to close log file: %w", err)
+	}
+	return w.openExisting()
+}
+
+// Close closes the file and waits for background compression and cleanup
+func (w *RotatingFileWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	if w.signals != nil {
+		signal.Stop(w.signals)
+		close(w.signals)
+	}
+	err := w.file.Close()
+	w.mu.Unlock()
+
+	close(w.millCh)
+	w.millWg.Wait()
+	return err
+}
+
+// rotationDue reports whether writing n more bytes needs a rotation; w.mu must be held
+func (w *RotatingFileWriter) rotationDue(n int64) bool {
+	if w.opts.MaxSize > 0 && w.size > 0 && w.size+n > w.opts.MaxSize {
+		return true
+	}
+	return w.opts.Interval > 0 && !w.now().Before(w.nextRotation)
+}
+
+// rotate moves the active file to a backup name; w.mu must be held
+func (w *RotatingFileWriter) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return w.restore(fmt.Errorf("failed to close log file: %w", err))
+	}
+
+	if w.dailyBase != "" {
+		// Each day writes its own file, so nothing is renamed
+		w.filename = dailyName(w.dailyBase, w.now())
+		if err := w.openExisting(); err != nil {
+			return w.restore(err)
+		}
+		return nil
+	}
+
+	if err := w.moveToBackup(); err != nil && !os.IsNotExist(err) {
+		return w.restore(fmt.Errorf("failed to rename log file: %w", err))
+	}
+
+	if err := w.openNew(); err != nil {
+		return w.restore(err)
+	}
+
+	// Wake the mill without blocking; one pending signal covers all rotations
+	select {
+	case w.millCh <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// moveToBackup renames the active file to a timestamped backup, or to
+// filename.1 after shifting numbered backups up; the oldest is overwritten
+func (w *RotatingFileWriter) moveToBackup() error {
+	if w.numbered == 0 {
+		return w.rename(w.filename, w.backupName(w.now()))
+	}
+	for i := w.numbered - 1; i > 0; i-- {
+		older := fmt.Sprintf("%s.%d", w.filename, i)
+		if !fileExists(older) {
+			continue
+		}
+		if err := w.rename(older, fmt.Sprintf("%s.%d", w.filename, i+1)); err != nil {
+			return err
+		}
+	}
+	return w.rename(w.filename, w.filename+".1")
+}
+
+// dailyName returns the file of DailyFileHandler for the day of t
+func dailyName(base string, t time.Time) string {
+	return fmt.Sprintf("%s-%s.log", base, t.Format("2006-01-02"))
+}
+
+// restore reopens the active file after a failed rotation, so later writes
+// retry the rotation instead of failing on a closed file; w.mu must be held
+func (w *RotatingFileWriter) restore(err error) error {
+	w.file.Close()
+	if reopenErr := w.openExisting(); reopenErr != nil {
+		return errors.Join(err, reopenErr)
+	}
+	return err
+}
+
+// openExisting opens the active file for appending. A file last written in an
+// earlier interval is rotated on the first write.
+func (w *RotatingFileWriter) openExisting() error {
+	info, err := os.Stat(w.filename)
+	if os.IsNotExist(err) {
+		return w.openNew()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get file info: %w", err)
+	}
+
+	file, err := os.OpenFile(w.filename, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	w.file = file
+	w.size = info.Size()
+	w.nextRotation = w.nextBoundary(info.ModTime())
+	return w.updateSymlink()
+}
+
+// openNew creates an empty active file
+func (w *RotatingFileWriter) openNew() error {
+	file, err := os.OpenFile(w.filename, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	w.file = file
+	w.size = 0
+	w.nextRotation = w.nextBoundary(w.now())
+	return w.updateSymlink()
+}
+
+// nextBoundary returns the first interval boundary after t. Whole days are
+// aligned to local midnight, shorter intervals to the clock.
+func (w *RotatingFileWriter) nextBoundary(t time.Time) time.Time {
+	interval := w.opts.Interval
+	if interval <= 0 {
+		return time.Time{}
+	}
+
+	const day = 24 * time.Hour
+	if interval%day == 0 {
+		midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+		return midnight.AddDate(0, 0, int(interval/day))
+	}
+	return t.Truncate(interval).Add(interval)
+}
+
+// updateSymlink points the symlink at the active file
+func (w *RotatingFileWriter) updateSymlink() error {
+	if w.opts.Symlink == "" {
+		return nil
+	}
+
+	target, err := filepath.Abs(w.filename)
+	if err != nil {
+		return fmt.Errorf("failed to resolve log file path: %w", err)
+	}
+	tmp := w.opts.Symlink + ".tmp"
+	os.Remove(tmp)
+	if err := os.Symlink(target, tmp); err != nil {
+		return fmt.Errorf("failed to create symlink: %w", err)
+	}
+	if err := os.Rename(tmp, w.opts.Symlink); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to update symlink: %w", err)
+	}
+	return nil
+}
+
+// backupName returns an unused backup file name for time t
+func (w *RotatingFileWriter) backupName(t time.Time) string {
+	dir, prefix, ext := w.nameParts()
+	for {
+		name := filepath.Join(dir, prefix+t.In(time.Local).Format(backupTimeFormat)+ext)
+		if !fileExists(name) && !fileExists(name+CompressGzip.extension()) && !fileExists(name+CompressZstd.extension()) {
+			return name
+		}
+		t = t.Add(time.Millisecond)
+	}
+}
+
+// nameParts splits the file name into directory, backup prefix and extension
+func (w *RotatingFileWriter) nameParts() (dir, prefix, ext string) {
+	dir = filepath.Dir(w.filename)
+	base := filepath.Base(w.filename)
+	ext = filepath.Ext(base)
+	return dir, strings.TrimSuffix(base, ext) + "-", ext
+}
+
+func fileExists(name string) bool {
+	_, err := os.Lstat(name)
+	return err == nil
+}
+
+// handleSignals reopens the file on SIGHUP until Close
+func (w *RotatingFileWriter) handleSignals() {
+	for range w.signals {
+		if err := w.Reopen(); err != nil && err != os.ErrClosed {
+			fmt.Fprintf(os.Stderr, "Failed to reopen log file: %v\n", err)
+		}
+	}
+}
+
+// ========== COMPRESSION AND RETENTION ==========
+
+// backupFile is a rotated log file
+type backupFile struct {
+	path       string
+	time       time.Time
+	compressed bool
+}
+
+// millRun compresses and prunes backups after each rotation
+func (w *RotatingFileWriter) millRun() {
+	defer w.millWg.Done()
+	for range w.millCh {
+		if err := w.mill(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to clean up log files: %v\n", err)
+		}
+	}
+}
+
+// mill compresses plain backups and removes backups past the retention limits
+func (w *RotatingFileWriter) mill() error {
+	backups, err := w.backups()
+	if err != nil {
+		return err
+	}
+
+	// Newest first
+	sort.Slice(backups, func(i, j int) bool { return backups[i].time.After(backups[j].time) })
+
+	cutoff := time.Time{}
+	if w.opts.MaxAge > 0 {
+		cutoff = w.now().Add(-w.opts.MaxAge)
+	}
+
+	var errs []error
+	for i, b := range backups {
+		expired := (w.opts.MaxBackups > 0 && i >= w.opts.MaxBackups) || (!cutoff.IsZero() && b.time.Before(cutoff))
+		if expired {
+			if err := os.Remove(b.path); err != nil && !os.IsNotExist(err) {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		if !b.compressed && w.opts.Compress != CompressNone {
+			if err := compressFile(b.path, b.path+w.opts.Compress.extension(), w.opts.Compress); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// backups lists rotated files of this writer
+func (w *RotatingFileWriter) backups() ([]backupFile, error) {
+	dir, prefix, ext := w.nameParts()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read log directory: %w", err)
+	}
+
+	var backups []backupFile
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+
+		stamp := strings.TrimPrefix(name, prefix)
+		compressed := false
+		for _, c := range []Compression{CompressGzip, CompressZstd} {
+			if strings.HasSuffix(stamp, ext+c.extension()) {
+				stamp = strings.TrimSuffix(stamp, c.extension())
+				compressed = true
+				break
+			}
+		}
+		if !strings.HasSuffix(stamp, ext) {
+			continue
+		}
+
+		t, err := time.ParseInLocation(backupTimeFormat, strings.TrimSuffix(stamp, ext), time.Local)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backupFile{path: filepath.Join(dir, name), time: t, compressed: compressed})
+	}
+	return backups, nil
+}
+
+// compressFile writes src compressed to dst and removes src
+func compressFile(src, dst string, compression Compression) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create compressed log file: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			out.Close()
+			os.Remove(dst)
+		}
+	}()
+
+	var enc io.WriteCloser
+	switch compression {
+	case CompressZstd:
+		if enc, err = zstd.NewWriter(out); err != nil {
+			return fmt.Errorf("failed to create zstd writer: %w", err)
+		}
+	default:
+		enc = gzip.NewWriter(out)
+	}
+
+	if _, err = io.Copy(enc, in); err != nil {
+		return fmt.Errorf("failed to compress log file: %w", err)
+	}
+	if err = enc.Close(); err != nil {
+		return fmt.Errorf("failed to compress log file: %w", err)
+	}
+	if err = out.Close(); err != nil {
+		return fmt.Errorf("failed to close compressed log file: %w", err)
+	}
+
+	in.Close()
+	return os.Remove(src)
+}
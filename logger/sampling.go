@@ -0,0 +1,191 @@
+package logger
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SamplingConfig keeps the first Initial entries per message and level in each
+// Interval, then every Thereafter-th entry. Thereafter of 0 drops the rest.
+type SamplingConfig struct {
+	Initial    int
+	Thereafter int
+	Interval   time.Duration // defaults to 1s
+}
+
+// SuppressedStats counts entries dropped before formatting
+type SuppressedStats struct {
+	Sampled      uint64
+	Deduplicated uint64
+	Capped       uint64
+}
+
+// throttle applies sampling, deduplication and level caps. It is shared by a
+// logger and all loggers derived from it.
+type throttle struct {
+	sampling    *SamplingConfig
+	dedupWindow time.Duration
+	levelCaps   map[Level]int
+
+	mu          sync.Mutex
+	sampleStart time.Time
+	samples     map[string]int
+	dedup       map[string]*dedupState
+	capStart    time.Time
+	capCounts   map[Level]int
+
+	sampled      atomic.Uint64
+	deduplicated atomic.Uint64
+	capped       atomic.Uint64
+}
+
+// dedupState tracks repeats of one entry inside the dedup window
+type dedupState struct {
+	level  Level
+	msg    string
+	count  int
+	logger *Logger // last logger that repeated the entry, for its fields
+	timer  *time.Timer
+}
+
+// newThrottle returns nil when config enables none of the filters
+func newThrottle(config Config) *throttle {
+	if config.Sampling == nil && config.DedupWindow <= 0 && len(config.LevelCaps) == 0 {
+		return nil
+	}
+
+	t := &throttle{
+		dedupWindow: config.DedupWindow,
+		samples:     make(map[string]int),
+		dedup:       make(map[string]*dedupState),
+		capCounts:   make(map[Level]int),
+	}
+	if config.Sampling != nil {
+		sampling := *config.Sampling
+		if sampling.Interval <= 0 {
+			sampling.Interval = time.Second
+		}
+		t.sampling = &sampling
+	}
+	if len(config.LevelCaps) > 0 {
+		t.levelCaps = make(map[Level]int, len(config.LevelCaps))
+		for level, limit := range config.LevelCaps {
+			t.levelCaps[level] = limit
+		}
+	}
+	return t
+}
+
+// allow reports whether the entry should be written. FATAL and PANIC always are.
+func (t *throttle) allow(l *Logger, level Level, msg string) bool {
+	if level >= FATAL {
+		return true
+	}
+
+	key := level.String() + "\x00" + msg
+	now := time.Now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.sampling != nil && !t.sample(key, now) {
+		t.sampled.Add(1)
+		return false
+	}
+
+	if t.dedupWindow > 0 {
+		if state, ok := t.dedup[key]; ok {
+			state.count++
+			state.logger = l
+			t.deduplicated.Add(1)
+			return false
+		}
+		state := &dedupState{level: level, msg: msg}
+		state.timer = time.AfterFunc(t.dedupWindow, func() { t.flushDuplicate(key) })
+		t.dedup[key] = state
+	}
+
+	if limit, ok := t.levelCaps[level]; ok && limit > 0 {
+		if now.Sub(t.capStart) >= time.Second {
+			t.capStart = now
+			clear(t.capCounts)
+		}
+		if t.capCounts[level] >= limit {
+			t.capped.Add(1)
+			return false
+		}
+		t.capCounts[level]++
+	}
+
+	return true
+}
+
+// sample counts the entry in the current interval; t.mu must be held
+func (t *throttle) sample(key string, now time.Time) bool {
+	if now.Sub(t.sampleStart) >= t.sampling.Interval {
+		t.sampleStart = now
+		clear(t.samples)
+	}
+
+	t.samples[key]++
+	n := t.samples[key]
+	if n <= t.sampling.Initial {
+		return true
+	}
+	return t.sampling.Thereafter > 0 && (n-t.sampling.Initial)%t.sampling.Thereafter == 0
+}
+
+// flushDuplicate closes the dedup window for key, writing one summary entry
+// with a repeated count when the entry was suppressed
+func (t *throttle) flushDuplicate(key string) {
+	t.mu.Lock()
+	state, ok := t.dedup[key]
+	if ok {
+		delete(t.dedup, key)
+		state.timer.Stop()
+	}
+	t.mu.Unlock()
+
+	if !ok || state.count == 0 {
+		return
+	}
+
+	summary := state.logger.WithField("repeated", state.count)
+	summary.throttle = nil
+	summary.config.WithCaller = false
+	summary.log(state.level, state.msg)
+}
+
+// flush writes pending dedup summaries immediately
+func (t *throttle) flush() {
+	t.mu.Lock()
+	keys := make([]string, 0, len(t.dedup))
+	for key := range t.dedup {
+		keys = append(keys, key)
+	}
+	t.mu.Unlock()
+
+	for _, key := range keys {
+		t.flushDuplicate(key)
+	}
+}
+
+// FlushSuppressed writes pending dedup summaries without waiting for the window to close
+func (l *Logger) FlushSuppressed() {
+	if l.throttle != nil {
+		l.throttle.flush()
+	}
+}
+
+// Suppressed returns how many entries were dropped by sampling, deduplication and level caps
+func (l *Logger) Suppressed() SuppressedStats {
+	if l.throttle == nil {
+		return SuppressedStats{}
+	}
+	return SuppressedStats{
+		Sampled:      l.throttle.sampled.Load(),
+		Deduplicated: l.throttle.deduplicated.Load(),
+		Capped:       l.throttle.capped.Load(),
+	}
+}
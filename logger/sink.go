@@ -0,0 +1,522 @@
+package logger
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LevelWriter is an output that also receives the level of each entry, so it
+// can filter or tag entries. Logger calls WriteLevel instead of Write when the
+// output implements it.
+type LevelWriter interface {
+	io.Writer
+	WriteLevel(level Level, p []byte) (n int, err error)
+}
+
+// writeOutput writes formatted to w, passing the level to LevelWriters
+func writeOutput(w io.Writer, level Level, formatted []byte) (int, error) {
+	if lw, ok := w.(LevelWriter); ok {
+		return lw.WriteLevel(level, formatted)
+	}
+	return w.Write(formatted)
+}
+
+// LevelFilter passes entries at or above MinLevel to the wrapped writer
+type LevelFilter struct {
+	w        io.Writer
+	minLevel Level
+}
+
+// NewLevelFilter wraps w so it only receives entries at or above minLevel
+func NewLevelFilter(w io.Writer, minLevel Level) *LevelFilter {
+	return &LevelFilter{w: w, minLevel: minLevel}
+}
+
+// Write writes p unfiltered; the level is unknown
+func (f *LevelFilter) Write(p []byte) (int, error) {
+	return f.w.Write(p)
+}
+
+// WriteLevel writes p when level is at or above the minimum
+func (f *LevelFilter) WriteLevel(level Level, p []byte) (int, error) {
+	if level < f.minLevel {
+		return len(p), nil
+	}
+	return writeOutput(f.w, level, p)
+}
+
+// ========== NETWORK SINK ==========
+
+// SinkOptions configures buffering and retries of network sinks
+type SinkOptions struct {
+	// MinLevel drops entries below this level
+	MinLevel Level
+	// BufferSize is the number of entries queued in memory; defaults to 1024
+	BufferSize int
+	// BatchSize is the maximum number of entries sent at once; defaults to 100
+	BatchSize int
+	// FlushInterval sends partial batches after this delay; defaults to 1s
+	FlushInterval time.Duration
+	// MaxRetries is the number of retries before a batch is spilled or dropped; defaults to 3
+	MaxRetries int
+	// RetryBackoff is the first retry delay, doubled up to MaxBackoff; defaults to 100ms
+	RetryBackoff time.Duration
+	// MaxBackoff caps the retry delay; defaults to 30s
+	MaxBackoff time.Duration
+	// SpillPath is a file that buffers entries while the remote is down; empty disables spilling
+	SpillPath string
+	// MaxSpillSize caps the spill file; entries beyond it are dropped. Defaults to 64MB
+	MaxSpillSize int64
+}
+
+// withDefaults fills unset options
+func (o SinkOptions) withDefaults() SinkOptions {
+	if o.BufferSize <= 0 {
+		o.BufferSize = 1024
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = 100
+	}
+	if o.FlushInterval <= 0 {
+		o.FlushInterval = time.Second
+	}
+	if o.MaxRetries < 0 {
+		o.MaxRetries = 0
+	} else if o.MaxRetries == 0 {
+		o.MaxRetries = 3
+	}
+	if o.RetryBackoff <= 0 {
+		o.RetryBackoff = 100 * time.Millisecond
+	}
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = 30 * time.Second
+	}
+	if o.MaxSpillSize <= 0 {
+		o.MaxSpillSize = 64 << 20
+	}
+	return o
+}
+
+// SinkStats counts entries handled by a network sink
+type SinkStats struct {
+	Sent    uint64
+	Retries uint64
+	Spilled uint64
+	Dropped uint64
+}
+
+// errPermanent marks send errors that retrying cannot fix
+var errPermanent = errors.New("permanent failure")
+
+// sinkItem is one formatted entry
+type sinkItem struct {
+	level Level
+	time  time.Time // when the entry was written, kept across spilling
+	data  []byte
+}
+
+// transport delivers batches to a remote; send must be safe to call again after an error
+type transport interface {
+	send(batch []sinkItem) error
+	close() error
+}
+
+// sink queues entries and ships them with a transport from a single worker.
+// Delivery is at least once: a batch that failed part way is sent again.
+// Entries keep their order unless the queue overflows into the spill file.
+type sink struct {
+	opts      SinkOptions
+	transport transport
+	spill     *spillFile
+
+	queue chan sinkItem
+	done  chan struct{}
+
+	mu        sync.Mutex
+	closed    bool
+	flushReqs chan chan struct{}
+
+	nextReplay time.Time     // owned by the worker
+	backoff    time.Duration // owned by the worker
+
+	sent    atomic.Uint64
+	retries atomic.Uint64
+	spilled atomic.Uint64
+	dropped atomic.Uint64
+}
+
+// newSink starts the worker for t
+func newSink(t transport, opts SinkOptions) (*sink, error) {
+	opts = opts.withDefaults()
+	s := &sink{
+		opts:      opts,
+		transport: t,
+		queue:     make(chan sinkItem, opts.BufferSize),
+		done:      make(chan struct{}),
+		flushReqs: make(chan chan struct{}),
+	}
+
+	if opts.SpillPath != "" {
+		spill, err := openSpillFile(opts.SpillPath, opts.MaxSpillSize)
+		if err != nil {
+			return nil, err
+		}
+		s.spill = spill
+	}
+
+	go s.run()
+	return s, nil
+}
+
+// Write queues p at INFO level
+func (s *sink) Write(p []byte) (int, error) {
+	return s.WriteLevel(INFO, p)
+}
+
+// WriteLevel queues p without blocking. When the queue is full the entry is
+// spilled to disk, or dropped when spilling is disabled.
+func (s *sink) WriteLevel(level Level, p []byte) (int, error) {
+	if level < s.opts.MinLevel {
+		return len(p), nil
+	}
+
+	item := sinkItem{level: level, time: time.Now(), data: append([]byte(nil), p...)}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return 0, os.ErrClosed
+	}
+
+	select {
+	case s.queue <- item:
+	default:
+		s.spillOrDrop([]sinkItem{item})
+	}
+	return len(p), nil
+}
+
+// Flush waits until queued entries have been sent, spilled or dropped
+func (s *sink) Flush() {
+	done := make(chan struct{})
+	select {
+	case s.flushReqs <- done:
+		<-done
+	case <-s.done:
+	}
+}
+
+// Close sends queued entries and closes the connection. Entries that cannot be
+// sent stay in the spill file for the next start.
+func (s *sink) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.queue)
+	s.mu.Unlock()
+
+	<-s.done
+	err := s.transport.close()
+	if s.spill != nil {
+		if spillErr := s.spill.close(); err == nil {
+			err = spillErr
+		}
+	}
+	return err
+}
+
+// Stats returns delivery counters
+func (s *sink) Stats() SinkStats {
+	return SinkStats{
+		Sent:    s.sent.Load(),
+		Retries: s.retries.Load(),
+		Spilled: s.spilled.Load(),
+		Dropped: s.dropped.Load(),
+	}
+}
+
+// run batches queued entries until the queue is closed
+func (s *sink) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.opts.FlushInterval)
+	defer ticker.Stop()
+
+	var batch []sinkItem
+	for {
+		select {
+		case item, ok := <-s.queue:
+			if !ok {
+				s.deliver(batch)
+				return
+			}
+			batch = append(batch, item)
+			if len(batch) < s.opts.BatchSize {
+				continue
+			}
+		case done := <-s.flushReqs:
+			// Drain what is queued now, then answer
+			for len(s.queue) > 0 {
+				batch = append(batch, <-s.queue)
+				if len(batch) >= s.opts.BatchSize {
+					s.deliver(batch)
+					batch = nil
+				}
+			}
+			s.deliver(batch)
+			batch = nil
+			close(done)
+			continue
+		case <-ticker.C:
+			if len(batch) == 0 {
+				s.replay(false)
+				continue
+			}
+		}
+
+		s.deliver(batch)
+		batch = nil
+	}
+}
+
+// deliver sends a batch, keeping spilled entries ahead of it
+func (s *sink) deliver(batch []sinkItem) {
+	if len(batch) == 0 {
+		return
+	}
+	if s.spill != nil && s.spill.pending() && !s.replay(true) {
+		s.spillOrDrop(batch)
+		return
+	}
+	if err := s.sendWithRetry(batch); err != nil {
+		if errors.Is(err, errPermanent) {
+			s.dropped.Add(uint64(len(batch)))
+			return
+		}
+		s.spillOrDrop(batch)
+		if s.backoff == 0 {
+			s.backoff = s.opts.RetryBackoff
+			s.nextReplay = time.Now().Add(s.backoff)
+		}
+	}
+}
+
+// sendWithRetry sends the batch, retrying with exponential backoff
+func (s *sink) sendWithRetry(batch []sinkItem) error {
+	backoff := s.opts.RetryBackoff
+	var err error
+	for attempt := 0; attempt <= s.opts.MaxRetries; attempt++ {
+		if attempt > 0 {
+			s.retries.Add(1)
+			time.Sleep(backoff)
+			backoff = min(backoff*2, s.opts.MaxBackoff)
+		}
+		if err = s.transport.send(batch); err == nil {
+			s.sent.Add(uint64(len(batch)))
+			s.backoff = 0
+			return nil
+		}
+		if errors.Is(err, errPermanent) {
+			return err
+		}
+	}
+	return err
+}
+
+// replay sends spilled entries, at most once per backoff period unless force
+// is set. It reports whether the spill file is empty afterwards.
+func (s *sink) replay(force bool) bool {
+	if s.spill == nil || !s.spill.pending() {
+		return true
+	}
+	if !force && time.Now().Before(s.nextReplay) {
+		return false
+	}
+
+	// Writers keep spilling while the entries read here are sent; the file
+	// lock is only held to read and to remove what was sent
+	items, offset, err := s.spill.readAll()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read log spill file: %v\n", err)
+		return false
+	}
+
+	for start := 0; start < len(items); start += s.opts.BatchSize {
+		end := min(start+s.opts.BatchSize, len(items))
+		if err := s.transport.send(items[start:end]); err != nil && !errors.Is(err, errPermanent) {
+			// Keep what was not sent and wait longer before the next attempt
+			if err := s.spill.consume(offset, items[start:]); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to rewrite log spill file: %v\n", err)
+			}
+			s.backoff = min(max(s.backoff*2, s.opts.RetryBackoff), s.opts.MaxBackoff)
+			s.nextReplay = time.Now().Add(s.backoff)
+			return false
+		} else if err != nil {
+			s.dropped.Add(uint64(end - start))
+		} else {
+			s.sent.Add(uint64(end - start))
+		}
+	}
+
+	if err := s.spill.consume(offset, nil); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to truncate log spill file: %v\n", err)
+	}
+	s.backoff = 0
+	return !s.spill.pending()
+}
+
+// spillOrDrop writes entries to the spill file, counting those that do not fit as dropped
+func (s *sink) spillOrDrop(items []sinkItem) {
+	if s.spill == nil {
+		s.dropped.Add(uint64(len(items)))
+		return
+	}
+	written, err := s.spill.append(items)
+	s.spilled.Add(uint64(written))
+	s.dropped.Add(uint64(len(items) - written))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to spill log entries: %v\n", err)
+	}
+}
+
+// ========== SPILL FILE ==========
+
+// spillHeaderSize is the size of a record header: 4-byte length, 1-byte level
+// and the 8-byte entry time in Unix nanoseconds
+const spillHeaderSize = 13
+
+// spillFile stores entries as [header][data] records
+type spillFile struct {
+	mu      sync.Mutex
+	path    string
+	maxSize int64
+	file    *os.File
+	size    int64
+}
+
+// openSpillFile opens or creates the spill file, keeping entries left by a previous run
+func openSpillFile(path string, maxSize int64) (*spillFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create spill directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open spill file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to get spill file info: %w", err)
+	}
+	return &spillFile{path: path, maxSize: maxSize, file: file, size: info.Size()}, nil
+}
+
+// pending reports whether the file holds entries
+func (f *spillFile) pending() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.size > 0
+}
+
+// append writes as many items as fit under maxSize and returns how many were written
+func (f *spillFile) append(items []sinkItem) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.appendLocked(items)
+}
+
+// appendLocked is append with f.mu held
+func (f *spillFile) appendLocked(items []sinkItem) (int, error) {
+	w := bufio.NewWriter(f.file)
+	written := 0
+	for _, item := range items {
+		recordSize := int64(spillHeaderSize + len(item.data))
+		if f.size+recordSize > f.maxSize {
+			break
+		}
+		var header [spillHeaderSize]byte
+		binary.BigEndian.PutUint32(header[:4], uint32(len(item.data)))
+		header[4] = byte(item.level)
+		binary.BigEndian.PutUint64(header[5:], uint64(item.time.UnixNano()))
+		w.Write(header[:])
+		w.Write(item.data)
+		f.size += recordSize
+		written++
+	}
+	return written, w.Flush()
+}
+
+// readAll reads every stored item. It returns the size read, which consume
+// takes to keep entries appended in the meantime.
+func (f *spillFile) readAll() ([]sinkItem, int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, err := f.file.Seek(0, io.SeekStart); err != nil {
+		return nil, 0, err
+	}
+	r := bufio.NewReader(io.LimitReader(f.file, f.size))
+
+	var items []sinkItem
+	for {
+		var header [spillHeaderSize]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				// A torn record at the end is dropped
+				return items, f.size, nil
+			}
+			return items, f.size, err
+		}
+		data := make([]byte, binary.BigEndian.Uint32(header[:4]))
+		if _, err := io.ReadFull(r, data); err != nil {
+			return items, f.size, nil
+		}
+		items = append(items, sinkItem{
+			level: Level(header[4]),
+			time:  time.Unix(0, int64(binary.BigEndian.Uint64(header[5:]))),
+			data:  data,
+		})
+	}
+}
+
+// consume removes the first offset bytes, as returned by readAll, and stores
+// keep ahead of the entries appended since
+func (f *spillFile) consume(offset int64, keep []sinkItem) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	tail := make([]byte, f.size-offset)
+	if _, err := f.file.ReadAt(tail, offset); err != nil && err != io.EOF {
+		return err
+	}
+	if err := f.file.Truncate(0); err != nil {
+		return err
+	}
+	f.size = 0
+
+	if _, err := f.appendLocked(keep); err != nil {
+		return err
+	}
+	if _, err := f.file.Write(tail); err != nil {
+		return err
+	}
+	f.size += int64(len(tail))
+	return nil
+}
+
+func (f *spillFile) close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.file.Close()
+}
@@ -0,0 +1,293 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/selanim/sego/redact"
+)
+
+// slog levels for FATAL and PANIC, which have no slog equivalent
+const (
+	SlogLevelFatal = slog.LevelError + 4
+	SlogLevelPanic = slog.LevelError + 8
+)
+
+// ========== SLOG HANDLER ==========
+
+// SlogHandler is an slog.Handler that writes through a Logger's formatter and
+// output, so slog records get the same format and destination as the Logger.
+// Groups are flattened into dotted field keys.
+type SlogHandler struct {
+	logger *Logger
+	attrs  map[string]interface{}
+	group  string // dotted prefix for attrs added after WithGroup
+}
+
+// NewSlogHandler creates an slog.Handler backed by l. The logger's level,
+// fields and prefix apply to every record.
+func NewSlogHandler(l *Logger) *SlogHandler {
+	return &SlogHandler{logger: l, attrs: make(map[string]interface{})}
+}
+
+// Enabled reports whether the logger's level allows records at level
+func (h *SlogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.logger.Enabled(LevelFromSlog(level))
+}
+
+// Handle formats and writes the record. Fields from the context logger,
+// logger.WithField(ctx, ...) and the current span are added to the entry.
+func (h *SlogHandler) Handle(ctx context.Context, record slog.Record) error {
+	l := h.logger
+	level := LevelFromSlog(record.Level)
+	if l.throttle != nil && !l.throttle.allow(l, level, record.Message) {
+		return nil
+	}
+
+	fields := make(map[string]interface{}, len(l.fields)+len(h.attrs)+record.NumAttrs())
+	if ctx != nil {
+		if ctxLogger, ok := ctx.Value(LoggerKey).(*Logger); ok && ctxLogger != l {
+			for k, v := range ctxLogger.fields {
+				fields[k] = v
+			}
+		}
+	}
+	for k, v := range l.fields {
+		fields[k] = v
+	}
+	for k, v := range h.attrs {
+		fields[k] = v
+	}
+	record.Attrs(func(attr slog.Attr) bool {
+		addSlogAttr(fields, h.group, attr)
+		return true
+	})
+	if ctx != nil {
+		for k, v := range getFieldsFromContext(ctx) {
+			fields[k] = v
+		}
+		addTraceFields(ctx, fields)
+	}
+
+	entry := &Entry{
+		Time:    record.Time,
+		Level:   level,
+		Message: record.Message,
+		Fields:  fields,
+		Prefix:  l.prefix,
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	if l.config.WithCaller && record.PC != 0 {
+		entry.Caller = callerFromPC(record.PC)
+	}
+
+	l.write(entry)
+	return nil
+}
+
+// WithAttrs returns a handler that adds attrs to every record
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+
+	newHandler := h.clone()
+	for _, attr := range attrs {
+		addSlogAttr(newHandler.attrs, h.group, attr)
+	}
+	return newHandler
+}
+
+// WithGroup returns a handler that qualifies later attrs with name
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	newHandler := h.clone()
+	newHandler.group = h.group + name + "."
+	return newHandler
+}
+
+func (h *SlogHandler) clone() *SlogHandler {
+	attrs := make(map[string]interface{}, len(h.attrs))
+	for k, v := range h.attrs {
+		attrs[k] = v
+	}
+	return &SlogHandler{logger: h.logger, attrs: attrs, group: h.group}
+}
+
+// addSlogAttr resolves attr and stores it under prefix, flattening groups
+func addSlogAttr(fields map[string]interface{}, prefix string, attr slog.Attr) {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+
+	if attr.Value.Kind() == slog.KindGroup {
+		group := attr.Value.Group()
+		if len(group) == 0 {
+			return
+		}
+		if attr.Key != "" {
+			prefix += attr.Key + "."
+		}
+		for _, a := range group {
+			addSlogAttr(fields, prefix, a)
+		}
+		return
+	}
+
+	fields[prefix+attr.Key] = attr.Value.Any()
+}
+
+// callerFromPC builds caller info from a program counter
+func callerFromPC(pc uintptr) *CallerInfo {
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	if frame.File == "" {
+		return nil
+	}
+
+	funcName := frame.Function
+	if idx := strings.LastIndex(funcName, "/"); idx != -1 {
+		funcName = funcName[idx+1:]
+	}
+
+	return &CallerInfo{
+		File:     frame.File,
+		Line:     frame.Line,
+		Function: funcName,
+	}
+}
+
+// ========== LEVEL MAPPING ==========
+
+// LevelFromSlog converts an slog level to the nearest Level at or below it
+func LevelFromSlog(level slog.Level) Level {
+	switch {
+	case level < slog.LevelInfo:
+		return DEBUG
+	case level < slog.LevelWarn:
+		return INFO
+	case level < slog.LevelError:
+		return WARN
+	case level < SlogLevelFatal:
+		return ERROR
+	case level < SlogLevelPanic:
+		return FATAL
+	default:
+		return PANIC
+	}
+}
+
+// SlogLevel converts the level to an slog level
+func (l Level) SlogLevel() slog.Level {
+	switch l {
+	case DEBUG:
+		return slog.LevelDebug
+	case INFO:
+		return slog.LevelInfo
+	case WARN:
+		return slog.LevelWarn
+	case ERROR:
+		return slog.LevelError
+	case FATAL:
+		return SlogLevelFatal
+	default:
+		return SlogLevelPanic
+	}
+}
+
+// ========== SLOG-BACKED LOGGER ==========
+
+// NewFromSlog creates a Logger that sends every entry to h. Fields become
+// attrs, a prefix becomes a group, and the level check is left to h.
+func NewFromSlog(h slog.Handler) *Logger {
+	config := DefaultConfig()
+	config.Level = DEBUG
+	config.Output = nil
+
+	return &Logger{
+		config:   config,
+		fields:   make(map[string]interface{}),
+		handler:  h,
+		redactor: redact.Default,
+	}
+}
+
+// Slog returns an *slog.Logger that writes through l, keeping its fields and prefix
+func (l *Logger) Slog() *slog.Logger {
+	if l.handler == nil {
+		return slog.New(NewSlogHandler(l))
+	}
+	return slog.New(l.slogHandler())
+}
+
+// slogHandler returns l.handler with the logger's prefix and fields applied
+func (l *Logger) slogHandler() slog.Handler {
+	h := l.handler
+	if l.prefix != "" {
+		h = h.WithGroup(l.prefix)
+	}
+	if len(l.fields) > 0 {
+		h = h.WithAttrs(fieldsToAttrs(l.fields))
+	}
+	return h
+}
+
+// logToHandler sends the message to l.handler
+func (l *Logger) logToHandler(level Level, msg string) {
+	ctx := l.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	slogLevel := level.SlogLevel()
+	if !l.handler.Enabled(ctx, slogLevel) {
+		return
+	}
+
+	// Skip runtime.Callers, logToHandler, log, Log/Logf and the level method
+	var pcs [1]uintptr
+	runtime.Callers(5, pcs[:])
+
+	l.handleRecord(ctx, slog.NewRecord(time.Now(), slogLevel, msg, pcs[0]))
+}
+
+// handleRecord adds the logger's fields to record and passes it to l.handler
+func (l *Logger) handleRecord(ctx context.Context, record slog.Record) {
+	h := l.handler
+	if l.prefix != "" {
+		h = h.WithGroup(l.prefix)
+	}
+
+	fields := l.fields
+	if l.redactor != nil {
+		record.Message = l.redactor.String(record.Message)
+		fields = l.redactor.Map(fields)
+	}
+	record.AddAttrs(fieldsToAttrs(fields)...)
+
+	_ = h.Handle(ctx, record)
+}
+
+// fieldsToAttrs converts fields to attrs sorted by key
+func fieldsToAttrs(fields map[string]interface{}) []slog.Attr {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	attrs := make([]slog.Attr, 0, len(keys))
+	for _, k := range keys {
+		attrs = append(attrs, slog.Any(k, fields[k]))
+	}
+	return attrs
+}
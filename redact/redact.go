@@ -0,0 +1,355 @@
+// Package redact removes secrets from log fields, error metadata and free text.
+// Values are redacted by key name, by regular expressions over string content
+// and by struct tags:
+//
+//	Password string `redact:"true"` // masked
+//	Internal string `redact:"omit"` // dropped
+package redact
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/selanim/sego/stringsutils"
+	"github.com/selanim/sego/validation"
+)
+
+// Placeholder replaces fully redacted values
+const Placeholder = "[REDACTED]"
+
+// Masker turns a secret into its redacted form
+type Masker func(value string) string
+
+// Replace returns a Masker that replaces the whole value with text
+func Replace(text string) Masker {
+	return func(string) string { return text }
+}
+
+// MaskHead returns a Masker that keeps the first visible characters, e.g. "sk_l*****"
+func MaskHead(visible int) Masker {
+	return func(value string) string {
+		return stringsutils.Mask(value, visible, '*')
+	}
+}
+
+// MaskTail returns a Masker that keeps the last visible characters, e.g. "************4242"
+func MaskTail(visible int) Masker {
+	return func(value string) string {
+		return stringsutils.Reverse(stringsutils.Mask(stringsutils.Reverse(value), visible, '*'))
+	}
+}
+
+// Hash returns a Masker that replaces the value with a short SHA-256 digest,
+// so equal secrets can still be correlated
+func Hash() Masker {
+	return func(value string) string {
+		sum := sha256.Sum256([]byte(value))
+		return "sha256:" + hex.EncodeToString(sum[:6])
+	}
+}
+
+// Pattern redacts matches of a regular expression inside strings. When the
+// expression has a capture group, only the first group is masked.
+type Pattern struct {
+	Name   string
+	Regexp *regexp.Regexp
+	// Validate, if set, must accept a match before it is masked
+	Validate func(match string) bool
+	// Mask defaults to Replace(Placeholder)
+	Mask Masker
+}
+
+// DefaultKeys are key names redacted by default. Keys are compared
+// case-insensitively ignoring '_', '-' and '.', and match when they contain one of these.
+var DefaultKeys = []string{
+	"password", "passwd", "secret", "token", "apikey", "authorization",
+	"cookie", "privatekey", "credential", "creditcard", "cardnumber", "cvv", "ssn",
+}
+
+// DefaultPatterns detect JWTs, bearer tokens and card numbers that pass the Luhn check
+var DefaultPatterns = []Pattern{
+	{
+		Name:   "jwt",
+		Regexp: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`),
+	},
+	{
+		Name:   "bearer",
+		Regexp: regexp.MustCompile(`(?i)\bbearer\s+([A-Za-z0-9._~+/=-]+)`),
+	},
+	{
+		Name:     "card",
+		Regexp:   regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`),
+		Validate: isCardNumber,
+		Mask:     MaskTail(4),
+	},
+}
+
+// Options configures a Redactor
+type Options struct {
+	// Keys are redacted in addition to DefaultKeys
+	Keys []string
+	// Patterns are applied in addition to DefaultPatterns
+	Patterns []Pattern
+	// Mask is used for values redacted by key or struct tag; defaults to Replace(Placeholder)
+	Mask Masker
+	// NoDefaults drops DefaultKeys and DefaultPatterns
+	NoDefaults bool
+}
+
+// Redactor redacts secrets from values. It is safe for concurrent use.
+type Redactor struct {
+	keys     []string
+	patterns []Pattern
+	mask     Masker
+	types    sync.Map // reflect.Type -> bool, whether the type has redact tags
+}
+
+// Default is the redactor used by the logger and errorutils packages
+var Default = New()
+
+// New creates a redactor
+func New(opts ...Options) *Redactor {
+	var opt Options
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	r := &Redactor{mask: opt.Mask}
+	if r.mask == nil {
+		r.mask = Replace(Placeholder)
+	}
+
+	keys := opt.Keys
+	patterns := opt.Patterns
+	if !opt.NoDefaults {
+		keys = append(append([]string(nil), DefaultKeys...), keys...)
+		patterns = append(append([]Pattern(nil), DefaultPatterns...), patterns...)
+	}
+	for _, key := range keys {
+		if key = normalizeKey(key); key != "" {
+			r.keys = append(r.keys, key)
+		}
+	}
+	for _, p := range patterns {
+		if p.Mask == nil {
+			p.Mask = Replace(Placeholder)
+		}
+		r.patterns = append(r.patterns, p)
+	}
+
+	return r
+}
+
+// IsSensitiveKey reports whether values under key are redacted
+func (r *Redactor) IsSensitiveKey(key string) bool {
+	key = normalizeKey(key)
+	for _, k := range r.keys {
+		if strings.Contains(key, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// String masks pattern matches in s
+func (r *Redactor) String(s string) string {
+	for _, p := range r.patterns {
+		s = p.apply(s)
+	}
+	return s
+}
+
+// Value returns v with secrets redacted, using key to decide whether the whole
+// value is sensitive. Maps and structs with redact tags are copied, never modified.
+func (r *Redactor) Value(key string, v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	if key != "" && r.IsSensitiveKey(key) {
+		return r.maskValue(v)
+	}
+
+	switch val := v.(type) {
+	case string:
+		return r.String(val)
+	case map[string]interface{}:
+		return r.Map(val)
+	case map[string]string:
+		out := make(map[string]string, len(val))
+		for k, s := range val {
+			if r.IsSensitiveKey(k) {
+				out[k] = r.mask(s)
+			} else {
+				out[k] = r.String(s)
+			}
+		}
+		return out
+	case error:
+		if s := val.Error(); r.String(s) != s {
+			return r.String(s)
+		}
+		return v
+	}
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() == reflect.Struct && r.hasTags(rv.Type()) {
+		return r.structToMap(rv)
+	}
+	return v
+}
+
+// Map returns a copy of m with secrets redacted
+func (r *Redactor) Map(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		out[k] = r.Value(k, v)
+	}
+	return out
+}
+
+// maskValue redacts a value found under a sensitive key
+func (r *Redactor) maskValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case string:
+		return r.mask(val)
+	case []byte:
+		return r.mask(string(val))
+	}
+	return Placeholder
+}
+
+// apply masks the matches of p in s
+func (p Pattern) apply(s string) string {
+	if p.Regexp == nil {
+		return s
+	}
+
+	matches := p.Regexp.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) == 0 {
+		return s
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		if len(m) >= 4 && m[2] >= 0 {
+			start, end = m[2], m[3]
+		}
+		if p.Validate != nil && !p.Validate(s[start:end]) {
+			continue
+		}
+		b.WriteString(s[last:start])
+		b.WriteString(p.Mask(s[start:end]))
+		last = end
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// ========== STRUCT TAGS ==========
+
+// hasTags reports whether t or a nested struct has redact tags
+func (r *Redactor) hasTags(t reflect.Type) bool {
+	if cached, ok := r.types.Load(t); ok {
+		return cached.(bool)
+	}
+	found := r.scanTags(t, make(map[reflect.Type]bool))
+	r.types.Store(t, found)
+	return found
+}
+
+// scanTags looks for redact tags in t and its nested structs. visiting holds
+// the types already reached, which stops recursive types; only the answer for
+// the outermost type is cached, so other goroutines never see a partial one.
+func (r *Redactor) scanTags(t reflect.Type, visiting map[reflect.Type]bool) bool {
+	if cached, ok := r.types.Load(t); ok {
+		return cached.(bool)
+	}
+	if visiting[t] {
+		return false
+	}
+	visiting[t] = true
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if _, ok := field.Tag.Lookup("redact"); ok {
+			return true
+		}
+		ft := field.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if ft.Kind() == reflect.Struct && r.scanTags(ft, visiting) {
+			return true
+		}
+	}
+	return false
+}
+
+// structToMap converts a struct to a map keyed by json names, applying redact tags
+func (r *Redactor) structToMap(rv reflect.Value) map[string]interface{} {
+	t := rv.Type()
+	out := make(map[string]interface{}, t.NumField())
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := field.Name
+		if tag := field.Tag.Get("json"); tag != "" {
+			jsonName, _, _ := strings.Cut(tag, ",")
+			if jsonName == "-" {
+				continue
+			}
+			if jsonName != "" {
+				name = jsonName
+			}
+		}
+
+		value := rv.Field(i).Interface()
+		switch tag := field.Tag.Get("redact"); tag {
+		case "omit", "-":
+			continue
+		case "", "false":
+			out[name] = r.Value(name, value)
+		default:
+			out[name] = r.maskValue(value)
+		}
+	}
+	return out
+}
+
+// ========== HELPERS ==========
+
+// normalizeKey lowercases key and removes separators
+func normalizeKey(key string) string {
+	return strings.Map(func(c rune) rune {
+		switch c {
+		case '_', '-', '.', ' ':
+			return -1
+		}
+		return c
+	}, strings.ToLower(key))
+}
+
+// isCardNumber reports whether s is a Luhn-valid card number of 13 to 19 digits
+func isCardNumber(s string) bool {
+	digits := strings.NewReplacer(" ", "", "-", "").Replace(s)
+	return len(digits) >= 13 && len(digits) <= 19 && validation.IsValidCreditCard(digits)
+}
@@ -0,0 +1,159 @@
+package redact
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRedactKeys(t *testing.T) {
+	r := New(Options{Keys: []string{"pin"}})
+
+	fields := map[string]interface{}{
+		"password":      "hunter2",
+		"X-Api-Key":     "abc123",
+		"refresh_token": "r-1",
+		"user_pin":      1234,
+		"user_id":       42,
+		"nested":        map[string]interface{}{"client_secret": "s3cr3t", "name": "svc"},
+	}
+	out := r.Map(fields)
+
+	for _, key := range []string{"password", "X-Api-Key", "refresh_token", "user_pin"} {
+		if out[key] != Placeholder {
+			t.Errorf("Expected %s to be redacted, got %v", key, out[key])
+		}
+	}
+	if out["user_id"] != 42 {
+		t.Errorf("Expected user_id to be kept, got %v", out["user_id"])
+	}
+	nested := out["nested"].(map[string]interface{})
+	if nested["client_secret"] != Placeholder || nested["name"] != "svc" {
+		t.Errorf("Expected nested map to be redacted, got %v", nested)
+	}
+	if fields["password"] != "hunter2" {
+		t.Error("Expected the input map to be left unchanged")
+	}
+}
+
+func TestRedactPatterns(t *testing.T) {
+	r := New()
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"jwt", "token eyJhbGciOiJIUzI1NiJ9.eyJzdWIiOiIxIn0.sig-_1 used", "token [REDACTED] used"},
+		{"bearer", "Authorization: Bearer abc.def-123", "Authorization: Bearer [REDACTED]"},
+		{"card", "paid with 4242 4242 4242 4242 today", "paid with ***************4242 today"},
+		{"invalid card", "order 1234567890123", "order 1234567890123"},
+		{"plain", "nothing to hide", "nothing to hide"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.String(tt.input); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+
+	err := errors.New("login failed: Bearer abc")
+	if got := r.Value("error", err); got != "login failed: Bearer [REDACTED]" {
+		t.Errorf("Expected error message to be redacted, got %v", got)
+	}
+}
+
+func TestRedactStructTags(t *testing.T) {
+	type credentials struct {
+		User     string `json:"user"`
+		Password string `json:"password" redact:"true"`
+		Internal string `redact:"omit"`
+	}
+	type request struct {
+		ID    int          `json:"id"`
+		Creds *credentials `json:"creds"`
+	}
+
+	out, ok := New(Options{NoDefaults: true}).Value("req", request{ID: 1, Creds: &credentials{User: "bob", Password: "pw", Internal: "x"}}).(map[string]interface{})
+	if !ok {
+		t.Fatal("Expected struct with redact tags to become a map")
+	}
+	creds := out["creds"].(map[string]interface{})
+	if creds["password"] != Placeholder || creds["user"] != "bob" {
+		t.Errorf("Unexpected credentials: %v", creds)
+	}
+	if _, ok := creds["Internal"]; ok {
+		t.Error("Expected omitted field to be dropped")
+	}
+
+	// Structs without tags are left alone
+	type plain struct{ Name string }
+	if _, ok := New().Value("p", plain{"a"}).(plain); !ok {
+		t.Error("Expected untagged struct to be returned unchanged")
+	}
+}
+
+// account and profile refer to each other; only account has a tag
+type account struct {
+	Profile  *profile `json:"profile"`
+	Password string   `json:"password" redact:"true"`
+}
+
+type profile struct {
+	Name    string   `json:"name"`
+	Account *account `json:"account"`
+}
+
+func TestRedactRecursiveStructTags(t *testing.T) {
+	password := func(out interface{}) interface{} {
+		m, ok := out.(map[string]interface{})
+		if !ok {
+			return out
+		}
+		return m["account"].(map[string]interface{})["password"]
+	}
+
+	// profile is reached while account is being scanned, and must not be
+	// remembered as untagged
+	r := New(Options{NoDefaults: true})
+	r.Value("a", account{})
+	if got := password(r.Value("p", profile{Name: "bob", Account: &account{Password: "pw"}})); got != Placeholder {
+		t.Errorf("Expected password in nested account to be redacted, got %v", got)
+	}
+
+	// Goroutines scanning the same types never see a partial answer
+	for i := 0; i < 50; i++ {
+		r := New(Options{NoDefaults: true})
+		var wg sync.WaitGroup
+		for j := 0; j < 8; j++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if got := password(r.Value("p", &profile{Account: &account{Password: "pw"}})); got != Placeholder {
+					t.Errorf("Expected password to be redacted, got %v", got)
+				}
+			}()
+		}
+		wg.Wait()
+	}
+}
+
+func TestMaskers(t *testing.T) {
+	if got := MaskHead(3)("sk_live_123"); got != "sk_********" {
+		t.Errorf("MaskHead: got %q", got)
+	}
+	if got := MaskTail(4)("4242424242424242"); got != "************4242" {
+		t.Errorf("MaskTail: got %q", got)
+	}
+	if got := Hash()("secret"); !strings.HasPrefix(got, "sha256:") || got != Hash()("secret") {
+		t.Errorf("Hash: got %q", got)
+	}
+
+	r := New(Options{Mask: MaskHead(2)})
+	if got := r.Value("api_key", "abcdef"); got != "ab****" {
+		t.Errorf("Expected custom mask for keys, got %v", got)
+	}
+}
@@ -0,0 +1,126 @@
+package repo
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/selanim/sego/database"
+)
+
+// Dialect describes the SQL differences between database engines
+type Dialect interface {
+	// Name returns the dialect name, which is also its database.DBType
+	Name() string
+	// Placeholder returns the bind parameter for the n-th argument (1-based)
+	Placeholder(n int) string
+	// QuoteIdent quotes a table or column name, including dotted names
+	QuoteIdent(name string) string
+	// SupportsReturning reports whether INSERT/UPDATE ... RETURNING is available
+	SupportsReturning() bool
+	// LimitOffset renders the LIMIT/OFFSET clause, or an empty string
+	LimitOffset(limit, offset int) string
+}
+
+// Supported dialects
+var (
+	Postgres Dialect = postgresDialect{}
+	MySQL    Dialect = mysqlDialect{}
+	SQLite   Dialect = sqliteDialect{}
+)
+
+// DialectFor returns the dialect for a database type
+func DialectFor(dbType database.DBType) (Dialect, error) {
+	switch dbType {
+	case database.PostgreSQL:
+		return Postgres, nil
+	case database.MySQL:
+		return MySQL, nil
+	case database.SQLite:
+		return SQLite, nil
+	default:
+		return nil, fmt.Errorf("unsupported dialect: %s", dbType)
+	}
+}
+
+// ========== POSTGRES ==========
+
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string { return "postgres" }
+
+func (postgresDialect) Placeholder(n int) string { return fmt.Sprintf("$%d", n) }
+
+func (postgresDialect) QuoteIdent(name string) string { return quoteIdent(name, `"`) }
+
+func (postgresDialect) SupportsReturning() bool { return true }
+
+func (postgresDialect) LimitOffset(limit, offset int) string {
+	return standardLimitOffset(limit, offset)
+}
+
+// ========== MYSQL ==========
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) Name() string { return "mysql" }
+
+func (mysqlDialect) Placeholder(int) string { return "?" }
+
+func (mysqlDialect) QuoteIdent(name string) string { return quoteIdent(name, "`") }
+
+func (mysqlDialect) SupportsReturning() bool { return false }
+
+func (mysqlDialect) LimitOffset(limit, offset int) string {
+	// MySQL does not accept OFFSET without LIMIT
+	if limit <= 0 && offset > 0 {
+		return fmt.Sprintf(" LIMIT 18446744073709551615 OFFSET %d", offset)
+	}
+	return standardLimitOffset(limit, offset)
+}
+
+// ========== SQLITE ==========
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string { return "sqlite" }
+
+func (sqliteDialect) Placeholder(int) string { return "?" }
+
+func (sqliteDialect) QuoteIdent(name string) string { return quoteIdent(name, `"`) }
+
+// SQLite has supported RETURNING since 3.35
+func (sqliteDialect) SupportsReturning() bool { return true }
+
+func (sqliteDialect) LimitOffset(limit, offset int) string {
+	// SQLite does not accept OFFSET without LIMIT
+	if limit <= 0 && offset > 0 {
+		return fmt.Sprintf(" LIMIT -1 OFFSET %d", offset)
+	}
+	return standardLimitOffset(limit, offset)
+}
+
+// ========== HELPERS ==========
+
+// quoteIdent quotes each dot-separated part of an identifier
+func quoteIdent(name, quote string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		if part == "*" {
+			continue
+		}
+		parts[i] = quote + strings.ReplaceAll(part, quote, quote+quote) + quote
+	}
+	return strings.Join(parts, ".")
+}
+
+// standardLimitOffset renders LIMIT and OFFSET independently
+func standardLimitOffset(limit, offset int) string {
+	var clause string
+	if limit > 0 {
+		clause += fmt.Sprintf(" LIMIT %d", limit)
+	}
+	if offset > 0 {
+		clause += fmt.Sprintf(" OFFSET %d", offset)
+	}
+	return clause
+}
@@ -0,0 +1,179 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// executor runs queries against either a pgx pool or a database/sql handle
+type executor interface {
+	query(ctx context.Context, query string, args ...interface{}) (rows, error)
+	exec(ctx context.Context, query string, args ...interface{}) (execResult, error)
+	begin(ctx context.Context) (txExecutor, error)
+	ping(ctx context.Context) error
+}
+
+// txExecutor is an executor bound to an open transaction
+type txExecutor interface {
+	executor
+	commit(ctx context.Context) error
+	rollback(ctx context.Context) error
+}
+
+// rows is the subset of row iteration shared by pgx and database/sql
+type rows interface {
+	Columns() ([]string, error)
+	Next() bool
+	Scan(dest ...interface{}) error
+	Err() error
+	Close() error
+}
+
+// execResult holds the outcome of an INSERT, UPDATE or DELETE
+type execResult struct {
+	rowsAffected int64
+	lastInsertID int64
+}
+
+// ========== PGX ==========
+
+// pgxQuerier is implemented by both *pgxpool.Pool and pgx.Tx
+type pgxQuerier interface {
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+type pgxExecutor struct {
+	db pgxQuerier
+}
+
+func (e pgxExecutor) query(ctx context.Context, query string, args ...interface{}) (rows, error) {
+	r, err := e.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgxRows{r}, nil
+}
+
+func (e pgxExecutor) exec(ctx context.Context, query string, args ...interface{}) (execResult, error) {
+	tag, err := e.db.Exec(ctx, query, args...)
+	if err != nil {
+		return execResult{}, err
+	}
+	return execResult{rowsAffected: tag.RowsAffected()}, nil
+}
+
+func (e pgxExecutor) begin(ctx context.Context) (txExecutor, error) {
+	tx, err := e.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return pgxTxExecutor{pgxExecutor{tx}, tx}, nil
+}
+
+func (e pgxExecutor) ping(ctx context.Context) error {
+	switch db := e.db.(type) {
+	case *pgxpool.Pool:
+		return db.Ping(ctx)
+	case pgx.Tx:
+		return db.Conn().Ping(ctx)
+	}
+	return errors.New("ping not supported")
+}
+
+type pgxTxExecutor struct {
+	pgxExecutor
+	tx pgx.Tx
+}
+
+func (e pgxTxExecutor) commit(ctx context.Context) error   { return e.tx.Commit(ctx) }
+func (e pgxTxExecutor) rollback(ctx context.Context) error { return e.tx.Rollback(ctx) }
+
+// pgxRows adapts pgx.Rows to the rows interface
+type pgxRows struct {
+	pgx.Rows
+}
+
+func (r pgxRows) Columns() ([]string, error) {
+	descriptions := r.FieldDescriptions()
+	columns := make([]string, len(descriptions))
+	for i, fd := range descriptions {
+		columns[i] = fd.Name
+	}
+	return columns, nil
+}
+
+func (r pgxRows) Close() error {
+	r.Rows.Close()
+	return nil
+}
+
+// ========== DATABASE/SQL ==========
+
+// sqlQuerier is implemented by both *sql.DB and *sql.Tx
+type sqlQuerier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+type sqlExecutor struct {
+	db sqlQuerier
+}
+
+func (e sqlExecutor) query(ctx context.Context, query string, args ...interface{}) (rows, error) {
+	r, err := e.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (e sqlExecutor) exec(ctx context.Context, query string, args ...interface{}) (execResult, error) {
+	res, err := e.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return execResult{}, err
+	}
+
+	var result execResult
+	if result.rowsAffected, err = res.RowsAffected(); err != nil {
+		return execResult{}, err
+	}
+	// Not every driver reports the last insert ID
+	result.lastInsertID, _ = res.LastInsertId()
+
+	return result, nil
+}
+
+func (e sqlExecutor) begin(ctx context.Context) (txExecutor, error) {
+	db, ok := e.db.(*sql.DB)
+	if !ok {
+		return nil, errors.New("nested transactions are not supported")
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return sqlTxExecutor{sqlExecutor{tx}, tx}, nil
+}
+
+func (e sqlExecutor) ping(ctx context.Context) error {
+	if db, ok := e.db.(*sql.DB); ok {
+		return db.PingContext(ctx)
+	}
+	return nil
+}
+
+type sqlTxExecutor struct {
+	sqlExecutor
+	tx *sql.Tx
+}
+
+func (e sqlTxExecutor) commit(context.Context) error   { return e.tx.Commit() }
+func (e sqlTxExecutor) rollback(context.Context) error { return e.tx.Rollback() }
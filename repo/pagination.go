@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"math"
-	"reflect"
 )
 
 // Pagination represents pagination metadata
@@ -16,12 +15,6 @@ type Pagination struct {
 	Offset     int   `json:"-"`
 }
 
-// PaginatedResult represents a paginated query result
-type PaginatedResult struct {
-	Data       interface{} `json:"data"`
-	Pagination *Pagination `json:"pagination"`
-}
-
 // PaginationOptions represents pagination input options
 type PaginationOptions struct {
 	Page  int `json:"page"`
@@ -89,14 +82,14 @@ func (p *Pagination) HasPrev() bool {
 	return p.Page > 1
 }
 
-// PaginatedQueryResult is a generic paginated result
+// PaginatedQueryResult represents a paginated query result
 type PaginatedQueryResult[T any] struct {
 	Data       []T         `json:"data"`
 	Pagination *Pagination `json:"pagination"`
 }
 
 // Paginate executes a paginated query
-func (r *Repository) Paginate(ctx context.Context, opts *PaginationOptions, queryOptions ...QueryOptions) (*PaginatedResult, error) {
+func (r *Repository[T]) Paginate(ctx context.Context, opts *PaginationOptions, queryOptions ...QueryOptions) (*PaginatedQueryResult[T], error) {
 	if opts == nil {
 		opts = DefaultPagination()
 	}
@@ -139,14 +132,18 @@ func (r *Repository) Paginate(ctx context.Context, opts *PaginationOptions, quer
 		return nil, fmt.Errorf("failed to fetch paginated data: %w", err)
 	}
 
-	return &PaginatedResult{
+	if data == nil {
+		data = []T{}
+	}
+
+	return &PaginatedQueryResult[T]{
 		Data:       data,
 		Pagination: pagination,
 	}, nil
 }
 
 // PaginateWithQueryBuilder executes a paginated query using QueryBuilder
-func (r *Repository) PaginateWithQueryBuilder(ctx context.Context, qb *QueryBuilder, opts *PaginationOptions) (*PaginatedResult, error) {
+func (r *Repository[T]) PaginateWithQueryBuilder(ctx context.Context, qb *QueryBuilder, opts *PaginationOptions) (*PaginatedQueryResult[T], error) {
 	if opts == nil {
 		opts = DefaultPagination()
 	}
@@ -157,8 +154,7 @@ func (r *Repository) PaginateWithQueryBuilder(ctx context.Context, qb *QueryBuil
 
 	// Get total count
 	countQuery, countArgs := qb.BuildCount()
-	var totalRows int64
-	err := r.db.QueryRow(ctx, countQuery, countArgs...).Scan(&totalRows)
+	totalRows, err := r.queryCount(ctx, countQuery, countArgs...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to count records: %w", err)
 	}
@@ -172,29 +168,21 @@ func (r *Repository) PaginateWithQueryBuilder(ctx context.Context, qb *QueryBuil
 
 	// Execute query
 	query, args := qb.Build()
-	rows, err := r.db.Query(ctx, query, args...)
+	rows, err := r.exec.query(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute paginated query: %w", err)
 	}
-	defer rows.Close()
 
 	// Scan results
-	var results []interface{}
-	for rows.Next() {
-		// Create new instance of model
-		model := reflect.New(r.modelType).Interface()
-		err := rows.Scan(model)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan row: %w", err)
-		}
-		results = append(results, model)
+	results, err := collectRows[T](rows)
+	if err != nil {
+		return nil, err
 	}
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("row iteration error: %w", err)
+	if results == nil {
+		results = []T{}
 	}
 
-	return &PaginatedResult{
+	return &PaginatedQueryResult[T]{
 		Data:       results,
 		Pagination: pagination,
 	}, nil
@@ -7,6 +7,7 @@ import (
 
 // QueryBuilder builds SQL queries dynamically
 type QueryBuilder struct {
+	dialect     Dialect
 	tableName   string
 	selectCols  []string
 	whereClause []string
@@ -19,14 +20,27 @@ type QueryBuilder struct {
 	joinClauses []string
 }
 
-// NewQueryBuilder creates a new query builder
+// NewQueryBuilder creates a new query builder using PostgreSQL placeholders
 func NewQueryBuilder(tableName string) *QueryBuilder {
+	return NewQueryBuilderWithDialect(tableName, Postgres)
+}
+
+// NewQueryBuilderWithDialect creates a new query builder for the given dialect.
+// Table and column expressions are used as given; only placeholders and
+// LIMIT/OFFSET follow the dialect.
+func NewQueryBuilderWithDialect(tableName string, dialect Dialect) *QueryBuilder {
 	return &QueryBuilder{
+		dialect:    dialect,
 		tableName:  tableName,
 		selectCols: []string{"*"},
 	}
 }
 
+// Placeholder returns the placeholder for the next argument
+func (qb *QueryBuilder) Placeholder() string {
+	return qb.dialect.Placeholder(len(qb.args) + 1)
+}
+
 // Select specifies columns to select
 func (qb *QueryBuilder) Select(cols ...string) *QueryBuilder {
 	if len(cols) > 0 {
@@ -44,7 +58,7 @@ func (qb *QueryBuilder) Where(condition string, args ...interface{}) *QueryBuild
 
 // WhereEq adds an equality WHERE condition
 func (qb *QueryBuilder) WhereEq(field string, value interface{}) *QueryBuilder {
-	qb.whereClause = append(qb.whereClause, fmt.Sprintf("%s = $%d", field, len(qb.args)+1))
+	qb.whereClause = append(qb.whereClause, fmt.Sprintf("%s = %s", field, qb.Placeholder()))
 	qb.args = append(qb.args, value)
 	return qb
 }
@@ -57,7 +71,7 @@ func (qb *QueryBuilder) WhereIn(field string, values []interface{}) *QueryBuilde
 
 	placeholders := make([]string, len(values))
 	for i := range values {
-		placeholders[i] = fmt.Sprintf("$%d", len(qb.args)+i+1)
+		placeholders[i] = qb.dialect.Placeholder(len(qb.args) + i + 1)
 	}
 
 	qb.whereClause = append(qb.whereClause, fmt.Sprintf("%s IN (%s)", field, strings.Join(placeholders, ", ")))
@@ -67,7 +81,7 @@ func (qb *QueryBuilder) WhereIn(field string, values []interface{}) *QueryBuilde
 
 // WhereLike adds a LIKE WHERE condition
 func (qb *QueryBuilder) WhereLike(field string, pattern string) *QueryBuilder {
-	qb.whereClause = append(qb.whereClause, fmt.Sprintf("%s LIKE $%d", field, len(qb.args)+1))
+	qb.whereClause = append(qb.whereClause, fmt.Sprintf("%s LIKE %s", field, qb.Placeholder()))
 	qb.args = append(qb.args, "%"+pattern+"%")
 	return qb
 }
@@ -170,15 +184,8 @@ func (qb *QueryBuilder) Build() (string, []interface{}) {
 		query.WriteString(qb.orderBy)
 	}
 
-	// LIMIT
-	if qb.limit > 0 {
-		query.WriteString(fmt.Sprintf(" LIMIT %d", qb.limit))
-	}
-
-	// OFFSET
-	if qb.offset > 0 {
-		query.WriteString(fmt.Sprintf(" OFFSET %d", qb.offset))
-	}
+	// LIMIT and OFFSET
+	query.WriteString(qb.dialect.LimitOffset(qb.limit, qb.offset))
 
 	return query.String(), qb.args
 }
@@ -2,19 +2,26 @@ package repo
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 	"time"
 
-	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/selanim/sego/database"
+	"github.com/selanim/sego/tracing"
 )
 
-// Repository represents a database repository
-type Repository struct {
+// Repository represents a database repository for model type T
+type Repository[T any] struct {
 	db        *pgxpool.Pool
+	sqlDB     *sql.DB
+	exec      executor
+	dialect   Dialect
+	inTx      bool
 	cache     *Cache
 	tableName string
 	modelType reflect.Type
@@ -27,45 +34,73 @@ type Options struct {
 	EnableLogs bool
 }
 
-// NewRepository creates a new repository
-func NewRepository(db *pgxpool.Pool, model interface{}, opts ...Options) *Repository {
+// NewRepository creates a new PostgreSQL repository for model type T
+func NewRepository[T any](db *pgxpool.Pool, opts ...Options) *Repository[T] {
+	repo := newRepository[T](Postgres, opts...)
+	repo.db = db
+	repo.exec = pgxExecutor{db}
+	return repo
+}
+
+// NewSQLRepository creates a repository for model type T on a database/sql handle
+func NewSQLRepository[T any](db *sql.DB, dialect Dialect, opts ...Options) *Repository[T] {
+	repo := newRepository[T](dialect, opts...)
+	repo.sqlDB = db
+	repo.exec = sqlExecutor{db}
+	return repo
+}
+
+// NewRepositoryFromDB creates a repository for model type T on a connected database.DB
+func NewRepositoryFromDB[T any](db *database.DB, opts ...Options) (*Repository[T], error) {
+	if db == nil {
+		return nil, fmt.Errorf("database not connected")
+	}
+
+	dialect, err := DialectFor(db.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	if db.Type == database.PostgreSQL && db.PostgresPool != nil {
+		return NewRepository[T](db.PostgresPool, opts...), nil
+	}
+	if db.SQLDB == nil {
+		return nil, fmt.Errorf("SQL database not connected")
+	}
+
+	return NewSQLRepository[T](db.SQLDB, dialect, opts...), nil
+}
+
+// newRepository sets up everything except the connection
+func newRepository[T any](dialect Dialect, opts ...Options) *Repository[T] {
 	var options Options
 	if len(opts) > 0 {
 		options = opts[0]
 	}
 
+	modelType := reflect.TypeOf((*T)(nil)).Elem()
+
 	if options.TableName == "" {
 		// Extract table name from model type
-		t := reflect.TypeOf(model)
-		if t.Kind() == reflect.Ptr {
-			t = t.Elem()
-		}
-		options.TableName = strings.ToLower(t.Name()) + "s"
+		options.TableName = strings.ToLower(modelType.Name()) + "s"
 	}
 
-	repo := &Repository{
-		db:        db,
+	return &Repository[T]{
+		dialect:   dialect,
 		cache:     NewCache(options.CacheTTL),
 		tableName: options.TableName,
-		modelType: reflect.TypeOf(model),
+		modelType: modelType,
 	}
-
-	return repo
 }
 
 // ========== CRUD OPERATIONS ==========
 
 // Create inserts a new record
-func (r *Repository) Create(ctx context.Context, data interface{}) (interface{}, error) {
-	fields, values, placeholders := r.extractFieldsAndValues(data, true)
+func (r *Repository[T]) Create(ctx context.Context, data *T) (_ *T, err error) {
+	ctx, span := r.startSpan(ctx, "Create")
+	defer endSpan(span, &err)
 
-	query := fmt.Sprintf(
-		"INSERT INTO %s (%s) VALUES (%s) RETURNING *",
-		r.tableName, fields, placeholders,
-	)
-
-	var result interface{}
-	err := r.db.QueryRow(ctx, query, values...).Scan(&result)
+	result, err := r.insert(ctx, data)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create record: %w", err)
 	}
@@ -77,39 +112,44 @@ func (r *Repository) Create(ctx context.Context, data interface{}) (interface{},
 }
 
 // FindByID finds a record by ID
-func (r *Repository) FindByID(ctx context.Context, id interface{}) (interface{}, error) {
+func (r *Repository[T]) FindByID(ctx context.Context, id interface{}) (_ *T, err error) {
+	ctx, span := r.startSpan(ctx, "FindByID")
+	defer endSpan(span, &err)
+
 	// Try cache first
 	if cached, found := r.cache.Get(fmt.Sprintf("id:%v", id)); found {
-		return cached, nil
+		if item, ok := cached.(T); ok {
+			span.SetAttribute("repo.cache_hit", true)
+			return &item, nil
+		}
 	}
 
-	query := fmt.Sprintf("SELECT * FROM %s WHERE id = $1", r.tableName)
-
-	var result interface{}
-	err := r.db.QueryRow(ctx, query, id).Scan(&result)
+	result, err := r.findByID(ctx, id)
 	if err != nil {
-		if err == pgx.ErrNoRows {
+		if errors.Is(err, ErrNotFound) {
 			return nil, ErrNotFound
 		}
 		return nil, fmt.Errorf("failed to find by ID: %w", err)
 	}
 
-	// Cache the result
-	r.cache.Set(fmt.Sprintf("id:%v", id), result)
+	// Cache a copy so callers cannot mutate the cached value
+	r.cache.Set(fmt.Sprintf("id:%v", id), *result)
 
 	return result, nil
 }
 
 // FindOne finds one record matching conditions
-func (r *Repository) FindOne(ctx context.Context, conditions map[string]interface{}) (interface{}, error) {
+func (r *Repository[T]) FindOne(ctx context.Context, conditions map[string]interface{}) (_ *T, err error) {
+	ctx, span := r.startSpan(ctx, "FindOne")
+	defer endSpan(span, &err)
+
 	whereClause, args := r.buildWhereClause(conditions)
 
-	query := fmt.Sprintf("SELECT * FROM %s WHERE %s LIMIT 1", r.tableName, whereClause)
+	query := fmt.Sprintf("SELECT * FROM %s WHERE %s%s", r.table(), whereClause, r.dialect.LimitOffset(1, 0))
 
-	var result interface{}
-	err := r.db.QueryRow(ctx, query, args...).Scan(&result)
+	result, err := queryOne[T](ctx, r.exec, query, args...)
 	if err != nil {
-		if err == pgx.ErrNoRows {
+		if errors.Is(err, ErrNotFound) {
 			return nil, ErrNotFound
 		}
 		return nil, fmt.Errorf("failed to find one: %w", err)
@@ -119,13 +159,16 @@ func (r *Repository) FindOne(ctx context.Context, conditions map[string]interfac
 }
 
 // FindAll finds all records
-func (r *Repository) FindAll(ctx context.Context, opts ...QueryOptions) ([]interface{}, error) {
+func (r *Repository[T]) FindAll(ctx context.Context, opts ...QueryOptions) (_ []T, err error) {
+	ctx, span := r.startSpan(ctx, "FindAll")
+	defer endSpan(span, &err)
+
 	options := QueryOptions{}
 	if len(opts) > 0 {
 		options = opts[0]
 	}
 
-	query := fmt.Sprintf("SELECT * FROM %s", r.tableName)
+	query := fmt.Sprintf("SELECT * FROM %s", r.table())
 
 	// Add WHERE clause if conditions exist
 	if len(options.Conditions) > 0 {
@@ -140,52 +183,38 @@ func (r *Repository) FindAll(ctx context.Context, opts ...QueryOptions) ([]inter
 	}
 
 	// Add LIMIT and OFFSET
-	if options.Limit > 0 {
-		query += fmt.Sprintf(" LIMIT %d", options.Limit)
-	}
-	if options.Offset > 0 {
-		query += fmt.Sprintf(" OFFSET %d", options.Offset)
-	}
+	query += r.dialect.LimitOffset(options.Limit, options.Offset)
 
-	rows, err := r.db.Query(ctx, query, options.Args...)
+	rows, err := r.exec.query(ctx, query, options.Args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to find all: %w", err)
 	}
-	defer rows.Close()
-
-	var results []interface{}
-	for rows.Next() {
-		var result interface{}
-		if err := rows.Scan(&result); err != nil {
-			return nil, fmt.Errorf("failed to scan row: %w", err)
-		}
-		results = append(results, result)
-	}
 
-	return results, nil
+	return collectRows[T](rows)
 }
 
 // Update updates a record
-func (r *Repository) Update(ctx context.Context, id interface{}, data interface{}) (interface{}, error) {
-	fields, values, _ := r.extractFieldsAndValues(data, false)
+func (r *Repository[T]) Update(ctx context.Context, id interface{}, data *T) (_ *T, err error) {
+	ctx, span := r.startSpan(ctx, "Update")
+	defer endSpan(span, &err)
 
-	// Build SET clause
-	var setClauses []string
-	for i, field := range strings.Split(fields, ",") {
-		setClauses = append(setClauses, fmt.Sprintf("%s = $%d", strings.TrimSpace(field), i+1))
+	query, values, err := r.buildUpdate(id, data)
+	if err != nil {
+		return nil, err
 	}
 
-	// Add ID as last parameter
-	values = append(values, id)
-
-	query := fmt.Sprintf(
-		"UPDATE %s SET %s WHERE id = $%d RETURNING *",
-		r.tableName, strings.Join(setClauses, ", "), len(values),
-	)
-
-	var result interface{}
-	err := r.db.QueryRow(ctx, query, values...).Scan(&result)
+	var result *T
+	if r.dialect.SupportsReturning() {
+		result, err = queryOne[T](ctx, r.exec, query+" RETURNING *", values...)
+	} else {
+		if _, err = r.exec.exec(ctx, query, values...); err == nil {
+			result, err = r.findByID(ctx, id)
+		}
+	}
 	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrNotFound
+		}
 		return nil, fmt.Errorf("failed to update record: %w", err)
 	}
 
@@ -197,15 +226,18 @@ func (r *Repository) Update(ctx context.Context, id interface{}, data interface{
 }
 
 // Delete deletes a record
-func (r *Repository) Delete(ctx context.Context, id interface{}) error {
-	query := fmt.Sprintf("DELETE FROM %s WHERE id = $1", r.tableName)
+func (r *Repository[T]) Delete(ctx context.Context, id interface{}) (err error) {
+	ctx, span := r.startSpan(ctx, "Delete")
+	defer endSpan(span, &err)
+
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s = %s", r.table(), r.dialect.QuoteIdent("id"), r.dialect.Placeholder(1))
 
-	result, err := r.db.Exec(ctx, query, id)
+	result, err := r.exec.exec(ctx, query, id)
 	if err != nil {
 		return fmt.Errorf("failed to delete record: %w", err)
 	}
 
-	if result.RowsAffected() == 0 {
+	if result.rowsAffected == 0 {
 		return ErrNotFound
 	}
 
@@ -219,36 +251,28 @@ func (r *Repository) Delete(ctx context.Context, id interface{}) error {
 // ========== BATCH OPERATIONS ==========
 
 // CreateMany inserts multiple records
-func (r *Repository) CreateMany(ctx context.Context, data []interface{}) ([]interface{}, error) {
+func (r *Repository[T]) CreateMany(ctx context.Context, data []T) (_ []T, err error) {
 	if len(data) == 0 {
-		return []interface{}{}, nil
-	}
-
-	tx, err := r.db.Begin(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+		return []T{}, nil
 	}
-	defer tx.Rollback(ctx)
-
-	var results []interface{}
-	for _, item := range data {
-		fields, values, placeholders := r.extractFieldsAndValues(item, true)
 
-		query := fmt.Sprintf(
-			"INSERT INTO %s (%s) VALUES (%s) RETURNING *",
-			r.tableName, fields, placeholders,
-		)
+	ctx, span := r.startSpan(ctx, "CreateMany")
+	defer endSpan(span, &err)
+	span.SetAttribute("repo.batch_size", len(data))
 
-		var result interface{}
-		err := tx.QueryRow(ctx, query, values...).Scan(&result)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create record: %w", err)
+	results := make([]T, 0, len(data))
+	err = r.Transaction(ctx, func(txRepo *Repository[T]) error {
+		for i := range data {
+			result, err := txRepo.insert(ctx, &data[i])
+			if err != nil {
+				return fmt.Errorf("failed to create record: %w", err)
+			}
+			results = append(results, *result)
 		}
-		results = append(results, result)
-	}
-
-	if err := tx.Commit(ctx); err != nil {
-		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// Invalidate cache
@@ -258,42 +282,29 @@ func (r *Repository) CreateMany(ctx context.Context, data []interface{}) ([]inte
 }
 
 // UpdateMany updates multiple records
-func (r *Repository) UpdateMany(ctx context.Context, updates map[interface{}]interface{}) error {
+func (r *Repository[T]) UpdateMany(ctx context.Context, updates map[interface{}]T) (err error) {
 	if len(updates) == 0 {
 		return nil
 	}
 
-	tx, err := r.db.Begin(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	defer tx.Rollback(ctx)
+	ctx, span := r.startSpan(ctx, "UpdateMany")
+	defer endSpan(span, &err)
+	span.SetAttribute("repo.batch_size", len(updates))
 
-	for id, data := range updates {
-		fields, values, _ := r.extractFieldsAndValues(data, false)
-
-		// Build SET clause
-		var setClauses []string
-		for i, field := range strings.Split(fields, ",") {
-			setClauses = append(setClauses, fmt.Sprintf("%s = $%d", strings.TrimSpace(field), i+1))
+	err = r.Transaction(ctx, func(txRepo *Repository[T]) error {
+		for id, data := range updates {
+			query, values, err := txRepo.buildUpdate(id, &data)
+			if err != nil {
+				return err
+			}
+			if _, err := txRepo.exec.exec(ctx, query, values...); err != nil {
+				return fmt.Errorf("failed to update record: %w", err)
+			}
 		}
-
-		// Add ID as last parameter
-		values = append(values, id)
-
-		query := fmt.Sprintf(
-			"UPDATE %s SET %s WHERE id = $%d",
-			r.tableName, strings.Join(setClauses, ", "), len(values),
-		)
-
-		_, err := tx.Exec(ctx, query, values...)
-		if err != nil {
-			return fmt.Errorf("failed to update record: %w", err)
-		}
-	}
-
-	if err := tx.Commit(ctx); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	// Invalidate cache
@@ -305,8 +316,11 @@ func (r *Repository) UpdateMany(ctx context.Context, updates map[interface{}]int
 // ========== QUERY OPERATIONS ==========
 
 // Count counts records
-func (r *Repository) Count(ctx context.Context, conditions ...map[string]interface{}) (int64, error) {
-	query := fmt.Sprintf("SELECT COUNT(*) FROM %s", r.tableName)
+func (r *Repository[T]) Count(ctx context.Context, conditions ...map[string]interface{}) (_ int64, err error) {
+	ctx, span := r.startSpan(ctx, "Count")
+	defer endSpan(span, &err)
+
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s", r.table())
 
 	var args []interface{}
 	if len(conditions) > 0 && len(conditions[0]) > 0 {
@@ -315,8 +329,7 @@ func (r *Repository) Count(ctx context.Context, conditions ...map[string]interfa
 		args = whereArgs
 	}
 
-	var count int64
-	err := r.db.QueryRow(ctx, query, args...).Scan(&count)
+	count, err := r.queryCount(ctx, query, args...)
 	if err != nil {
 		return 0, fmt.Errorf("failed to count records: %w", err)
 	}
@@ -325,7 +338,7 @@ func (r *Repository) Count(ctx context.Context, conditions ...map[string]interfa
 }
 
 // Exists checks if a record exists
-func (r *Repository) Exists(ctx context.Context, conditions map[string]interface{}) (bool, error) {
+func (r *Repository[T]) Exists(ctx context.Context, conditions map[string]interface{}) (bool, error) {
 	count, err := r.Count(ctx, conditions)
 	if err != nil {
 		return false, err
@@ -334,14 +347,14 @@ func (r *Repository) Exists(ctx context.Context, conditions map[string]interface
 }
 
 // FindByField finds records by field value
-func (r *Repository) FindByField(ctx context.Context, field string, value interface{}) ([]interface{}, error) {
+func (r *Repository[T]) FindByField(ctx context.Context, field string, value interface{}) ([]T, error) {
 	return r.FindAll(ctx, QueryOptions{
 		Conditions: map[string]interface{}{field: value},
 	})
 }
 
 // FindByFields finds records by multiple field values
-func (r *Repository) FindByFields(ctx context.Context, fields map[string]interface{}) ([]interface{}, error) {
+func (r *Repository[T]) FindByFields(ctx context.Context, fields map[string]interface{}) ([]T, error) {
 	return r.FindAll(ctx, QueryOptions{
 		Conditions: fields,
 	})
@@ -349,27 +362,39 @@ func (r *Repository) FindByFields(ctx context.Context, fields map[string]interfa
 
 // ========== TRANSACTION SUPPORT ==========
 
-// Transaction executes a function within a transaction
-func (r *Repository) Transaction(ctx context.Context, fn func(*Repository) error) error {
-	tx, err := r.db.Begin(ctx)
+// Transaction executes a function within a transaction.
+// Calls on a repository that is already inside a transaction reuse it.
+func (r *Repository[T]) Transaction(ctx context.Context, fn func(*Repository[T]) error) (err error) {
+	if r.inTx {
+		return fn(r)
+	}
+
+	ctx, span := r.startSpan(ctx, "Transaction")
+	defer endSpan(span, &err)
+
+	tx, err := r.exec.begin(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 
-	// Create a new repository with transaction
-	txRepo := &Repository{
-		db:        &pgxpool.Pool{}, // This would need proper tx wrapper
-		cache:     r.cache,
-		tableName: r.tableName,
-		modelType: r.modelType,
-	}
+	// Create a new repository bound to the transaction
+	txRepo := *r
+	txRepo.exec = tx
+	txRepo.inTx = true
 
-	if err := fn(txRepo); err != nil {
-		tx.Rollback(ctx)
+	defer func() {
+		if p := recover(); p != nil {
+			tx.rollback(ctx)
+			panic(p)
+		}
+	}()
+
+	if err := fn(&txRepo); err != nil {
+		tx.rollback(ctx)
 		return err
 	}
 
-	if err := tx.Commit(ctx); err != nil {
+	if err := tx.commit(ctx); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
@@ -379,34 +404,153 @@ func (r *Repository) Transaction(ctx context.Context, fn func(*Repository) error
 // ========== UTILITY METHODS ==========
 
 // GetTableName returns the table name
-func (r *Repository) GetTableName() string {
+func (r *Repository[T]) GetTableName() string {
 	return r.tableName
 }
 
-// GetDB returns the database connection
-func (r *Repository) GetDB() *pgxpool.Pool {
+// GetDB returns the PostgreSQL connection pool, or nil for database/sql repositories
+func (r *Repository[T]) GetDB() *pgxpool.Pool {
 	return r.db
 }
 
+// GetSQLDB returns the database/sql handle, or nil for PostgreSQL pool repositories
+func (r *Repository[T]) GetSQLDB() *sql.DB {
+	return r.sqlDB
+}
+
+// GetDialect returns the SQL dialect
+func (r *Repository[T]) GetDialect() Dialect {
+	return r.dialect
+}
+
 // GetCache returns the cache instance
-func (r *Repository) GetCache() *Cache {
+func (r *Repository[T]) GetCache() *Cache {
 	return r.cache
 }
 
 // ClearCache clears the cache
-func (r *Repository) ClearCache() {
+func (r *Repository[T]) ClearCache() {
 	r.cache.Clear()
 }
 
 // Ping checks database connection
-func (r *Repository) Ping(ctx context.Context) error {
-	return r.db.Ping(ctx)
+func (r *Repository[T]) Ping(ctx context.Context) error {
+	return r.exec.ping(ctx)
+}
+
+// QueryBuilder returns a query builder for the repository table and dialect
+func (r *Repository[T]) QueryBuilder() *QueryBuilder {
+	return NewQueryBuilderWithDialect(r.table(), r.dialect)
 }
 
 // ========== PRIVATE HELPER METHODS ==========
 
-// extractFieldsAndValues extracts fields and values from a struct
-func (r *Repository) extractFieldsAndValues(data interface{}, includeID bool) (string, []interface{}, string) {
+// startSpan starts a span for a repository operation; the span is nil when tracing is off
+func (r *Repository[T]) startSpan(ctx context.Context, operation string) (context.Context, *tracing.Span) {
+	ctx, span := tracing.Start(ctx, "repo."+operation+" "+r.tableName, tracing.SpanKindInternal)
+	span.SetAttributes(map[string]interface{}{
+		"db.system":      database.DBType(r.dialect.Name()).System(),
+		"db.sql.table":   r.tableName,
+		"repo.operation": operation,
+	})
+	return ctx, span
+}
+
+// endSpan ends span, recording err unless it is ErrNotFound, which is an expected outcome
+func endSpan(span *tracing.Span, err *error) {
+	if errors.Is(*err, ErrNotFound) {
+		span.SetAttribute("repo.not_found", true)
+		span.End()
+		return
+	}
+	tracing.End(span, err)
+}
+
+// table returns the quoted table name
+func (r *Repository[T]) table() string {
+	return r.dialect.QuoteIdent(r.tableName)
+}
+
+// insert inserts a record and reads it back
+func (r *Repository[T]) insert(ctx context.Context, data *T) (*T, error) {
+	fields, values, placeholders := r.extractFieldsAndValues(data, true)
+
+	query := fmt.Sprintf(
+		"INSERT INTO %s (%s) VALUES (%s)",
+		r.table(), fields, placeholders,
+	)
+
+	if r.dialect.SupportsReturning() {
+		return queryOne[T](ctx, r.exec, query+" RETURNING *", values...)
+	}
+
+	// Without RETURNING, read the row back using the generated or supplied ID
+	result, err := r.exec.exec(ctx, query, values...)
+	if err != nil {
+		return nil, err
+	}
+
+	// A key set by the caller, such as a UUID, wins over the generated one
+	id := idValue(data)
+	if id == nil || reflect.ValueOf(id).IsZero() {
+		id = result.lastInsertID
+	}
+
+	return r.findByID(ctx, id)
+}
+
+// findByID loads a record by ID without touching the cache
+func (r *Repository[T]) findByID(ctx context.Context, id interface{}) (*T, error) {
+	query := fmt.Sprintf("SELECT * FROM %s WHERE %s = %s", r.table(), r.dialect.QuoteIdent("id"), r.dialect.Placeholder(1))
+	return queryOne[T](ctx, r.exec, query, id)
+}
+
+// buildUpdate builds an UPDATE statement for a record; it fails with
+// ErrInvalidData when the model has no columns besides the ID
+func (r *Repository[T]) buildUpdate(id interface{}, data *T) (string, []interface{}, error) {
+	fields, values, _ := r.extractFieldsAndValues(data, false)
+	if fields == "" {
+		return "", nil, fmt.Errorf("%w: no fields to update", ErrInvalidData)
+	}
+
+	// Build SET clause
+	var setClauses []string
+	for i, field := range strings.Split(fields, ", ") {
+		setClauses = append(setClauses, fmt.Sprintf("%s = %s", field, r.dialect.Placeholder(i+1)))
+	}
+
+	// Add ID as last parameter
+	values = append(values, id)
+
+	query := fmt.Sprintf(
+		"UPDATE %s SET %s WHERE %s = %s",
+		r.table(), strings.Join(setClauses, ", "), r.dialect.QuoteIdent("id"), r.dialect.Placeholder(len(values)),
+	)
+
+	return query, values, nil
+}
+
+// queryCount runs a query returning a single integer
+func (r *Repository[T]) queryCount(ctx context.Context, query string, args ...interface{}) (int64, error) {
+	rows, err := r.exec.query(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var count int64
+	if rows.Next() {
+		if err := rows.Scan(&count); err != nil {
+			return 0, err
+		}
+	}
+
+	return count, rows.Err()
+}
+
+// extractFieldsAndValues extracts fields and values from a struct.
+// A zero-valued ID is left out so the database can generate it.
+func (r *Repository[T]) extractFieldsAndValues(data interface{}, includeID bool) (string, []interface{}, string) {
 	v := reflect.ValueOf(data)
 	if v.Kind() == reflect.Ptr {
 		v = v.Elem()
@@ -417,42 +561,99 @@ func (r *Repository) extractFieldsAndValues(data interface{}, includeID bool) (s
 	var placeholders []string
 
 	for i := 0; i < v.NumField(); i++ {
-		field := v.Type().Field(i)
-		value := v.Field(i).Interface()
-		dbTag := field.Tag.Get("db")
-
-		if dbTag == "" {
-			dbTag = strings.ToLower(field.Name)
+		dbTag, ok := columnName(v.Type().Field(i))
+		if !ok {
+			continue
 		}
 
 		// Skip ID field if not included
-		if dbTag == "id" && !includeID {
+		if dbTag == "id" && (!includeID || v.Field(i).IsZero()) {
 			continue
 		}
 
-		fields = append(fields, dbTag)
-		values = append(values, value)
-		placeholders = append(placeholders, fmt.Sprintf("$%d", len(values)))
+		fields = append(fields, r.dialect.QuoteIdent(dbTag))
+		values = append(values, v.Field(i).Interface())
+		placeholders = append(placeholders, r.dialect.Placeholder(len(values)))
 	}
 
 	return strings.Join(fields, ", "), values, strings.Join(placeholders, ", ")
 }
 
-// buildWhereClause builds WHERE clause from conditions
-func (r *Repository) buildWhereClause(conditions map[string]interface{}) (string, []interface{}) {
+// buildWhereClause builds WHERE clause from conditions, ordered by field name
+func (r *Repository[T]) buildWhereClause(conditions map[string]interface{}) (string, []interface{}) {
+	fields := make([]string, 0, len(conditions))
+	for field := range conditions {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
 	var clauses []string
 	var args []interface{}
 
-	i := 1
-	for field, value := range conditions {
-		clauses = append(clauses, fmt.Sprintf("%s = $%d", field, i))
-		args = append(args, value)
-		i++
+	for i, field := range fields {
+		clauses = append(clauses, fmt.Sprintf("%s = %s", r.dialect.QuoteIdent(field), r.dialect.Placeholder(i+1)))
+		args = append(args, conditions[field])
 	}
 
 	return strings.Join(clauses, " AND "), args
 }
 
+// idValue returns the value of the model's id column
+func idValue(data interface{}) interface{} {
+	v := reflect.ValueOf(data)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+
+	if idx, ok := fieldIndexes(v.Type())["id"]; ok {
+		return v.Field(idx).Interface()
+	}
+	return nil
+}
+
+// queryOne runs a query and scans the first row into a new T
+func queryOne[T any](ctx context.Context, exec executor, query string, args ...interface{}) (*T, error) {
+	rows, err := exec.query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := collectRows[T](rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return &results[0], nil
+}
+
+// collectRows scans all rows into a slice of T and closes the rows
+func collectRows[T any](rows rows) ([]T, error) {
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns: %w", err)
+	}
+
+	var results []T
+	for rows.Next() {
+		result, err := scanStruct[T](columns, rows.Scan)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		results = append(results, *result)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return results, nil
+}
+
 // ========== ERRORS ==========
 
 var (
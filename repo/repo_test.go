@@ -1,12 +1,18 @@
 package repo
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"math"
 	"reflect"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/selanim/sego/database"
+	"github.com/selanim/sego/tracing"
 )
 
 // TestModel is a test model for testing
@@ -56,7 +62,7 @@ func TestNewRepository(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			// Create a repository without DB for table name testing
 			// We'll create a minimal test that only checks table name logic
-			repo := &Repository{
+			repo := &Repository[TestModel]{
 				tableName: tt.expected,
 				cache:     NewCache(tt.opts.CacheTTL),
 			}
@@ -329,7 +335,8 @@ func TestPagination(t *testing.T) {
 
 func TestExtractFieldsAndValues(t *testing.T) {
 	// Create a test repository without DB
-	repo := &Repository{
+	repo := &Repository[TestModel]{
+		dialect:   Postgres,
 		tableName: "testmodels",
 		modelType: reflect.TypeOf(TestModel{}),
 		cache:     NewCache(time.Minute),
@@ -380,7 +387,8 @@ func TestExtractFieldsAndValues(t *testing.T) {
 
 func TestBuildWhereClause(t *testing.T) {
 	// Create repository without DB
-	repo := &Repository{
+	repo := &Repository[TestModel]{
+		dialect:   Postgres,
 		tableName: "testmodels",
 		modelType: reflect.TypeOf(TestModel{}),
 		cache:     NewCache(time.Minute),
@@ -394,29 +402,24 @@ func TestBuildWhereClause(t *testing.T) {
 
 	where, args := repo.buildWhereClause(conditions)
 
-	expectedParts := []string{"name = $1", "age = $2", "email = $3"}
-	for _, part := range expectedParts {
-		if !strings.Contains(where, part) {
-			t.Errorf("Expected WHERE clause to contain %s, got: %s", part, where)
-		}
-	}
-
-	if !strings.Contains(where, " AND ") {
-		t.Errorf("Expected WHERE clause to contain AND, got: %s", where)
+	// Conditions are ordered by field name so the SQL is deterministic
+	expected := `"age" = $1 AND "email" = $2 AND "name" = $3`
+	if where != expected {
+		t.Errorf("Expected WHERE clause %s, got: %s", expected, where)
 	}
 
 	if len(args) != 3 {
-		t.Errorf("Expected 3 args, got %d", len(args))
+		t.Fatalf("Expected 3 args, got %d", len(args))
 	}
 
-	if args[0] != "John" {
-		t.Errorf("Expected first arg to be 'John', got %v", args[0])
+	if args[0] != 30 {
+		t.Errorf("Expected first arg to be 30, got %v", args[0])
 	}
-	if args[1] != 30 {
-		t.Errorf("Expected second arg to be 30, got %v", args[1])
+	if args[1] != "john@example.com" {
+		t.Errorf("Expected second arg to be 'john@example.com', got %v", args[1])
 	}
-	if args[2] != "john@example.com" {
-		t.Errorf("Expected third arg to be 'john@example.com', got %v", args[2])
+	if args[2] != "John" {
+		t.Errorf("Expected third arg to be 'John', got %v", args[2])
 	}
 }
 
@@ -557,6 +560,362 @@ func TestQueryBuilderJoins(t *testing.T) {
 	}
 }
 
+func TestScanStruct(t *testing.T) {
+	now := time.Now()
+	columns := []string{"id", "name", "unknown_column", "created_at"}
+
+	result, err := scanStruct[TestModel](columns, func(dest ...interface{}) error {
+		if len(dest) != len(columns) {
+			t.Fatalf("Expected %d destinations, got %d", len(columns), len(dest))
+		}
+		*dest[0].(*int) = 7
+		*dest[1].(*string) = "Jane"
+		if err := dest[2].(discard).Scan("ignored"); err != nil {
+			return err
+		}
+		*dest[3].(*time.Time) = now
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if result.ID != 7 {
+		t.Errorf("Expected ID 7, got %d", result.ID)
+	}
+	if result.Name != "Jane" {
+		t.Errorf("Expected name Jane, got %s", result.Name)
+	}
+	if !result.CreatedAt.Equal(now) {
+		t.Errorf("Expected created_at %v, got %v", now, result.CreatedAt)
+	}
+
+	// Scan errors are returned unchanged
+	scanErr := fmt.Errorf("scan failed")
+	if _, err := scanStruct[TestModel](columns, func(dest ...interface{}) error { return scanErr }); err != scanErr {
+		t.Errorf("Expected scan error, got: %v", err)
+	}
+
+	// Non-struct models are rejected
+	if _, err := scanStruct[int](columns, func(dest ...interface{}) error { return nil }); err == nil {
+		t.Error("Expected error for non-struct model")
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	type model struct {
+		ID       int `db:"id"`
+		FullName string
+		Secret   string `db:"-"`
+		internal string
+	}
+
+	typ := reflect.TypeOf(model{})
+	tests := []struct {
+		field    string
+		expected string
+		ok       bool
+	}{
+		{"ID", "id", true},
+		{"FullName", "fullname", true},
+		{"Secret", "", false},
+		{"internal", "", false},
+	}
+
+	for _, tt := range tests {
+		field, _ := typ.FieldByName(tt.field)
+		name, ok := columnName(field)
+		if name != tt.expected || ok != tt.ok {
+			t.Errorf("columnName(%s) = (%q, %v), want (%q, %v)", tt.field, name, ok, tt.expected, tt.ok)
+		}
+	}
+
+	indexes := fieldIndexes(typ)
+	if len(indexes) != 2 || indexes["id"] != 0 || indexes["fullname"] != 1 {
+		t.Errorf("Unexpected field indexes: %v", indexes)
+	}
+}
+
+func TestNewRepositoryGeneric(t *testing.T) {
+	r := NewRepository[TestModel](nil)
+	if r.GetTableName() != "testmodels" {
+		t.Errorf("Expected table name testmodels, got %s", r.GetTableName())
+	}
+
+	r = NewRepository[TestModel](nil, Options{TableName: "people"})
+	if r.GetTableName() != "people" {
+		t.Errorf("Expected table name people, got %s", r.GetTableName())
+	}
+}
+
+func TestDialects(t *testing.T) {
+	tests := []struct {
+		dialect     Dialect
+		placeholder string
+		quoted      string
+		returning   bool
+		offsetOnly  string
+	}{
+		{Postgres, "$3", `"public"."users"`, true, " OFFSET 5"},
+		{MySQL, "?", "`public`.`users`", false, " LIMIT 18446744073709551615 OFFSET 5"},
+		{SQLite, "?", `"public"."users"`, true, " LIMIT -1 OFFSET 5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dialect.Name(), func(t *testing.T) {
+			if got := tt.dialect.Placeholder(3); got != tt.placeholder {
+				t.Errorf("Expected placeholder %s, got %s", tt.placeholder, got)
+			}
+			if got := tt.dialect.QuoteIdent("public.users"); got != tt.quoted {
+				t.Errorf("Expected quoted identifier %s, got %s", tt.quoted, got)
+			}
+			if got := tt.dialect.SupportsReturning(); got != tt.returning {
+				t.Errorf("Expected SupportsReturning %v, got %v", tt.returning, got)
+			}
+			if got := tt.dialect.LimitOffset(0, 5); got != tt.offsetOnly {
+				t.Errorf("Expected offset clause %q, got %q", tt.offsetOnly, got)
+			}
+			if got := tt.dialect.LimitOffset(10, 0); got != " LIMIT 10" {
+				t.Errorf("Expected limit clause %q, got %q", " LIMIT 10", got)
+			}
+		})
+	}
+
+	// Embedded quote characters are escaped
+	if got := Postgres.QuoteIdent(`we"ird`); got != `"we""ird"` {
+		t.Errorf("Expected escaped identifier, got %s", got)
+	}
+
+	if _, err := DialectFor(database.MongoDB); err == nil {
+		t.Error("Expected error for MongoDB dialect")
+	}
+	if d, err := DialectFor(database.SQLite); err != nil || d != SQLite {
+		t.Errorf("Expected SQLite dialect, got %v (%v)", d, err)
+	}
+}
+
+func TestQueryBuilderDialect(t *testing.T) {
+	query, args := NewQueryBuilderWithDialect("users", MySQL).
+		WhereEq("active", true).
+		WhereIn("role", []interface{}{"admin", "editor"}).
+		WhereLike("name", "jo").
+		Offset(20).
+		Build()
+
+	expected := "SELECT * FROM users WHERE active = ? AND role IN (?, ?) AND name LIKE ? LIMIT 18446744073709551615 OFFSET 20"
+	if query != expected {
+		t.Errorf("Expected query: %s\nGot: %s", expected, query)
+	}
+	if len(args) != 4 {
+		t.Errorf("Expected 4 args, got %d", len(args))
+	}
+}
+
+// Product is a test model for SQLite integration tests
+type Product struct {
+	ID     int64   `db:"id"`
+	Name   string  `db:"name"`
+	Price  float64 `db:"price"`
+	Active bool    `db:"active"`
+}
+
+func newSQLiteProductRepo(t *testing.T) *Repository[Product] {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open SQLite: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	_, err = db.Exec(`CREATE TABLE products (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		price REAL NOT NULL,
+		active BOOLEAN NOT NULL DEFAULT 0
+	)`)
+	if err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	return NewSQLRepository[Product](db, SQLite)
+}
+
+func TestSQLiteRepositoryCRUD(t *testing.T) {
+	ctx := context.Background()
+	repo := newSQLiteProductRepo(t)
+
+	if err := repo.Ping(ctx); err != nil {
+		t.Fatalf("Ping failed: %v", err)
+	}
+
+	created, err := repo.Create(ctx, &Product{Name: "Pen", Price: 1.5, Active: true})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if created.ID == 0 || created.Name != "Pen" || !created.Active {
+		t.Errorf("Unexpected created record: %+v", created)
+	}
+
+	found, err := repo.FindByID(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("FindByID failed: %v", err)
+	}
+	if *found != *created {
+		t.Errorf("Expected %+v, got %+v", created, found)
+	}
+
+	found.Price = 2.25
+	updated, err := repo.Update(ctx, found.ID, found)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if updated.Price != 2.25 {
+		t.Errorf("Expected price 2.25, got %v", updated.Price)
+	}
+
+	if _, err := repo.Update(ctx, int64(999), found); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound updating missing record, got %v", err)
+	}
+
+	type idOnly struct {
+		ID int64 `db:"id"`
+	}
+	if _, err := NewSQLRepository[idOnly](nil, SQLite).Update(ctx, int64(1), &idOnly{ID: 1}); !errors.Is(err, ErrInvalidData) {
+		t.Errorf("Expected ErrInvalidData for a model without updatable fields, got %v", err)
+	}
+
+	one, err := repo.FindOne(ctx, map[string]interface{}{"name": "Pen"})
+	if err != nil || one.ID != created.ID {
+		t.Errorf("FindOne returned %+v, %v", one, err)
+	}
+
+	exists, err := repo.Exists(ctx, map[string]interface{}{"name": "Pen"})
+	if err != nil || !exists {
+		t.Errorf("Expected record to exist, got %v, %v", exists, err)
+	}
+
+	if err := repo.Delete(ctx, created.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := repo.Delete(ctx, created.ID); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound deleting twice, got %v", err)
+	}
+	if _, err := repo.FindByID(ctx, created.ID); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound after delete, got %v", err)
+	}
+}
+
+// noReturning is SQLite without RETURNING, to read inserts back like MySQL
+type noReturning struct{ Dialect }
+
+func (noReturning) SupportsReturning() bool { return false }
+
+func TestSQLiteRepositoryCreateWithStringKey(t *testing.T) {
+	type session struct {
+		ID   string `db:"id"`
+		User string `db:"user"`
+	}
+
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open SQLite: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+	if _, err := db.Exec(`CREATE TABLE sessions (id TEXT PRIMARY KEY, user TEXT NOT NULL)`); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+
+	ctx := context.Background()
+	for i, dialect := range []Dialect{SQLite, noReturning{SQLite}} {
+		repo := NewSQLRepository[session](db, dialect, Options{TableName: "sessions"})
+		id := fmt.Sprintf("3f2b6c1e-%d", i)
+
+		created, err := repo.Create(ctx, &session{ID: id, User: "ann"})
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		if created.ID != id || created.User != "ann" {
+			t.Errorf("Expected the caller's key %s, got %+v", id, created)
+		}
+	}
+}
+
+func TestSQLiteRepositoryBatchAndPaginate(t *testing.T) {
+	ctx := context.Background()
+	repo := newSQLiteProductRepo(t)
+
+	var items []Product
+	for i := 1; i <= 25; i++ {
+		items = append(items, Product{Name: fmt.Sprintf("item-%02d", i), Price: float64(i), Active: i%2 == 0})
+	}
+
+	created, err := repo.CreateMany(ctx, items)
+	if err != nil {
+		t.Fatalf("CreateMany failed: %v", err)
+	}
+	if len(created) != 25 {
+		t.Fatalf("Expected 25 records, got %d", len(created))
+	}
+
+	page, err := repo.Paginate(ctx, &PaginationOptions{Page: 3, Limit: 10}, QueryOptions{OrderBy: "id ASC"})
+	if err != nil {
+		t.Fatalf("Paginate failed: %v", err)
+	}
+	if page.Pagination.TotalRows != 25 || page.Pagination.TotalPages != 3 {
+		t.Errorf("Unexpected pagination: %+v", page.Pagination)
+	}
+	if len(page.Data) != 5 || page.Data[0].Name != "item-21" {
+		t.Errorf("Unexpected page data: %+v", page.Data)
+	}
+
+	qb := repo.QueryBuilder().WhereEq("active", true).OrderBy("price", true)
+	qbPage, err := repo.PaginateWithQueryBuilder(ctx, qb, &PaginationOptions{Page: 1, Limit: 5})
+	if err != nil {
+		t.Fatalf("PaginateWithQueryBuilder failed: %v", err)
+	}
+	if qbPage.Pagination.TotalRows != 12 || len(qbPage.Data) != 5 || qbPage.Data[0].Price != 24 {
+		t.Errorf("Unexpected query builder page: %+v %+v", qbPage.Pagination, qbPage.Data)
+	}
+
+	updates := map[interface{}]Product{
+		created[0].ID: {Name: "renamed", Price: 100},
+	}
+	if err := repo.UpdateMany(ctx, updates); err != nil {
+		t.Fatalf("UpdateMany failed: %v", err)
+	}
+	renamed, err := repo.FindByField(ctx, "name", "renamed")
+	if err != nil || len(renamed) != 1 || renamed[0].ID != created[0].ID {
+		t.Errorf("FindByField returned %+v, %v", renamed, err)
+	}
+}
+
+func TestSQLiteRepositoryTransactionRollback(t *testing.T) {
+	ctx := context.Background()
+	repo := newSQLiteProductRepo(t)
+
+	rollbackErr := fmt.Errorf("rollback")
+	err := repo.Transaction(ctx, func(tx *Repository[Product]) error {
+		if _, err := tx.Create(ctx, &Product{Name: "temp", Price: 1}); err != nil {
+			return err
+		}
+		return rollbackErr
+	})
+	if err != rollbackErr {
+		t.Fatalf("Expected rollback error, got %v", err)
+	}
+
+	count, err := repo.Count(ctx)
+	if err != nil {
+		t.Fatalf("Count failed: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("Expected 0 records after rollback, got %d", count)
+	}
+}
+
 // Integration test example (would require actual database)
 func TestRepositoryIntegration(t *testing.T) {
 	if testing.Short() {
@@ -601,3 +960,78 @@ func BenchmarkQueryBuilder(b *testing.B) {
 		qb.Build()
 	}
 }
+
+func TestSQLiteRepositoryTracing(t *testing.T) {
+	exporter := tracing.NewInMemoryExporter()
+	tracer := tracing.NewTracer(tracing.Options{Exporter: exporter})
+	defer tracer.Shutdown(context.Background())
+
+	repo := newSQLiteProductRepo(t)
+	ctx, root := tracer.Start(context.Background(), "request", tracing.SpanKindServer)
+
+	created, err := repo.Create(ctx, &Product{Name: "Pen", Price: 1.5})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, err := repo.FindByID(ctx, int64(999)); err != ErrNotFound {
+		t.Fatalf("Expected ErrNotFound, got %v", err)
+	}
+	repo.Delete(ctx, created.ID)
+	root.End()
+	tracer.Flush(context.Background())
+
+	spans := exporter.Spans()
+	names := make(map[string]tracing.SpanData)
+	for _, span := range spans {
+		names[span.Name] = span
+	}
+
+	for _, name := range []string{"repo.Create products", "repo.FindByID products", "repo.Delete products"} {
+		span, ok := names[name]
+		if !ok {
+			t.Errorf("Expected span %q, got %d spans", name, len(spans))
+			continue
+		}
+		if span.ParentSpanID != root.SpanContext().SpanID {
+			t.Errorf("%s: expected parent to be the request span", name)
+		}
+		if span.Attributes["db.system"] != "sqlite" || span.Attributes["db.sql.table"] != "products" {
+			t.Errorf("%s: unexpected attributes %v", name, span.Attributes)
+		}
+	}
+
+	if find := names["repo.FindByID products"]; find.Status == tracing.StatusError || find.Attributes["repo.not_found"] != true {
+		t.Errorf("Expected not-found to be recorded without an error status, got %+v", find)
+	}
+}
+
+func TestRepositoryTracingDBSystem(t *testing.T) {
+	exporter := tracing.NewInMemoryExporter()
+	tracer := tracing.NewTracer(tracing.Options{Exporter: exporter})
+	defer tracer.Shutdown(context.Background())
+
+	ctx, root := tracer.Start(context.Background(), "request", tracing.SpanKindServer)
+	_, span := NewRepository[Product](nil).startSpan(ctx, "Create")
+	span.End()
+	root.End()
+	tracer.Flush(context.Background())
+
+	// Repository and database spans use the same OpenTelemetry value
+	want := database.PostgreSQL.System()
+	if want != "postgresql" {
+		t.Errorf("Expected postgresql for PostgreSQL, got %q", want)
+	}
+	found := false
+	for _, span := range exporter.Spans() {
+		if span.Name != "repo.Create products" {
+			continue
+		}
+		found = true
+		if span.Attributes["db.system"] != want {
+			t.Errorf("Expected db.system %q, got %v", want, span.Attributes["db.system"])
+		}
+	}
+	if !found {
+		t.Error("Expected repo.Create span")
+	}
+}
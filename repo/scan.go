@@ -0,0 +1,78 @@
+package repo
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// fieldIndexCache caches column-to-field mappings per model type
+var fieldIndexCache sync.Map // map[reflect.Type]map[string]int
+
+// columnName returns the column name for a struct field.
+// It uses the `db` tag when present and falls back to the lowercased field name.
+func columnName(field reflect.StructField) (string, bool) {
+	if !field.IsExported() {
+		return "", false
+	}
+
+	dbTag := field.Tag.Get("db")
+	if dbTag == "-" {
+		return "", false
+	}
+	if dbTag == "" {
+		dbTag = strings.ToLower(field.Name)
+	}
+
+	return dbTag, true
+}
+
+// fieldIndexes maps column names to struct field indexes for a model type
+func fieldIndexes(t reflect.Type) map[string]int {
+	if cached, ok := fieldIndexCache.Load(t); ok {
+		return cached.(map[string]int)
+	}
+
+	indexes := make(map[string]int, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		if name, ok := columnName(t.Field(i)); ok {
+			indexes[name] = i
+		}
+	}
+
+	fieldIndexCache.Store(t, indexes)
+	return indexes
+}
+
+// discard swallows values for columns that have no matching struct field
+type discard struct{}
+
+// Scan implements sql.Scanner
+func (discard) Scan(interface{}) error { return nil }
+
+// scanStruct scans a single row into a new T, matching columns to fields by name
+func scanStruct[T any](columns []string, scan func(dest ...interface{}) error) (*T, error) {
+	var result T
+
+	v := reflect.ValueOf(&result).Elem()
+	if v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%w: model must be a struct, got %s", ErrInvalidData, v.Kind())
+	}
+
+	indexes := fieldIndexes(v.Type())
+	dest := make([]interface{}, len(columns))
+	for i, column := range columns {
+		if idx, ok := indexes[column]; ok {
+			dest[i] = v.Field(idx).Addr().Interface()
+		} else {
+			dest[i] = discard{}
+		}
+	}
+
+	if err := scan(dest...); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
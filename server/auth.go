@@ -0,0 +1,156 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/selanim/sego/authutils"
+	"github.com/selanim/sego/responseutils"
+)
+
+// TokenVerifier validates a bearer token and returns its claims.
+// *authutils.AuthUtils satisfies this interface.
+type TokenVerifier interface {
+	ValidateToken(token string) (*authutils.Claims, error)
+}
+
+// TokenVerifierFunc adapts a function to TokenVerifier
+type TokenVerifierFunc func(token string) (*authutils.Claims, error)
+
+// ValidateToken calls f(token)
+func (f TokenVerifierFunc) ValidateToken(token string) (*authutils.Claims, error) {
+	return f(token)
+}
+
+// AuthMiddleware validates the bearer token and stores its claims in the request context.
+// Requests that already carry claims, e.g. from a group middleware, are not validated again.
+func AuthMiddleware(verifier TokenVerifier) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := ClaimsFromRequest(r); ok {
+				next(w, r)
+				return
+			}
+
+			if verifier == nil {
+				log.Printf("AuthMiddleware: no token verifier configured")
+				responseutils.InternalServerError(w, "Authentication is not configured")
+				return
+			}
+
+			authHeader := r.Header.Get("Authorization")
+			if authHeader == "" {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				responseutils.Unauthorized(w, "Authorization required")
+				return
+			}
+
+			token, ok := bearerToken(authHeader)
+			if !ok {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_request"`)
+				responseutils.Unauthorized(w, "Invalid authorization format")
+				return
+			}
+
+			claims, err := verifier.ValidateToken(token)
+			if err != nil || claims == nil {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				responseutils.Unauthorized(w, tokenErrorMessage(err))
+				return
+			}
+
+			next(w, r.WithContext(authutils.ContextWithClaims(r.Context(), claims)))
+		}
+	}
+}
+
+// RoleBasedMiddleware allows requests whose claims contain any of the given roles.
+// It must run after AuthMiddleware.
+func RoleBasedMiddleware(roles ...string) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := ClaimsFromRequest(r)
+			if !ok {
+				responseutils.Unauthorized(w, "Authentication required")
+				return
+			}
+
+			if !claims.HasAnyRole(roles...) {
+				responseutils.Forbidden(w, "Insufficient permissions")
+				return
+			}
+
+			next(w, r)
+		}
+	}
+}
+
+// PermissionBasedMiddleware allows requests whose claims contain all of the given permissions.
+// It must run after AuthMiddleware.
+func PermissionBasedMiddleware(permissions ...string) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := ClaimsFromRequest(r)
+			if !ok {
+				responseutils.Unauthorized(w, "Authentication required")
+				return
+			}
+
+			for _, permission := range permissions {
+				if !claims.HasPermission(permission) {
+					responseutils.Forbidden(w, fmt.Sprintf("Missing permission: %s", permission))
+					return
+				}
+			}
+
+			next(w, r)
+		}
+	}
+}
+
+// ClaimsFromContext returns the claims stored by AuthMiddleware
+func ClaimsFromContext(ctx context.Context) (*authutils.Claims, bool) {
+	return authutils.ClaimsFromContext(ctx)
+}
+
+// ClaimsFromRequest returns the claims stored by AuthMiddleware
+func ClaimsFromRequest(r *http.Request) (*authutils.Claims, bool) {
+	return authutils.ClaimsFromContext(r.Context())
+}
+
+// UserIDFromRequest returns the authenticated user ID or an empty string
+func UserIDFromRequest(r *http.Request) string {
+	if claims, ok := ClaimsFromRequest(r); ok {
+		return claims.UserID
+	}
+	return ""
+}
+
+// WithClaims returns a shallow copy of r carrying the given claims
+func WithClaims(r *http.Request, claims *authutils.Claims) *http.Request {
+	return r.WithContext(authutils.ContextWithClaims(r.Context(), claims))
+}
+
+// bearerToken extracts the token from an "Authorization: Bearer <token>" header
+func bearerToken(header string) (string, bool) {
+	scheme, token, found := strings.Cut(strings.TrimSpace(header), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// tokenErrorMessage maps verifier errors to client-facing messages
+func tokenErrorMessage(err error) string {
+	var authErr *authutils.AuthError
+	if errors.As(err, &authErr) && authErr.Code == "TOKEN_EXPIRED" {
+		return "Token has expired"
+	}
+	return "Invalid token"
+}
@@ -0,0 +1,498 @@
+package server
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/selanim/sego/responseutils"
+	"github.com/selanim/sego/validation"
+)
+
+// DefaultMaxBodySize limits request bodies read by Bind
+const DefaultMaxBodySize = 10 << 20
+
+// BindOptions configures request binding
+type BindOptions struct {
+	// MaxBodySize limits the body in bytes; 0 uses DefaultMaxBodySize
+	MaxBodySize int64
+	// DisallowUnknownFields rejects JSON and form fields that the target does not have
+	DisallowUnknownFields bool
+	// SkipValidation only decodes, leaving validate tags unchecked
+	SkipValidation bool
+}
+
+// BindError describes why a request could not be bound. Fields holds
+// per-field errors for decoding and validation failures.
+type BindError struct {
+	Status  int
+	Message string
+	Fields  []responseutils.ValidationFieldError
+	Err     error
+}
+
+func (e *BindError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Message, e.Err)
+	}
+	return e.Message
+}
+
+func (e *BindError) Unwrap() error {
+	return e.Err
+}
+
+// Bind decodes the request into dst and validates it. On failure it writes
+// the error response and returns false:
+//
+//	var req struct {
+//		ID    int    `path:"id"`
+//		Page  int    `query:"page" default:"1" validate:"min:1"`
+//		Email string `json:"email" validate:"required|email"`
+//	}
+//	if !server.Bind(w, r, &req) {
+//		return
+//	}
+func Bind(w http.ResponseWriter, r *http.Request, dst interface{}, opts ...BindOptions) bool {
+	err := DecodeRequest(w, r, dst, opts...)
+	if err == nil {
+		return true
+	}
+
+	var bindErr *BindError
+	if !errors.As(err, &bindErr) {
+		responseutils.InternalServerError(w, err.Error())
+		return false
+	}
+	if len(bindErr.Fields) > 0 {
+		responseutils.ValidationErrorWithCode(w, bindErr.Fields)
+		return false
+	}
+	responseutils.Error(w, bindErr.Status, bindErr.Message)
+	return false
+}
+
+// DecodeRequest fills dst, a pointer to a struct, from the request and validates it.
+// Values are taken, in increasing priority, from `default` tags, the body
+// (JSON, form or multipart, using `json` and `form` tags), `query` tags and
+// `path` tags naming route parameters. Errors are *BindError values with
+// messages in the locale of the request. w is told to close the connection
+// when the body is too large.
+func DecodeRequest(w http.ResponseWriter, r *http.Request, dst interface{}, opts ...BindOptions) error {
+	options := BindOptions{}
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+	if options.MaxBodySize <= 0 {
+		options.MaxBodySize = DefaultMaxBodySize
+	}
+
+	val := reflect.ValueOf(dst)
+	if val.Kind() != reflect.Ptr || val.IsNil() || val.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("failed to bind request: target must be a pointer to a struct, got %T", dst)
+	}
+	val = val.Elem()
+
+	v := validation.NewForRequest(r)
+	if err := applyDefaults(val); err != nil {
+		return err
+	}
+	if err := decodeBody(w, r, dst, val, v, options); err != nil {
+		return err
+	}
+	bindStrings(val, "query", r.URL.Query(), v)
+	bindStrings(val, "path", pathValues(r), v)
+
+	// Values that could not be converted are reported without running the rules
+	names := boundNames(val)
+	if !v.HasErrors() && !options.SkipValidation {
+		for field, name := range names {
+			v.SetLabel(field, name)
+		}
+		// Context rules, such as database lookups, use the request's deadline
+		if _, err := v.ValidateContext(r.Context(), dst); err != nil {
+			return &BindError{Status: http.StatusServiceUnavailable, Message: "Validation could not be completed", Err: err}
+		}
+	}
+	if !v.HasErrors() {
+		return nil
+	}
+
+	fields := v.FieldErrors()
+	for i := range fields {
+		if name, ok := names[fields[i].Field]; ok {
+			fields[i].Field = name
+		}
+	}
+	return &BindError{Status: http.StatusBadRequest, Message: "Validation failed", Fields: fields}
+}
+
+// boundNames maps the Go names of fields without a json tag, which the validator
+// reports them by, to their query, path or form names
+func boundNames(val reflect.Value) map[string]string {
+	names := make(map[string]string)
+	for _, tag := range []string{"form", "query", "path"} {
+		for _, field := range taggedFields(val, tag) {
+			if _, hasJSON := field.tag.Lookup("json"); !hasJSON {
+				names[field.goName] = field.name
+			}
+		}
+	}
+	return names
+}
+
+// decodeBody decodes the body according to its content type
+func decodeBody(w http.ResponseWriter, r *http.Request, dst interface{}, val reflect.Value, v *validation.Validator, options BindOptions) error {
+	if r.Body == nil || r.Body == http.NoBody || r.ContentLength == 0 {
+		return nil
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, options.MaxBodySize)
+
+	mediaType := "application/json"
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		parsed, _, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			return &BindError{Status: http.StatusUnsupportedMediaType, Message: "Invalid Content-Type", Err: err}
+		}
+		mediaType = parsed
+	}
+
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		return decodeJSON(r.Body, dst, v, options)
+	case mediaType == "application/x-www-form-urlencoded":
+		if err := r.ParseForm(); err != nil {
+			return bodyError(err)
+		}
+		bindForm(val, r.PostForm, nil, v, options)
+	case mediaType == "multipart/form-data":
+		if err := r.ParseMultipartForm(options.MaxBodySize); err != nil {
+			return bodyError(err)
+		}
+		bindForm(val, r.MultipartForm.Value, r.MultipartForm.File, v, options)
+	default:
+		return &BindError{Status: http.StatusUnsupportedMediaType, Message: "Unsupported Content-Type: " + mediaType}
+	}
+	return nil
+}
+
+// decodeJSON decodes a JSON body, reporting type mismatches and unknown fields per field
+func decodeJSON(body io.Reader, dst interface{}, v *validation.Validator, options BindOptions) error {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return bodyError(err)
+	}
+
+	err = json.NewDecoder(bytes.NewReader(data)).Decode(dst)
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case err == nil:
+	case errors.Is(err, io.EOF):
+		return nil
+	case errors.As(err, &typeErr):
+		v.AddErrorCode(typeErr.Field, typeCode(typeErr.Type), nil)
+		return nil
+	default:
+		return bodyError(err)
+	}
+
+	if options.DisallowUnknownFields {
+		for _, field := range unknownJSONFields(reflect.TypeOf(dst).Elem(), data, "") {
+			v.AddErrorCode(field, "unknown_field", nil)
+		}
+	}
+	return nil
+}
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// unknownJSONFields returns the keys of a JSON object that typ has no field
+// for, recursing into nested objects. Keys match case-insensitively, as they
+// do when decoding.
+func unknownJSONFields(typ reflect.Type, data []byte, prefix string) []string {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil {
+		return nil
+	}
+
+	fields := make(map[string]reflect.Type)
+	jsonFields(typ, fields)
+
+	var unknown []string
+	for key, value := range object {
+		fieldType, ok := fields[strings.ToLower(key)]
+		if !ok {
+			unknown = append(unknown, prefix+key)
+			continue
+		}
+		for fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		// Types that decode themselves, such as time.Time, have no fields to check
+		if fieldType.Kind() == reflect.Struct && !reflect.PointerTo(fieldType).Implements(jsonUnmarshalerType) {
+			unknown = append(unknown, unknownJSONFields(fieldType, value, prefix+key+".")...)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// jsonFields adds the lowercased JSON names of typ's fields to fields,
+// including those promoted from embedded structs
+func jsonFields(typ reflect.Type, fields map[string]reflect.Type) {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				jsonFields(embedded, fields)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[strings.ToLower(name)] = field.Type
+	}
+}
+
+// bodyError converts errors reading the body
+func bodyError(err error) error {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return &BindError{Status: http.StatusRequestEntityTooLarge, Message: "Request body too large", Err: err}
+	}
+	return &BindError{Status: http.StatusBadRequest, Message: "Invalid request body", Err: err}
+}
+
+// bindForm sets fields with `form` tags from form values and uploaded files
+func bindForm(val reflect.Value, values url.Values, files map[string][]*multipart.FileHeader, v *validation.Validator, options BindOptions) {
+	bindStrings(val, "form", values, v)
+
+	known := make(map[string]bool)
+	for _, field := range taggedFields(val, "form") {
+		known[field.name] = true
+		headers, ok := files[field.name]
+		if !ok {
+			continue
+		}
+		switch field.value.Type() {
+		case reflect.TypeOf(&multipart.FileHeader{}):
+			field.value.Set(reflect.ValueOf(headers[0]))
+		case reflect.TypeOf([]*multipart.FileHeader{}):
+			field.value.Set(reflect.ValueOf(headers))
+		}
+	}
+
+	if options.DisallowUnknownFields {
+		for name := range values {
+			if !known[name] {
+				v.AddErrorCode(name, "unknown_field", nil)
+			}
+		}
+		for name := range files {
+			if !known[name] {
+				v.AddErrorCode(name, "unknown_field", nil)
+			}
+		}
+	}
+}
+
+// bindStrings sets the fields tagged with tag from values
+func bindStrings(val reflect.Value, tag string, values url.Values, v *validation.Validator) {
+	for _, field := range taggedFields(val, tag) {
+		raw, ok := values[field.name]
+		if !ok || len(raw) == 0 {
+			continue
+		}
+		if err := setValue(field.value, raw); err != nil {
+			v.AddErrorCode(field.name, typeCode(field.value.Type()), nil)
+		}
+	}
+}
+
+// pathValues returns the route parameters of the request
+func pathValues(r *http.Request) url.Values {
+	params := PathParams(r)
+	values := make(url.Values, len(params))
+	for _, param := range params {
+		values.Set(param.Key, param.Value)
+	}
+	return values
+}
+
+// applyDefaults sets zero fields with a `default` tag, recursing into nested structs
+func applyDefaults(val reflect.Value) error {
+	typ := val.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		fv := val.Field(i)
+
+		if def, ok := field.Tag.Lookup("default"); ok {
+			if !fv.IsZero() {
+				continue
+			}
+			raw := []string{def}
+			if fv.Kind() == reflect.Slice {
+				raw = strings.Split(def, ",")
+			}
+			if err := setValue(fv, raw); err != nil {
+				return fmt.Errorf("failed to apply default for %s: %w", field.Name, err)
+			}
+			continue
+		}
+
+		if fv.Kind() == reflect.Struct && fv.Type() != reflect.TypeOf(time.Time{}) {
+			if err := applyDefaults(fv); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// boundField is a struct field and the name it is bound to
+type boundField struct {
+	name   string
+	goName string
+	tag    reflect.StructTag
+	value  reflect.Value
+}
+
+// taggedFields returns the fields with tag, including those of embedded structs
+func taggedFields(val reflect.Value, tag string) []boundField {
+	var fields []boundField
+	typ := val.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			fields = append(fields, taggedFields(val.Field(i), tag)...)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		name := strings.Split(field.Tag.Get(tag), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		fields = append(fields, boundField{name: name, goName: field.Name, tag: field.Tag, value: val.Field(i)})
+	}
+	return fields
+}
+
+// setValue converts raw strings into fv. Slices take every value; other kinds the first.
+func setValue(fv reflect.Value, raw []string) error {
+	if fv.Kind() == reflect.Ptr {
+		if fv.IsNil() {
+			fv.Set(reflect.New(fv.Type().Elem()))
+		}
+		return setValue(fv.Elem(), raw)
+	}
+
+	if fv.CanAddr() {
+		if u, ok := fv.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			return u.UnmarshalText([]byte(raw[0]))
+		}
+	}
+
+	if fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() != reflect.Uint8 {
+		slice := reflect.MakeSlice(fv.Type(), len(raw), len(raw))
+		for i, s := range raw {
+			if err := setValue(slice.Index(i), []string{s}); err != nil {
+				return err
+			}
+		}
+		fv.Set(slice)
+		return nil
+	}
+
+	s := raw[0]
+	if fv.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(int64(d))
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+	case reflect.Slice:
+		fv.SetBytes([]byte(s))
+	default:
+		return fmt.Errorf("unsupported field type %s", fv.Type())
+	}
+	return nil
+}
+
+// typeCode returns the validation message code for a value of the wrong type
+func typeCode(typ reflect.Type) string {
+	for typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Slice {
+		typ = typ.Elem()
+	}
+	if typ == reflect.TypeOf(time.Time{}) {
+		return "datetime"
+	}
+	switch typ.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "numeric"
+	case reflect.Bool:
+		return "boolean"
+	}
+	return "invalid"
+}
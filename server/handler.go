@@ -1,11 +1,10 @@
 package server
 
 import (
-	"encoding/json"
 	"fmt"
 	"net/http"
+	"path"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/selanim/sego/responseutils"
@@ -23,7 +22,7 @@ func NewHandlers(server *Server) *Handlers {
 
 // GetUsers returns list of users
 func (h *Handlers) GetUsers(w http.ResponseWriter, r *http.Request) {
-	// Parse query parameters
+	// Parse query parameters; invalid values fall back to the defaults
 	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
 	if page < 1 {
 		page = 1
@@ -61,15 +60,7 @@ func (h *Handlers) GetUsers(w http.ResponseWriter, r *http.Request) {
 
 // GetUser returns a single user
 func (h *Handlers) GetUser(w http.ResponseWriter, r *http.Request) {
-	// Extract ID from URL path
-	pathParts := strings.Split(r.URL.Path, "/")
-	if len(pathParts) < 4 {
-		responseutils.NotFound(w, "User not found")
-		return
-	}
-
-	idStr := pathParts[3]
-	id, err := strconv.Atoi(idStr)
+	id, err := userIDParam(r)
 	if err != nil {
 		responseutils.BadRequest(w, "Invalid user ID")
 		return
@@ -92,35 +83,24 @@ func (h *Handlers) GetUser(w http.ResponseWriter, r *http.Request) {
 	responseutils.Success(w, user, "User retrieved successfully")
 }
 
+// userRequest is the body of CreateUser
+type userRequest struct {
+	Name  string `json:"name" form:"name" validate:"required|max_len:100"`
+	Email string `json:"email" form:"email" validate:"required|email"`
+}
+
 // CreateUser creates a new user
 func (h *Handlers) CreateUser(w http.ResponseWriter, r *http.Request) {
-	// Parse request body
-	var data map[string]interface{}
-	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
-		responseutils.BadRequest(w, "Invalid request body")
-		return
-	}
-
-	// Validate required fields
-	if name, ok := data["name"].(string); !ok || name == "" {
-		responseutils.ValidationError(w, map[string]string{
-			"name": "Name is required",
-		})
-		return
-	}
-
-	if email, ok := data["email"].(string); !ok || email == "" {
-		responseutils.ValidationError(w, map[string]string{
-			"email": "Email is required",
-		})
+	var req userRequest
+	if !Bind(w, r, &req) {
 		return
 	}
 
 	// Simulate user creation
 	newUser := map[string]interface{}{
 		"id":         101, // Simulated new ID
-		"name":       data["name"],
-		"email":      data["email"],
+		"name":       req.Name,
+		"email":      req.Email,
 		"created_at": time.Now(),
 		"updated_at": time.Now(),
 	}
@@ -130,51 +110,43 @@ func (h *Handlers) CreateUser(w http.ResponseWriter, r *http.Request) {
 	responseutils.CreatedWithLocation(w, newUser, location, "User created successfully")
 }
 
+// updateUserRequest is the body of UpdateUser; empty fields are left unchanged
+type updateUserRequest struct {
+	Name  string `json:"name" form:"name" validate:"max_len:100"`
+	Email string `json:"email" form:"email" validate:"email"`
+}
+
 // UpdateUser updates an existing user
 func (h *Handlers) UpdateUser(w http.ResponseWriter, r *http.Request) {
-	// Extract ID
-	pathParts := strings.Split(r.URL.Path, "/")
-	if len(pathParts) < 4 {
-		responseutils.NotFound(w, "User not found")
-		return
-	}
-
-	idStr := pathParts[3]
-	id, err := strconv.Atoi(idStr)
+	id, err := userIDParam(r)
 	if err != nil {
 		responseutils.BadRequest(w, "Invalid user ID")
 		return
 	}
 
-	// Parse request body
-	var data map[string]interface{}
-	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
-		responseutils.BadRequest(w, "Invalid request body")
+	var req updateUserRequest
+	if !Bind(w, r, &req) {
 		return
 	}
 
 	// Simulate update
 	updatedUser := map[string]interface{}{
 		"id":         id,
-		"name":       data["name"],
-		"email":      data["email"],
 		"updated_at": time.Now(),
 	}
+	if req.Name != "" {
+		updatedUser["name"] = req.Name
+	}
+	if req.Email != "" {
+		updatedUser["email"] = req.Email
+	}
 
 	responseutils.Success(w, updatedUser, "User updated successfully")
 }
 
 // DeleteUser deletes a user
 func (h *Handlers) DeleteUser(w http.ResponseWriter, r *http.Request) {
-	// Extract ID
-	pathParts := strings.Split(r.URL.Path, "/")
-	if len(pathParts) < 4 {
-		responseutils.NotFound(w, "User not found")
-		return
-	}
-
-	idStr := pathParts[3]
-	_, err := strconv.Atoi(idStr)
+	_, err := userIDParam(r)
 	if err != nil {
 		responseutils.BadRequest(w, "Invalid user ID")
 		return
@@ -184,6 +156,15 @@ func (h *Handlers) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	responseutils.NoContent(w)
 }
 
+// userIDParam reads the {id} path parameter, falling back to the last path
+// segment when the handler is called outside the router
+func userIDParam(r *http.Request) (int, error) {
+	if _, ok := PathParams(r).Get("id"); ok {
+		return GetParamInt(r, "id")
+	}
+	return strconv.Atoi(path.Base(r.URL.Path))
+}
+
 // ServeFrontend serves frontend HTML
 func (h *Handlers) ServeFrontend(w http.ResponseWriter, r *http.Request) {
 	html := `<!DOCTYPE html>
@@ -484,22 +465,7 @@ func (h *Handlers) HealthHandler(w http.ResponseWriter, r *http.Request) {
 
 // MetricsHandler handles metrics endpoint
 func (h *Handlers) MetricsHandler(w http.ResponseWriter, r *http.Request) {
-	metrics := map[string]interface{}{
-		"requests": map[string]interface{}{
-			"total":           1234,
-			"success":         1200,
-			"failed":          34,
-			"rate_per_second": 12.5,
-		},
-		"memory": map[string]interface{}{
-			"allocated": "128 MB",
-			"used":      "64 MB",
-			"free":      "64 MB",
-		},
-		"uptime": h.server.health.GetUptime(),
-	}
-
-	responseutils.WriteMetrics(w, metrics)
+	h.server.metrics.Handler(w, r)
 }
 
 // EchoHandler echoes back request data (for testing)
@@ -590,6 +556,8 @@ func (h *Handlers) ConfigHandler(w http.ResponseWriter, r *http.Request) {
 
 // MiddlewareTestHandler tests middleware
 func (h *Handlers) MiddlewareTestHandler(w http.ResponseWriter, r *http.Request) {
+	claims, _ := ClaimsFromRequest(r)
+
 	data := map[string]interface{}{
 		"message": "Middleware test successful",
 		"headers": map[string][]string{
@@ -598,7 +566,7 @@ func (h *Handlers) MiddlewareTestHandler(w http.ResponseWriter, r *http.Request)
 		},
 		"request_context": map[string]interface{}{
 			"request_id": r.Context().Value("request_id"),
-			"user":       r.Context().Value("user"),
+			"user":       claims,
 		},
 		"timestamp": time.Now().Format(time.RFC3339),
 	}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/selanim/sego/logger"
+	"github.com/selanim/sego/responseutils"
+)
+
+// rootLoggerName addresses the root of the named logger hierarchy in URLs
+const rootLoggerName = "root"
+
+// logLevelRequest is the body of a level change
+type logLevelRequest struct {
+	Level string `json:"level"`
+	// TTL reverts the change after a duration such as "15m"
+	TTL string `json:"ttl,omitempty"`
+}
+
+// ServeLogLevels registers an admin API for named logger levels under path:
+//
+//	GET    path         lists named loggers and their levels
+//	GET    path/{name}  shows one logger
+//	PUT    path/{name}  sets the level, e.g. {"level": "debug", "ttl": "15m"}
+//	DELETE path/{name}  removes the level so the logger inherits again
+//
+// The name "root" addresses the root of the hierarchy. The API changes
+// logging for the whole process, so protect it with middleware such as RequireAuth.
+func (s *Server) ServeLogLevels(path string, middleware ...func(http.HandlerFunc) http.HandlerFunc) {
+	group := s.router.Group(strings.TrimSuffix(path, "/"), middleware...)
+	group.Get("", listLogLevels).Named("loglevels.list")
+	group.Get("/{name}", getLogLevel).Named("loglevels.get")
+	group.Put("/{name}", setLogLevel).Named("loglevels.set")
+	group.Delete("/{name}", resetLogLevel).Named("loglevels.reset")
+}
+
+// listLogLevels serves all named logger levels
+func listLogLevels(w http.ResponseWriter, r *http.Request) {
+	writeLogLevels(w, map[string]interface{}{"loggers": logger.NamedLevels()})
+}
+
+// getLogLevel serves the level of one named logger
+func getLogLevel(w http.ResponseWriter, r *http.Request) {
+	writeLogLevels(w, logger.NamedLevelOf(loggerNameParam(r)))
+}
+
+// setLogLevel changes the level of a named logger
+func setLogLevel(w http.ResponseWriter, r *http.Request) {
+	var req logLevelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		responseutils.BadRequest(w, "Invalid request body")
+		return
+	}
+
+	level, ok := logger.LookupLevel(req.Level)
+	if !ok {
+		responseutils.BadRequest(w, "Invalid log level: "+req.Level)
+		return
+	}
+
+	var ttl time.Duration
+	if req.TTL != "" {
+		var err error
+		if ttl, err = time.ParseDuration(req.TTL); err != nil || ttl <= 0 {
+			responseutils.BadRequest(w, "Invalid ttl: "+req.TTL)
+			return
+		}
+	}
+
+	name := loggerNameParam(r)
+	if err := logger.SetNamedLevel(name, level, ttl); err != nil {
+		responseutils.BadRequest(w, err.Error())
+		return
+	}
+	writeLogLevels(w, logger.NamedLevelOf(name))
+}
+
+// resetLogLevel removes the level of a named logger
+func resetLogLevel(w http.ResponseWriter, r *http.Request) {
+	name := loggerNameParam(r)
+	logger.ResetNamedLevel(name)
+	writeLogLevels(w, logger.NamedLevelOf(name))
+}
+
+// loggerNameParam returns the logger name from the path, mapping "root" to ""
+func loggerNameParam(r *http.Request) string {
+	name := GetParam(r, "name")
+	if name == rootLoggerName {
+		return ""
+	}
+	return name
+}
+
+// writeLogLevels writes data as JSON
+func writeLogLevels(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(data)
+}
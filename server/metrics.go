@@ -1,25 +1,61 @@
 package server
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
 
+// unmatchedRoute is the route label for requests that matched no route,
+// so unknown paths cannot grow label cardinality
+const unmatchedRoute = "unmatched"
+
+// MetricsOptions configures NewMetrics
+type MetricsOptions struct {
+	// Namespace prefixes metric names, e.g. "shop" gives shop_http_requests_total
+	Namespace string
+	// Buckets for the request duration histogram in seconds; defaults to DefaultBuckets
+	Buckets []float64
+	// Registry to register metrics in; a new registry with runtime metrics is created when nil
+	Registry *Registry
+	// DisableRuntimeMetrics skips Go runtime metrics on a registry created by NewMetrics
+	DisableRuntimeMetrics bool
+}
+
 // Metrics collects and exposes server metrics
 type Metrics struct {
 	mu             sync.RWMutex
 	startTime      time.Time
-	requestCounts  map[string]int64
-	responseTimes  map[string][]time.Duration
+	endpoints      map[string]*endpointStats
 	statusCodes    map[int]int64
 	activeRequests int64
 	totalRequests  int64
 	errorCount     int64
+	totalDuration  time.Duration
+	system         SystemMetrics
 	collector      *MetricsCollector
+
+	registry  *Registry
+	requests  *CounterVec
+	responses *CounterVec
+	errors    *CounterVec
+	duration  *HistogramVec
+	inFlight  *Gauge
+}
+
+// endpointStats holds JSON summary stats for one method and route
+type endpointStats struct {
+	count         int64
+	errors        int64
+	totalDuration time.Duration
+	lastCall      time.Time
 }
 
 // MetricsCollector periodically collects system metrics
@@ -65,23 +101,70 @@ type EndpointMetrics struct {
 }
 
 // NewMetrics creates new metrics instance
-func NewMetrics() *Metrics {
-	return &Metrics{
-		startTime:     time.Now(),
-		requestCounts: make(map[string]int64),
-		responseTimes: make(map[string][]time.Duration),
-		statusCodes:   make(map[int]int64),
+func NewMetrics(opts ...MetricsOptions) *Metrics {
+	var opt MetricsOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	registry := opt.Registry
+	if registry == nil {
+		registry = NewRegistry()
+		if !opt.DisableRuntimeMetrics {
+			registry.RegisterRuntimeMetrics()
+		}
+	}
+
+	prefix := ""
+	if opt.Namespace != "" {
+		prefix = opt.Namespace + "_"
+	}
+
+	m := &Metrics{
+		startTime:   time.Now(),
+		endpoints:   make(map[string]*endpointStats),
+		statusCodes: make(map[int]int64),
 		collector: &MetricsCollector{
 			stopChan: make(chan bool),
 			interval: 10 * time.Second,
 		},
+		registry: registry,
+		requests: registry.NewCounter(prefix+"http_requests_total",
+			"Total HTTP requests by method, route template and status code.", "method", "route", "code"),
+		responses: registry.NewCounter(prefix+"http_responses_total",
+			"Total HTTP responses by status class.", "class"),
+		errors: registry.NewCounter(prefix+"http_request_errors_total",
+			"Total HTTP requests answered with a 4xx or 5xx status.", "method", "route"),
+		duration: registry.NewHistogram(prefix+"http_request_duration_seconds",
+			"HTTP request latency in seconds by method and route template.", opt.Buckets, "method", "route"),
+		inFlight: registry.NewGauge(prefix+"http_requests_in_flight",
+			"HTTP requests currently being served.").WithLabelValues(),
 	}
+	registry.NewGaugeFunc(prefix+"http_uptime_seconds", "Seconds since the metrics were created or reset.", func() float64 {
+		m.mu.RLock()
+		defer m.mu.RUnlock()
+		return time.Since(m.startTime).Seconds()
+	})
+
+	m.collector.metrics = m
+	m.collector.collect()
+	return m
 }
 
-// Handler handles metrics endpoint requests
+// Registry returns the registry backing the metrics, for registering custom metrics
+func (m *Metrics) Registry() *Registry {
+	return m.registry
+}
+
+// Handler serves metrics as JSON, or in the Prometheus text format when
+// requested with ?format=prometheus or a text/plain or OpenMetrics Accept header
 func (m *Metrics) Handler(w http.ResponseWriter, r *http.Request) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+	accept := r.Header.Get("Accept")
+	if r.URL.Query().Get("format") == "prometheus" ||
+		strings.Contains(accept, "text/plain") || strings.Contains(accept, "application/openmetrics-text") {
+		m.PrometheusHandler(w, r)
+		return
+	}
 
 	metrics := m.GetMetrics()
 
@@ -89,8 +172,16 @@ func (m *Metrics) Handler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(metrics)
 }
 
+// PrometheusHandler serves metrics in the Prometheus text exposition format
+func (m *Metrics) PrometheusHandler(w http.ResponseWriter, r *http.Request) {
+	m.registry.Handler(w, r)
+}
+
 // GetMetrics returns current metrics
 func (m *Metrics) GetMetrics() MetricData {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
 	uptime := time.Since(m.startTime)
 
 	// Calculate request rate
@@ -98,20 +189,12 @@ func (m *Metrics) GetMetrics() MetricData {
 
 	// Prepare endpoint metrics
 	endpointMetrics := make(map[string]EndpointMetrics)
-	for path, count := range m.requestCounts {
-		var avgTime time.Duration
-		if times, exists := m.responseTimes[path]; exists && len(times) > 0 {
-			var total time.Duration
-			for _, t := range times {
-				total += t
-			}
-			avgTime = total / time.Duration(len(times))
-		}
-
-		endpointMetrics[path] = EndpointMetrics{
-			Count:    count,
-			AvgTime:  avgTime,
-			LastCall: time.Now(), // Would track actual last call time
+	for key, stats := range m.endpoints {
+		endpointMetrics[key] = EndpointMetrics{
+			Count:      stats.count,
+			AvgTime:    stats.totalDuration / time.Duration(stats.count),
+			ErrorCount: stats.errors,
+			LastCall:   stats.lastCall,
 		}
 	}
 
@@ -121,6 +204,11 @@ func (m *Metrics) GetMetrics() MetricData {
 		statusCodes[fmt.Sprintf("%d", code)] = count
 	}
 
+	var avgLatency time.Duration
+	if m.totalRequests > 0 {
+		avgLatency = m.totalDuration / time.Duration(m.totalRequests)
+	}
+
 	return MetricData{
 		Timestamp: time.Now().Format(time.RFC3339),
 		Uptime:    formatDuration(uptime),
@@ -129,42 +217,50 @@ func (m *Metrics) GetMetrics() MetricData {
 			Active:         m.activeRequests,
 			Errors:         m.errorCount,
 			RatePerSecond:  rate,
-			AverageLatency: "0ms", // Would calculate actual average
-		},
-		System: SystemMetrics{
-			MemoryAllocated: "0 MB", // Would get from runtime
-			MemoryUsed:      "0 MB",
-			Goroutines:      0, // Would get from runtime
+			AverageLatency: avgLatency.String(),
 		},
+		System:      m.system,
 		Endpoints:   endpointMetrics,
 		StatusCodes: statusCodes,
 	}
 }
 
-// RecordRequest records a request
+// RecordRequest records a request. path should be the route template, such
+// as /users/{id}, not the raw request path.
 func (m *Metrics) RecordRequest(method, path string, statusCode int, duration time.Duration) {
+	method = normalizeMethod(method)
+	if path == "" {
+		path = unmatchedRoute
+	}
+	isError := statusCode >= 400
+
+	m.requests.WithLabelValues(method, path, strconv.Itoa(statusCode)).Inc()
+	m.responses.WithLabelValues(statusClass(statusCode)).Inc()
+	m.duration.WithLabelValues(method, path).ObserveDuration(duration)
+	if isError {
+		m.errors.WithLabelValues(method, path).Inc()
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	// Increment counters
 	key := fmt.Sprintf("%s %s", method, path)
-	m.requestCounts[key]++
-	m.totalRequests++
-	m.statusCodes[statusCode]++
-
-	// Track response time (keep last 100 samples)
-	if _, exists := m.responseTimes[key]; !exists {
-		m.responseTimes[key] = make([]time.Duration, 0, 100)
+	stats, exists := m.endpoints[key]
+	if !exists {
+		stats = &endpointStats{}
+		m.endpoints[key] = stats
 	}
+	stats.count++
+	stats.totalDuration += duration
+	stats.lastCall = time.Now()
 
-	times := m.responseTimes[key]
-	if len(times) >= 100 {
-		times = times[1:]
-	}
-	m.responseTimes[key] = append(times, duration)
+	m.totalRequests++
+	m.totalDuration += duration
+	m.statusCodes[statusCode]++
 
 	// Track errors
-	if statusCode >= 400 {
+	if isError {
+		stats.errors++
 		m.errorCount++
 	}
 }
@@ -184,6 +280,7 @@ func (m *Metrics) StopCollector() {
 
 // IncActiveRequests increments active requests counter
 func (m *Metrics) IncActiveRequests() {
+	m.inFlight.Inc()
 	m.mu.Lock()
 	m.activeRequests++
 	m.mu.Unlock()
@@ -191,6 +288,7 @@ func (m *Metrics) IncActiveRequests() {
 
 // DecActiveRequests decrements active requests counter
 func (m *Metrics) DecActiveRequests() {
+	m.inFlight.Dec()
 	m.mu.Lock()
 	m.activeRequests--
 	m.mu.Unlock()
@@ -201,8 +299,10 @@ func (m *Metrics) GetRequestCount(method, path string) int64 {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	key := fmt.Sprintf("%s %s", method, path)
-	return m.requestCounts[key]
+	if stats, ok := m.endpoints[fmt.Sprintf("%s %s", normalizeMethod(method), path)]; ok {
+		return stats.count
+	}
+	return 0
 }
 
 // GetErrorRate returns error rate
@@ -222,29 +322,29 @@ func (m *Metrics) GetAverageResponseTime(method, path string) time.Duration {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	key := fmt.Sprintf("%s %s", method, path)
-	times, exists := m.responseTimes[key]
-	if !exists || len(times) == 0 {
+	stats, exists := m.endpoints[fmt.Sprintf("%s %s", normalizeMethod(method), path)]
+	if !exists || stats.count == 0 {
 		return 0
 	}
 
-	var total time.Duration
-	for _, t := range times {
-		total += t
-	}
-
-	return total / time.Duration(len(times))
+	return stats.totalDuration / time.Duration(stats.count)
 }
 
-// Reset resets all metrics (for testing)
+// Reset resets all request metrics (for testing). Runtime metrics are unaffected.
 func (m *Metrics) Reset() {
+	m.requests.reset()
+	m.responses.reset()
+	m.errors.reset()
+	m.duration.reset()
+	m.inFlight.Set(0)
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	m.requestCounts = make(map[string]int64)
-	m.responseTimes = make(map[string][]time.Duration)
+	m.endpoints = make(map[string]*endpointStats)
 	m.statusCodes = make(map[int]int64)
 	m.totalRequests = 0
+	m.totalDuration = 0
 	m.errorCount = 0
 	m.activeRequests = 0
 	m.startTime = time.Now()
@@ -275,16 +375,20 @@ func (mc *MetricsCollector) stop() {
 	}
 }
 
-// collect collects system metrics
+// collect refreshes the system metrics shown in the JSON summary
 func (mc *MetricsCollector) collect() {
-	// In a real implementation, this would collect:
-	// - Memory usage from runtime.MemStats
-	// - Goroutine count from runtime.NumGoroutine
-	// - CPU usage
-	// - Disk usage
-	// - Network stats
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
 
-	log.Println("Metrics collection cycle completed")
+	system := SystemMetrics{
+		MemoryAllocated: formatBytes(stats.Alloc),
+		MemoryUsed:      formatBytes(stats.Sys),
+		Goroutines:      runtime.NumGoroutine(),
+	}
+
+	mc.metrics.mu.Lock()
+	mc.metrics.system = system
+	mc.metrics.mu.Unlock()
 }
 
 // Middleware for metrics collection
@@ -300,16 +404,22 @@ func MetricsMiddleware(metrics *Metrics) func(http.HandlerFunc) http.HandlerFunc
 			// Create response wrapper to capture status code
 			rw := &metricsResponseWriter{ResponseWriter: w, statusCode: 200}
 
+			// Learn the route template even when running before the router
+			route := RoutePattern(r)
+			var recorded *string
+			if route == "" {
+				r, recorded = withRouteRecorder(r)
+			}
+
 			// Process request
 			next(rw, r)
 
-			// Record metrics
-			duration := time.Since(start)
-			metrics.RecordRequest(r.Method, r.URL.Path, rw.statusCode, duration)
+			if recorded != nil {
+				route = *recorded
+			}
 
-			// Add metrics headers
-			w.Header().Set("X-Response-Time", duration.String())
-			w.Header().Set("X-Request-Count", fmt.Sprintf("%d", metrics.GetRequestCount(r.Method, r.URL.Path)))
+			// Record metrics
+			metrics.RecordRequest(r.Method, route, rw.statusCode, time.Since(start))
 		}
 	}
 }
@@ -317,41 +427,35 @@ func MetricsMiddleware(metrics *Metrics) func(http.HandlerFunc) http.HandlerFunc
 // metricsResponseWriter wraps ResponseWriter to capture status code
 type metricsResponseWriter struct {
 	http.ResponseWriter
-	statusCode int
+	statusCode  int
+	wroteHeader bool
 }
 
 func (mrw *metricsResponseWriter) WriteHeader(statusCode int) {
-	mrw.statusCode = statusCode
+	if !mrw.wroteHeader {
+		mrw.statusCode = statusCode
+		mrw.wroteHeader = true
+	}
 	mrw.ResponseWriter.WriteHeader(statusCode)
 }
 
-// Prometheus-style metrics export
-func (m *Metrics) ExportPrometheus() string {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-
-	var output string
-
-	// Total requests
-	output += "# HELP http_requests_total Total HTTP requests\n"
-	output += "# TYPE http_requests_total counter\n"
-	output += fmt.Sprintf("http_requests_total %d\n\n", m.totalRequests)
-
-	// Active requests
-	output += "# HELP http_requests_active Active HTTP requests\n"
-	output += "# TYPE http_requests_active gauge\n"
-	output += fmt.Sprintf("http_requests_active %d\n\n", m.activeRequests)
-
-	// Request duration histogram (simplified)
-	output += "# HELP http_request_duration_seconds HTTP request duration\n"
-	output += "# TYPE http_request_duration_seconds histogram\n"
+func (mrw *metricsResponseWriter) Write(b []byte) (int, error) {
+	mrw.wroteHeader = true
+	return mrw.ResponseWriter.Write(b)
+}
 
-	// Endpoint-specific metrics
-	for key, count := range m.requestCounts {
-		output += fmt.Sprintf("http_request_count{endpoint=\"%s\"} %d\n", key, count)
+// Flush implements http.Flusher when the underlying writer does
+func (mrw *metricsResponseWriter) Flush() {
+	if f, ok := mrw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
 	}
+}
 
-	return output
+// ExportPrometheus returns all metrics in the Prometheus text exposition format
+func (m *Metrics) ExportPrometheus() string {
+	var b bytes.Buffer
+	m.registry.WritePrometheus(&b)
+	return b.String()
 }
 
 // ExportJSON exports metrics as JSON
@@ -362,5 +466,29 @@ func (m *Metrics) ExportJSON() ([]byte, error) {
 
 // GetUptime returns server uptime
 func (m *Metrics) GetUptime() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	return formatDuration(time.Since(m.startTime))
 }
+
+// normalizeMethod maps non-standard methods to OTHER to bound label cardinality
+func normalizeMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodOptions, http.MethodConnect, http.MethodTrace:
+		return method
+	}
+	return "OTHER"
+}
+
+// statusClass returns the status class label, e.g. 2xx
+func statusClass(code int) string {
+	if code < 100 || code > 599 {
+		return "unknown"
+	}
+	return fmt.Sprintf("%dxx", code/100)
+}
+
+func formatBytes(n uint64) string {
+	return fmt.Sprintf("%.1f MB", float64(n)/(1024*1024))
+}
@@ -11,7 +11,6 @@ import (
 	"net/http"
 	"runtime/debug"
 	"strings"
-	"sync"
 	"time"
 )
 
@@ -169,99 +168,6 @@ func CompressionMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
-// AuthMiddleware for authentication
-func AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			http.Error(w, "Authorization required", http.StatusUnauthorized)
-			return
-		}
-
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			http.Error(w, "Invalid authorization format", http.StatusUnauthorized)
-			return
-		}
-
-		// Validate token
-		token := parts[1]
-		if !isValidToken(token) {
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
-			return
-		}
-
-		// Add user to context
-		user, err := getUserFromToken(token)
-		if err != nil {
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
-			return
-		}
-
-		ctx := context.WithValue(r.Context(), "user", user)
-		next(w, r.WithContext(ctx))
-	}
-}
-
-// RateLimitMiddleware limits requests
-func RateLimitMiddleware(limit int, window time.Duration) func(http.HandlerFunc) http.HandlerFunc {
-	type clientInfo struct {
-		count    int
-		lastSeen time.Time
-	}
-
-	clients := make(map[string]*clientInfo)
-	var mu sync.RWMutex
-
-	return func(next http.HandlerFunc) http.HandlerFunc {
-		return func(w http.ResponseWriter, r *http.Request) {
-			clientIP := getClientIP(r)
-
-			mu.Lock()
-
-			// Clean old entries
-			for ip, info := range clients {
-				if time.Since(info.lastSeen) > window {
-					delete(clients, ip)
-				}
-			}
-
-			// Get client info
-			info, exists := clients[clientIP]
-			if !exists {
-				info = &clientInfo{}
-				clients[clientIP] = info
-			}
-
-			// Reset counter if window passed
-			if time.Since(info.lastSeen) > window {
-				info.count = 0
-			}
-
-			// Check limit
-			if info.count >= limit {
-				mu.Unlock()
-				w.Header().Set("Retry-After", fmt.Sprintf("%.0f", window.Seconds()))
-				http.Error(w, "Too many requests", http.StatusTooManyRequests)
-				return
-			}
-
-			// Update
-			info.count++
-			info.lastSeen = time.Now()
-
-			mu.Unlock()
-
-			// Set headers
-			w.Header().Set("X-RateLimit-Limit", fmt.Sprintf("%d", limit))
-			w.Header().Set("X-RateLimit-Remaining", fmt.Sprintf("%d", limit-info.count))
-			w.Header().Set("X-RateLimit-Reset", fmt.Sprintf("%d", time.Now().Add(window).Unix()))
-
-			next(w, r)
-		}
-	}
-}
-
 // JSONMiddleware sets JSON content type
 func JSONMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -308,33 +214,6 @@ func generateRequestID() string {
 	return base64.URLEncoding.EncodeToString(b)
 }
 
-func getClientIP(r *http.Request) string {
-	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
-		parts := strings.Split(forwarded, ",")
-		return strings.TrimSpace(parts[0])
-	}
-
-	if realIP := r.Header.Get("X-Real-IP"); realIP != "" {
-		return realIP
-	}
-
-	return strings.Split(r.RemoteAddr, ":")[0]
-}
-
-func isValidToken(token string) bool {
-	// Implement your token validation
-	return len(token) > 0
-}
-
-func getUserFromToken(token string) (map[string]interface{}, error) {
-	// Implement your user extraction
-	return map[string]interface{}{
-		"id":    "user123",
-		"email": "user@example.com",
-		"role":  "user",
-	}, nil
-}
-
 // Response writer wrappers
 type responseWriter struct {
 	http.ResponseWriter
@@ -0,0 +1,632 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/selanim/sego/responseutils"
+	"github.com/selanim/sego/validation"
+)
+
+// RouteDoc describes a route in the generated OpenAPI document
+type RouteDoc struct {
+	Summary     string
+	Description string
+	Tags        []string
+	OperationID string
+	Deprecated  bool
+	// Request is a sample value whose type describes the JSON request body
+	Request interface{}
+	// RequestSchema adds validation constraints to the request body
+	RequestSchema *validation.Schema
+	// Response is a sample value whose type describes the response data
+	Response interface{}
+	// Status is the success status code; defaults to 201 for POST and 200 otherwise
+	Status int
+	// Secured marks the route as requiring a bearer token
+	Secured bool
+}
+
+// Describe attaches OpenAPI metadata to the route
+func (r *Route) Describe(doc RouteDoc) *Route {
+	if r.router != nil {
+		r.router.mu.Lock()
+		defer r.router.mu.Unlock()
+	}
+	r.Doc = &doc
+	return r
+}
+
+// OpenAPIInfo holds document level OpenAPI metadata
+type OpenAPIInfo struct {
+	Title       string
+	Version     string
+	Description string
+	Servers     []string
+}
+
+// OpenAPI generates an OpenAPI 3.1 document from the registered routes.
+// Routes registered with Any are skipped because they have no single operation.
+func (r *Router) OpenAPI(info OpenAPIInfo) map[string]interface{} {
+	routes := r.GetRoutes()
+	sort.SliceStable(routes, func(i, j int) bool { return routes[i].Path < routes[j].Path })
+
+	gen := &openAPIGenerator{
+		schemas: make(map[string]interface{}),
+		names:   make(map[reflect.Type]string),
+	}
+
+	paths := make(map[string]interface{})
+	secured := false
+	for _, route := range routes {
+		if route.Method == "*" {
+			continue
+		}
+
+		path, params := openAPIPath(route.tokens)
+		item, _ := paths[path].(map[string]interface{})
+		if item == nil {
+			item = make(map[string]interface{})
+			paths[path] = item
+		}
+
+		item[strings.ToLower(route.Method)] = gen.operation(route, params)
+		if route.Doc != nil && route.Doc.Secured {
+			secured = true
+		}
+	}
+
+	if info.Title == "" {
+		info.Title = "API"
+	}
+	if info.Version == "" {
+		info.Version = "1.0.0"
+	}
+	infoDoc := map[string]interface{}{"title": info.Title, "version": info.Version}
+	if info.Description != "" {
+		infoDoc["description"] = info.Description
+	}
+
+	gen.schemas["ErrorResponse"] = gen.schemaFor(reflect.TypeOf(responseutils.JSONResponse{}), false)
+	components := map[string]interface{}{"schemas": gen.schemas}
+	if secured {
+		components["securitySchemes"] = map[string]interface{}{
+			"bearerAuth": map[string]interface{}{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+		}
+	}
+
+	doc := map[string]interface{}{
+		"openapi":    "3.1.0",
+		"info":       infoDoc,
+		"paths":      paths,
+		"components": components,
+	}
+
+	if len(info.Servers) > 0 {
+		var servers []interface{}
+		for _, url := range info.Servers {
+			servers = append(servers, map[string]interface{}{"url": url})
+		}
+		doc["servers"] = servers
+	}
+
+	return doc
+}
+
+// OpenAPIHandler serves the generated document as JSON, or as YAML when
+// requested with ?format=yaml, a .yaml/.yml path or a YAML Accept header
+func OpenAPIHandler(router *Router, info OpenAPIInfo) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		doc := router.OpenAPI(info)
+
+		wantYAML := r.URL.Query().Get("format") == "yaml" ||
+			strings.HasSuffix(r.URL.Path, ".yaml") || strings.HasSuffix(r.URL.Path, ".yml") ||
+			strings.Contains(r.Header.Get("Accept"), "yaml")
+
+		if wantYAML {
+			body, err := MarshalYAML(doc)
+			if err != nil {
+				responseutils.InternalServerError(w, "Failed to encode OpenAPI document")
+				return
+			}
+			w.Header().Set("Content-Type", "application/yaml; charset=utf-8")
+			w.Write(body)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(doc)
+	}
+}
+
+// ServeOpenAPI registers the OpenAPI document at path; a .json path also gets a .yaml twin.
+// It returns the route at path, which can be given a name with Named.
+func (s *Server) ServeOpenAPI(path string, info OpenAPIInfo) *Route {
+	handler := OpenAPIHandler(s.router, info)
+	route := s.router.Get(path, handler)
+
+	if strings.HasSuffix(path, ".json") {
+		s.router.Get(strings.TrimSuffix(path, ".json")+".yaml", handler)
+	}
+	return route
+}
+
+// ========== GENERATOR ==========
+
+type openAPIGenerator struct {
+	schemas map[string]interface{}
+	names   map[reflect.Type]string
+}
+
+// operation builds the OpenAPI operation object for a route
+func (g *openAPIGenerator) operation(route Route, params []interface{}) map[string]interface{} {
+	doc := RouteDoc{}
+	if route.Doc != nil {
+		doc = *route.Doc
+	}
+
+	op := map[string]interface{}{}
+	switch {
+	case doc.OperationID != "":
+		op["operationId"] = doc.OperationID
+	case route.Name != "":
+		op["operationId"] = route.Name
+	default:
+		op["operationId"] = operationID(route.Method, route.Path)
+	}
+	if doc.Summary != "" {
+		op["summary"] = doc.Summary
+	}
+	if doc.Description != "" {
+		op["description"] = doc.Description
+	}
+	if len(doc.Tags) > 0 {
+		op["tags"] = doc.Tags
+	}
+	if doc.Deprecated {
+		op["deprecated"] = true
+	}
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+
+	errorResponse := func(description string) map[string]interface{} {
+		return map[string]interface{}{
+			"description": description,
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{
+					"schema": map[string]interface{}{"$ref": "#/components/schemas/ErrorResponse"},
+				},
+			},
+		}
+	}
+	responses := map[string]interface{}{"default": errorResponse("Error response")}
+
+	if body := g.requestSchema(doc); body != nil {
+		op["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": body},
+			},
+		}
+		responses["400"] = errorResponse("Invalid request")
+	}
+
+	if doc.Secured {
+		op["security"] = []interface{}{map[string]interface{}{"bearerAuth": []interface{}{}}}
+		responses["401"] = errorResponse("Authentication required")
+		responses["403"] = errorResponse("Insufficient permissions")
+	}
+
+	status := doc.Status
+	if status == 0 {
+		status = http.StatusOK
+		if route.Method == http.MethodPost {
+			status = http.StatusCreated
+		}
+	}
+
+	success := map[string]interface{}{"description": http.StatusText(status)}
+	if status != http.StatusNoContent {
+		data := map[string]interface{}{}
+		if doc.Response != nil {
+			data = g.schemaFor(reflect.TypeOf(doc.Response), true)
+		}
+		success["content"] = map[string]interface{}{
+			"application/json": map[string]interface{}{
+				"schema": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"success":   map[string]interface{}{"type": "boolean"},
+						"message":   map[string]interface{}{"type": "string"},
+						"data":      data,
+						"timestamp": map[string]interface{}{"type": "string"},
+					},
+					"required": []string{"success"},
+				},
+			},
+		}
+	}
+	responses[strconv.Itoa(status)] = success
+	op["responses"] = responses
+
+	return op
+}
+
+// requestSchema combines the request type and validation schema
+func (g *openAPIGenerator) requestSchema(doc RouteDoc) map[string]interface{} {
+	var typed map[string]interface{}
+	if doc.Request != nil {
+		typed = g.schemaFor(reflect.TypeOf(doc.Request), true)
+	}
+
+	switch {
+	case typed != nil && doc.RequestSchema != nil:
+		return map[string]interface{}{"allOf": []interface{}{typed, doc.RequestSchema.JSONSchema()}}
+	case doc.RequestSchema != nil:
+		return doc.RequestSchema.JSONSchema()
+	}
+	return typed
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	durationType  = reflect.TypeOf(time.Duration(0))
+	rawJSONType   = reflect.TypeOf(json.RawMessage{})
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// schemaFor returns a JSON Schema for t. Named structs are stored in
+// components and referenced when ref is true.
+func (g *openAPIGenerator) schemaFor(t reflect.Type, ref bool) map[string]interface{} {
+	nullable := false
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		nullable = true
+	}
+
+	schema := g.baseSchema(t, ref)
+	if nullable {
+		if typ, ok := schema["type"].(string); ok {
+			schema["type"] = []string{typ, "null"}
+		}
+	}
+	return schema
+}
+
+func (g *openAPIGenerator) baseSchema(t reflect.Type, ref bool) map[string]interface{} {
+	switch t {
+	case timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case durationType:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case rawJSONType:
+		return map[string]interface{}{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return map[string]interface{}{"type": "integer", "format": "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float32:
+		return map[string]interface{}{"type": "number", "format": "float"}
+	case reflect.Float64:
+		return map[string]interface{}{"type": "number", "format": "double"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "contentEncoding": "base64"}
+		}
+		return map[string]interface{}{"type": "array", "items": g.schemaFor(t.Elem(), true)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schemaFor(t.Elem(), true)}
+	case reflect.Struct:
+		if t.Implements(marshalerType) || reflect.PointerTo(t).Implements(marshalerType) {
+			return map[string]interface{}{}
+		}
+		if !ref || t.Name() == "" {
+			return g.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + g.componentName(t)}
+	}
+
+	return map[string]interface{}{}
+}
+
+// componentName registers t as a component schema and returns its name
+func (g *openAPIGenerator) componentName(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+
+	name := t.Name()
+	if _, taken := g.schemas[name]; taken {
+		pkg := t.PkgPath()
+		name = strings.ReplaceAll(pkg[strings.LastIndex(pkg, "/")+1:], ".", "_") + "." + name
+	}
+
+	g.names[t] = name
+	g.schemas[name] = map[string]interface{}{} // placeholder for recursive types
+	g.schemas[name] = g.structSchema(t)
+	return name
+}
+
+// structSchema builds an object schema from json and validate tags
+func (g *openAPIGenerator) structSchema(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	var required []string
+
+	var walk func(t reflect.Type)
+	walk = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			jsonTag := field.Tag.Get("json")
+			if jsonTag == "-" {
+				continue
+			}
+			name, _, _ := strings.Cut(jsonTag, ",")
+
+			if field.Anonymous && name == "" {
+				ft := field.Type
+				if ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+				if ft.Kind() == reflect.Struct {
+					walk(ft)
+					continue
+				}
+			}
+			if !field.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+
+			prop := g.schemaFor(field.Type, true)
+			if tag := field.Tag.Get("validate"); tag != "" {
+				rules := validation.RulesFromTag(tag)
+				for k, v := range rules.JSONSchema() {
+					if _, isRef := prop["$ref"]; isRef && k == "type" {
+						continue
+					}
+					if k == "type" {
+						if _, typed := prop["type"]; typed {
+							continue
+						}
+					}
+					prop[k] = v
+				}
+				if rules.Required {
+					required = append(required, name)
+				}
+			}
+			if desc := field.Tag.Get("description"); desc != "" {
+				prop["description"] = desc
+			}
+
+			properties[name] = prop
+		}
+	}
+	walk(t)
+
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// openAPIPath converts a route pattern to an OpenAPI path template and its parameters
+func openAPIPath(tokens []token) (string, []interface{}) {
+	var b strings.Builder
+	var params []interface{}
+
+	for _, tok := range tokens {
+		switch tok.kind {
+		case staticNode:
+			b.WriteString(tok.value)
+			continue
+		case paramNode:
+		case catchAllNode:
+			if tok.value == "*" {
+				tok.value = "path"
+			}
+		}
+
+		b.WriteString("{" + tok.value + "}")
+		schema := map[string]interface{}{"type": "string"}
+		if tok.pattern != "" {
+			schema["pattern"] = "^(?:" + tok.pattern + ")$"
+		}
+		param := map[string]interface{}{
+			"name":     tok.value,
+			"in":       "path",
+			"required": true,
+			"schema":   schema,
+		}
+		if tok.kind == catchAllNode {
+			param["description"] = "Remaining path, may contain '/'"
+		}
+		params = append(params, param)
+	}
+
+	return b.String(), params
+}
+
+// operationID derives an identifier such as getUsersById from method and path
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+
+	upperNext, constraint := true, false
+	for _, r := range path {
+		switch {
+		case constraint:
+			// skip regexp constraints up to the closing brace
+			constraint = r != '}'
+		case r == '{':
+			b.WriteString("By")
+			upperNext = true
+		case r == ':':
+			constraint = true
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if upperNext {
+				r = unicode.ToUpper(r)
+			}
+			b.WriteRune(r)
+			upperNext = false
+		default:
+			upperNext = true
+		}
+	}
+
+	return b.String()
+}
+
+// ========== YAML ==========
+
+// MarshalYAML encodes JSON-compatible values as block-style YAML with sorted keys
+func MarshalYAML(v interface{}) ([]byte, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode value: %w", err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var generic interface{}
+	if err := dec.Decode(&generic); err != nil {
+		return nil, fmt.Errorf("failed to decode value: %w", err)
+	}
+
+	var b strings.Builder
+	writeYAML(&b, generic, 0)
+	return []byte(b.String()), nil
+}
+
+func writeYAML(b *strings.Builder, v interface{}, indent int) {
+	pad := strings.Repeat("  ", indent)
+
+	switch val := v.(type) {
+	case map[string]interface{}:
+		if len(val) == 0 {
+			b.WriteString(pad + "{}\n")
+			return
+		}
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			b.WriteString(pad + yamlScalar(k) + ":")
+			writeYAMLValue(b, val[k], indent)
+		}
+
+	case []interface{}:
+		if len(val) == 0 {
+			b.WriteString(pad + "[]\n")
+			return
+		}
+		for _, item := range val {
+			if isYAMLCollection(item) {
+				var nested strings.Builder
+				writeYAML(&nested, item, indent+1)
+				// Replace the indentation of the first line with the item marker
+				b.WriteString(pad + "- " + strings.TrimPrefix(nested.String(), pad+"  "))
+				continue
+			}
+			b.WriteString(pad + "-")
+			writeYAMLValue(b, item, indent)
+		}
+
+	default:
+		b.WriteString(pad + yamlScalar(val) + "\n")
+	}
+}
+
+// writeYAMLValue writes the value of a mapping key or sequence item
+func writeYAMLValue(b *strings.Builder, v interface{}, indent int) {
+	if isYAMLCollection(v) {
+		b.WriteString("\n")
+		writeYAML(b, v, indent+1)
+		return
+	}
+
+	switch val := v.(type) {
+	case map[string]interface{}:
+		b.WriteString(" {}\n")
+	case []interface{}:
+		b.WriteString(" []\n")
+	default:
+		b.WriteString(" " + yamlScalar(val) + "\n")
+	}
+}
+
+func isYAMLCollection(v interface{}) bool {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		return len(val) > 0
+	case []interface{}:
+		return len(val) > 0
+	}
+	return false
+}
+
+// yamlScalar formats a scalar, quoting strings that YAML would misread
+func yamlScalar(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(val)
+	case json.Number:
+		return val.String()
+	case string:
+		if yamlPlainSafe(val) {
+			return val
+		}
+		return strconv.Quote(val)
+	}
+	return strconv.Quote(fmt.Sprint(v))
+}
+
+func yamlPlainSafe(s string) bool {
+	if s == "" || strings.TrimSpace(s) != s {
+		return false
+	}
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "on", "off", "null", "~", "y", "n":
+		return false
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return false
+	}
+
+	for i, r := range s {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+		case r == '_' || r == '/' || r == '.' || r == ' ':
+		case r == '-' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,538 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// MetricType is the Prometheus type of a metric family
+type MetricType string
+
+const (
+	CounterType   MetricType = "counter"
+	GaugeType     MetricType = "gauge"
+	HistogramType MetricType = "histogram"
+)
+
+// PrometheusContentType is the content type of the text exposition format
+const PrometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultBuckets are latency buckets in seconds, from 5ms to 10s
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+var (
+	metricNameRegex = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	labelNameRegex  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// LinearBuckets returns count buckets starting at start, width apart
+func LinearBuckets(start, width float64, count int) []float64 {
+	buckets := make([]float64, count)
+	for i := range buckets {
+		buckets[i] = start + float64(i)*width
+	}
+	return buckets
+}
+
+// ExponentialBuckets returns count buckets starting at start, each factor times the previous
+func ExponentialBuckets(start, factor float64, count int) []float64 {
+	buckets := make([]float64, count)
+	for i := range buckets {
+		buckets[i] = start
+		start *= factor
+	}
+	return buckets
+}
+
+// ========== REGISTRY ==========
+
+// family is a named set of series sharing help text, type and label names
+type family interface {
+	name() string
+	write(b *bytes.Buffer)
+}
+
+// Registry holds metric families and renders them in the Prometheus text format
+type Registry struct {
+	mu       sync.RWMutex
+	families map[string]family
+	hooks    []func()
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]family)}
+}
+
+// register adds a family, panicking on invalid or duplicate names
+func (r *Registry) register(f family, labels []string) {
+	if !metricNameRegex.MatchString(f.name()) {
+		panic(fmt.Sprintf("metrics: invalid metric name %q", f.name()))
+	}
+	for _, label := range labels {
+		if !labelNameRegex.MatchString(label) || strings.HasPrefix(label, "__") {
+			panic(fmt.Sprintf("metrics: invalid label name %q for %s", label, f.name()))
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.families[f.name()]; exists {
+		panic(fmt.Sprintf("metrics: duplicate metric %s", f.name()))
+	}
+	r.families[f.name()] = f
+}
+
+// NewCounter registers a counter family with the given label names
+func (r *Registry) NewCounter(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{vec: newVec(name, help, CounterType, labels)}
+	r.register(c, labels)
+	return c
+}
+
+// NewGauge registers a gauge family with the given label names
+func (r *Registry) NewGauge(name, help string, labels ...string) *GaugeVec {
+	g := &GaugeVec{vec: newVec(name, help, GaugeType, labels)}
+	r.register(g, labels)
+	return g
+}
+
+// NewHistogram registers a histogram family. Nil buckets use DefaultBuckets.
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	for i := 1; i < len(buckets); i++ {
+		if buckets[i] <= buckets[i-1] {
+			panic(fmt.Sprintf("metrics: buckets for %s must be strictly increasing", name))
+		}
+	}
+	if len(buckets) > 0 && math.IsInf(buckets[len(buckets)-1], 1) {
+		buckets = buckets[:len(buckets)-1]
+	}
+	for _, label := range labels {
+		if label == "le" {
+			panic(fmt.Sprintf("metrics: histogram %s cannot use label \"le\"", name))
+		}
+	}
+
+	h := &HistogramVec{vec: newVec(name, help, HistogramType, labels), buckets: append([]float64(nil), buckets...)}
+	r.register(h, labels)
+	return h
+}
+
+// NewGaugeFunc registers an unlabeled gauge whose value is read at scrape time
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	r.register(&funcFamily{metricName: name, help: help, typ: GaugeType, fn: fn}, nil)
+}
+
+// NewCounterFunc registers an unlabeled counter whose value is read at scrape time
+func (r *Registry) NewCounterFunc(name, help string, fn func() float64) {
+	r.register(&funcFamily{metricName: name, help: help, typ: CounterType, fn: fn}, nil)
+}
+
+// OnCollect registers a hook run before each scrape, e.g. to snapshot expensive stats
+func (r *Registry) OnCollect(fn func()) {
+	r.mu.Lock()
+	r.hooks = append(r.hooks, fn)
+	r.mu.Unlock()
+}
+
+// WritePrometheus writes all families in the text exposition format, sorted by name
+func (r *Registry) WritePrometheus(w io.Writer) error {
+	r.mu.RLock()
+	hooks := append([]func(){}, r.hooks...)
+	families := make([]family, 0, len(r.families))
+	for _, f := range r.families {
+		families = append(families, f)
+	}
+	r.mu.RUnlock()
+
+	for _, hook := range hooks {
+		hook()
+	}
+	sort.Slice(families, func(i, j int) bool { return families[i].name() < families[j].name() })
+
+	var b bytes.Buffer
+	for _, f := range families {
+		f.write(&b)
+	}
+
+	if _, err := w.Write(b.Bytes()); err != nil {
+		return fmt.Errorf("failed to write metrics: %w", err)
+	}
+	return nil
+}
+
+// Handler serves the registry in the Prometheus text format
+func (r *Registry) Handler(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", PrometheusContentType)
+	r.WritePrometheus(w)
+}
+
+// ========== VECTORS ==========
+
+// vec holds the series of a family keyed by label values
+type vec struct {
+	metricName string
+	help       string
+	typ        MetricType
+	labels     []string
+
+	mu     sync.RWMutex
+	series map[string]interface{}
+	values map[string][]string
+}
+
+func newVec(name, help string, typ MetricType, labels []string) vec {
+	return vec{
+		metricName: name,
+		help:       help,
+		typ:        typ,
+		labels:     append([]string(nil), labels...),
+		series:     make(map[string]interface{}),
+		values:     make(map[string][]string),
+	}
+}
+
+func (v *vec) name() string { return v.metricName }
+
+// get returns the series for values, creating it with create when missing
+func (v *vec) get(values []string, create func() interface{}) interface{} {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", v.metricName, len(v.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+
+	v.mu.RLock()
+	s, ok := v.series[key]
+	v.mu.RUnlock()
+	if ok {
+		return s
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if s, ok := v.series[key]; ok {
+		return s
+	}
+	s = create()
+	v.series[key] = s
+	v.values[key] = append([]string(nil), values...)
+	return s
+}
+
+// sorted returns series keys in label value order
+func (v *vec) sorted() []string {
+	keys := make([]string, 0, len(v.series))
+	for key := range v.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (v *vec) header(b *bytes.Buffer) {
+	fmt.Fprintf(b, "# HELP %s %s\n", v.metricName, escapeHelp(v.help))
+	fmt.Fprintf(b, "# TYPE %s %s\n", v.metricName, v.typ)
+}
+
+// reset removes all series
+func (v *vec) reset() {
+	v.mu.Lock()
+	v.series = make(map[string]interface{})
+	v.values = make(map[string][]string)
+	v.mu.Unlock()
+}
+
+// CounterVec is a counter family
+type CounterVec struct {
+	vec
+}
+
+// Counter is a monotonically increasing value
+type Counter struct {
+	bits atomic.Uint64
+}
+
+// WithLabelValues returns the counter for the label values, in label order
+func (c *CounterVec) WithLabelValues(values ...string) *Counter {
+	return c.get(values, func() interface{} { return &Counter{} }).(*Counter)
+}
+
+// Inc adds one to the counter
+func (c *Counter) Inc() { c.Add(1) }
+
+// Add adds a non-negative delta to the counter
+func (c *Counter) Add(delta float64) {
+	if delta < 0 {
+		panic("metrics: counter cannot decrease")
+	}
+	addFloat(&c.bits, delta)
+}
+
+// Value returns the current value
+func (c *Counter) Value() float64 { return math.Float64frombits(c.bits.Load()) }
+
+func (c *CounterVec) write(b *bytes.Buffer) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	c.header(b)
+	for _, key := range c.sorted() {
+		writeSample(b, c.metricName, c.labels, c.values[key], "", "", c.series[key].(*Counter).Value())
+	}
+}
+
+// GaugeVec is a gauge family
+type GaugeVec struct {
+	vec
+}
+
+// Gauge is a value that can go up and down
+type Gauge struct {
+	bits atomic.Uint64
+}
+
+// WithLabelValues returns the gauge for the label values, in label order
+func (g *GaugeVec) WithLabelValues(values ...string) *Gauge {
+	return g.get(values, func() interface{} { return &Gauge{} }).(*Gauge)
+}
+
+// Set sets the gauge
+func (g *Gauge) Set(value float64) { g.bits.Store(math.Float64bits(value)) }
+
+// Inc adds one to the gauge
+func (g *Gauge) Inc() { addFloat(&g.bits, 1) }
+
+// Dec subtracts one from the gauge
+func (g *Gauge) Dec() { addFloat(&g.bits, -1) }
+
+// Add adds delta to the gauge
+func (g *Gauge) Add(delta float64) { addFloat(&g.bits, delta) }
+
+// Value returns the current value
+func (g *Gauge) Value() float64 { return math.Float64frombits(g.bits.Load()) }
+
+func (g *GaugeVec) write(b *bytes.Buffer) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	g.header(b)
+	for _, key := range g.sorted() {
+		writeSample(b, g.metricName, g.labels, g.values[key], "", "", g.series[key].(*Gauge).Value())
+	}
+}
+
+// HistogramVec is a histogram family
+type HistogramVec struct {
+	vec
+	buckets []float64
+}
+
+// Histogram counts observations into cumulative buckets
+type Histogram struct {
+	mu     sync.Mutex
+	upper  []float64
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// WithLabelValues returns the histogram for the label values, in label order
+func (h *HistogramVec) WithLabelValues(values ...string) *Histogram {
+	return h.get(values, func() interface{} {
+		return &Histogram{upper: h.buckets, counts: make([]uint64, len(h.buckets))}
+	}).(*Histogram)
+}
+
+// Observe records a value
+func (h *Histogram) Observe(value float64) {
+	i := sort.SearchFloat64s(h.upper, value)
+
+	h.mu.Lock()
+	if i < len(h.counts) {
+		h.counts[i]++
+	}
+	h.sum += value
+	h.count++
+	h.mu.Unlock()
+}
+
+// ObserveDuration records a duration in seconds
+func (h *Histogram) ObserveDuration(d time.Duration) {
+	h.Observe(d.Seconds())
+}
+
+// Snapshot returns the observation count and sum
+func (h *Histogram) Snapshot() (count uint64, sum float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.count, h.sum
+}
+
+func (h *HistogramVec) write(b *bytes.Buffer) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	h.header(b)
+	for _, key := range h.sorted() {
+		hist := h.series[key].(*Histogram)
+		values := h.values[key]
+
+		hist.mu.Lock()
+		var cumulative uint64
+		for i, upper := range hist.upper {
+			cumulative += hist.counts[i]
+			writeSample(b, h.metricName+"_bucket", h.labels, values, "le", formatFloat(upper), float64(cumulative))
+		}
+		writeSample(b, h.metricName+"_bucket", h.labels, values, "le", "+Inf", float64(hist.count))
+		writeSample(b, h.metricName+"_sum", h.labels, values, "", "", hist.sum)
+		writeSample(b, h.metricName+"_count", h.labels, values, "", "", float64(hist.count))
+		hist.mu.Unlock()
+	}
+}
+
+// funcFamily is an unlabeled metric read from a callback at scrape time
+type funcFamily struct {
+	metricName string
+	help       string
+	typ        MetricType
+	fn         func() float64
+}
+
+func (f *funcFamily) name() string { return f.metricName }
+
+func (f *funcFamily) write(b *bytes.Buffer) {
+	fmt.Fprintf(b, "# HELP %s %s\n", f.metricName, escapeHelp(f.help))
+	fmt.Fprintf(b, "# TYPE %s %s\n", f.metricName, f.typ)
+	writeSample(b, f.metricName, nil, nil, "", "", f.fn())
+}
+
+// ========== RUNTIME METRICS ==========
+
+// RegisterRuntimeMetrics registers Go runtime and process metrics.
+// Memory statistics are read once per scrape.
+func (r *Registry) RegisterRuntimeMetrics() {
+	var mu sync.Mutex
+	var stats runtime.MemStats
+	start := time.Now()
+
+	r.OnCollect(func() {
+		mu.Lock()
+		runtime.ReadMemStats(&stats)
+		mu.Unlock()
+	})
+
+	memStat := func(fn func(*runtime.MemStats) float64) func() float64 {
+		return func() float64 {
+			mu.Lock()
+			defer mu.Unlock()
+			return fn(&stats)
+		}
+	}
+
+	r.NewGaugeFunc("go_goroutines", "Number of goroutines that currently exist.", func() float64 {
+		return float64(runtime.NumGoroutine())
+	})
+	r.NewGaugeFunc("go_gomaxprocs", "Value of GOMAXPROCS.", func() float64 {
+		return float64(runtime.GOMAXPROCS(0))
+	})
+	r.NewGauge("go_info", "Information about the Go environment.", "version").
+		WithLabelValues(runtime.Version()).Set(1)
+
+	r.NewGaugeFunc("go_memstats_alloc_bytes", "Number of bytes allocated and still in use.",
+		memStat(func(s *runtime.MemStats) float64 { return float64(s.Alloc) }))
+	r.NewCounterFunc("go_memstats_alloc_bytes_total", "Total number of bytes allocated, even if freed.",
+		memStat(func(s *runtime.MemStats) float64 { return float64(s.TotalAlloc) }))
+	r.NewGaugeFunc("go_memstats_sys_bytes", "Number of bytes obtained from system.",
+		memStat(func(s *runtime.MemStats) float64 { return float64(s.Sys) }))
+	r.NewGaugeFunc("go_memstats_heap_inuse_bytes", "Number of heap bytes that are in use.",
+		memStat(func(s *runtime.MemStats) float64 { return float64(s.HeapInuse) }))
+	r.NewGaugeFunc("go_memstats_heap_objects", "Number of allocated objects.",
+		memStat(func(s *runtime.MemStats) float64 { return float64(s.HeapObjects) }))
+	r.NewCounterFunc("go_gc_cycles_total", "Number of completed GC cycles.",
+		memStat(func(s *runtime.MemStats) float64 { return float64(s.NumGC) }))
+	r.NewCounterFunc("go_gc_pause_seconds_total", "Total GC stop-the-world pause time in seconds.",
+		memStat(func(s *runtime.MemStats) float64 { return float64(s.PauseTotalNs) / 1e9 }))
+	r.NewGaugeFunc("go_memstats_last_gc_time_seconds", "Number of seconds since 1970 of last garbage collection.",
+		memStat(func(s *runtime.MemStats) float64 { return float64(s.LastGC) / 1e9 }))
+
+	r.NewGaugeFunc("process_start_time_seconds", "Start time of the process since unix epoch in seconds.", func() float64 {
+		return float64(start.UnixNano()) / 1e9
+	})
+}
+
+// ========== TEXT FORMAT ==========
+
+// writeSample writes one sample line; extraName/extraValue add a trailing label such as le
+func writeSample(b *bytes.Buffer, name string, labels, values []string, extraName, extraValue string, value float64) {
+	b.WriteString(name)
+	if len(labels) > 0 || extraName != "" {
+		b.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(label)
+			b.WriteString(`="`)
+			b.WriteString(escapeLabelValue(values[i]))
+			b.WriteByte('"')
+		}
+		if extraName != "" {
+			if len(labels) > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(extraName)
+			b.WriteString(`="`)
+			b.WriteString(extraValue)
+			b.WriteByte('"')
+		}
+		b.WriteByte('}')
+	}
+	b.WriteByte(' ')
+	b.WriteString(formatFloat(value))
+	b.WriteByte('\n')
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string       { return helpEscaper.Replace(s) }
+func escapeLabelValue(s string) string { return labelEscaper.Replace(s) }
+
+// addFloat atomically adds delta to a float64 stored as bits
+func addFloat(bits *atomic.Uint64, delta float64) {
+	for {
+		old := bits.Load()
+		if bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+delta)) {
+			return
+		}
+	}
+}
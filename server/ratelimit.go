@@ -0,0 +1,256 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/selanim/sego/authutils"
+	"github.com/selanim/sego/responseutils"
+)
+
+// RateLimitKeyFunc derives the rate limit key for a request. An empty key skips limiting.
+type RateLimitKeyFunc func(r *http.Request) string
+
+// RateLimitOptions configures the RateLimit middleware
+type RateLimitOptions struct {
+	// Limiter applies the algorithm and stores state; required
+	Limiter *authutils.RateLimiter
+	// KeyFunc defaults to KeyByIP with the default trusted proxies
+	KeyFunc RateLimitKeyFunc
+	// OnLimited writes the response for rejected requests; defaults to a 429 JSON error
+	OnLimited http.HandlerFunc
+	// FailOpen lets requests through when the backend returns an error
+	FailOpen bool
+}
+
+// RateLimit limits requests using the given limiter and sets the standard
+// RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and RateLimit-Policy headers
+func RateLimit(opts RateLimitOptions) func(http.HandlerFunc) http.HandlerFunc {
+	if opts.Limiter == nil {
+		panic("server: RateLimit requires a Limiter")
+	}
+	if opts.KeyFunc == nil {
+		opts.KeyFunc = KeyByIP(DefaultConfig().TrustedProxies...)
+	}
+	if opts.OnLimited == nil {
+		opts.OnLimited = func(w http.ResponseWriter, r *http.Request) {
+			responseutils.TooManyRequests(w, "Too many requests")
+		}
+	}
+	policy := opts.Limiter.Policy()
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			key := opts.KeyFunc(r)
+			if key == "" {
+				next(w, r)
+				return
+			}
+
+			result, err := opts.Limiter.Allow(r.Context(), key)
+			if err != nil {
+				log.Printf("RateLimit: %v", err)
+				if opts.FailOpen {
+					next(w, r)
+				} else {
+					responseutils.ServiceUnavailable(w, "Rate limiter unavailable")
+				}
+				return
+			}
+
+			w.Header().Set("RateLimit-Limit", fmt.Sprintf("%d", result.Limit))
+			w.Header().Set("RateLimit-Remaining", fmt.Sprintf("%d", result.Remaining))
+			w.Header().Set("RateLimit-Reset", fmt.Sprintf("%d", ceilSeconds(result.ResetAfter)))
+			w.Header().Set("RateLimit-Policy", policy)
+
+			if !result.Allowed {
+				w.Header().Set("Retry-After", fmt.Sprintf("%d", ceilSeconds(result.RetryAfter)))
+				opts.OnLimited(w, r)
+				return
+			}
+
+			next(w, r)
+		}
+	}
+}
+
+// RateLimitMiddleware limits each client IP to limit requests per sliding window, in memory
+func RateLimitMiddleware(limit int, window time.Duration) func(http.HandlerFunc) http.HandlerFunc {
+	limiter := authutils.NewRateLimiterWithBackend(
+		authutils.NewMemoryRateLimitBackend(0),
+		authutils.SlidingWindowLog(limit, window),
+	)
+	return RateLimit(RateLimitOptions{Limiter: limiter})
+}
+
+// RateLimit limits requests keyed by client IP, honoring Config.TrustedProxies
+func (s *Server) RateLimit(limiter *authutils.RateLimiter, keyFunc ...RateLimitKeyFunc) func(http.HandlerFunc) http.HandlerFunc {
+	opts := RateLimitOptions{Limiter: limiter, KeyFunc: KeyByIP(s.config.TrustedProxies...)}
+	if len(keyFunc) > 0 {
+		opts.KeyFunc = KeyComposite(keyFunc...)
+	}
+	return RateLimit(opts)
+}
+
+// ClientIP returns the client IP, honoring Config.TrustedProxies
+func (s *Server) ClientIP(r *http.Request) string {
+	return clientIP(r, parseTrustedProxies(s.config.TrustedProxies))
+}
+
+// ========== KEY FUNCTIONS ==========
+
+// KeyByIP keys requests by client IP. Forwarding headers are only honored
+// when the direct peer is one of the trusted proxies (IPs, CIDRs or "localhost").
+func KeyByIP(trustedProxies ...string) RateLimitKeyFunc {
+	trusted := parseTrustedProxies(trustedProxies)
+	return func(r *http.Request) string {
+		return "ip:" + clientIP(r, trusted)
+	}
+}
+
+// KeyByUser keys requests by the authenticated user ID, falling back for anonymous requests
+func KeyByUser(fallback RateLimitKeyFunc) RateLimitKeyFunc {
+	return func(r *http.Request) string {
+		if userID := UserIDFromRequest(r); userID != "" {
+			return "user:" + userID
+		}
+		if fallback != nil {
+			return fallback(r)
+		}
+		return ""
+	}
+}
+
+// KeyByAPIKey keys requests by a hash of the API key in header, falling back when it is absent
+func KeyByAPIKey(header string, fallback RateLimitKeyFunc) RateLimitKeyFunc {
+	return func(r *http.Request) string {
+		if apiKey := r.Header.Get(header); apiKey != "" {
+			sum := sha256.Sum256([]byte(apiKey))
+			return "apikey:" + hex.EncodeToString(sum[:16])
+		}
+		if fallback != nil {
+			return fallback(r)
+		}
+		return ""
+	}
+}
+
+// KeyByRoute keys requests by method and matched route pattern
+func KeyByRoute() RateLimitKeyFunc {
+	return func(r *http.Request) string {
+		pattern := RoutePattern(r)
+		if pattern == "" {
+			pattern = r.URL.Path
+		}
+		return "route:" + r.Method + " " + pattern
+	}
+}
+
+// KeyComposite joins several keys, e.g. per user per route. Any empty key skips limiting.
+func KeyComposite(funcs ...RateLimitKeyFunc) RateLimitKeyFunc {
+	return func(r *http.Request) string {
+		parts := make([]string, 0, len(funcs))
+		for _, fn := range funcs {
+			part := fn(r)
+			if part == "" {
+				return ""
+			}
+			parts = append(parts, part)
+		}
+		return strings.Join(parts, "|")
+	}
+}
+
+// ========== CLIENT IP ==========
+
+// parseTrustedProxies parses IPs, CIDRs and "localhost" into networks
+func parseTrustedProxies(proxies []string) []*net.IPNet {
+	var nets []*net.IPNet
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "localhost" {
+			_, v4, _ := net.ParseCIDR("127.0.0.0/8")
+			_, v6, _ := net.ParseCIDR("::1/128")
+			nets = append(nets, v4, v6)
+			continue
+		}
+
+		if _, network, err := net.ParseCIDR(proxy); err == nil {
+			nets = append(nets, network)
+			continue
+		}
+
+		if ip := net.ParseIP(proxy); ip != nil {
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+		}
+	}
+	return nets
+}
+
+func isTrustedProxy(ip string, trusted []*net.IPNet) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, network := range trusted {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP walks X-Forwarded-For from the right, skipping trusted proxies,
+// so clients cannot spoof their address by prepending entries
+func clientIP(r *http.Request, trusted []*net.IPNet) string {
+	remote, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remote = r.RemoteAddr
+	}
+
+	if !isTrustedProxy(remote, trusted) {
+		return remote
+	}
+
+	var hops []string
+	for _, value := range r.Header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(value, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		if net.ParseIP(hops[i]) == nil {
+			break
+		}
+		if !isTrustedProxy(hops[i], trusted) || i == 0 {
+			return hops[i]
+		}
+	}
+
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(realIP) != nil {
+		return realIP
+	}
+
+	return remote
+}
+
+func ceilSeconds(d time.Duration) int64 {
+	if d <= 0 {
+		return 0
+	}
+	return int64(math.Ceil(d.Seconds()))
+}
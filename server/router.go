@@ -1,197 +1,372 @@
 package server
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+
+	"github.com/selanim/sego/responseutils"
 )
 
+// TrailingSlashPolicy controls requests whose path differs from a route only by a trailing slash
+type TrailingSlashPolicy int
+
+const (
+	// TrailingSlashRedirect redirects to the registered path (301 for GET/HEAD, 308 otherwise)
+	TrailingSlashRedirect TrailingSlashPolicy = iota
+	// TrailingSlashIgnore serves the route as if the path matched exactly
+	TrailingSlashIgnore
+	// TrailingSlashStrict treats the paths as different and returns 404
+	TrailingSlashStrict
+)
+
+// allMethods is reported in the Allow header for routes registered with Any
+var allMethods = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut,
+	http.MethodPatch, http.MethodDelete, http.MethodOptions,
+}
+
 // Router methods
 
-// ServeHTTP implements http.Handler interface
+// ServeHTTP implements http.Handler interface. The handler is resolved under
+// the read lock and runs without it, so handlers may call back into the router.
 func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	handler, req := r.resolve(req)
+	handler(w, req)
+}
+
+// resolve picks the handler for req, wrapped in its middleware
+func (r *Router) resolve(req *http.Request) (http.HandlerFunc, *http.Request) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	// Find matching route
-	for _, route := range r.routes {
-		if route.Match(req.Method, req.URL.Path) {
-			// Apply route-specific middleware
-			handler := route.Handler
-			for i := len(route.Middlewares) - 1; i >= 0; i-- {
-				handler = route.Middlewares[i](handler)
-			}
+	path := req.URL.Path
+	if path == "" {
+		path = "/"
+	}
 
-			// Apply global middleware
-			for i := len(r.middleware) - 1; i >= 0; i-- {
-				handler = r.middleware[i](handler)
-			}
+	if route, params := r.lookup(req.Method, path); route != nil {
+		return r.serve(req, route, params)
+	}
 
-			handler(w, req)
-			return
+	// Path exists for other methods: automatic OPTIONS or 405
+	if allowed := r.allowedMethods(path); len(allowed) > 0 {
+		handler := r.methodNotAllowedHandler()
+		if req.Method == http.MethodOptions {
+			handler = func(w http.ResponseWriter, req *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			}
 		}
+		handler = r.chain(handler)
+		allow := strings.Join(allowed, ", ")
+		return func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("Allow", allow)
+			handler(w, req)
+		}, req
 	}
 
-	// No route found, check if there's a NotFound handler
-	for _, route := range r.routes {
-		if route.Path == "*" && route.Method == "*" {
-			handler := route.Handler
-			for i := len(route.Middlewares) - 1; i >= 0; i-- {
-				handler = route.Middlewares[i](handler)
+	// Trailing slash policy
+	if r.trailingSlash != TrailingSlashStrict && path != "/" {
+		alt := path + "/"
+		if strings.HasSuffix(path, "/") {
+			alt = strings.TrimSuffix(path, "/")
+		}
+
+		if route, params := r.lookup(req.Method, alt); route != nil {
+			if r.trailingSlash == TrailingSlashIgnore {
+				return r.serve(req, route, params)
 			}
-			handler(w, req)
-			return
+
+			code := http.StatusPermanentRedirect
+			if req.Method == http.MethodGet || req.Method == http.MethodHead {
+				code = http.StatusMovedPermanently
+			}
+			target := *req.URL
+			target.Path = alt
+			target.RawPath = ""
+			return func(w http.ResponseWriter, req *http.Request) {
+				http.Redirect(w, req, target.String(), code)
+			}, req
 		}
 	}
 
+	if r.notFound != nil {
+		return r.chain(r.notFound), req
+	}
+
 	// Default 404
-	http.NotFound(w, req)
+	return r.chain(http.NotFound), req
 }
 
-// Match checks if route matches request
-func (r *Route) Match(method, path string) bool {
-	// Check method
-	if r.Method != method && r.Method != "*" {
-		return false
+// lookup finds the route for method and path; HEAD falls back to GET
+func (r *Router) lookup(method, path string) (*Route, Params) {
+	params := make(Params, 0, 4)
+	if n := r.root.match(path, &params, func(n *node) bool { return n.handlerFor(method) != nil }); n != nil {
+		return n.handlerFor(method), params
 	}
 
-	// Check path
-	return r.MatchPath(path)
+	if method == http.MethodHead {
+		params = params[:0]
+		if n := r.root.match(path, &params, func(n *node) bool { return n.handlerFor(http.MethodGet) != nil }); n != nil {
+			return n.handlerFor(http.MethodGet), params
+		}
+	}
+
+	return nil, nil
 }
 
-// MatchPath checks if path matches route pattern
-func (r *Route) MatchPath(path string) bool {
-	if r.Path == path {
-		return true
-	}
+// allowedMethods returns the sorted methods registered for any route matching path
+func (r *Router) allowedMethods(path string) []string {
+	methods := make(map[string]bool)
+	params := make(Params, 0, 4)
+
+	// accept never succeeds, so every matching node is visited
+	r.root.match(path, &params, func(n *node) bool {
+		for method := range n.routes {
+			if method == "*" {
+				for _, m := range allMethods {
+					methods[m] = true
+				}
+				continue
+			}
+			methods[method] = true
+		}
+		return false
+	})
 
-	// Handle wildcards
-	if strings.Contains(r.Path, "{") && strings.Contains(r.Path, "}") {
-		return r.matchPattern(path)
+	if len(methods) == 0 {
+		return nil
 	}
-
-	// Handle trailing slashes
-	if strings.HasSuffix(r.Path, "/") && path == strings.TrimSuffix(r.Path, "/") {
-		return true
+	if methods[http.MethodGet] {
+		methods[http.MethodHead] = true
 	}
+	methods[http.MethodOptions] = true
 
-	if strings.HasSuffix(path, "/") && r.Path == strings.TrimSuffix(path, "/") {
-		return true
+	allowed := make([]string, 0, len(methods))
+	for method := range methods {
+		allowed = append(allowed, method)
 	}
+	sort.Strings(allowed)
 
-	return false
+	return allowed
 }
 
-// matchPattern matches path with pattern (e.g., /users/{id})
-func (r *Route) matchPattern(path string) bool {
-	patternParts := strings.Split(r.Path, "/")
-	pathParts := strings.Split(path, "/")
+// serve wraps the route handler in route and global middleware. The request
+// context gets a copy of the route, which stays valid without the lock.
+func (r *Router) serve(req *http.Request, found *Route, params Params) (http.HandlerFunc, *http.Request) {
+	route := *found
+	handler := route.Handler
+	for i := len(route.Middlewares) - 1; i >= 0; i-- {
+		handler = route.Middlewares[i](handler)
+	}
 
-	if len(patternParts) != len(pathParts) {
-		return false
+	if recorded, ok := req.Context().Value(routeRecorderKey{}).(*string); ok {
+		*recorded = route.Path
 	}
 
-	for i, patternPart := range patternParts {
-		pathPart := pathParts[i]
+	ctx := context.WithValue(req.Context(), routeContextKey{}, &routeContext{route: &route, params: params})
+	return r.chain(handler), req.WithContext(ctx)
+}
 
-		if strings.HasPrefix(patternPart, "{") && strings.HasSuffix(patternPart, "}") {
-			// This is a parameter, accept any value
-			continue
-		}
+// chain wraps handler in global middleware
+func (r *Router) chain(handler http.HandlerFunc) http.HandlerFunc {
+	for i := len(r.middleware) - 1; i >= 0; i-- {
+		handler = r.middleware[i](handler)
+	}
+	return handler
+}
 
-		if patternPart != pathPart {
-			return false
-		}
+func (r *Router) methodNotAllowedHandler() http.HandlerFunc {
+	if r.methodNotAllowed != nil {
+		return r.methodNotAllowed
+	}
+	return func(w http.ResponseWriter, req *http.Request) {
+		responseutils.MethodNotAllowed(w)
+	}
+}
+
+// Match checks if route matches request
+func (r *Route) Match(method, path string) bool {
+	// Check method
+	if r.Method != method && r.Method != "*" {
+		return false
 	}
 
-	return true
+	// Check path
+	return r.MatchPath(path)
+}
+
+// MatchPath checks if path matches route pattern
+func (r *Route) MatchPath(path string) bool {
+	_, ok := r.matchParams(path)
+	return ok
 }
 
 // GetParams extracts parameters from path
 func (r *Route) GetParams(path string) map[string]string {
-	params := make(map[string]string)
+	params, _ := r.matchParams(path)
 
-	if !strings.Contains(r.Path, "{") {
-		return params
+	result := make(map[string]string, len(params))
+	for _, p := range params {
+		result[p.Key] = p.Value
 	}
+	return result
+}
 
-	patternParts := strings.Split(r.Path, "/")
-	pathParts := strings.Split(path, "/")
+// matchParams matches path against this route alone
+func (r *Route) matchParams(path string) (Params, bool) {
+	tokens := r.tokens
+	if tokens == nil {
+		var err error
+		if tokens, err = parsePattern(r.Path); err != nil {
+			return nil, false
+		}
+	}
 
-	if len(patternParts) != len(pathParts) {
-		return params
+	root := &node{}
+	if err := root.insert(tokens, "*", r); err != nil {
+		return nil, false
 	}
 
-	for i, patternPart := range patternParts {
-		if strings.HasPrefix(patternPart, "{") && strings.HasSuffix(patternPart, "}") {
-			paramName := strings.TrimSuffix(strings.TrimPrefix(patternPart, "{"), "}")
-			params[paramName] = pathParts[i]
-		}
+	params := make(Params, 0, 4)
+	found := root.match(path, &params, func(n *node) bool { return n.routes != nil })
+	return params, found != nil
+}
+
+// Named gives the route a name for reverse URL building with Router.URL
+func (r *Route) Named(name string) *Route {
+	if r.router == nil {
+		panic("server: Named called on a route that is not registered")
+	}
+
+	r.router.mu.Lock()
+	defer r.router.mu.Unlock()
+
+	if existing, ok := r.router.named[name]; ok && existing != r {
+		panic(fmt.Sprintf("server: route name %q already used by %s %s", name, existing.Method, existing.Path))
+	}
+	if r.Name != "" {
+		delete(r.router.named, r.Name)
 	}
+	r.Name = name
+	r.router.named[name] = r
 
-	return params
+	return r
 }
 
-// AddRoute adds a route to router
-func (r *Router) AddRoute(method, path string, handler http.HandlerFunc, middleware ...func(http.HandlerFunc) http.HandlerFunc) {
+// AddRoute adds a route to router. It panics if the pattern is invalid.
+//
+// Patterns support {name} parameters, regexp-constrained {name:[0-9]+}
+// parameters and a trailing *name wildcard that matches the rest of the path.
+func (r *Router) AddRoute(method, path string, handler http.HandlerFunc, middleware ...func(http.HandlerFunc) http.HandlerFunc) *Route {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	route := Route{
+	tokens, err := parsePattern(path)
+	if err != nil {
+		panic("server: " + err.Error())
+	}
+
+	route := &Route{
 		Method:      strings.ToUpper(method),
 		Path:        path,
 		Handler:     handler,
 		Middlewares: middleware,
+		router:      r,
+		tokens:      tokens,
+	}
+
+	if err := r.root.insert(tokens, route.Method, route); err != nil {
+		panic("server: " + err.Error())
+	}
+
+	replaced := false
+	for i, existing := range r.routes {
+		if existing.Method == route.Method && existing.Path == route.Path {
+			if existing.Name != "" {
+				delete(r.named, existing.Name)
+			}
+			r.routes[i] = route
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		r.routes = append(r.routes, route)
 	}
 
-	r.routes = append(r.routes, route)
 	log.Printf("Route registered: %-6s %s", method, path)
+	return route
 }
 
 // Get adds GET route
-func (r *Router) Get(path string, handler http.HandlerFunc, middleware ...func(http.HandlerFunc) http.HandlerFunc) {
-	r.AddRoute("GET", path, handler, middleware...)
+func (r *Router) Get(path string, handler http.HandlerFunc, middleware ...func(http.HandlerFunc) http.HandlerFunc) *Route {
+	return r.AddRoute("GET", path, handler, middleware...)
 }
 
 // Post adds POST route
-func (r *Router) Post(path string, handler http.HandlerFunc, middleware ...func(http.HandlerFunc) http.HandlerFunc) {
-	r.AddRoute("POST", path, handler, middleware...)
+func (r *Router) Post(path string, handler http.HandlerFunc, middleware ...func(http.HandlerFunc) http.HandlerFunc) *Route {
+	return r.AddRoute("POST", path, handler, middleware...)
 }
 
 // Put adds PUT route
-func (r *Router) Put(path string, handler http.HandlerFunc, middleware ...func(http.HandlerFunc) http.HandlerFunc) {
-	r.AddRoute("PUT", path, handler, middleware...)
+func (r *Router) Put(path string, handler http.HandlerFunc, middleware ...func(http.HandlerFunc) http.HandlerFunc) *Route {
+	return r.AddRoute("PUT", path, handler, middleware...)
 }
 
 // Delete adds DELETE route
-func (r *Router) Delete(path string, handler http.HandlerFunc, middleware ...func(http.HandlerFunc) http.HandlerFunc) {
-	r.AddRoute("DELETE", path, handler, middleware...)
+func (r *Router) Delete(path string, handler http.HandlerFunc, middleware ...func(http.HandlerFunc) http.HandlerFunc) *Route {
+	return r.AddRoute("DELETE", path, handler, middleware...)
 }
 
 // Patch adds PATCH route
-func (r *Router) Patch(path string, handler http.HandlerFunc, middleware ...func(http.HandlerFunc) http.HandlerFunc) {
-	r.AddRoute("PATCH", path, handler, middleware...)
+func (r *Router) Patch(path string, handler http.HandlerFunc, middleware ...func(http.HandlerFunc) http.HandlerFunc) *Route {
+	return r.AddRoute("PATCH", path, handler, middleware...)
 }
 
 // Options adds OPTIONS route
-func (r *Router) Options(path string, handler http.HandlerFunc, middleware ...func(http.HandlerFunc) http.HandlerFunc) {
-	r.AddRoute("OPTIONS", path, handler, middleware...)
+func (r *Router) Options(path string, handler http.HandlerFunc, middleware ...func(http.HandlerFunc) http.HandlerFunc) *Route {
+	return r.AddRoute("OPTIONS", path, handler, middleware...)
 }
 
 // Head adds HEAD route
-func (r *Router) Head(path string, handler http.HandlerFunc, middleware ...func(http.HandlerFunc) http.HandlerFunc) {
-	r.AddRoute("HEAD", path, handler, middleware...)
+func (r *Router) Head(path string, handler http.HandlerFunc, middleware ...func(http.HandlerFunc) http.HandlerFunc) *Route {
+	return r.AddRoute("HEAD", path, handler, middleware...)
 }
 
 // Any adds route for any method
-func (r *Router) Any(path string, handler http.HandlerFunc, middleware ...func(http.HandlerFunc) http.HandlerFunc) {
-	r.AddRoute("*", path, handler, middleware...)
+func (r *Router) Any(path string, handler http.HandlerFunc, middleware ...func(http.HandlerFunc) http.HandlerFunc) *Route {
+	return r.AddRoute("*", path, handler, middleware...)
 }
 
 // NotFound sets 404 handler
 func (r *Router) NotFound(handler http.HandlerFunc) {
-	r.AddRoute("*", "*", handler)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.notFound = handler
+}
+
+// MethodNotAllowed sets 405 handler; the Allow header is set before it runs
+func (r *Router) MethodNotAllowed(handler http.HandlerFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.methodNotAllowed = handler
+}
+
+// SetTrailingSlashPolicy sets how trailing slash mismatches are handled
+func (r *Router) SetTrailingSlashPolicy(policy TrailingSlashPolicy) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.trailingSlash = policy
 }
 
 // Use adds global middleware
@@ -207,9 +382,9 @@ func (r *Router) AddMiddlewareToRoute(method, path string, middleware ...func(ht
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	for i, route := range r.routes {
+	for _, route := range r.routes {
 		if route.Method == method && route.Path == path {
-			r.routes[i].Middlewares = append(r.routes[i].Middlewares, middleware...)
+			route.Middlewares = append(route.Middlewares, middleware...)
 			return
 		}
 	}
@@ -221,16 +396,78 @@ func (r *Router) GetRoutes() []Route {
 	defer r.mu.RUnlock()
 
 	routes := make([]Route, len(r.routes))
-	copy(routes, r.routes)
+	for i, route := range r.routes {
+		routes[i] = *route
+	}
 	return routes
 }
 
+// GetRoute returns the route registered under name
+func (r *Router) GetRoute(name string) (*Route, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	route, ok := r.named[name]
+	return route, ok
+}
+
+// URL builds the path of a named route from key/value pairs,
+// e.g. URL("user", "id", "42") for /users/{id}
+func (r *Router) URL(name string, pairs ...string) (string, error) {
+	if len(pairs)%2 != 0 {
+		return "", fmt.Errorf("odd number of URL parameters for route %q", name)
+	}
+
+	route, ok := r.GetRoute(name)
+	if !ok {
+		return "", fmt.Errorf("route %q not found", name)
+	}
+
+	values := make(map[string]string, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		values[pairs[i]] = pairs[i+1]
+	}
+
+	var b strings.Builder
+	for _, tok := range route.tokens {
+		switch tok.kind {
+		case staticNode:
+			b.WriteString(tok.value)
+
+		case paramNode:
+			value, ok := values[tok.value]
+			if !ok || value == "" {
+				return "", fmt.Errorf("missing parameter %q for route %q", tok.value, name)
+			}
+			if tok.pattern != "" {
+				if matched, _ := regexp.MatchString("^(?:"+tok.pattern+")$", value); !matched {
+					return "", fmt.Errorf("parameter %q value %q does not match %s", tok.value, value, tok.pattern)
+				}
+			}
+			b.WriteString(url.PathEscape(value))
+
+		case catchAllNode:
+			segments := strings.Split(values[tok.value], "/")
+			for i, segment := range segments {
+				segments[i] = url.PathEscape(segment)
+			}
+			b.WriteString(strings.Join(segments, "/"))
+		}
+	}
+
+	return b.String(), nil
+}
+
 // PrintRoutes prints all registered routes
 func (r *Router) PrintRoutes() {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
 	for _, route := range r.routes {
+		if route.Name != "" {
+			fmt.Printf("  %-6s %s (%s)\n", route.Method, route.Path, route.Name)
+			continue
+		}
 		fmt.Printf("  %-6s %s\n", route.Method, route.Path)
 	}
 }
@@ -251,46 +488,42 @@ type RouteGroup struct {
 	middleware []func(http.HandlerFunc) http.HandlerFunc
 }
 
+// route registers a group route; the middleware slice is copied so groups never share backing arrays
+func (g *RouteGroup) route(method, path string, handler http.HandlerFunc, middleware []func(http.HandlerFunc) http.HandlerFunc) *Route {
+	allMiddleware := make([]func(http.HandlerFunc) http.HandlerFunc, 0, len(g.middleware)+len(middleware))
+	allMiddleware = append(allMiddleware, g.middleware...)
+	allMiddleware = append(allMiddleware, middleware...)
+	return g.router.AddRoute(method, g.prefix+path, handler, allMiddleware...)
+}
+
 // Get adds GET route to group
-func (g *RouteGroup) Get(path string, handler http.HandlerFunc, middleware ...func(http.HandlerFunc) http.HandlerFunc) {
-	fullPath := g.prefix + path
-	allMiddleware := append(g.middleware, middleware...)
-	g.router.Get(fullPath, handler, allMiddleware...)
+func (g *RouteGroup) Get(path string, handler http.HandlerFunc, middleware ...func(http.HandlerFunc) http.HandlerFunc) *Route {
+	return g.route("GET", path, handler, middleware)
 }
 
 // Post adds POST route to group
-func (g *RouteGroup) Post(path string, handler http.HandlerFunc, middleware ...func(http.HandlerFunc) http.HandlerFunc) {
-	fullPath := g.prefix + path
-	allMiddleware := append(g.middleware, middleware...)
-	g.router.Post(fullPath, handler, allMiddleware...)
+func (g *RouteGroup) Post(path string, handler http.HandlerFunc, middleware ...func(http.HandlerFunc) http.HandlerFunc) *Route {
+	return g.route("POST", path, handler, middleware)
 }
 
 // Put adds PUT route to group
-func (g *RouteGroup) Put(path string, handler http.HandlerFunc, middleware ...func(http.HandlerFunc) http.HandlerFunc) {
-	fullPath := g.prefix + path
-	allMiddleware := append(g.middleware, middleware...)
-	g.router.Put(fullPath, handler, allMiddleware...)
+func (g *RouteGroup) Put(path string, handler http.HandlerFunc, middleware ...func(http.HandlerFunc) http.HandlerFunc) *Route {
+	return g.route("PUT", path, handler, middleware)
 }
 
 // Delete adds DELETE route to group
-func (g *RouteGroup) Delete(path string, handler http.HandlerFunc, middleware ...func(http.HandlerFunc) http.HandlerFunc) {
-	fullPath := g.prefix + path
-	allMiddleware := append(g.middleware, middleware...)
-	g.router.Delete(fullPath, handler, allMiddleware...)
+func (g *RouteGroup) Delete(path string, handler http.HandlerFunc, middleware ...func(http.HandlerFunc) http.HandlerFunc) *Route {
+	return g.route("DELETE", path, handler, middleware)
 }
 
 // Patch adds PATCH route to group
-func (g *RouteGroup) Patch(path string, handler http.HandlerFunc, middleware ...func(http.HandlerFunc) http.HandlerFunc) {
-	fullPath := g.prefix + path
-	allMiddleware := append(g.middleware, middleware...)
-	g.router.Patch(fullPath, handler, allMiddleware...)
+func (g *RouteGroup) Patch(path string, handler http.HandlerFunc, middleware ...func(http.HandlerFunc) http.HandlerFunc) *Route {
+	return g.route("PATCH", path, handler, middleware)
 }
 
 // Any adds route for any method to group
-func (g *RouteGroup) Any(path string, handler http.HandlerFunc, middleware ...func(http.HandlerFunc) http.HandlerFunc) {
-	fullPath := g.prefix + path
-	allMiddleware := append(g.middleware, middleware...)
-	g.router.Any(fullPath, handler, allMiddleware...)
+func (g *RouteGroup) Any(path string, handler http.HandlerFunc, middleware ...func(http.HandlerFunc) http.HandlerFunc) *Route {
+	return g.route("*", path, handler, middleware)
 }
 
 // Use adds middleware to group
@@ -300,27 +533,79 @@ func (g *RouteGroup) Use(middleware ...func(http.HandlerFunc) http.HandlerFunc)
 
 // SubGroup creates a subgroup
 func (g *RouteGroup) SubGroup(prefix string, middleware ...func(http.HandlerFunc) http.HandlerFunc) *RouteGroup {
+	allMiddleware := make([]func(http.HandlerFunc) http.HandlerFunc, 0, len(g.middleware)+len(middleware))
+	allMiddleware = append(allMiddleware, g.middleware...)
+	allMiddleware = append(allMiddleware, middleware...)
+
 	return &RouteGroup{
 		router:     g.router,
 		prefix:     g.prefix + prefix,
-		middleware: append(g.middleware, middleware...),
+		middleware: allMiddleware,
 	}
 }
 
-// Helper function to get path parameters from request context
-func GetParam(r *http.Request, name string) string {
-	// In a real implementation, you'd extract params from context
-	// For this simple router, we parse from URL path
-	parts := strings.Split(r.URL.Path, "/")
-
-	// Simple extraction for patterns like /users/{id}
-	for i, part := range parts {
-		if part == "{"+name+"}" {
-			if i+1 < len(parts) {
-				return parts[i+1]
-			}
-		}
+// routeContextKey stores the matched route and its params in the request context
+type routeContextKey struct{}
+
+type routeContext struct {
+	route  *Route
+	params Params
+}
+
+// routeRecorderKey holds a *string the router fills with the matched route
+// pattern, for middleware that runs before routing
+type routeRecorderKey struct{}
+
+// withRouteRecorder returns a request whose matched route pattern is written to
+// the returned string. Nested middleware share the outermost recorder.
+func withRouteRecorder(r *http.Request) (*http.Request, *string) {
+	if recorded, ok := r.Context().Value(routeRecorderKey{}).(*string); ok {
+		return r, recorded
+	}
+	recorded := new(string)
+	return r.WithContext(context.WithValue(r.Context(), routeRecorderKey{}, recorded)), recorded
+}
+
+// RoutePattern returns the pattern of the route that matched the request, e.g. /users/{id}
+func RoutePattern(r *http.Request) string {
+	if rc, ok := r.Context().Value(routeContextKey{}).(*routeContext); ok {
+		return rc.route.Path
 	}
+	return ""
+}
 
+// RouteName returns the name of the route that matched the request
+func RouteName(r *http.Request) string {
+	if rc, ok := r.Context().Value(routeContextKey{}).(*routeContext); ok {
+		return rc.route.Name
+	}
 	return ""
 }
+
+// PathParams returns all path parameters of the matched route
+func PathParams(r *http.Request) Params {
+	if rc, ok := r.Context().Value(routeContextKey{}).(*routeContext); ok {
+		return rc.params
+	}
+	return nil
+}
+
+// GetParam returns a path parameter of the matched route
+func GetParam(r *http.Request, name string) string {
+	value, _ := PathParams(r).Get(name)
+	return value
+}
+
+// GetParamInt returns a path parameter parsed as an int
+func GetParamInt(r *http.Request, name string) (int, error) {
+	value, ok := PathParams(r).Get(name)
+	if !ok {
+		return 0, fmt.Errorf("path parameter %q not found", name)
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("path parameter %q is not an integer: %w", name, err)
+	}
+	return n, nil
+}
@@ -13,21 +13,33 @@ import (
 	"sync"
 	"syscall"
 	"time"
+
+	"github.com/selanim/sego/logger"
+	"github.com/selanim/sego/tracing"
 )
 
 // Route represents a single route
 type Route struct {
 	Method      string
 	Path        string
+	Name        string
 	Handler     http.HandlerFunc
 	Middlewares []func(http.HandlerFunc) http.HandlerFunc
+	Doc         *RouteDoc
+	router      *Router
+	tokens      []token
 }
 
-// Router handles routing
+// Router handles routing with a radix tree
 type Router struct {
-	routes     []Route
-	middleware []func(http.HandlerFunc) http.HandlerFunc
-	mu         sync.RWMutex
+	root             *node
+	routes           []*Route
+	named            map[string]*Route
+	middleware       []func(http.HandlerFunc) http.HandlerFunc
+	notFound         http.HandlerFunc
+	methodNotAllowed http.HandlerFunc
+	trailingSlash    TrailingSlashPolicy
+	mu               sync.RWMutex
 }
 
 // Server represents the HTTP server
@@ -40,6 +52,9 @@ type Server struct {
 	metrics    *Metrics
 	health     *Health
 	listener   net.Listener
+	verifier   TokenVerifier
+	tracer     *tracing.Tracer
+	loggers    []*logger.AsyncLogger
 }
 
 // Config holds server configuration
@@ -91,7 +106,9 @@ func DefaultConfig() *Config {
 // NewRouter creates a new router
 func NewRouter() *Router {
 	return &Router{
-		routes:     []Route{},
+		root:       &node{},
+		routes:     []*Route{},
+		named:      make(map[string]*Route),
 		middleware: []func(http.HandlerFunc) http.HandlerFunc{},
 	}
 }
@@ -156,6 +173,11 @@ func NewServer(config *Config) *Server {
 
 // setupMiddleware configures server middleware
 func (s *Server) setupMiddleware() {
+	// Metrics run outermost so they observe the final status of every request
+	if s.config.EnableMetrics {
+		s.middleware.Use(MetricsMiddleware(s.metrics))
+	}
+
 	if s.config.EnableLogging {
 		s.middleware.Use(LoggingMiddleware)
 	}
@@ -193,14 +215,15 @@ func (s *Server) setupRoutes() {
 	// Metrics endpoint
 	if s.config.EnableMetrics {
 		s.router.Get("/api/v1/metrics", s.metrics.Handler)
+		s.router.Get("/metrics", s.metrics.PrometheusHandler)
 	}
 
 	// Example API routes
-	s.router.Get("/api/v1/users", s.handlers.GetUsers)
-	s.router.Get("/api/v1/users/{id}", s.handlers.GetUser)
-	s.router.Post("/api/v1/users", s.handlers.CreateUser)
-	s.router.Put("/api/v1/users/{id}", s.handlers.UpdateUser)
-	s.router.Delete("/api/v1/users/{id}", s.handlers.DeleteUser)
+	s.router.Get("/api/v1/users", s.handlers.GetUsers).Named("users.list")
+	s.router.Get("/api/v1/users/{id}", s.handlers.GetUser).Named("users.get")
+	s.router.Post("/api/v1/users", s.handlers.CreateUser).Named("users.create")
+	s.router.Put("/api/v1/users/{id}", s.handlers.UpdateUser).Named("users.update")
+	s.router.Delete("/api/v1/users/{id}", s.handlers.DeleteUser).Named("users.delete")
 
 	// Static file serving
 	if s.config.StaticDir != "" {
@@ -268,6 +291,9 @@ func (s *Server) StartTLS(certFile, keyFile string) error {
 	return s.Start()
 }
 
+// flushTimeout bounds flushing traces and logs after requests have drained
+const flushTimeout = 5 * time.Second
+
 // Shutdown gracefully shuts down the server
 func (s *Server) Shutdown(ctx context.Context) error {
 	log.Println("🛑 Server shutting down gracefully...")
@@ -287,7 +313,28 @@ func (s *Server) Shutdown(ctx context.Context) error {
 	defer cancel()
 
 	// Attempt graceful shutdown
-	if err := s.httpServer.Shutdown(shutdownCtx); err != nil {
+	err := s.httpServer.Shutdown(shutdownCtx)
+
+	// Flushing gets a deadline of its own: shutdownCtx has already expired
+	// when draining timed out, which is when the spans and logs matter most
+	flushCtx, cancelFlush := context.WithTimeout(context.WithoutCancel(ctx), flushTimeout)
+	defer cancelFlush()
+
+	// Export spans of the drained requests
+	if s.tracer != nil {
+		if flushErr := s.tracer.Flush(flushCtx); flushErr != nil {
+			log.Printf("⚠️  Failed to flush traces: %v", flushErr)
+		}
+	}
+
+	// Write log entries buffered while draining
+	for _, al := range s.loggers {
+		if closeErr := al.Close(flushCtx); closeErr != nil {
+			log.Printf("⚠️  Failed to flush logs: %v", closeErr)
+		}
+	}
+
+	if err != nil {
 		log.Printf("⚠️  Graceful shutdown failed: %v", err)
 		return s.httpServer.Close()
 	}
@@ -296,6 +343,11 @@ func (s *Server) Shutdown(ctx context.Context) error {
 	return nil
 }
 
+// UseAsyncLogger closes al on Shutdown, after in-flight requests have finished
+func (s *Server) UseAsyncLogger(al *logger.AsyncLogger) {
+	s.loggers = append(s.loggers, al)
+}
+
 // Run starts the server with graceful shutdown
 func (s *Server) Run() error {
 	// Create channel for OS signals
@@ -340,43 +392,43 @@ func (s *Server) GetHTTPHandler() http.Handler {
 }
 
 // AddRoute adds a route to the server
-func (s *Server) AddRoute(method, path string, handler http.HandlerFunc, middleware ...func(http.HandlerFunc) http.HandlerFunc) {
-	s.router.AddRoute(method, path, handler, middleware...)
+func (s *Server) AddRoute(method, path string, handler http.HandlerFunc, middleware ...func(http.HandlerFunc) http.HandlerFunc) *Route {
+	return s.router.AddRoute(method, path, handler, middleware...)
 }
 
 // Get adds GET route
-func (s *Server) Get(path string, handler http.HandlerFunc, middleware ...func(http.HandlerFunc) http.HandlerFunc) {
-	s.router.Get(path, handler, middleware...)
+func (s *Server) Get(path string, handler http.HandlerFunc, middleware ...func(http.HandlerFunc) http.HandlerFunc) *Route {
+	return s.router.Get(path, handler, middleware...)
 }
 
 // Post adds POST route
-func (s *Server) Post(path string, handler http.HandlerFunc, middleware ...func(http.HandlerFunc) http.HandlerFunc) {
-	s.router.Post(path, handler, middleware...)
+func (s *Server) Post(path string, handler http.HandlerFunc, middleware ...func(http.HandlerFunc) http.HandlerFunc) *Route {
+	return s.router.Post(path, handler, middleware...)
 }
 
 // Put adds PUT route
-func (s *Server) Put(path string, handler http.HandlerFunc, middleware ...func(http.HandlerFunc) http.HandlerFunc) {
-	s.router.Put(path, handler, middleware...)
+func (s *Server) Put(path string, handler http.HandlerFunc, middleware ...func(http.HandlerFunc) http.HandlerFunc) *Route {
+	return s.router.Put(path, handler, middleware...)
 }
 
 // Delete adds DELETE route
-func (s *Server) Delete(path string, handler http.HandlerFunc, middleware ...func(http.HandlerFunc) http.HandlerFunc) {
-	s.router.Delete(path, handler, middleware...)
+func (s *Server) Delete(path string, handler http.HandlerFunc, middleware ...func(http.HandlerFunc) http.HandlerFunc) *Route {
+	return s.router.Delete(path, handler, middleware...)
 }
 
 // Patch adds PATCH route
-func (s *Server) Patch(path string, handler http.HandlerFunc, middleware ...func(http.HandlerFunc) http.HandlerFunc) {
-	s.router.Patch(path, handler, middleware...)
+func (s *Server) Patch(path string, handler http.HandlerFunc, middleware ...func(http.HandlerFunc) http.HandlerFunc) *Route {
+	return s.router.Patch(path, handler, middleware...)
 }
 
 // Options adds OPTIONS route
-func (s *Server) Options(path string, handler http.HandlerFunc, middleware ...func(http.HandlerFunc) http.HandlerFunc) {
-	s.router.Options(path, handler, middleware...)
+func (s *Server) Options(path string, handler http.HandlerFunc, middleware ...func(http.HandlerFunc) http.HandlerFunc) *Route {
+	return s.router.Options(path, handler, middleware...)
 }
 
 // Head adds HEAD route
-func (s *Server) Head(path string, handler http.HandlerFunc, middleware ...func(http.HandlerFunc) http.HandlerFunc) {
-	s.router.Head(path, handler, middleware...)
+func (s *Server) Head(path string, handler http.HandlerFunc, middleware ...func(http.HandlerFunc) http.HandlerFunc) *Route {
+	return s.router.Head(path, handler, middleware...)
 }
 
 // AddMiddleware adds global middleware
@@ -389,6 +441,35 @@ func (s *Server) AddRouteMiddleware(method, path string, middleware ...func(http
 	s.router.AddMiddlewareToRoute(method, path, middleware...)
 }
 
+// Group creates a route group with prefix
+func (s *Server) Group(prefix string, middleware ...func(http.HandlerFunc) http.HandlerFunc) *RouteGroup {
+	return s.router.Group(prefix, middleware...)
+}
+
+// SetTokenVerifier sets the verifier used by RequireAuth, e.g. an *authutils.AuthUtils
+func (s *Server) SetTokenVerifier(verifier TokenVerifier) {
+	s.verifier = verifier
+}
+
+// GetTokenVerifier returns the configured token verifier
+func (s *Server) GetTokenVerifier() TokenVerifier {
+	return s.verifier
+}
+
+// RequireAuth returns AuthMiddleware bound to the server token verifier
+func (s *Server) RequireAuth() func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			AuthMiddleware(s.verifier)(next)(w, r)
+		}
+	}
+}
+
+// URL builds the path of a named route from key/value pairs
+func (s *Server) URL(name string, pairs ...string) (string, error) {
+	return s.router.URL(name, pairs...)
+}
+
 // SetNotFoundHandler sets custom 404 handler
 func (s *Server) SetNotFoundHandler(handler http.HandlerFunc) {
 	s.router.NotFound(handler)
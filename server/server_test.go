@@ -4,15 +4,23 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"log"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 	"time"
+
+	"github.com/selanim/sego/authutils"
+	"github.com/selanim/sego/logger"
+	"github.com/selanim/sego/tracing"
 )
 
 // TestNewServer tests server creation
@@ -94,6 +102,13 @@ func TestHandlerGetUsers(t *testing.T) {
 	if !result["success"].(bool) {
 		t.Error("Expected success to be true")
 	}
+
+	// Invalid paging falls back to the defaults
+	w = httptest.NewRecorder()
+	handlers.GetUsers(w, httptest.NewRequest("GET", "/api/v1/users?page=abc&per_page=-5", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 for invalid paging, got %d", w.Code)
+	}
 }
 
 // TestHandlerGetUser tests GET /api/v1/users/{id}
@@ -202,6 +217,20 @@ func TestHandlerUpdateUser(t *testing.T) {
 	if resp.StatusCode != http.StatusOK {
 		t.Errorf("Expected status 200, got %d", resp.StatusCode)
 	}
+
+	// Fields are optional, but those sent are validated
+	for body, status := range map[string]int{
+		`{"name":"Only Name"}`:  http.StatusOK,
+		`{"email":"not-email"}`: http.StatusBadRequest,
+	} {
+		req := httptest.NewRequest("PUT", "/api/v1/users/123", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		handlers.UpdateUser(w, req)
+		if w.Code != status {
+			t.Errorf("Expected status %d for %s, got %d: %s", status, body, w.Code, w.Body.String())
+		}
+	}
 }
 
 // TestHandlerDeleteUser tests DELETE /api/v1/users/{id}
@@ -259,6 +288,237 @@ func TestRouter(t *testing.T) {
 	}
 }
 
+// TestRouterMatching tests params, precedence, wildcards and regexp constraints
+func TestRouterMatching(t *testing.T) {
+	router := NewRouter()
+
+	handler := func(name string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			params := []string{}
+			for _, p := range PathParams(r) {
+				params = append(params, p.Key+"="+p.Value)
+			}
+			w.Write([]byte(name + " " + strings.Join(params, ",")))
+		}
+	}
+
+	router.Get("/users/new", handler("new"))
+	router.Get("/users/{id:[0-9]+}", handler("byID"))
+	router.Get("/users/{name}", handler("byName"))
+	router.Get("/users/{id:[0-9]+}/posts/{post}", handler("post"))
+	router.Get("/files/*path", handler("files"))
+	router.Get("/usage", handler("usage"))
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"/users/new", "new "},
+		{"/users/42", "byID id=42"},
+		{"/users/alice", "byName name=alice"},
+		{"/users/7/posts/9", "post id=7,post=9"},
+		{"/files/css/site.css", "files path=css/site.css"},
+		{"/files/", "files path="},
+		{"/usage", "usage "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d", w.Code)
+			}
+			if w.Body.String() != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, w.Body.String())
+			}
+		})
+	}
+
+	req := httptest.NewRequest("GET", "/users/7/comments", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+}
+
+// TestRouterMethods tests 405 responses and automatic HEAD/OPTIONS
+func TestRouterMethods(t *testing.T) {
+	router := NewRouter()
+	router.Get("/items", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("list"))
+	})
+	router.Post("/items", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	tests := []struct {
+		method string
+		status int
+		allow  string
+	}{
+		{"GET", http.StatusOK, ""},
+		{"POST", http.StatusCreated, ""},
+		{"HEAD", http.StatusOK, ""},
+		{"DELETE", http.StatusMethodNotAllowed, "GET, HEAD, OPTIONS, POST"},
+		{"OPTIONS", http.StatusNoContent, "GET, HEAD, OPTIONS, POST"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/items", nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, w.Code)
+			}
+			if got := w.Header().Get("Allow"); got != tt.allow {
+				t.Errorf("Expected Allow %q, got %q", tt.allow, got)
+			}
+		})
+	}
+}
+
+// TestRouterTrailingSlash tests the trailing slash policies
+func TestRouterTrailingSlash(t *testing.T) {
+	router := NewRouter()
+	router.Get("/about", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	router.Post("/forms/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	req := httptest.NewRequest("GET", "/about/?lang=sw", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusMovedPermanently {
+		t.Errorf("Expected status 301, got %d", w.Code)
+	}
+	if got := w.Header().Get("Location"); got != "/about?lang=sw" {
+		t.Errorf("Expected Location /about?lang=sw, got %s", got)
+	}
+
+	req = httptest.NewRequest("POST", "/forms", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusPermanentRedirect {
+		t.Errorf("Expected status 308, got %d", w.Code)
+	}
+
+	router.SetTrailingSlashPolicy(TrailingSlashIgnore)
+	req = httptest.NewRequest("GET", "/about/", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 with TrailingSlashIgnore, got %d", w.Code)
+	}
+
+	router.SetTrailingSlashPolicy(TrailingSlashStrict)
+	req = httptest.NewRequest("GET", "/about/", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 with TrailingSlashStrict, got %d", w.Code)
+	}
+}
+
+// TestRouterNamedRoutes tests reverse URL building
+func TestRouterNamedRoutes(t *testing.T) {
+	router := NewRouter()
+	noop := func(w http.ResponseWriter, r *http.Request) {}
+
+	router.Get("/users/{id:[0-9]+}", noop).Named("user")
+	router.Get("/files/*path", noop).Named("file")
+
+	url, err := router.URL("user", "id", "42")
+	if err != nil || url != "/users/42" {
+		t.Errorf("Expected /users/42, got %s (%v)", url, err)
+	}
+
+	url, err = router.URL("file", "path", "docs/a b.txt")
+	if err != nil || url != "/files/docs/a%20b.txt" {
+		t.Errorf("Expected /files/docs/a%%20b.txt, got %s (%v)", url, err)
+	}
+
+	if _, err := router.URL("user", "id", "abc"); err == nil {
+		t.Error("Expected error for value not matching constraint")
+	}
+	if _, err := router.URL("user"); err == nil {
+		t.Error("Expected error for missing parameter")
+	}
+	if _, err := router.URL("missing"); err == nil {
+		t.Error("Expected error for unknown route")
+	}
+
+	server := NewServer(nil)
+	if url, _ := server.URL("users.get", "id", "7"); url != "/api/v1/users/7" {
+		t.Errorf("Expected /api/v1/users/7, got %s", url)
+	}
+}
+
+// TestRouterHandlerCallsRouter tests handlers using the router while a route is being added
+func TestRouterHandlerCallsRouter(t *testing.T) {
+	router := NewRouter()
+	noop := func(w http.ResponseWriter, r *http.Request) {}
+	router.Get("/users/{id}", noop).Named("user")
+
+	router.Get("/links", func(w http.ResponseWriter, r *http.Request) {
+		// A waiting writer blocks new readers, so a lock still held here would deadlock
+		added := make(chan struct{})
+		go func() {
+			router.Get("/late", noop)
+			close(added)
+		}()
+		<-added
+		url, _ := router.URL("user", "id", "7")
+		fmt.Fprintf(w, "%s %d", url, len(router.GetRoutes()))
+	})
+
+	done := make(chan string)
+	go func() {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", "/links", nil))
+		done <- rec.Body.String()
+	}()
+
+	select {
+	case body := <-done:
+		if body != "/users/7 3" {
+			t.Errorf("Expected /users/7 3, got %q", body)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected handler to call the router without deadlocking")
+	}
+}
+
+// TestRouterInvalidPatterns tests pattern validation
+func TestRouterInvalidPatterns(t *testing.T) {
+	patterns := []string{
+		"users",
+		"/users/{id",
+		"/users/{id}/{id}",
+		"/files/*path/more",
+		"/users/{id:[0-9+}",
+		"/users/x{id}",
+	}
+
+	for _, pattern := range patterns {
+		t.Run(pattern, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected panic for pattern %s", pattern)
+				}
+			}()
+			NewRouter().Get(pattern, func(w http.ResponseWriter, r *http.Request) {})
+		})
+	}
+}
+
 // TestMiddleware tests middleware functionality
 func TestMiddleware(t *testing.T) {
 	router := NewRouter()
@@ -303,6 +563,349 @@ func TestMiddleware(t *testing.T) {
 	}
 }
 
+// TestAuthMiddleware tests JWT authentication and role/permission checks
+func TestAuthMiddleware(t *testing.T) {
+	auth := authutils.NewAuthUtils(authutils.TokenConfig{
+		SecretKey:          "test-secret-key",
+		AccessTokenExpiry:  time.Minute,
+		RefreshTokenExpiry: time.Hour,
+		Issuer:             "sego-test",
+	})
+
+	tokens, err := auth.GenerateTokens(&authutils.UserData{
+		ID:          "user-1",
+		Username:    "alice",
+		Email:       "alice@example.com",
+		Roles:       []string{"editor"},
+		Permissions: []string{"posts:read", "posts:write"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to generate tokens: %v", err)
+	}
+
+	server := NewServer(nil)
+	server.SetTokenVerifier(auth)
+
+	validations := 0
+	verifier := TokenVerifierFunc(func(token string) (*authutils.Claims, error) {
+		validations++
+		return auth.ValidateToken(token)
+	})
+
+	router := NewRouter()
+	group := router.Group("/api", AuthMiddleware(verifier))
+	group.Get("/me", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(UserIDFromRequest(r)))
+	}, AuthMiddleware(verifier))
+	group.Get("/posts", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}, PermissionBasedMiddleware("posts:read", "posts:write"))
+	group.Get("/admin", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}, RoleBasedMiddleware("admin"))
+	group.Get("/billing", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}, PermissionBasedMiddleware("billing:read"))
+	router.Get("/server", func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := ClaimsFromRequest(r)
+		if !ok {
+			t.Error("Expected claims in request context")
+			return
+		}
+		w.Write([]byte(claims.Username))
+	}, server.RequireAuth())
+
+	tests := []struct {
+		name   string
+		path   string
+		header string
+		status int
+		body   string
+	}{
+		{"missing header", "/api/me", "", http.StatusUnauthorized, ""},
+		{"bad scheme", "/api/me", "Basic " + tokens.AccessToken, http.StatusUnauthorized, ""},
+		{"invalid token", "/api/me", "Bearer not-a-jwt", http.StatusUnauthorized, ""},
+		{"valid token", "/api/me", "Bearer " + tokens.AccessToken, http.StatusOK, "user-1"},
+		{"lowercase scheme", "/api/me", "bearer " + tokens.AccessToken, http.StatusOK, "user-1"},
+		{"has permissions", "/api/posts", "Bearer " + tokens.AccessToken, http.StatusOK, ""},
+		{"missing role", "/api/admin", "Bearer " + tokens.AccessToken, http.StatusForbidden, ""},
+		{"missing permission", "/api/billing", "Bearer " + tokens.AccessToken, http.StatusForbidden, ""},
+		{"server verifier", "/server", "Bearer " + tokens.AccessToken, http.StatusOK, "alice"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, w.Code)
+			}
+			if tt.body != "" && w.Body.String() != tt.body {
+				t.Errorf("Expected body %q, got %q", tt.body, w.Body.String())
+			}
+		})
+	}
+
+	// Group and route middleware share the claims validated once
+	validations = 0
+	req := httptest.NewRequest("GET", "/api/me", nil)
+	req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	if validations != 1 {
+		t.Errorf("Expected token to be validated once, got %d", validations)
+	}
+
+	// Role middleware without authentication
+	w := httptest.NewRecorder()
+	RoleBasedMiddleware("editor")(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 without claims, got %d", w.Code)
+	}
+}
+
+// TestRateLimitAlgorithms tests token bucket, sliding window log and GCRA
+func TestRateLimitAlgorithms(t *testing.T) {
+	algorithms := map[string]authutils.RateLimitAlgorithm{
+		"token bucket":       authutils.TokenBucket(3, time.Second, 3),
+		"sliding window log": authutils.SlidingWindowLog(3, time.Second),
+		"gcra":               authutils.GCRA(3, time.Second, 3),
+	}
+
+	for name, algorithm := range algorithms {
+		t.Run(name, func(t *testing.T) {
+			now := time.Unix(1700000000, 0)
+			limiter := authutils.NewRateLimiterWithBackend(
+				authutils.NewMemoryRateLimitBackend(4),
+				algorithm,
+				authutils.RateLimiterOptions{Clock: func() time.Time { return now }},
+			)
+			ctx := context.Background()
+
+			for i := 0; i < 3; i++ {
+				result, err := limiter.Allow(ctx, "client")
+				if err != nil {
+					t.Fatalf("Allow failed: %v", err)
+				}
+				if !result.Allowed {
+					t.Fatalf("Request %d should be allowed", i+1)
+				}
+				if result.Remaining != 2-i {
+					t.Errorf("Expected remaining %d, got %d", 2-i, result.Remaining)
+				}
+			}
+
+			result, _ := limiter.Allow(ctx, "client")
+			if result.Allowed {
+				t.Error("Fourth request should be limited")
+			}
+			if result.RetryAfter <= 0 {
+				t.Errorf("Expected positive RetryAfter, got %v", result.RetryAfter)
+			}
+
+			if other, _ := limiter.Allow(ctx, "other"); !other.Allowed {
+				t.Error("Other key should not be limited")
+			}
+
+			now = now.Add(time.Second)
+			if result, _ := limiter.Allow(ctx, "client"); !result.Allowed {
+				t.Error("Request should be allowed after the window")
+			}
+		})
+	}
+}
+
+// memoryTokenStore is an AtomicTokenStore shared by several limiters
+type memoryTokenStore struct {
+	mu     sync.Mutex
+	values map[string]string
+	// readDelay widens the gap between reading and writing state
+	readDelay time.Duration
+}
+
+func (s *memoryTokenStore) SetToken(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = fmt.Sprint(value)
+	return nil
+}
+
+func (s *memoryTokenStore) GetToken(ctx context.Context, key string) (string, error) {
+	time.Sleep(s.readDelay)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok := s.values[key]
+	if !ok {
+		return "", fmt.Errorf("key not found")
+	}
+	return value, nil
+}
+
+func (s *memoryTokenStore) DeleteToken(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.values, key)
+	return nil
+}
+
+func (s *memoryTokenStore) Exists(ctx context.Context, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.values[key]
+	return ok, nil
+}
+
+func (s *memoryTokenStore) CompareAndSwap(ctx context.Context, key, old, value string, expiration time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.values[key] != old {
+		return false, nil
+	}
+	s.values[key] = value
+	return true, nil
+}
+
+// TestRateLimitTokenStoreBackend tests that replicas sharing a store share one limit
+func TestRateLimitTokenStoreBackend(t *testing.T) {
+	// Reads are slow, so replicas read the same state before either writes it
+	store := &memoryTokenStore{values: make(map[string]string), readDelay: time.Millisecond}
+	replicas := []*authutils.RateLimiter{
+		authutils.NewRateLimiter(store, 10, time.Minute),
+		authutils.NewRateLimiter(store, 10, time.Minute),
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	allowed := 0
+	for i := 0; i < 40; i++ {
+		wg.Add(1)
+		go func(limiter *authutils.RateLimiter) {
+			defer wg.Done()
+			ok, _, err := limiter.CheckRateLimit(context.Background(), "shared")
+			if err != nil && !errors.Is(err, authutils.ErrRateLimitContention) {
+				t.Errorf("CheckRateLimit failed: %v", err)
+			}
+			if ok {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}(replicas[i%2])
+	}
+	wg.Wait()
+
+	if allowed > 10 || allowed == 0 {
+		t.Errorf("Expected between 1 and 10 allowed requests across replicas, got %d", allowed)
+	}
+}
+
+// TestRateLimitMiddleware tests headers and key functions
+func TestRateLimitMiddleware(t *testing.T) {
+	handler := RateLimitMiddleware(2, time.Minute)(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	codes := []int{}
+	var last *httptest.ResponseRecorder
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "203.0.113.7:5555"
+		last = httptest.NewRecorder()
+		handler(last, req)
+		codes = append(codes, last.Code)
+	}
+
+	if codes[0] != http.StatusOK || codes[1] != http.StatusOK || codes[2] != http.StatusTooManyRequests {
+		t.Errorf("Expected [200 200 429], got %v", codes)
+	}
+	if got := last.Header().Get("RateLimit-Limit"); got != "2" {
+		t.Errorf("Expected RateLimit-Limit 2, got %q", got)
+	}
+	if got := last.Header().Get("RateLimit-Remaining"); got != "0" {
+		t.Errorf("Expected RateLimit-Remaining 0, got %q", got)
+	}
+	if got := last.Header().Get("RateLimit-Policy"); got != "2;w=60" {
+		t.Errorf("Expected RateLimit-Policy 2;w=60, got %q", got)
+	}
+	if last.Header().Get("Retry-After") == "" {
+		t.Error("Expected Retry-After header on 429")
+	}
+
+	// Spoofed X-Forwarded-For from an untrusted peer is ignored
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "203.0.113.7:5555"
+	req.Header.Set("X-Forwarded-For", "198.51.100.1")
+	w := httptest.NewRecorder()
+	handler(w, req)
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected spoofed request to be limited, got %d", w.Code)
+	}
+}
+
+// TestRateLimitKeys tests client IP resolution and key functions
+func TestRateLimitKeys(t *testing.T) {
+	byIP := KeyByIP("10.0.0.0/8", "localhost")
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		expected   string
+	}{
+		{"direct client", "203.0.113.7:1234", "", "ip:203.0.113.7"},
+		{"untrusted peer ignores header", "203.0.113.7:1234", "198.51.100.1", "ip:203.0.113.7"},
+		{"trusted proxy", "10.0.0.5:1234", "198.51.100.1", "ip:198.51.100.1"},
+		{"proxy chain", "127.0.0.1:1234", "6.6.6.6, 198.51.100.1, 10.1.1.1", "ip:198.51.100.1"},
+		{"ipv6 client", "[2001:db8::1]:1234", "", "ip:2001:db8::1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			if got := byIP(req); got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "203.0.113.7:1234"
+	if got := KeyByUser(byIP)(req); got != "ip:203.0.113.7" {
+		t.Errorf("Expected IP fallback for anonymous user, got %s", got)
+	}
+	if got := KeyByUser(nil)(WithClaims(req, &authutils.Claims{UserID: "u1"})); got != "user:u1" {
+		t.Errorf("Expected user:u1, got %s", got)
+	}
+
+	req.Header.Set("X-API-Key", "secret")
+	if got := KeyByAPIKey("X-API-Key", nil)(req); !strings.HasPrefix(got, "apikey:") || strings.Contains(got, "secret") {
+		t.Errorf("Expected hashed API key, got %s", got)
+	}
+
+	var routeKey string
+	router := NewRouter()
+	router.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		routeKey = KeyComposite(KeyByRoute(), byIP)(r)
+	})
+	routeReq := httptest.NewRequest("GET", "/users/42", nil)
+	routeReq.RemoteAddr = "203.0.113.7:1234"
+	router.ServeHTTP(httptest.NewRecorder(), routeReq)
+	if routeKey != "route:GET /users/{id}|ip:203.0.113.7" {
+		t.Errorf("Unexpected route key %s", routeKey)
+	}
+}
+
 // TestHealthCheck tests health check endpoint
 func TestHealthCheck(t *testing.T) {
 	health := NewHealth()
@@ -479,6 +1082,29 @@ func BenchmarkHandler(b *testing.B) {
 	}
 }
 
+// BenchmarkRouter benchmarks route lookup with a few hundred routes
+func BenchmarkRouter(b *testing.B) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	router := NewRouter()
+	noop := func(w http.ResponseWriter, r *http.Request) {}
+	for i := 0; i < 100; i++ {
+		router.Get(fmt.Sprintf("/api/v1/resource%d", i), noop)
+		router.Get(fmt.Sprintf("/api/v1/resource%d/{id}", i), noop)
+		router.Post(fmt.Sprintf("/api/v1/resource%d/{id}/items", i), noop)
+	}
+
+	req := httptest.NewRequest("GET", "/api/v1/resource99/42", nil)
+	w := httptest.NewRecorder()
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		router.ServeHTTP(w, req)
+	}
+}
+
 // TestFileUpload tests file upload handler
 func TestFileUpload(t *testing.T) {
 	server := NewServer(nil)
@@ -486,7 +1112,7 @@ func TestFileUpload(t *testing.T) {
 
 	// Create test file
 	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
+	writer := fakeMultipart.NewWriter(body)
 	part, _ := writer.CreateFormFile("file", "test.txt")
 	io.WriteString(part, "test file content")
 	writer.Close()
@@ -556,7 +1182,7 @@ func ExampleServer() {
 }
 
 // Helper for multipart writer
-var multipart = struct {
+var fakeMultipart = struct {
 	NewWriter func(io.Writer) *multipartWriter
 }{
 	NewWriter: func(w io.Writer) *multipartWriter {
@@ -577,3 +1203,619 @@ func (mw *multipartWriter) FormDataContentType() string {
 func (mw *multipartWriter) Close() error {
 	return nil
 }
+
+type openAPIAddress struct {
+	City string `json:"city" validate:"required"`
+}
+
+type openAPIUserRequest struct {
+	Name    string          `json:"name" validate:"required|min_len:3|max_len:50"`
+	Email   string          `json:"email" validate:"required|email"`
+	Role    string          `json:"role" validate:"in:admin,user"`
+	Age     *int            `json:"age,omitempty" validate:"min:18"`
+	Address *openAPIAddress `json:"address,omitempty"`
+	Secret  string          `json:"-"`
+}
+
+type openAPIUser struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TestOpenAPI tests OpenAPI document generation from route metadata
+func TestOpenAPI(t *testing.T) {
+	router := NewRouter()
+	handler := func(w http.ResponseWriter, r *http.Request) {}
+
+	router.Post("/users", handler).Named("users.create").Describe(RouteDoc{
+		Summary:  "Create user",
+		Tags:     []string{"users"},
+		Request:  openAPIUserRequest{},
+		Response: openAPIUser{},
+		Secured:  true,
+	})
+	router.Get("/users/{id:[0-9]+}", handler).Describe(RouteDoc{Response: openAPIUser{}})
+	router.Get("/files/*path", handler)
+
+	doc := router.OpenAPI(OpenAPIInfo{Title: "Test API", Version: "2.0.0"})
+
+	// Round-trip through JSON to inspect the document as a client would
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatalf("Failed to encode document: %v", err)
+	}
+	var spec map[string]interface{}
+	if err := json.Unmarshal(raw, &spec); err != nil {
+		t.Fatalf("Failed to decode document: %v", err)
+	}
+
+	if spec["openapi"] != "3.1.0" {
+		t.Errorf("Expected openapi 3.1.0, got %v", spec["openapi"])
+	}
+
+	paths := spec["paths"].(map[string]interface{})
+	for _, path := range []string{"/users", "/users/{id}", "/files/{path}"} {
+		if _, ok := paths[path]; !ok {
+			t.Errorf("Expected path %s in document", path)
+		}
+	}
+
+	create := paths["/users"].(map[string]interface{})["post"].(map[string]interface{})
+	if create["operationId"] != "users.create" {
+		t.Errorf("Expected operationId 'users.create', got %v", create["operationId"])
+	}
+	if create["summary"] != "Create user" {
+		t.Errorf("Expected summary 'Create user', got %v", create["summary"])
+	}
+	if _, ok := create["security"]; !ok {
+		t.Error("Expected security requirement on secured route")
+	}
+	responses := create["responses"].(map[string]interface{})
+	for _, status := range []string{"201", "400", "401"} {
+		if _, ok := responses[status]; !ok {
+			t.Errorf("Expected %s response", status)
+		}
+	}
+
+	get := paths["/users/{id}"].(map[string]interface{})["get"].(map[string]interface{})
+	if get["operationId"] != "getUsersById" {
+		t.Errorf("Expected derived operationId 'getUsersById', got %v", get["operationId"])
+	}
+	param := get["parameters"].([]interface{})[0].(map[string]interface{})
+	if param["name"] != "id" || param["in"] != "path" {
+		t.Errorf("Expected path parameter id, got %v", param)
+	}
+	if pattern := param["schema"].(map[string]interface{})["pattern"]; pattern != "^(?:[0-9]+)$" {
+		t.Errorf("Expected id pattern, got %v", pattern)
+	}
+
+	components := spec["components"].(map[string]interface{})
+	if _, ok := components["securitySchemes"]; !ok {
+		t.Error("Expected bearer security scheme")
+	}
+	schemas := components["schemas"].(map[string]interface{})
+	request, ok := schemas["openAPIUserRequest"].(map[string]interface{})
+	if !ok {
+		t.Fatal("Expected openAPIUserRequest component schema")
+	}
+
+	props := request["properties"].(map[string]interface{})
+	if _, ok := props["Secret"]; ok {
+		t.Error("Expected json:\"-\" field to be skipped")
+	}
+
+	name := props["name"].(map[string]interface{})
+	if name["minLength"] != float64(3) || name["maxLength"] != float64(50) {
+		t.Errorf("Expected name length constraints, got %v", name)
+	}
+	if email := props["email"].(map[string]interface{}); email["format"] != "email" {
+		t.Errorf("Expected email format, got %v", email["format"])
+	}
+	if role := props["role"].(map[string]interface{}); len(role["enum"].([]interface{})) != 2 {
+		t.Errorf("Expected role enum, got %v", role["enum"])
+	}
+	age := props["age"].(map[string]interface{})
+	if age["minimum"] != float64(18) {
+		t.Errorf("Expected age minimum 18, got %v", age["minimum"])
+	}
+	if types, ok := age["type"].([]interface{}); !ok || len(types) != 2 || types[1] != "null" {
+		t.Errorf("Expected nullable integer age, got %v", age["type"])
+	}
+	if ref := props["address"].(map[string]interface{})["$ref"]; ref != "#/components/schemas/openAPIAddress" {
+		t.Errorf("Expected address $ref, got %v", ref)
+	}
+
+	required := request["required"].([]interface{})
+	if len(required) != 2 {
+		t.Errorf("Expected 2 required fields, got %v", required)
+	}
+
+	user := schemas["openAPIUser"].(map[string]interface{})
+	createdAt := user["properties"].(map[string]interface{})["created_at"].(map[string]interface{})
+	if createdAt["format"] != "date-time" {
+		t.Errorf("Expected created_at date-time format, got %v", createdAt["format"])
+	}
+}
+
+// TestOpenAPIEndpoint tests serving the document as JSON and YAML
+func TestOpenAPIEndpoint(t *testing.T) {
+	server := NewServer(nil)
+	server.Get("/ping", func(w http.ResponseWriter, r *http.Request) {}).Describe(RouteDoc{
+		Summary: "Ping: check liveness",
+	})
+	server.ServeOpenAPI("/openapi.json", OpenAPIInfo{Title: "Test API"}).Named("openapi")
+	server.ServeOpenAPI("/v2/openapi.json", OpenAPIInfo{Title: "Test API", Version: "2.0.0"}).Named("openapi.v2")
+	if url, err := server.URL("openapi.v2"); err != nil || url != "/v2/openapi.json" {
+		t.Errorf("Expected /v2/openapi.json, got %s (%v)", url, err)
+	}
+
+	req := httptest.NewRequest("GET", "/openapi.json", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	var spec map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("Failed to decode JSON document: %v", err)
+	}
+	if _, ok := spec["paths"].(map[string]interface{})["/ping"]; !ok {
+		t.Error("Expected /ping in JSON document")
+	}
+
+	for _, target := range []string{"/openapi.yaml", "/openapi.json?format=yaml"} {
+		req := httptest.NewRequest("GET", target, nil)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+
+		if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/yaml") {
+			t.Errorf("%s: expected YAML content type, got %s", target, ct)
+		}
+		body := w.Body.String()
+		if !strings.Contains(body, "openapi: 3.1.0\n") {
+			t.Errorf("%s: expected openapi version line, got:\n%s", target, body)
+		}
+		if !strings.Contains(body, `summary: "Ping: check liveness"`) {
+			t.Errorf("%s: expected quoted summary, got:\n%s", target, body)
+		}
+	}
+}
+
+// TestMarshalYAML tests the YAML encoder
+func TestMarshalYAML(t *testing.T) {
+	out, err := MarshalYAML(map[string]interface{}{
+		"b":     []interface{}{map[string]interface{}{"id": 1, "on": "true"}, "plain"},
+		"a":     "hello world",
+		"empty": map[string]interface{}{},
+	})
+	if err != nil {
+		t.Fatalf("MarshalYAML failed: %v", err)
+	}
+
+	expected := "a: hello world\nb:\n  - id: 1\n    \"on\": \"true\"\n  - plain\nempty: {}\n"
+	if string(out) != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, out)
+	}
+}
+
+// TestPrometheusExposition tests the text exposition format
+func TestPrometheusExposition(t *testing.T) {
+	registry := NewRegistry()
+
+	requests := registry.NewCounter("app_requests_total", "Total requests.\nBy path.", "path")
+	requests.WithLabelValues(`/a"b`).Add(2)
+	requests.WithLabelValues("/").Inc()
+
+	registry.NewGauge("app_queue_depth", "Queue depth.").WithLabelValues().Set(3.5)
+
+	latency := registry.NewHistogram("app_latency_seconds", "Latency.", []float64{0.1, 1}, "op")
+	latency.WithLabelValues("read").Observe(0.05)
+	latency.WithLabelValues("read").Observe(0.5)
+	latency.WithLabelValues("read").Observe(3)
+
+	var b bytes.Buffer
+	if err := registry.WritePrometheus(&b); err != nil {
+		t.Fatalf("WritePrometheus failed: %v", err)
+	}
+
+	expected := `# HELP app_latency_seconds Latency.
+# TYPE app_latency_seconds histogram
+app_latency_seconds_bucket{op="read",le="0.1"} 1
+app_latency_seconds_bucket{op="read",le="1"} 2
+app_latency_seconds_bucket{op="read",le="+Inf"} 3
+app_latency_seconds_sum{op="read"} 3.55
+app_latency_seconds_count{op="read"} 3
+# HELP app_queue_depth Queue depth.
+# TYPE app_queue_depth gauge
+app_queue_depth 3.5
+# HELP app_requests_total Total requests.\nBy path.
+# TYPE app_requests_total counter
+app_requests_total{path="/"} 1
+app_requests_total{path="/a\"b"} 2
+`
+	if b.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, b.String())
+	}
+
+	// Invalid names, duplicates and label mismatches are programming errors
+	for name, fn := range map[string]func(){
+		"invalid name":   func() { registry.NewCounter("app-bad", "") },
+		"duplicate":      func() { registry.NewGauge("app_queue_depth", "") },
+		"reserved label": func() { registry.NewHistogram("app_h", "", nil, "le") },
+		"label count":    func() { requests.WithLabelValues("a", "b") },
+		"bucket order":   func() { registry.NewHistogram("app_h2", "", []float64{1, 0.5}) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: expected panic", name)
+				}
+			}()
+			fn()
+		}()
+	}
+}
+
+// TestMetricsRouteLabels tests that request metrics use route templates
+func TestMetricsRouteLabels(t *testing.T) {
+	config := DefaultConfig()
+	config.EnableLogging = false
+	server := NewServer(config)
+	handler := server.httpServer.Handler
+
+	for _, path := range []string{"/api/v1/users/1", "/api/v1/users/2", "/no/such/path/123"} {
+		req := httptest.NewRequest("GET", path, nil)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	if count := server.metrics.GetRequestCount("GET", "/api/v1/users/{id}"); count != 2 {
+		t.Errorf("Expected 2 requests for route template, got %d", count)
+	}
+	if count := server.metrics.GetRequestCount("GET", "/api/v1/users/1"); count != 0 {
+		t.Errorf("Expected no series for raw path, got %d", count)
+	}
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	if ct := w.Header().Get("Content-Type"); ct != PrometheusContentType {
+		t.Errorf("Expected Prometheus content type, got %s", ct)
+	}
+
+	body := w.Body.String()
+	for _, want := range []string{
+		`http_requests_total{method="GET",route="/api/v1/users/{id}",code="200"} 2`,
+		`http_requests_total{method="GET",route="unmatched",code="404"} 1`,
+		`http_responses_total{class="4xx"} 1`,
+		`http_request_errors_total{method="GET",route="unmatched"} 1`,
+		`http_request_duration_seconds_bucket{method="GET",route="/api/v1/users/{id}",le="+Inf"} 2`,
+		"# TYPE go_goroutines gauge",
+		"go_memstats_alloc_bytes ",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected metrics to contain %q", want)
+		}
+	}
+	if strings.Contains(body, "/no/such/path") {
+		t.Error("Expected raw paths to stay out of labels")
+	}
+
+	// The JSON endpoint negotiates the Prometheus format from Accept
+	req = httptest.NewRequest("GET", "/api/v1/metrics", nil)
+	req.Header.Set("Accept", "text/plain;version=0.0.4")
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain") {
+		t.Errorf("Expected text/plain for Prometheus Accept header, got %s", w.Header().Get("Content-Type"))
+	}
+}
+
+// TestTracingMiddleware tests server spans and trace context propagation
+func TestTracingMiddleware(t *testing.T) {
+	exporter := tracing.NewInMemoryExporter()
+	tracer := tracing.NewTracer(tracing.Options{Exporter: exporter})
+	defer tracer.Shutdown(context.Background())
+
+	config := DefaultConfig()
+	config.EnableLogging = false
+	server := NewServer(config)
+	server.UseTracing(tracer)
+
+	var handlerTraceID string
+	server.Get("/orders/{id}", func(w http.ResponseWriter, r *http.Request) {
+		handlerTraceID = TraceIDFromRequest(r)
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest("GET", "/orders/42", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	server.httpServer.Handler.ServeHTTP(httptest.NewRecorder(), req)
+	tracer.Flush(context.Background())
+
+	if handlerTraceID != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("Expected handler to see the incoming trace id, got %q", handlerTraceID)
+	}
+
+	spans := exporter.Spans()
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name != "GET /orders/{id}" {
+		t.Errorf("Expected span named after route template, got %q", span.Name)
+	}
+	if span.Kind != tracing.SpanKindServer || span.ParentSpanID.String() != "00f067aa0ba902b7" {
+		t.Errorf("Expected server span continuing the remote parent, got %+v", span)
+	}
+	if span.Attributes["http.route"] != "/orders/{id}" || span.Attributes["http.response.status_code"] != 500 {
+		t.Errorf("Unexpected attributes: %v", span.Attributes)
+	}
+	if span.Status != tracing.StatusError {
+		t.Error("Expected 5xx response to mark the span as failed")
+	}
+
+	// Without traceparent a new trace is started
+	exporter.Reset()
+	server.httpServer.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/orders/7", nil))
+	tracer.Flush(context.Background())
+	if spans := exporter.Spans(); len(spans) != 1 || spans[0].ParentSpanID.IsValid() {
+		t.Error("Expected a new root span")
+	}
+}
+
+func TestShutdownClosesAsyncLogger(t *testing.T) {
+	var buf bytes.Buffer
+	al := logger.NewAsyncLogger(logger.NewWithConfig(logger.Config{Level: logger.INFO, Output: &buf}), 100)
+
+	config := DefaultConfig()
+	config.Port = 0
+	config.EnableLogging = false
+	config.EnableMetrics = false
+	config.EnableHealth = false
+	server := NewServer(config)
+	server.UseAsyncLogger(al)
+
+	for i := 0; i < 10; i++ {
+		al.Infof("request %d", i)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		t.Fatalf("Failed to shutdown: %v", err)
+	}
+
+	if got := strings.Count(buf.String(), "request"); got != 10 {
+		t.Errorf("Expected 10 log entries written on shutdown, got %d", got)
+	}
+
+	// Logs are flushed even when the shutdown deadline has passed
+	var late bytes.Buffer
+	al = logger.NewAsyncLogger(logger.NewWithConfig(logger.Config{Level: logger.INFO, Output: &late}), 100)
+	server = NewServer(config)
+	server.UseAsyncLogger(al)
+	for i := 0; i < 10; i++ {
+		al.Infof("request %d", i)
+	}
+
+	expired, cancelExpired := context.WithCancel(context.Background())
+	cancelExpired()
+	server.Shutdown(expired)
+	if got := strings.Count(late.String(), "request"); got != 10 {
+		t.Errorf("Expected 10 log entries written after an expired shutdown, got %d", got)
+	}
+}
+
+func TestServeLogLevels(t *testing.T) {
+	var buf bytes.Buffer
+	base := logger.NewWithConfig(logger.Config{Level: logger.INFO, Output: &buf})
+	repoLogger := base.Named("loglevels-test").Named("repo")
+
+	server := NewServer(nil)
+	server.ServeLogLevels("/admin/loggers")
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("PUT", "/admin/loggers/loglevels-test", `{"level":"debug","ttl":"50ms"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var level logger.NamedLevel
+	if err := json.Unmarshal(w.Body.Bytes(), &level); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if level.Level != "DEBUG" || !level.Explicit || level.ExpiresAt == nil {
+		t.Errorf("Expected explicit temporary DEBUG level, got %+v", level)
+	}
+
+	repoLogger.Debug("inherited debug")
+	if !strings.Contains(buf.String(), "inherited debug") {
+		t.Error("Expected child logger to inherit DEBUG level")
+	}
+
+	w = do("GET", "/admin/loggers", "")
+	if !strings.Contains(w.Body.String(), `"name":"loglevels-test.repo"`) ||
+		!strings.Contains(w.Body.String(), `"inherited_from":"loglevels-test"`) {
+		t.Errorf("Expected child logger in listing, got %s", w.Body.String())
+	}
+
+	if w := do("PUT", "/admin/loggers/loglevels-test", `{"level":"verbose"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for invalid level, got %d", w.Code)
+	}
+
+	// The temporary level reverts after the TTL
+	time.Sleep(100 * time.Millisecond)
+	buf.Reset()
+	repoLogger.Debug("after revert")
+	if buf.Len() != 0 {
+		t.Errorf("Expected DEBUG to be disabled after TTL, got %q", buf.String())
+	}
+
+	do("PUT", "/admin/loggers/loglevels-test.repo", `{"level":"error"}`)
+	if repoLogger.Enabled(logger.WARN) {
+		t.Error("Expected WARN to be disabled after setting ERROR")
+	}
+	do("DELETE", "/admin/loggers/loglevels-test.repo", "")
+	if !repoLogger.Enabled(logger.WARN) {
+		t.Error("Expected configured INFO level after reset")
+	}
+}
+
+// TestBind tests decoding and validating requests
+func TestBind(t *testing.T) {
+	type orderRequest struct {
+		ID       int      `path:"id"`
+		Expand   bool     `query:"expand"`
+		Tags     []string `query:"tag"`
+		PageSize int      `query:"page_size" default:"20" validate:"max:50"`
+		Email    string   `json:"email" validate:"required|email"`
+		Quantity int      `json:"quantity" default:"1" validate:"min:1"`
+	}
+
+	var got orderRequest
+	server := NewServer(nil)
+	server.Post("/orders/{id}", func(w http.ResponseWriter, r *http.Request) {
+		got = orderRequest{}
+		if !Bind(w, r, &got, BindOptions{MaxBodySize: 256, DisallowUnknownFields: true}) {
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	do := func(path, contentType, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("Accept-Language", "fr")
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+	fieldCodes := func(w *httptest.ResponseRecorder) map[string]string {
+		var resp struct {
+			Validation []struct {
+				Field string `json:"field"`
+				Code  string `json:"code"`
+			} `json:"validation"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		codes := make(map[string]string)
+		for _, v := range resp.Validation {
+			codes[v.Field] = v.Code
+		}
+		return codes
+	}
+
+	w := do("/orders/42?expand=true&tag=a&tag=b", "application/json", `{"email":"a@example.com"}`)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d: %s", w.Code, w.Body.String())
+	}
+	if got.ID != 42 || !got.Expand || len(got.Tags) != 2 || got.PageSize != 20 || got.Quantity != 1 || got.Email != "a@example.com" {
+		t.Errorf("Expected bound request, got %+v", got)
+	}
+
+	w = do("/orders/42?page_size=100", "application/json", `{"email":"invalid","quantity":0}`)
+	codes := fieldCodes(w)
+	if w.Code != http.StatusBadRequest || codes["email"] != "email" || codes["page_size"] != "max" || codes["quantity"] != "min" {
+		t.Errorf("Expected validation errors, got %d: %s", w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), "Doit être une adresse e-mail valide") {
+		t.Errorf("Expected French messages, got %s", w.Body.String())
+	}
+
+	if codes := fieldCodes(do("/orders/abc", "application/json", `{"email":"a@example.com"}`)); codes["id"] != "integer" {
+		t.Errorf("Expected integer error for id, got %v", codes)
+	}
+	if codes := fieldCodes(do("/orders/1", "application/json", `{"email":"a@example.com","quantity":"x"}`)); codes["quantity"] != "integer" {
+		t.Errorf("Expected integer error for quantity, got %v", codes)
+	}
+	if codes := fieldCodes(do("/orders/1", "application/json", `{"email":"a@example.com","admin":true,"role":"x"}`)); codes["admin"] != "unknown_field" || codes["role"] != "unknown_field" {
+		t.Errorf("Expected unknown field errors, got %v", codes)
+	}
+	if codes := fieldCodes(do("/orders/1", "application/json", `{"EMAIL":"a@example.com"}`)); len(codes) != 0 {
+		t.Errorf("Expected keys to match case-insensitively, got %v", codes)
+	}
+
+	type shipment struct {
+		Address struct {
+			City string `json:"city"`
+		} `json:"address"`
+		SentAt time.Time `json:"sent_at"`
+	}
+	req := httptest.NewRequest("POST", "/shipments", strings.NewReader(`{"address":{"city":"Oslo","zip":"0150"},"sent_at":"2024-01-02T03:04:05Z"}`))
+	err := DecodeRequest(httptest.NewRecorder(), req, &shipment{}, BindOptions{DisallowUnknownFields: true})
+	var bindErr *BindError
+	if !errors.As(err, &bindErr) || len(bindErr.Fields) != 1 || bindErr.Fields[0].Field != "address.zip" {
+		t.Errorf("Expected unknown nested field address.zip, got %v", err)
+	}
+
+	if w := do("/orders/1", "application/json", `{"email":"`+strings.Repeat("a", 300)+`"}`); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status 413, got %d", w.Code)
+	}
+	if w := do("/orders/1", "application/json", `{"email":`); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for malformed JSON, got %d", w.Code)
+	}
+	if w := do("/orders/1", "text/csv", "a,b"); w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected status 415, got %d", w.Code)
+	}
+}
+
+// TestBindForm tests binding form and multipart bodies
+func TestBindForm(t *testing.T) {
+	type profileRequest struct {
+		Name   string   `form:"name" validate:"required"`
+		Age    int      `form:"age" validate:"min:18"`
+		Skills []string `form:"skill"`
+	}
+
+	req := httptest.NewRequest("POST", "/profile", strings.NewReader("name=Ann&age=30&skill=go&skill=sql"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	var profile profileRequest
+	if err := DecodeRequest(httptest.NewRecorder(), req, &profile); err != nil {
+		t.Fatalf("Expected form to bind, got %v", err)
+	}
+	if profile.Name != "Ann" || profile.Age != 30 || len(profile.Skills) != 2 {
+		t.Errorf("Expected bound form, got %+v", profile)
+	}
+
+	req = httptest.NewRequest("POST", "/profile", strings.NewReader("age=12&role=admin"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	err := DecodeRequest(httptest.NewRecorder(), req, &profileRequest{}, BindOptions{DisallowUnknownFields: true})
+	var bindErr *BindError
+	if !errors.As(err, &bindErr) || len(bindErr.Fields) != 1 || bindErr.Fields[0].Code != "unknown_field" {
+		t.Errorf("Expected unknown field error, got %v", err)
+	}
+
+	req = httptest.NewRequest("POST", "/profile", strings.NewReader("age=12"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if err := DecodeRequest(httptest.NewRecorder(), req, &profileRequest{}); !errors.As(err, &bindErr) || len(bindErr.Fields) != 2 {
+		t.Errorf("Expected required and min errors, got %v", err)
+	}
+
+	type uploadRequest struct {
+		Title string                `form:"title" validate:"required"`
+		File  *multipart.FileHeader `form:"file"`
+	}
+	body := "--XYZ\r\n" +
+		"Content-Disposition: form-data; name=\"title\"\r\n\r\nReport\r\n" +
+		"--XYZ\r\n" +
+		"Content-Disposition: form-data; name=\"file\"; filename=\"report.txt\"\r\n" +
+		"Content-Type: text/plain\r\n\r\nhello\r\n" +
+		"--XYZ--\r\n"
+	req = httptest.NewRequest("POST", "/upload", strings.NewReader(body))
+	req.Header.Set("Content-Type", "multipart/form-data; boundary=XYZ")
+	var upload uploadRequest
+	if err := DecodeRequest(httptest.NewRecorder(), req, &upload); err != nil {
+		t.Fatalf("Expected multipart to bind, got %v", err)
+	}
+	if upload.Title != "Report" || upload.File == nil || upload.File.Filename != "report.txt" {
+		t.Errorf("Expected bound upload, got %+v", upload)
+	}
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/selanim/sego/tracing"
+)
+
+// TracingMiddleware starts a server span per request, continuing the trace from
+// incoming traceparent/tracestate headers. The span is named after the matched
+// route template once routing has run.
+func TracingMiddleware(tracer *tracing.Tracer) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			ctx := tracing.Extract(r.Context(), r.Header)
+			ctx, span := tracer.Start(ctx, r.Method, tracing.SpanKindServer)
+			defer span.End()
+
+			span.SetAttributes(map[string]interface{}{
+				"http.request.method": r.Method,
+				"url.path":            r.URL.Path,
+				"url.scheme":          requestScheme(r),
+				"server.address":      r.Host,
+				"client.address":      clientIP(r, nil),
+				"user_agent.original": r.UserAgent(),
+			})
+
+			r = r.WithContext(ctx)
+			route := RoutePattern(r)
+			var recorded *string
+			if route == "" {
+				r, recorded = withRouteRecorder(r)
+			}
+
+			rw := &metricsResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			next(rw, r)
+
+			if recorded != nil {
+				route = *recorded
+			}
+			if route != "" {
+				span.SetName(r.Method + " " + route)
+				span.SetAttribute("http.route", route)
+			}
+
+			span.SetAttribute("http.response.status_code", rw.statusCode)
+			if rw.statusCode >= 500 {
+				span.SetStatus(tracing.StatusError, fmt.Sprintf("HTTP %d", rw.statusCode))
+			}
+		}
+	}
+}
+
+// UseTracing adds TracingMiddleware to the server and flushes the tracer on Shutdown
+func (s *Server) UseTracing(tracer *tracing.Tracer) {
+	s.tracer = tracer
+	s.middleware.Use(TracingMiddleware(tracer))
+}
+
+// TraceIDFromRequest returns the trace ID of the request, or an empty string
+func TraceIDFromRequest(r *http.Request) string {
+	sc := tracing.SpanContextFromContext(r.Context())
+	if !sc.IsValid() {
+		return ""
+	}
+	return sc.TraceID.String()
+}
+
+func requestScheme(r *http.Request) string {
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
@@ -0,0 +1,289 @@
+package server
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// nodeKind is the kind of a radix tree node
+type nodeKind uint8
+
+const (
+	staticNode nodeKind = iota
+	paramNode
+	catchAllNode
+)
+
+// node is a radix tree node. Static nodes hold a shared path prefix; param
+// nodes match one path segment, optionally constrained by a regexp; catch-all
+// nodes match the rest of the path.
+type node struct {
+	kind     nodeKind
+	prefix   string
+	indices  []byte
+	static   []*node
+	params   []*node
+	catchAll *node
+
+	paramName string
+	pattern   string
+	re        *regexp.Regexp
+
+	routes map[string]*Route
+}
+
+// Param is a single path parameter
+type Param struct {
+	Key   string
+	Value string
+}
+
+// Params holds the path parameters of a matched route in pattern order
+type Params []Param
+
+// Get returns the value of the named parameter
+func (ps Params) Get(name string) (string, bool) {
+	for _, p := range ps {
+		if p.Key == name {
+			return p.Value, true
+		}
+	}
+	return "", false
+}
+
+// token is a piece of a route pattern
+type token struct {
+	kind    nodeKind
+	value   string // static text or param name
+	pattern string // regexp source for constrained params
+}
+
+// parsePattern splits a route pattern such as /users/{id:[0-9]+}/files/*path into tokens
+func parsePattern(path string) ([]token, error) {
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("route pattern %q must start with '/'", path)
+	}
+
+	var tokens []token
+	names := make(map[string]bool)
+	var static strings.Builder
+
+	flush := func() {
+		if static.Len() > 0 {
+			tokens = append(tokens, token{kind: staticNode, value: static.String()})
+			static.Reset()
+		}
+	}
+
+	for i := 0; i < len(path); i++ {
+		switch c := path[i]; c {
+		case '{':
+			if path[i-1] != '/' {
+				return nil, fmt.Errorf("route pattern %q: parameter must start a segment", path)
+			}
+			end, depth := -1, 0
+			for j := i; j < len(path) && end < 0; j++ {
+				switch path[j] {
+				case '{':
+					depth++
+				case '}':
+					depth--
+					if depth == 0 {
+						end = j
+					}
+				}
+			}
+			if end < 0 {
+				return nil, fmt.Errorf("route pattern %q: unclosed '{'", path)
+			}
+			if end+1 < len(path) && path[end+1] != '/' {
+				return nil, fmt.Errorf("route pattern %q: parameter must end a segment", path)
+			}
+
+			name, pattern, _ := strings.Cut(path[i+1:end], ":")
+			if name == "" {
+				return nil, fmt.Errorf("route pattern %q: empty parameter name", path)
+			}
+			if names[name] {
+				return nil, fmt.Errorf("route pattern %q: duplicate parameter %q", path, name)
+			}
+			names[name] = true
+
+			flush()
+			tokens = append(tokens, token{kind: paramNode, value: name, pattern: pattern})
+			i = end
+
+		case '*':
+			if path[i-1] != '/' {
+				return nil, fmt.Errorf("route pattern %q: wildcard must start a segment", path)
+			}
+			name := path[i+1:]
+			if strings.ContainsAny(name, "/{}*") {
+				return nil, fmt.Errorf("route pattern %q: wildcard must be the last segment", path)
+			}
+			if name == "" {
+				name = "*"
+			}
+			if names[name] {
+				return nil, fmt.Errorf("route pattern %q: duplicate parameter %q", path, name)
+			}
+
+			flush()
+			tokens = append(tokens, token{kind: catchAllNode, value: name})
+			i = len(path)
+
+		default:
+			static.WriteByte(c)
+		}
+	}
+	flush()
+
+	return tokens, nil
+}
+
+// insert adds a route reached by tokens below n
+func (n *node) insert(tokens []token, method string, route *Route) error {
+	if len(tokens) == 0 {
+		if n.routes == nil {
+			n.routes = make(map[string]*Route)
+		}
+		n.routes[method] = route
+		return nil
+	}
+
+	tok, rest := tokens[0], tokens[1:]
+	switch tok.kind {
+	case paramNode:
+		for _, child := range n.params {
+			if child.pattern != tok.pattern {
+				continue
+			}
+			if child.paramName != tok.value {
+				return fmt.Errorf("parameter {%s} conflicts with existing {%s} in %s", tok.value, child.paramName, route.Path)
+			}
+			return child.insert(rest, method, route)
+		}
+
+		child := &node{kind: paramNode, paramName: tok.value, pattern: tok.pattern}
+		if tok.pattern != "" {
+			re, err := regexp.Compile("^(?:" + tok.pattern + ")$")
+			if err != nil {
+				return fmt.Errorf("invalid pattern for parameter {%s}: %w", tok.value, err)
+			}
+			child.re = re
+		}
+		// Constrained params are tried before unconstrained ones
+		if child.re != nil {
+			n.params = append([]*node{child}, n.params...)
+		} else {
+			n.params = append(n.params, child)
+		}
+		return child.insert(rest, method, route)
+
+	case catchAllNode:
+		if n.catchAll == nil {
+			n.catchAll = &node{kind: catchAllNode, paramName: tok.value}
+		} else if n.catchAll.paramName != tok.value {
+			return fmt.Errorf("wildcard *%s conflicts with existing *%s in %s", tok.value, n.catchAll.paramName, route.Path)
+		}
+		return n.catchAll.insert(rest, method, route)
+	}
+
+	return n.insertStatic(tok.value, rest, method, route)
+}
+
+// insertStatic inserts static text, splitting edges on the longest common prefix
+func (n *node) insertStatic(text string, rest []token, method string, route *Route) error {
+	for i, c := range n.indices {
+		if c != text[0] {
+			continue
+		}
+
+		child := n.static[i]
+		common := longestCommonPrefix(text, child.prefix)
+		if common < len(child.prefix) {
+			split := &node{
+				prefix:  child.prefix[:common],
+				indices: []byte{child.prefix[common]},
+				static:  []*node{child},
+			}
+			child.prefix = child.prefix[common:]
+			n.static[i] = split
+			child = split
+		}
+
+		if common == len(text) {
+			return child.insert(rest, method, route)
+		}
+		return child.insertStatic(text[common:], rest, method, route)
+	}
+
+	child := &node{prefix: text}
+	n.indices = append(n.indices, text[0])
+	n.static = append(n.static, child)
+	return child.insert(rest, method, route)
+}
+
+// match finds the first node below n matching path that satisfies accept.
+// Static edges take precedence over params, and params over catch-alls.
+func (n *node) match(path string, params *Params, accept func(*node) bool) *node {
+	if path == "" && accept(n) {
+		return n
+	}
+
+	if path != "" {
+		for i, c := range n.indices {
+			if c != path[0] {
+				continue
+			}
+			child := n.static[i]
+			if strings.HasPrefix(path, child.prefix) {
+				if found := child.match(path[len(child.prefix):], params, accept); found != nil {
+					return found
+				}
+			}
+			break
+		}
+
+		end := strings.IndexByte(path, '/')
+		if end < 0 {
+			end = len(path)
+		}
+		if segment := path[:end]; segment != "" {
+			for _, child := range n.params {
+				if child.re != nil && !child.re.MatchString(segment) {
+					continue
+				}
+				*params = append(*params, Param{Key: child.paramName, Value: segment})
+				if found := child.match(path[end:], params, accept); found != nil {
+					return found
+				}
+				*params = (*params)[:len(*params)-1]
+			}
+		}
+	}
+
+	if n.catchAll != nil && accept(n.catchAll) {
+		*params = append(*params, Param{Key: n.catchAll.paramName, Value: path})
+		return n.catchAll
+	}
+
+	return nil
+}
+
+// handlerFor returns the route registered for method, falling back to Any routes
+func (n *node) handlerFor(method string) *Route {
+	if route, ok := n.routes[method]; ok {
+		return route
+	}
+	return n.routes["*"]
+}
+
+func longestCommonPrefix(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
@@ -0,0 +1,383 @@
+package timeutils
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSchedule is a parsed cron expression.
+//
+// Expressions have five fields (minute hour day-of-month month day-of-week)
+// or six when a leading seconds field is given. Fields accept "*", "?",
+// single values, ranges ("1-5"), steps ("*/15", "10-40/5"), lists ("1,15")
+// and month/weekday names ("JAN", "MON-FRI"). The macros @yearly, @annually,
+// @monthly, @weekly, @daily, @midnight, @hourly and "@every <duration>" are
+// also supported, as is a "CRON_TZ=<zone>" prefix.
+//
+// Schedules are evaluated in wall-clock time of their location. A time that
+// falls into a DST gap fires once when the gap ends; a time that occurs twice
+// when clocks go back fires only on its first occurrence.
+type CronSchedule struct {
+	expr     string
+	second   uint64
+	minute   uint64
+	hour     uint64
+	dom      uint64
+	month    uint64
+	dow      uint64
+	domStar  bool
+	dowStar  bool
+	every    time.Duration
+	location *time.Location
+}
+
+// cronField describes the bounds and names of one cron field
+type cronField struct {
+	name  string
+	min   int
+	max   int
+	names map[string]int
+}
+
+var (
+	secondField = cronField{name: "second", min: 0, max: 59}
+	minuteField = cronField{name: "minute", min: 0, max: 59}
+	hourField   = cronField{name: "hour", min: 0, max: 23}
+	domField    = cronField{name: "day of month", min: 1, max: 31}
+	monthField  = cronField{name: "month", min: 1, max: 12, names: map[string]int{
+		"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+		"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12,
+	}}
+	dowField = cronField{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6,
+	}}
+)
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 0 1 1 *",
+	"@annually": "0 0 0 1 1 *",
+	"@monthly":  "0 0 0 1 * *",
+	"@weekly":   "0 0 0 * * 0",
+	"@daily":    "0 0 0 * * *",
+	"@midnight": "0 0 0 * * *",
+	"@hourly":   "0 0 * * * *",
+}
+
+// ErrInvalidCron is returned for malformed cron expressions
+var ErrInvalidCron = errors.New("invalid cron expression")
+
+// ParseCron parses a cron expression in the TimeUtils location
+func (tu *TimeUtils) ParseCron(expr string) (*CronSchedule, error) {
+	tu.mu.RLock()
+	loc := tu.location
+	tu.mu.RUnlock()
+
+	return ParseCronIn(expr, loc)
+}
+
+// NextCronTime calculates next time based on cron expression
+func (tu *TimeUtils) NextCronTime(expr string, fromTime ...time.Time) (time.Time, error) {
+	schedule, err := tu.ParseCron(expr)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	from := tu.Now()
+	if len(fromTime) > 0 {
+		from = fromTime[0]
+	}
+
+	next := schedule.Next(from)
+	if next.IsZero() {
+		return time.Time{}, fmt.Errorf("cron expression %q never fires", expr)
+	}
+	return next, nil
+}
+
+// ParseCronIn parses a cron expression evaluated in the given location
+func ParseCronIn(expr string, loc *time.Location) (*CronSchedule, error) {
+	if loc == nil {
+		loc = time.Local
+	}
+
+	spec := strings.TrimSpace(expr)
+	if strings.HasPrefix(spec, "CRON_TZ=") || strings.HasPrefix(spec, "TZ=") {
+		tz, rest, _ := strings.Cut(spec, " ")
+		_, name, _ := strings.Cut(tz, "=")
+		l, err := LoadLocation(name)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCron, err)
+		}
+		loc = l
+		spec = strings.TrimSpace(rest)
+	}
+
+	schedule := &CronSchedule{expr: expr, location: loc}
+
+	if strings.HasPrefix(spec, "@every") {
+		d, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(spec, "@every")))
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("%w: bad @every duration in %q", ErrInvalidCron, expr)
+		}
+		schedule.every = d
+		return schedule, nil
+	}
+
+	if strings.HasPrefix(spec, "@") {
+		macro, ok := cronMacros[strings.ToLower(spec)]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown macro %q", ErrInvalidCron, spec)
+		}
+		spec = macro
+	}
+
+	fields := strings.Fields(spec)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, fmt.Errorf("%w: expected 5 or 6 fields, got %d", ErrInvalidCron, len(fields))
+	}
+
+	var err error
+	if schedule.second, err = parseCronField(fields[0], secondField); err != nil {
+		return nil, err
+	}
+	if schedule.minute, err = parseCronField(fields[1], minuteField); err != nil {
+		return nil, err
+	}
+	if schedule.hour, err = parseCronField(fields[2], hourField); err != nil {
+		return nil, err
+	}
+	if schedule.dom, err = parseCronField(fields[3], domField); err != nil {
+		return nil, err
+	}
+	if schedule.month, err = parseCronField(fields[4], monthField); err != nil {
+		return nil, err
+	}
+	if schedule.dow, err = parseCronField(fields[5], dowField); err != nil {
+		return nil, err
+	}
+
+	// 7 is an alias for Sunday
+	if schedule.dow&(1<<7) != 0 {
+		schedule.dow = schedule.dow&^(1<<7) | 1
+	}
+
+	schedule.domStar = isCronWildcard(fields[3])
+	schedule.dowStar = isCronWildcard(fields[5])
+
+	return schedule, nil
+}
+
+// String returns the original expression
+func (s *CronSchedule) String() string {
+	return s.expr
+}
+
+// Location returns the location the schedule is evaluated in
+func (s *CronSchedule) Location() *time.Location {
+	return s.location
+}
+
+// Next returns the first activation time strictly after from,
+// or the zero time if the schedule never fires within five years.
+func (s *CronSchedule) Next(from time.Time) time.Time {
+	if s.every > 0 {
+		return from.Add(s.every)
+	}
+
+	from = from.In(s.location)
+	civil := toCivil(from)
+
+	for {
+		civil = s.nextCivil(civil)
+		if civil.IsZero() {
+			return time.Time{}
+		}
+
+		next := s.fromCivil(civil)
+		if next.After(from) {
+			return next
+		}
+	}
+}
+
+// nextCivil finds the next matching wall-clock time after c.
+// Wall-clock times are represented as UTC so there are no DST transitions.
+func (s *CronSchedule) nextCivil(c time.Time) time.Time {
+	t := c.Add(time.Second).Truncate(time.Second)
+	yearLimit := t.Year() + 5
+
+wrap:
+	if t.Year() > yearLimit {
+		return time.Time{}
+	}
+
+	for s.month&(1<<uint(t.Month())) == 0 {
+		t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		if t.Year() > yearLimit {
+			return time.Time{}
+		}
+	}
+
+	for !s.dayMatches(t) {
+		t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+		if t.Day() == 1 {
+			goto wrap
+		}
+	}
+
+	for s.hour&(1<<uint(t.Hour())) == 0 {
+		t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, time.UTC)
+		if t.Hour() == 0 {
+			goto wrap
+		}
+	}
+
+	for s.minute&(1<<uint(t.Minute())) == 0 {
+		t = t.Truncate(time.Minute).Add(time.Minute)
+		if t.Minute() == 0 {
+			goto wrap
+		}
+	}
+
+	for s.second&(1<<uint(t.Second())) == 0 {
+		t = t.Add(time.Second)
+		if t.Second() == 0 {
+			goto wrap
+		}
+	}
+
+	return t
+}
+
+// dayMatches applies the standard cron rule: when both day fields are
+// restricted, a day matches if either of them does.
+func (s *CronSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// fromCivil maps a wall-clock time to an instant in the schedule location
+func (s *CronSchedule) fromCivil(c time.Time) time.Time {
+	t := time.Date(c.Year(), c.Month(), c.Day(), c.Hour(), c.Minute(), c.Second(), 0, s.location)
+	wall := toCivil(t)
+
+	if !wall.Equal(c) {
+		// c falls into a DST gap: fire when the gap ends
+		start, end := t.ZoneBounds()
+		if wall.After(c) {
+			return start
+		}
+		return end
+	}
+
+	// c may occur twice when clocks go back: prefer the first occurrence
+	start, _ := t.ZoneBounds()
+	if !start.IsZero() {
+		_, offset := t.Zone()
+		_, prevOffset := start.Add(-time.Nanosecond).Zone()
+		if prevOffset > offset {
+			earlier := t.Add(-time.Duration(prevOffset-offset) * time.Second)
+			if toCivil(earlier).Equal(c) {
+				return earlier
+			}
+		}
+	}
+
+	return t
+}
+
+// toCivil returns the wall-clock time of t expressed in UTC
+func toCivil(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
+
+// isCronWildcard reports whether a day field places no restriction
+func isCronWildcard(field string) bool {
+	return field == "*" || field == "?"
+}
+
+// parseCronField parses a comma-separated cron field into a bitset
+func parseCronField(field string, spec cronField) (uint64, error) {
+	var bits uint64
+
+	for _, part := range strings.Split(field, ",") {
+		if part == "" {
+			return 0, fmt.Errorf("%w: empty %s value", ErrInvalidCron, spec.name)
+		}
+
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("%w: bad %s step %q", ErrInvalidCron, spec.name, stepPart)
+			}
+			step = n
+		}
+
+		var low, high int
+		switch {
+		case rangePart == "*" || rangePart == "?":
+			low, high = spec.min, spec.max
+			if spec.name == dowField.name {
+				high = 6
+			}
+		case strings.Contains(rangePart, "-"):
+			lowPart, highPart, _ := strings.Cut(rangePart, "-")
+			var err error
+			if low, err = parseCronValue(lowPart, spec); err != nil {
+				return 0, err
+			}
+			if high, err = parseCronValue(highPart, spec); err != nil {
+				return 0, err
+			}
+		default:
+			value, err := parseCronValue(rangePart, spec)
+			if err != nil {
+				return 0, err
+			}
+			low, high = value, value
+			if hasStep {
+				high = spec.max
+			}
+		}
+
+		if low > high {
+			return 0, fmt.Errorf("%w: %s range %d-%d is reversed", ErrInvalidCron, spec.name, low, high)
+		}
+
+		for v := low; v <= high; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+
+	return bits, nil
+}
+
+// parseCronValue parses a single numeric or named value
+func parseCronValue(value string, spec cronField) (int, error) {
+	if n, ok := spec.names[strings.ToUpper(value)]; ok {
+		return n, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%w: bad %s value %q", ErrInvalidCron, spec.name, value)
+	}
+	if n < spec.min || n > spec.max {
+		return 0, fmt.Errorf("%w: %s value %d out of range %d-%d", ErrInvalidCron, spec.name, n, spec.min, spec.max)
+	}
+	return n, nil
+}
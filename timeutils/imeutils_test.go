@@ -2,7 +2,10 @@ package timeutils
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -330,6 +333,211 @@ func TestIsLeapYear(t *testing.T) {
 	}
 }
 
+func TestNextCronTime(t *testing.T) {
+	tu := New(time.UTC)
+	from := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC) // Monday
+
+	testCases := []struct {
+		name     string
+		expr     string
+		expected time.Time
+	}{
+		{"Every minute", "* * * * *", time.Date(2024, 1, 15, 10, 31, 0, 0, time.UTC)},
+		{"Step minutes", "*/20 * * * *", time.Date(2024, 1, 15, 10, 40, 0, 0, time.UTC)},
+		{"Range with step", "10-40/15 * * * *", time.Date(2024, 1, 15, 10, 40, 0, 0, time.UTC)},
+		{"List of hours", "0 9,17 * * *", time.Date(2024, 1, 15, 17, 0, 0, 0, time.UTC)},
+		{"Weekdays by name", "0 9 * * MON-FRI", time.Date(2024, 1, 16, 9, 0, 0, 0, time.UTC)},
+		{"Sunday as 7", "0 0 * * 7", time.Date(2024, 1, 21, 0, 0, 0, 0, time.UTC)},
+		{"Named month", "0 0 1 MAR *", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"Day of month or weekday", "0 0 20 * FRI", time.Date(2024, 1, 19, 0, 0, 0, 0, time.UTC)},
+		{"Leap day", "0 0 29 2 *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"Seconds field", "*/15 * * * * *", time.Date(2024, 1, 15, 10, 30, 15, 0, time.UTC)},
+		{"Daily macro", "@daily", time.Date(2024, 1, 16, 0, 0, 0, 0, time.UTC)},
+		{"Hourly macro", "@hourly", time.Date(2024, 1, 15, 11, 0, 0, 0, time.UTC)},
+		{"Yearly macro", "@yearly", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"Every macro", "@every 5m", time.Date(2024, 1, 15, 10, 35, 0, 0, time.UTC)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			next, err := tu.NextCronTime(tc.expr, from)
+			if err != nil {
+				t.Fatalf("NextCronTime(%q) error: %v", tc.expr, err)
+			}
+			if !next.Equal(tc.expected) {
+				t.Errorf("NextCronTime(%q) = %v, expected %v", tc.expr, next, tc.expected)
+			}
+		})
+	}
+
+	invalid := []string{"", "* * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "*/0 * * * *", "5-1 * * * *", "@never", "@every -1s", "* * * FOO *"}
+	for _, expr := range invalid {
+		if _, err := tu.ParseCron(expr); err == nil {
+			t.Errorf("ParseCron(%q) expected error", expr)
+		}
+	}
+
+	if _, err := tu.NextCronTime("0 0 30 2 *", from); err == nil {
+		t.Error("Expected error for schedule that never fires")
+	}
+}
+
+func TestNextCronTimeLocation(t *testing.T) {
+	tokyo, err := LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+
+	tu := New(tokyo)
+	from := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC) // 09:00 in Tokyo
+
+	next, err := tu.NextCronTime("0 9 * * *", from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := time.Date(2024, 1, 16, 9, 0, 0, 0, tokyo)
+	if !next.Equal(expected) {
+		t.Errorf("Expected %v, got %v", expected, next)
+	}
+
+	// CRON_TZ overrides the TimeUtils location
+	next, err = tu.NextCronTime("CRON_TZ=UTC 0 9 * * *", from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !next.Equal(time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected 09:00 UTC, got %v", next)
+	}
+}
+
+func TestNextCronTimeDST(t *testing.T) {
+	ny, err := LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+	tu := New(ny)
+
+	// 2024-03-10 02:30 does not exist; it fires when the gap ends at 03:00 EDT
+	next, err := tu.NextCronTime("30 2 * * *", time.Date(2024, 3, 10, 0, 0, 0, 0, ny))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := time.Date(2024, 3, 10, 3, 0, 0, 0, ny); !next.Equal(expected) {
+		t.Errorf("Spring forward: expected %v, got %v", expected, next)
+	}
+
+	// 2024-11-03 01:30 happens twice; it fires only on the first occurrence
+	first, err := tu.NextCronTime("30 1 * * *", time.Date(2024, 11, 3, 0, 0, 0, 0, ny))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, offset := first.Zone(); offset != -4*3600 {
+		t.Errorf("Fall back: expected first occurrence in EDT, got %v", first)
+	}
+	second, err := tu.NextCronTime("30 1 * * *", first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := time.Date(2024, 11, 4, 1, 30, 0, 0, ny); !second.Equal(expected) {
+		t.Errorf("Fall back: expected next run %v, got %v", expected, second)
+	}
+
+	// Hourly jobs keep running across the gap
+	next, err = tu.NextCronTime("0 * * * *", time.Date(2024, 3, 10, 1, 30, 0, 0, ny))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := time.Date(2024, 3, 10, 3, 0, 0, 0, ny); !next.Equal(expected) {
+		t.Errorf("Hourly across gap: expected %v, got %v", expected, next)
+	}
+}
+
+func TestScheduler(t *testing.T) {
+	tu := New(time.UTC)
+
+	var mu sync.Mutex
+	var errs []error
+	scheduler := tu.NewScheduler(SchedulerOptions{
+		OnError: func(name string, err error) {
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+		},
+	})
+
+	var fastRuns, slowRuns int32
+	release := make(chan struct{})
+
+	if err := scheduler.Add("fast", "@every 10ms", func(ctx context.Context) error {
+		atomic.AddInt32(&fastRuns, 1)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := scheduler.Add("slow", "@every 10ms", func(ctx context.Context) error {
+		atomic.AddInt32(&slowRuns, 1)
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := scheduler.Add("failing", "@every 10ms", func(ctx context.Context) error {
+		panic("boom")
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := scheduler.Add("fast", "@every 1s", func(ctx context.Context) error { return nil }); !errors.Is(err, ErrJobExists) {
+		t.Errorf("Expected ErrJobExists, got %v", err)
+	}
+	if err := scheduler.Add("bad", "not a cron", func(ctx context.Context) error { return nil }); err == nil {
+		t.Error("Expected parse error")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	scheduler.Start(ctx)
+
+	time.Sleep(100 * time.Millisecond)
+
+	if runs := atomic.LoadInt32(&fastRuns); runs < 3 {
+		t.Errorf("Expected fast job to run several times, got %d", runs)
+	}
+	if runs := atomic.LoadInt32(&slowRuns); runs != 1 {
+		t.Errorf("Expected overlapping runs to be skipped, got %d runs", runs)
+	}
+
+	for _, info := range scheduler.Jobs() {
+		if info.Name == "slow" && (!info.Running || info.Skipped == 0) {
+			t.Errorf("Expected slow job running with skipped runs, got %+v", info)
+		}
+	}
+
+	mu.Lock()
+	if len(errs) == 0 {
+		t.Error("Expected error hook to receive panics")
+	}
+	mu.Unlock()
+
+	if err := scheduler.Remove("fast"); err != nil {
+		t.Errorf("Remove failed: %v", err)
+	}
+	if err := scheduler.Remove("fast"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Expected ErrJobNotFound, got %v", err)
+	}
+
+	// Stop cancels the job context so the slow job returns
+	stopCtx, stopCancel := context.WithTimeout(context.Background(), time.Second)
+	defer stopCancel()
+	if err := scheduler.Stop(stopCtx); err != nil {
+		t.Errorf("Stop failed: %v", err)
+	}
+	close(release)
+}
+
 func BenchmarkFormat(b *testing.B) {
 	tu := Default()
 	now := time.Now()
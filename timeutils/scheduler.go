@@ -0,0 +1,335 @@
+package timeutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Job is a unit of scheduled work
+type Job func(ctx context.Context) error
+
+// JobOptions configures a scheduled job
+type JobOptions struct {
+	// AllowOverlap lets a new run start while the previous one is still running
+	AllowOverlap bool
+	// Timeout cancels the job context after the given duration
+	Timeout time.Duration
+	// OnError is called when the job returns an error or panics
+	OnError func(name string, err error)
+}
+
+// SchedulerOptions configures a Scheduler
+type SchedulerOptions struct {
+	// OnError is called for jobs without their own OnError hook
+	OnError func(name string, err error)
+}
+
+// JobInfo describes the state of a scheduled job
+type JobInfo struct {
+	Name     string    `json:"name"`
+	Schedule string    `json:"schedule"`
+	Next     time.Time `json:"next"`
+	Prev     time.Time `json:"prev"`
+	Running  bool      `json:"running"`
+	Runs     int64     `json:"runs"`
+	Skipped  int64     `json:"skipped"`
+	Failures int64     `json:"failures"`
+}
+
+// Scheduler errors
+var (
+	ErrJobExists   = errors.New("job already exists")
+	ErrJobNotFound = errors.New("job not found")
+)
+
+// Scheduler runs jobs on cron schedules
+type Scheduler struct {
+	tu      *TimeUtils
+	options SchedulerOptions
+
+	mu      sync.Mutex
+	jobs    map[string]*scheduledJob
+	wake    chan struct{}
+	cancel  context.CancelFunc
+	done    chan struct{}
+	running sync.WaitGroup
+}
+
+type scheduledJob struct {
+	name     string
+	schedule *CronSchedule
+	job      Job
+	options  JobOptions
+	next     time.Time
+	prev     time.Time
+	active   int
+	runs     int64
+	skipped  int64
+	failures int64
+}
+
+// NewScheduler creates a scheduler evaluating schedules in the TimeUtils location
+func (tu *TimeUtils) NewScheduler(opts ...SchedulerOptions) *Scheduler {
+	var options SchedulerOptions
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+
+	return &Scheduler{
+		tu:      tu,
+		options: options,
+		jobs:    make(map[string]*scheduledJob),
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// Add registers a job under a unique name
+func (s *Scheduler) Add(name, expr string, job Job, opts ...JobOptions) error {
+	schedule, err := s.tu.ParseCron(expr)
+	if err != nil {
+		return err
+	}
+	return s.AddSchedule(name, schedule, job, opts...)
+}
+
+// AddSchedule registers a job with an already parsed schedule
+func (s *Scheduler) AddSchedule(name string, schedule *CronSchedule, job Job, opts ...JobOptions) error {
+	if job == nil {
+		return fmt.Errorf("job %q is nil", name)
+	}
+
+	var options JobOptions
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.jobs[name]; exists {
+		return fmt.Errorf("%w: %s", ErrJobExists, name)
+	}
+
+	s.jobs[name] = &scheduledJob{
+		name:     name,
+		schedule: schedule,
+		job:      job,
+		options:  options,
+		next:     schedule.Next(s.tu.Now()),
+	}
+	s.notify()
+
+	return nil
+}
+
+// Remove unregisters a job. Runs already in progress are not interrupted.
+func (s *Scheduler) Remove(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.jobs[name]; !exists {
+		return fmt.Errorf("%w: %s", ErrJobNotFound, name)
+	}
+	delete(s.jobs, name)
+	s.notify()
+
+	return nil
+}
+
+// Jobs returns the state of all jobs sorted by next run time
+func (s *Scheduler) Jobs() []JobInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	infos := make([]JobInfo, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		infos = append(infos, JobInfo{
+			Name:     j.name,
+			Schedule: j.schedule.String(),
+			Next:     j.next,
+			Prev:     j.prev,
+			Running:  j.active > 0,
+			Runs:     j.runs,
+			Skipped:  j.skipped,
+			Failures: j.failures,
+		})
+	}
+
+	sort.Slice(infos, func(i, k int) bool {
+		return infos[i].Next.Before(infos[k].Next)
+	})
+
+	return infos
+}
+
+// Start runs the scheduler loop in the background until ctx is cancelled or Stop is called
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	if s.cancel != nil {
+		s.mu.Unlock()
+		return
+	}
+	ctx, s.cancel = context.WithCancel(ctx)
+	s.done = make(chan struct{})
+	now := s.tu.Now()
+	for _, j := range s.jobs {
+		j.next = j.schedule.Next(now)
+	}
+	s.mu.Unlock()
+
+	go s.loop(ctx)
+}
+
+// Stop stops the scheduler and waits for running jobs to finish or ctx to expire
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	cancel, done := s.cancel, s.done
+	s.cancel = nil
+	s.mu.Unlock()
+
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+	<-done
+
+	finished := make(chan struct{})
+	go func() {
+		s.running.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// RunNow triggers a job immediately, subject to its overlap policy
+func (s *Scheduler) RunNow(ctx context.Context, name string) error {
+	s.mu.Lock()
+	j, exists := s.jobs[name]
+	if exists {
+		s.dispatch(ctx, j)
+	}
+	s.mu.Unlock()
+
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrJobNotFound, name)
+	}
+	return nil
+}
+
+// loop waits for the earliest job and dispatches due jobs
+func (s *Scheduler) loop(ctx context.Context) {
+	defer close(s.done)
+
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+
+	for {
+		s.mu.Lock()
+		now := s.tu.Now()
+		var earliest time.Time
+		for _, j := range s.jobs {
+			if j.next.IsZero() {
+				continue
+			}
+			if !j.next.After(now) {
+				s.dispatch(ctx, j)
+				j.next = j.schedule.Next(now)
+				if j.next.IsZero() {
+					continue
+				}
+			}
+			if earliest.IsZero() || j.next.Before(earliest) {
+				earliest = j.next
+			}
+		}
+		s.mu.Unlock()
+
+		wait := time.Hour
+		if !earliest.IsZero() {
+			wait = earliest.Sub(now)
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wake:
+		case <-timer.C:
+		}
+	}
+}
+
+// dispatch starts a job run; callers must hold s.mu
+func (s *Scheduler) dispatch(ctx context.Context, j *scheduledJob) {
+	if j.active > 0 && !j.options.AllowOverlap {
+		j.skipped++
+		return
+	}
+
+	j.active++
+	j.runs++
+	j.prev = s.tu.Now()
+	s.running.Add(1)
+
+	go func() {
+		defer s.running.Done()
+
+		err := s.run(ctx, j)
+
+		s.mu.Lock()
+		j.active--
+		if err != nil {
+			j.failures++
+		}
+		s.mu.Unlock()
+
+		if err != nil {
+			if j.options.OnError != nil {
+				j.options.OnError(j.name, err)
+			} else if s.options.OnError != nil {
+				s.options.OnError(j.name, err)
+			}
+		}
+	}()
+}
+
+// run executes a job, converting panics to errors
+func (s *Scheduler) run(ctx context.Context, j *scheduledJob) (err error) {
+	if j.options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, j.options.Timeout)
+		defer cancel()
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job %s panicked: %v", j.name, r)
+		}
+	}()
+
+	return j.job(ctx)
+}
+
+// notify wakes the scheduler loop without blocking
+func (s *Scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
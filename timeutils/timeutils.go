@@ -590,40 +590,6 @@ func (tu *TimeUtils) NextOccurrence(scheduleTime time.Time, interval time.Durati
 	return scheduleTime.Add(time.Duration(intervals) * interval)
 }
 
-// Cron utilities
-
-// ParseCron parses cron expression (simplified version)
-func (tu *TimeUtils) ParseCron(expr string) (minute, hour, day, month, weekday string, err error) {
-	parts := strings.Fields(expr)
-	if len(parts) != 5 {
-		return "", "", "", "", "", errors.New("cron expression must have 5 fields")
-	}
-	return parts[0], parts[1], parts[2], parts[3], parts[4], nil
-}
-
-// NextCronTime calculates next time based on cron expression
-func (tu *TimeUtils) NextCronTime(expr string, fromTime ...time.Time) (time.Time, error) {
-	from := tu.Now()
-	if len(fromTime) > 0 {
-		from = fromTime[0]
-	}
-
-	// Simple implementation - in production, use a proper cron parser library
-	// This is a placeholder implementation
-	minute, hour, day, month, weekday, err := tu.ParseCron(expr)
-	if err != nil {
-		return time.Time{}, err
-	}
-
-	// Basic validation
-	if minute == "*" && hour == "*" && day == "*" && month == "*" && weekday == "*" {
-		return from.Add(time.Minute), nil
-	}
-
-	// Simplified next minute calculation
-	return from.Add(time.Minute), nil
-}
-
 // Utility functions
 
 // SleepWithContext sleeps for duration but can be cancelled by context
@@ -0,0 +1,319 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Exporter sends finished spans to a backend
+type Exporter interface {
+	ExportSpans(ctx context.Context, spans []SpanData) error
+	Shutdown(ctx context.Context) error
+}
+
+// ========== STDOUT ==========
+
+// StdoutExporter writes one JSON object per span
+type StdoutExporter struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+// NewStdoutExporter creates an exporter writing to w, or os.Stdout when nil
+func NewStdoutExporter(w io.Writer) *StdoutExporter {
+	if w == nil {
+		w = os.Stdout
+	}
+	return &StdoutExporter{out: w}
+}
+
+// stdoutSpan is the JSON shape written by StdoutExporter
+type stdoutSpan struct {
+	TraceID      string  `json:"trace_id"`
+	SpanID       string  `json:"span_id"`
+	ParentSpanID string  `json:"parent_span_id,omitempty"`
+	Kind         string  `json:"kind"`
+	Status       string  `json:"status"`
+	DurationMs   float64 `json:"duration_ms"`
+	SpanData
+}
+
+// ExportSpans writes the spans as JSON lines
+func (e *StdoutExporter) ExportSpans(ctx context.Context, spans []SpanData) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+
+	for _, span := range spans {
+		out := stdoutSpan{
+			TraceID:    span.TraceID.String(),
+			SpanID:     span.SpanID.String(),
+			Kind:       span.Kind.String(),
+			Status:     span.Status.String(),
+			DurationMs: float64(span.Duration().Microseconds()) / 1000,
+			SpanData:   span,
+		}
+		if span.ParentSpanID.IsValid() {
+			out.ParentSpanID = span.ParentSpanID.String()
+		}
+		if err := enc.Encode(out); err != nil {
+			return fmt.Errorf("failed to encode span: %w", err)
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, err := e.out.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write spans: %w", err)
+	}
+	return nil
+}
+
+// Shutdown is a no-op
+func (e *StdoutExporter) Shutdown(ctx context.Context) error { return nil }
+
+// ========== IN MEMORY ==========
+
+// InMemoryExporter keeps exported spans in memory, for tests
+type InMemoryExporter struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+// NewInMemoryExporter creates an empty in-memory exporter
+func NewInMemoryExporter() *InMemoryExporter {
+	return &InMemoryExporter{}
+}
+
+// ExportSpans stores the spans
+func (e *InMemoryExporter) ExportSpans(ctx context.Context, spans []SpanData) error {
+	e.mu.Lock()
+	e.spans = append(e.spans, spans...)
+	e.mu.Unlock()
+	return nil
+}
+
+// Shutdown is a no-op
+func (e *InMemoryExporter) Shutdown(ctx context.Context) error { return nil }
+
+// Spans returns a copy of the exported spans
+func (e *InMemoryExporter) Spans() []SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]SpanData(nil), e.spans...)
+}
+
+// Reset discards the exported spans
+func (e *InMemoryExporter) Reset() {
+	e.mu.Lock()
+	e.spans = nil
+	e.mu.Unlock()
+}
+
+// ========== OTLP/HTTP ==========
+
+// OTLPOptions configures the OTLP/HTTP exporter
+type OTLPOptions struct {
+	// Headers are added to every request, e.g. for collector authentication
+	Headers map[string]string
+	// Timeout per export request; defaults to 10s
+	Timeout time.Duration
+	// Client overrides the HTTP client
+	Client *http.Client
+}
+
+// OTLPExporter posts spans to an OpenTelemetry collector using OTLP/HTTP with JSON encoding
+type OTLPExporter struct {
+	endpoint string
+	headers  map[string]string
+	client   *http.Client
+}
+
+// NewOTLPExporter creates an exporter for endpoint, e.g. http://localhost:4318.
+// /v1/traces is appended when endpoint has no path.
+func NewOTLPExporter(endpoint string, opts ...OTLPOptions) (*OTLPExporter, error) {
+	var opt OTLPOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid OTLP endpoint %q", endpoint)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/v1/traces"
+	}
+
+	client := opt.Client
+	if client == nil {
+		timeout := opt.Timeout
+		if timeout <= 0 {
+			timeout = 10 * time.Second
+		}
+		client = &http.Client{Timeout: timeout}
+	}
+
+	return &OTLPExporter{endpoint: u.String(), headers: opt.Headers, client: client}, nil
+}
+
+// ExportSpans posts the spans as an ExportTraceServiceRequest
+func (e *OTLPExporter) ExportSpans(ctx context.Context, spans []SpanData) error {
+	if len(spans) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(otlpRequest(spans))
+	if err != nil {
+		return fmt.Errorf("failed to encode spans: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create export request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to export spans: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("collector returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Shutdown closes idle connections
+func (e *OTLPExporter) Shutdown(ctx context.Context) error {
+	e.client.CloseIdleConnections()
+	return nil
+}
+
+// otlpRequest builds the OTLP JSON payload, grouping spans by service
+func otlpRequest(spans []SpanData) map[string]interface{} {
+	byService := make(map[string][]interface{})
+	var services []string
+
+	for _, span := range spans {
+		if _, ok := byService[span.ServiceName]; !ok {
+			services = append(services, span.ServiceName)
+		}
+		byService[span.ServiceName] = append(byService[span.ServiceName], otlpSpan(span))
+	}
+
+	var resourceSpans []interface{}
+	for _, service := range services {
+		var resourceAttrs []interface{}
+		if service != "" {
+			resourceAttrs = OTLPAttributes(map[string]interface{}{"service.name": service})
+		}
+		resourceSpans = append(resourceSpans, map[string]interface{}{
+			"resource": map[string]interface{}{"attributes": resourceAttrs},
+			"scopeSpans": []interface{}{map[string]interface{}{
+				"scope": map[string]interface{}{"name": "github.com/selanim/sego/tracing"},
+				"spans": byService[service],
+			}},
+		})
+	}
+
+	return map[string]interface{}{"resourceSpans": resourceSpans}
+}
+
+func otlpSpan(span SpanData) map[string]interface{} {
+	out := map[string]interface{}{
+		"traceId":           span.TraceID.String(),
+		"spanId":            span.SpanID.String(),
+		"name":              span.Name,
+		"kind":              int(span.Kind),
+		"startTimeUnixNano": strconv.FormatInt(span.StartTime.UnixNano(), 10),
+		"endTimeUnixNano":   strconv.FormatInt(span.EndTime.UnixNano(), 10),
+		"attributes":        OTLPAttributes(span.Attributes),
+		"status":            map[string]interface{}{"code": int(span.Status), "message": span.StatusMessage},
+	}
+	if span.ParentSpanID.IsValid() {
+		out["parentSpanId"] = span.ParentSpanID.String()
+	}
+	if span.TraceState != "" {
+		out["traceState"] = span.TraceState
+	}
+
+	var events []interface{}
+	for _, event := range span.Events {
+		events = append(events, map[string]interface{}{
+			"name":         event.Name,
+			"timeUnixNano": strconv.FormatInt(event.Time.UnixNano(), 10),
+			"attributes":   OTLPAttributes(event.Attributes),
+		})
+	}
+	if len(events) > 0 {
+		out["events"] = events
+	}
+
+	return out
+}
+
+// OTLPAttributes converts attributes to OTLP/JSON KeyValues, sorted by key
+func OTLPAttributes(attrs map[string]interface{}) []interface{} {
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	out := make([]interface{}, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, map[string]interface{}{"key": k, "value": otlpValue(attrs[k])})
+	}
+	return out
+}
+
+// otlpValue converts a value to an OTLP AnyValue; 64-bit integers are strings in OTLP JSON
+func otlpValue(v interface{}) map[string]interface{} {
+	switch val := v.(type) {
+	case string:
+		return map[string]interface{}{"stringValue": val}
+	case bool:
+		return map[string]interface{}{"boolValue": val}
+	case int:
+		return map[string]interface{}{"intValue": strconv.FormatInt(int64(val), 10)}
+	case int32:
+		return map[string]interface{}{"intValue": strconv.FormatInt(int64(val), 10)}
+	case int64:
+		return map[string]interface{}{"intValue": strconv.FormatInt(val, 10)}
+	case uint:
+		return map[string]interface{}{"intValue": strconv.FormatUint(uint64(val), 10)}
+	case uint32:
+		return map[string]interface{}{"intValue": strconv.FormatUint(uint64(val), 10)}
+	case uint64:
+		return map[string]interface{}{"intValue": strconv.FormatUint(val, 10)}
+	case float32:
+		return map[string]interface{}{"doubleValue": float64(val)}
+	case float64:
+		return map[string]interface{}{"doubleValue": val}
+	case []string:
+		values := make([]interface{}, len(val))
+		for i, s := range val {
+			values[i] = map[string]interface{}{"stringValue": s}
+		}
+		return map[string]interface{}{"arrayValue": map[string]interface{}{"values": values}}
+	case fmt.Stringer:
+		return map[string]interface{}{"stringValue": val.String()}
+	}
+	return map[string]interface{}{"stringValue": fmt.Sprint(v)}
+}
@@ -0,0 +1,131 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// W3C Trace Context header names
+const (
+	TraceparentHeader = "traceparent"
+	TracestateHeader  = "tracestate"
+)
+
+// ErrInvalidTraceparent is returned for malformed traceparent headers
+var ErrInvalidTraceparent = errors.New("invalid traceparent header")
+
+// maxTracestateMembers is the W3C limit on tracestate list members
+const maxTracestateMembers = 32
+
+// ParseTraceparent parses a W3C traceparent header such as
+// 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+func ParseTraceparent(header string) (SpanContext, error) {
+	header = strings.TrimSpace(header)
+	if len(header) < 55 {
+		return SpanContext{}, ErrInvalidTraceparent
+	}
+
+	version := header[:2]
+	if !isLowerHex(version) || version == "ff" {
+		return SpanContext{}, ErrInvalidTraceparent
+	}
+	// Version 00 has exactly four fields; later versions may append more after a dash
+	if version == "00" && len(header) != 55 {
+		return SpanContext{}, ErrInvalidTraceparent
+	}
+	if len(header) > 55 && header[55] != '-' {
+		return SpanContext{}, ErrInvalidTraceparent
+	}
+	if header[2] != '-' || header[35] != '-' || header[52] != '-' {
+		return SpanContext{}, ErrInvalidTraceparent
+	}
+
+	traceHex, spanHex, flagsHex := header[3:35], header[36:52], header[53:55]
+	if !isLowerHex(traceHex) || !isLowerHex(spanHex) || !isLowerHex(flagsHex) {
+		return SpanContext{}, ErrInvalidTraceparent
+	}
+
+	var sc SpanContext
+	hex.Decode(sc.TraceID[:], []byte(traceHex))
+	hex.Decode(sc.SpanID[:], []byte(spanHex))
+	var flags [1]byte
+	hex.Decode(flags[:], []byte(flagsHex))
+	sc.Flags = flags[0]
+
+	if !sc.IsValid() {
+		return SpanContext{}, fmt.Errorf("%w: all-zero trace or span id", ErrInvalidTraceparent)
+	}
+	sc.Remote = true
+	return sc, nil
+}
+
+// Traceparent formats the span context as a version 00 traceparent header
+func (sc SpanContext) Traceparent() string {
+	return fmt.Sprintf("00-%s-%s-%02x", sc.TraceID, sc.SpanID, sc.Flags&FlagSampled)
+}
+
+// Extract reads traceparent and tracestate from headers into ctx.
+// Invalid headers are ignored so the request starts a new trace.
+func Extract(ctx context.Context, header http.Header) context.Context {
+	sc, err := ParseTraceparent(header.Get(TraceparentHeader))
+	if err != nil {
+		return ctx
+	}
+	sc.TraceState = normalizeTracestate(header.Values(TracestateHeader))
+	return ContextWithRemoteSpanContext(ctx, sc)
+}
+
+// Inject writes the current span context in ctx to headers
+func Inject(ctx context.Context, header http.Header) {
+	sc := SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+	header.Set(TraceparentHeader, sc.Traceparent())
+	if sc.TraceState != "" {
+		header.Set(TracestateHeader, sc.TraceState)
+	} else {
+		header.Del(TracestateHeader)
+	}
+}
+
+// normalizeTracestate joins tracestate headers, dropping malformed and
+// duplicate members. The whole value is discarded when over the member limit.
+func normalizeTracestate(values []string) string {
+	var members []string
+	seen := make(map[string]bool)
+
+	for _, value := range values {
+		for _, member := range strings.Split(value, ",") {
+			member = strings.TrimSpace(member)
+			if member == "" {
+				continue
+			}
+			key, val, ok := strings.Cut(member, "=")
+			if !ok || key == "" || val == "" || strings.ContainsAny(key, " \t") || seen[key] {
+				continue
+			}
+			seen[key] = true
+			members = append(members, member)
+		}
+	}
+
+	if len(members) > maxTracestateMembers {
+		return ""
+	}
+	return strings.Join(members, ",")
+}
+
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
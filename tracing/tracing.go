@@ -0,0 +1,632 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// TraceID identifies a trace
+type TraceID [16]byte
+
+// SpanID identifies a span within a trace
+type SpanID [8]byte
+
+// String returns the lowercase hex form
+func (t TraceID) String() string { return hex.EncodeToString(t[:]) }
+
+// IsValid reports whether the ID is not all zeros
+func (t TraceID) IsValid() bool { return t != TraceID{} }
+
+// String returns the lowercase hex form
+func (s SpanID) String() string { return hex.EncodeToString(s[:]) }
+
+// IsValid reports whether the ID is not all zeros
+func (s SpanID) IsValid() bool { return s != SpanID{} }
+
+// FlagSampled is the W3C trace-flags bit marking a sampled trace
+const FlagSampled byte = 0x01
+
+// SpanContext is the part of a span that propagates across process boundaries
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Flags      byte
+	TraceState string
+	Remote     bool
+}
+
+// IsValid reports whether both IDs are set
+func (sc SpanContext) IsValid() bool { return sc.TraceID.IsValid() && sc.SpanID.IsValid() }
+
+// IsSampled reports whether the sampled flag is set
+func (sc SpanContext) IsSampled() bool { return sc.Flags&FlagSampled != 0 }
+
+// SpanKind describes the relationship of a span to its callers and callees
+type SpanKind int
+
+const (
+	SpanKindInternal SpanKind = iota + 1
+	SpanKindServer
+	SpanKindClient
+	SpanKindProducer
+	SpanKindConsumer
+)
+
+// String returns the kind name
+func (k SpanKind) String() string {
+	switch k {
+	case SpanKindServer:
+		return "server"
+	case SpanKindClient:
+		return "client"
+	case SpanKindProducer:
+		return "producer"
+	case SpanKindConsumer:
+		return "consumer"
+	default:
+		return "internal"
+	}
+}
+
+// StatusCode is the outcome of a span
+type StatusCode int
+
+const (
+	StatusUnset StatusCode = iota
+	StatusOK
+	StatusError
+)
+
+// String returns the status name
+func (c StatusCode) String() string {
+	switch c {
+	case StatusOK:
+		return "ok"
+	case StatusError:
+		return "error"
+	default:
+		return "unset"
+	}
+}
+
+// Event is a timestamped annotation on a span
+type Event struct {
+	Name       string                 `json:"name"`
+	Time       time.Time              `json:"time"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// SpanData is an immutable snapshot of a finished span handed to exporters
+type SpanData struct {
+	Name          string                 `json:"name"`
+	Kind          SpanKind               `json:"-"`
+	TraceID       TraceID                `json:"-"`
+	SpanID        SpanID                 `json:"-"`
+	ParentSpanID  SpanID                 `json:"-"`
+	TraceState    string                 `json:"trace_state,omitempty"`
+	StartTime     time.Time              `json:"start_time"`
+	EndTime       time.Time              `json:"end_time"`
+	Attributes    map[string]interface{} `json:"attributes,omitempty"`
+	Events        []Event                `json:"events,omitempty"`
+	Status        StatusCode             `json:"-"`
+	StatusMessage string                 `json:"status_message,omitempty"`
+	ServiceName   string                 `json:"service_name,omitempty"`
+}
+
+// Duration returns the span duration
+func (d SpanData) Duration() time.Duration { return d.EndTime.Sub(d.StartTime) }
+
+// ========== SPAN ==========
+
+// Span is a timed operation. All methods are safe on a nil span, so
+// instrumented code works unchanged when tracing is disabled.
+type Span struct {
+	tracer *Tracer
+	sc     SpanContext
+	parent SpanID
+
+	mu         sync.Mutex
+	name       string
+	kind       SpanKind
+	start      time.Time
+	end        time.Time
+	attributes map[string]interface{}
+	events     []Event
+	status     StatusCode
+	statusMsg  string
+	ended      bool
+}
+
+// SpanContext returns the propagated identity of the span
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.sc
+}
+
+// IsRecording reports whether the span is sampled and not yet ended
+func (s *Span) IsRecording() bool {
+	if s == nil || !s.sc.IsSampled() {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.ended
+}
+
+// SetName renames the span, e.g. once the matched route is known
+func (s *Span) SetName(name string) {
+	if !s.IsRecording() {
+		return
+	}
+	s.mu.Lock()
+	s.name = name
+	s.mu.Unlock()
+}
+
+// SetAttribute sets a span attribute
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if !s.IsRecording() {
+		return
+	}
+	s.mu.Lock()
+	s.attributes[key] = value
+	s.mu.Unlock()
+}
+
+// SetAttributes sets several span attributes
+func (s *Span) SetAttributes(attrs map[string]interface{}) {
+	if !s.IsRecording() {
+		return
+	}
+	s.mu.Lock()
+	for k, v := range attrs {
+		s.attributes[k] = v
+	}
+	s.mu.Unlock()
+}
+
+// AddEvent records a named event
+func (s *Span) AddEvent(name string, attrs ...map[string]interface{}) {
+	if !s.IsRecording() {
+		return
+	}
+	event := Event{Name: name, Time: time.Now()}
+	if len(attrs) > 0 {
+		event.Attributes = attrs[0]
+	}
+	s.mu.Lock()
+	s.events = append(s.events, event)
+	s.mu.Unlock()
+}
+
+// SetStatus sets the span status. An error status is never downgraded to OK.
+func (s *Span) SetStatus(code StatusCode, message string) {
+	if !s.IsRecording() {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.status == StatusError && code != StatusError {
+		return
+	}
+	s.status = code
+	s.statusMsg = message
+}
+
+// RecordError records err as an exception event and marks the span failed. Nil errors are ignored.
+func (s *Span) RecordError(err error) {
+	if err == nil || !s.IsRecording() {
+		return
+	}
+	s.AddEvent("exception", map[string]interface{}{
+		"exception.type":    fmt.Sprintf("%T", err),
+		"exception.message": err.Error(),
+	})
+	s.SetStatus(StatusError, err.Error())
+}
+
+// End finishes the span and hands it to the exporter. Calls after the first are ignored.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mu.Unlock()
+
+	if s.sc.IsSampled() && s.tracer != nil {
+		s.tracer.export(s.snapshot())
+	}
+}
+
+// snapshot copies the span into SpanData
+func (s *Span) snapshot() SpanData {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attrs := make(map[string]interface{}, len(s.attributes))
+	for k, v := range s.attributes {
+		attrs[k] = v
+	}
+
+	return SpanData{
+		Name:          s.name,
+		Kind:          s.kind,
+		TraceID:       s.sc.TraceID,
+		SpanID:        s.sc.SpanID,
+		ParentSpanID:  s.parent,
+		TraceState:    s.sc.TraceState,
+		StartTime:     s.start,
+		EndTime:       s.end,
+		Attributes:    attrs,
+		Events:        append([]Event(nil), s.events...),
+		Status:        s.status,
+		StatusMessage: s.statusMsg,
+		ServiceName:   s.tracer.serviceName,
+	}
+}
+
+// ========== SAMPLING ==========
+
+// Sampler decides whether a new trace is recorded. parent is invalid for root spans.
+type Sampler func(parent SpanContext, traceID TraceID) bool
+
+// AlwaysSample records every trace
+func AlwaysSample() Sampler {
+	return func(SpanContext, TraceID) bool { return true }
+}
+
+// NeverSample records no traces; IDs are still generated and propagated
+func NeverSample() Sampler {
+	return func(SpanContext, TraceID) bool { return false }
+}
+
+// TraceIDRatio samples the given fraction of traces, deterministically by trace ID
+func TraceIDRatio(fraction float64) Sampler {
+	if fraction >= 1 {
+		return AlwaysSample()
+	}
+	if fraction <= 0 {
+		return NeverSample()
+	}
+	bound := uint64(fraction * (1 << 63))
+	return func(_ SpanContext, traceID TraceID) bool {
+		var x uint64
+		for _, b := range traceID[8:] {
+			x = x<<8 | uint64(b)
+		}
+		return x>>1 < bound
+	}
+}
+
+// ParentBased follows the parent's sampled flag and uses root for new traces
+func ParentBased(root Sampler) Sampler {
+	return func(parent SpanContext, traceID TraceID) bool {
+		if parent.IsValid() {
+			return parent.IsSampled()
+		}
+		return root(parent, traceID)
+	}
+}
+
+// ========== TRACER ==========
+
+// Options configures a Tracer
+type Options struct {
+	// ServiceName is reported as the service.name resource attribute
+	ServiceName string
+	// Exporter receives finished spans; spans are dropped when nil
+	Exporter Exporter
+	// Sampler defaults to ParentBased(AlwaysSample())
+	Sampler Sampler
+	// BatchSize is the number of spans per export call; defaults to 512
+	BatchSize int
+	// BatchTimeout is the longest a span waits before export; defaults to 5s
+	BatchTimeout time.Duration
+	// QueueSize bounds buffered spans; spans are dropped when full. Defaults to 2048.
+	QueueSize int
+}
+
+// Tracer creates spans and exports them in batches
+type Tracer struct {
+	serviceName string
+	exporter    Exporter
+	sampler     Sampler
+
+	batchSize    int
+	batchTimeout time.Duration
+	queue        chan SpanData
+	flush        chan chan error
+	done         chan struct{}
+	stopped      chan struct{}
+	closeOnce    sync.Once
+
+	mu      sync.Mutex
+	dropped int64
+}
+
+// NewTracer creates a tracer and starts its export loop
+func NewTracer(opts ...Options) *Tracer {
+	var opt Options
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	if opt.Sampler == nil {
+		opt.Sampler = ParentBased(AlwaysSample())
+	}
+	if opt.BatchSize <= 0 {
+		opt.BatchSize = 512
+	}
+	if opt.BatchTimeout <= 0 {
+		opt.BatchTimeout = 5 * time.Second
+	}
+	if opt.QueueSize <= 0 {
+		opt.QueueSize = 2048
+	}
+
+	t := &Tracer{
+		serviceName:  opt.ServiceName,
+		exporter:     opt.Exporter,
+		sampler:      opt.Sampler,
+		batchSize:    opt.BatchSize,
+		batchTimeout: opt.BatchTimeout,
+		queue:        make(chan SpanData, opt.QueueSize),
+		flush:        make(chan chan error),
+		done:         make(chan struct{}),
+		stopped:      make(chan struct{}),
+	}
+	go t.loop()
+	return t
+}
+
+// Start creates a span as a child of the span or remote span context in ctx
+func (t *Tracer) Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	parent := SpanContextFromContext(ctx)
+
+	sc := SpanContext{TraceID: parent.TraceID, TraceState: parent.TraceState}
+	if !parent.IsValid() {
+		sc.TraceID = newTraceID()
+		sc.TraceState = ""
+	}
+	sc.SpanID = newSpanID()
+	if t.sampler(parent, sc.TraceID) {
+		sc.Flags |= FlagSampled
+	}
+
+	span := &Span{
+		tracer:     t,
+		sc:         sc,
+		name:       name,
+		kind:       kind,
+		start:      time.Now(),
+		attributes: make(map[string]interface{}),
+	}
+	if parent.IsValid() {
+		span.parent = parent.SpanID
+	}
+
+	return ContextWithSpan(ctx, span), span
+}
+
+// Dropped returns the number of spans dropped because the queue was full
+func (t *Tracer) Dropped() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.dropped
+}
+
+// export queues a finished span without blocking the caller
+func (t *Tracer) export(span SpanData) {
+	if t.exporter == nil {
+		return
+	}
+	select {
+	case <-t.done:
+		return
+	default:
+	}
+
+	select {
+	case t.queue <- span:
+	default:
+		t.mu.Lock()
+		t.dropped++
+		t.mu.Unlock()
+	}
+}
+
+// Flush exports all queued spans
+func (t *Tracer) Flush(ctx context.Context) error {
+	reply := make(chan error, 1)
+	select {
+	case t.flush <- reply:
+	case <-t.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case err := <-reply:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown exports queued spans, stops the export loop and shuts down the exporter
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	t.closeOnce.Do(func() { close(t.done) })
+
+	select {
+	case <-t.stopped:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if t.exporter != nil {
+		return t.exporter.Shutdown(ctx)
+	}
+	return nil
+}
+
+// loop batches queued spans and exports them
+func (t *Tracer) loop() {
+	defer close(t.stopped)
+
+	ticker := time.NewTicker(t.batchTimeout)
+	defer ticker.Stop()
+
+	batch := make([]SpanData, 0, t.batchSize)
+	exportBatch := func() error {
+		if len(batch) == 0 || t.exporter == nil {
+			batch = batch[:0]
+			return nil
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		err := t.exporter.ExportSpans(ctx, batch)
+		if err != nil {
+			log.Printf("tracing: failed to export %d spans: %v", len(batch), err)
+		}
+		batch = make([]SpanData, 0, t.batchSize)
+		return err
+	}
+	drain := func() {
+		for {
+			select {
+			case span := <-t.queue:
+				batch = append(batch, span)
+				if len(batch) >= t.batchSize {
+					exportBatch()
+				}
+			default:
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case span := <-t.queue:
+			batch = append(batch, span)
+			if len(batch) >= t.batchSize {
+				exportBatch()
+			}
+		case <-ticker.C:
+			exportBatch()
+		case reply := <-t.flush:
+			drain()
+			reply <- exportBatch()
+		case <-t.done:
+			drain()
+			exportBatch()
+			return
+		}
+	}
+}
+
+// ========== CONTEXT ==========
+
+type spanContextKey struct{}
+
+type remoteContextKey struct{}
+
+// ContextWithSpan returns a context carrying span
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, span)
+}
+
+// ContextWithRemoteSpanContext returns a context carrying a span context received from a caller
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	sc.Remote = true
+	return context.WithValue(ctx, remoteContextKey{}, sc)
+}
+
+// SpanFromContext returns the current span, or nil
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanContextKey{}).(*Span)
+	return span
+}
+
+// SpanContextFromContext returns the current span context, falling back to a remote parent
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if span := SpanFromContext(ctx); span != nil {
+		return span.sc
+	}
+	sc, _ := ctx.Value(remoteContextKey{}).(SpanContext)
+	return sc
+}
+
+// ========== GLOBAL TRACER ==========
+
+var (
+	globalMu     sync.RWMutex
+	globalTracer *Tracer
+)
+
+// SetGlobal sets the tracer used by Start when the context has no traced parent
+func SetGlobal(t *Tracer) {
+	globalMu.Lock()
+	globalTracer = t
+	globalMu.Unlock()
+}
+
+// Global returns the global tracer, or nil when tracing is disabled
+func Global() *Tracer {
+	globalMu.RLock()
+	defer globalMu.RUnlock()
+	return globalTracer
+}
+
+// Start creates a span using the tracer of the parent span in ctx, or the
+// global tracer. It returns ctx unchanged and a nil span when neither exists.
+func Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	tracer := Global()
+	if parent := SpanFromContext(ctx); parent != nil && parent.tracer != nil {
+		tracer = parent.tracer
+	}
+	if tracer == nil {
+		return ctx, nil
+	}
+	return tracer.Start(ctx, name, kind)
+}
+
+// End records err on span, if any, and ends it. It is meant for deferred calls
+// with named error results.
+func End(span *Span, err *error) {
+	if err != nil && *err != nil {
+		span.RecordError(*err)
+	}
+	span.End()
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return id
+}
@@ -0,0 +1,312 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		valid   bool
+		sampled bool
+	}{
+		{"sampled", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true, true},
+		{"not sampled", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", true, false},
+		{"future version with extra field", "cc-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-what", true, true},
+		{"version 00 with extra field", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-what", false, false},
+		{"version ff", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false, false},
+		{"uppercase hex", "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", false, false},
+		{"zero trace id", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", false, false},
+		{"zero span id", "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false, false},
+		{"too short", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7", false, false},
+		{"bad separator", "00_4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false, false},
+		{"empty", "", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, err := ParseTraceparent(tt.header)
+			if tt.valid != (err == nil) {
+				t.Fatalf("Expected valid=%v, got error %v", tt.valid, err)
+			}
+			if !tt.valid {
+				return
+			}
+			if sc.IsSampled() != tt.sampled {
+				t.Errorf("Expected sampled=%v", tt.sampled)
+			}
+			if sc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+				t.Errorf("Unexpected trace id %s", sc.TraceID)
+			}
+			if sc.SpanID.String() != "00f067aa0ba902b7" {
+				t.Errorf("Unexpected span id %s", sc.SpanID)
+			}
+			if !strings.HasPrefix(tt.header, "00") {
+				return
+			}
+			if sc.Traceparent() != tt.header {
+				t.Errorf("Expected round trip %s, got %s", tt.header, sc.Traceparent())
+			}
+		})
+	}
+}
+
+func TestExtractInject(t *testing.T) {
+	header := http.Header{}
+	header.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	header.Add(TracestateHeader, "congo=t61rcWkgMzE, bad")
+	header.Add(TracestateHeader, "rojo=00f067aa0ba902b7,congo=dup")
+
+	ctx := Extract(context.Background(), header)
+	remote := SpanContextFromContext(ctx)
+	if !remote.IsValid() || !remote.Remote {
+		t.Fatalf("Expected remote span context, got %+v", remote)
+	}
+	if remote.TraceState != "congo=t61rcWkgMzE,rojo=00f067aa0ba902b7" {
+		t.Errorf("Unexpected tracestate %q", remote.TraceState)
+	}
+
+	tracer := NewTracer()
+	defer tracer.Shutdown(context.Background())
+	ctx, span := tracer.Start(ctx, "child", SpanKindServer)
+
+	out := http.Header{}
+	Inject(ctx, out)
+
+	sc, err := ParseTraceparent(out.Get(TraceparentHeader))
+	if err != nil {
+		t.Fatalf("Injected invalid traceparent: %v", err)
+	}
+	if sc.TraceID != remote.TraceID {
+		t.Error("Expected child to continue the remote trace")
+	}
+	if sc.SpanID == remote.SpanID || sc.SpanID != span.SpanContext().SpanID {
+		t.Error("Expected injected span id to be the child span")
+	}
+	if out.Get(TracestateHeader) != remote.TraceState {
+		t.Errorf("Expected tracestate to propagate, got %q", out.Get(TracestateHeader))
+	}
+
+	// Invalid headers start a new trace
+	bad := http.Header{}
+	bad.Set(TraceparentHeader, "garbage")
+	if SpanContextFromContext(Extract(context.Background(), bad)).IsValid() {
+		t.Error("Expected invalid traceparent to be ignored")
+	}
+}
+
+func TestTracerSpans(t *testing.T) {
+	exporter := NewInMemoryExporter()
+	tracer := NewTracer(Options{ServiceName: "test", Exporter: exporter})
+	defer tracer.Shutdown(context.Background())
+
+	ctx, parent := tracer.Start(context.Background(), "parent", SpanKindServer)
+	parent.SetAttribute("http.route", "/users/{id}")
+
+	_, child := Start(ctx, "child", SpanKindClient)
+	child.RecordError(errors.New("boom"))
+	child.SetStatus(StatusOK, "")
+	child.End()
+	child.End()
+	parent.End()
+
+	if err := tracer.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	spans := exporter.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(spans))
+	}
+
+	c, p := spans[0], spans[1]
+	if c.Name != "child" || p.Name != "parent" {
+		t.Fatalf("Unexpected span order: %s, %s", c.Name, p.Name)
+	}
+	if c.TraceID != p.TraceID {
+		t.Error("Expected child to share the parent trace")
+	}
+	if c.ParentSpanID != p.SpanID {
+		t.Error("Expected child parent to be the parent span")
+	}
+	if p.ParentSpanID.IsValid() {
+		t.Error("Expected root span to have no parent")
+	}
+	if c.Status != StatusError || c.StatusMessage != "boom" {
+		t.Errorf("Expected error status to stick, got %v %q", c.Status, c.StatusMessage)
+	}
+	if len(c.Events) != 1 || c.Events[0].Name != "exception" {
+		t.Errorf("Expected exception event, got %+v", c.Events)
+	}
+	if p.Attributes["http.route"] != "/users/{id}" || p.ServiceName != "test" {
+		t.Errorf("Unexpected parent data: %+v", p)
+	}
+
+	// Without a tracer in context or globally, Start returns a nil span that is safe to use
+	ctx, span := Start(context.Background(), "noop", SpanKindInternal)
+	span.SetAttribute("k", "v")
+	span.RecordError(errors.New("ignored"))
+	span.End()
+	if span != nil || SpanFromContext(ctx) != nil {
+		t.Error("Expected no span without a tracer")
+	}
+}
+
+func TestSamplers(t *testing.T) {
+	exporter := NewInMemoryExporter()
+	tracer := NewTracer(Options{Exporter: exporter, Sampler: ParentBased(NeverSample())})
+	defer tracer.Shutdown(context.Background())
+
+	// Unsampled roots still get IDs for log correlation but are not exported
+	ctx, root := tracer.Start(context.Background(), "root", SpanKindInternal)
+	if !root.SpanContext().IsValid() || root.SpanContext().IsSampled() || root.IsRecording() {
+		t.Error("Expected a valid, unsampled, non-recording root span")
+	}
+	_, child := tracer.Start(ctx, "child", SpanKindInternal)
+	child.End()
+	root.End()
+
+	// A sampled remote parent is followed
+	remote, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	_, followed := tracer.Start(ContextWithRemoteSpanContext(context.Background(), remote), "followed", SpanKindServer)
+	followed.End()
+
+	tracer.Flush(context.Background())
+	spans := exporter.Spans()
+	if len(spans) != 1 || spans[0].Name != "followed" {
+		t.Errorf("Expected only the sampled span to be exported, got %d", len(spans))
+	}
+
+	ratio := TraceIDRatio(0.5)
+	sampled := 0
+	for i := 0; i < 1000; i++ {
+		if ratio(SpanContext{}, newTraceID()) {
+			sampled++
+		}
+	}
+	if sampled < 400 || sampled > 600 {
+		t.Errorf("Expected about half of traces sampled, got %d/1000", sampled)
+	}
+}
+
+func TestStdoutExporter(t *testing.T) {
+	var buf bytes.Buffer
+	tracer := NewTracer(Options{Exporter: NewStdoutExporter(&buf)})
+
+	_, span := tracer.Start(context.Background(), "work", SpanKindInternal)
+	span.SetAttribute("items", 3)
+	span.End()
+	tracer.Shutdown(context.Background())
+
+	var out map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("Expected one JSON line, got %q: %v", buf.String(), err)
+	}
+	if out["name"] != "work" || out["kind"] != "internal" || len(out["trace_id"].(string)) != 32 {
+		t.Errorf("Unexpected span JSON: %v", out)
+	}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	var mu sync.Mutex
+	var payloads []map[string]interface{}
+
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" || r.Header.Get("Content-Type") != "application/json" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		var payload map[string]interface{}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		mu.Lock()
+		payloads = append(payloads, payload)
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer collector.Close()
+
+	exporter, err := NewOTLPExporter(collector.URL, OTLPOptions{
+		Headers: map[string]string{"Authorization": "Bearer secret"},
+		Timeout: time.Second,
+	})
+	if err != nil {
+		t.Fatalf("NewOTLPExporter failed: %v", err)
+	}
+
+	tracer := NewTracer(Options{ServiceName: "checkout", Exporter: exporter})
+	ctx, parent := tracer.Start(context.Background(), "GET /orders/{id}", SpanKindServer)
+	_, child := tracer.Start(ctx, "SELECT", SpanKindClient)
+	child.SetAttributes(map[string]interface{}{"db.rows": int64(2), "db.cached": false})
+	child.RecordError(errors.New("slow query"))
+	child.End()
+	parent.End()
+
+	if err := tracer.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(payloads) != 1 {
+		t.Fatalf("Expected 1 export request, got %d", len(payloads))
+	}
+
+	resource := payloads[0]["resourceSpans"].([]interface{})[0].(map[string]interface{})
+	attr := resource["resource"].(map[string]interface{})["attributes"].([]interface{})[0].(map[string]interface{})
+	if attr["key"] != "service.name" || attr["value"].(map[string]interface{})["stringValue"] != "checkout" {
+		t.Errorf("Unexpected resource attribute: %v", attr)
+	}
+
+	spans := resource["scopeSpans"].([]interface{})[0].(map[string]interface{})["spans"].([]interface{})
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(spans))
+	}
+
+	db := spans[0].(map[string]interface{})
+	if db["kind"] != float64(SpanKindClient) || db["parentSpanId"] != parent.SpanContext().SpanID.String() {
+		t.Errorf("Unexpected client span: %v", db)
+	}
+	if db["traceId"] != parent.SpanContext().TraceID.String() {
+		t.Errorf("Expected hex trace id, got %v", db["traceId"])
+	}
+	if _, ok := db["startTimeUnixNano"].(string); !ok {
+		t.Error("Expected nanosecond timestamps encoded as strings")
+	}
+	if status := db["status"].(map[string]interface{}); status["code"] != float64(StatusError) {
+		t.Errorf("Expected error status, got %v", status)
+	}
+	rows := db["attributes"].([]interface{})[1].(map[string]interface{})
+	if rows["key"] != "db.rows" || rows["value"].(map[string]interface{})["intValue"] != "2" {
+		t.Errorf("Expected int attribute as string, got %v", rows)
+	}
+
+	// Collector errors surface from ExportSpans
+	failing, _ := NewOTLPExporter(collector.URL)
+	if err := failing.ExportSpans(context.Background(), []SpanData{{Name: "x"}}); err == nil {
+		t.Error("Expected error for rejected export")
+	}
+	if _, err := NewOTLPExporter("localhost:4318"); err == nil {
+		t.Error("Expected error for endpoint without scheme")
+	}
+}
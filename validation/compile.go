@@ -0,0 +1,563 @@
+package validation
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Compiled plans parse `validate` tags once per type. Checking a value walks
+// the plan with typed accessors instead of re-parsing tags and boxing every
+// field, so valid values are checked without allocating. When a value fails,
+// the tag path runs to produce the same messages Validator.Validate would.
+
+// RuleFunc reports whether value satisfies a registered rule. Param is the
+// text after ':' in the tag, e.g. "3" for `validate:"sku:3"`.
+type RuleFunc func(value reflect.Value, param string) bool
+
+// customRules holds rules added with RegisterRule, by name
+var customRules sync.Map
+
+// RegisterRule adds a tag rule such as `validate:"required|sku"`. Rules are not
+// applied to empty values. Message is the English template for the rule's
+// code, which is its name; other locales are added with RegisterBundle.
+// Register rules before compiling types that use them.
+func RegisterRule(name string, fn RuleFunc, message ...string) error {
+	if err := checkRuleName(name); err != nil {
+		return err
+	}
+	customRules.Store(name, fn)
+	if len(message) > 0 {
+		RegisterBundle(DefaultLocale, Bundle{name: message[0]})
+	}
+	return nil
+}
+
+// checkRuleName rejects names that cannot be used in tags or are built in
+func checkRuleName(name string) error {
+	if name == "" || strings.ContainsAny(name, ":|") {
+		return fmt.Errorf("invalid rule name %q", name)
+	}
+	if isBuiltinRule(name) || name == "dive" {
+		return fmt.Errorf("rule %q is built in", name)
+	}
+	return nil
+}
+
+// lookupRule returns a registered rule
+func lookupRule(name string) (RuleFunc, bool) {
+	fn, ok := customRules.Load(name)
+	if !ok {
+		return nil, false
+	}
+	return fn.(RuleFunc), true
+}
+
+// validateCustomRule applies a registered rule in the tag path
+func (v *Validator) validateCustomRule(field string, value interface{}, name, param string) {
+	fn, ok := lookupRule(name)
+	if !ok || isEmpty(value) {
+		return
+	}
+	if !fn(reflect.ValueOf(value), param) {
+		v.fail(field, name, params{"param": param})
+	}
+}
+
+// ========== PLANS ==========
+
+// Plan validates values of struct type T with rules compiled from its tags
+type Plan[T any] struct {
+	plan *structPlan
+}
+
+// Compile builds the validation plan for T, reporting unknown rules and
+// malformed parameters. Plans are cached per type.
+func Compile[T any]() (*Plan[T], error) {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	if typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("failed to compile validation plan: %s is not a struct", typ)
+	}
+	if cached, ok := plans.Load(typ); ok {
+		return &Plan[T]{plan: cached.(*structPlan)}, nil
+	}
+
+	plan, err := newCompiler().compileStruct(typ)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile validation plan for %s: %w", typ, err)
+	}
+	cached, _ := plans.LoadOrStore(typ, plan)
+	return &Plan[T]{plan: cached.(*structPlan)}, nil
+}
+
+// MustCompile is like Compile but panics on error, for package-level plans
+func MustCompile[T any]() *Plan[T] {
+	plan, err := Compile[T]()
+	if err != nil {
+		panic(err)
+	}
+	return plan
+}
+
+// Valid reports whether value passes every rule, without collecting messages.
+// Context rules are not run.
+func (p *Plan[T]) Valid(value *T) bool {
+	if value == nil {
+		return false
+	}
+	return p.plan.check(reflect.ValueOf(value).Elem(), nil)
+}
+
+// Validate validates value, returning errors keyed by JSON path like Schema.Validate
+func (p *Plan[T]) Validate(value *T) (bool, map[string][]string) {
+	if !p.plan.async && p.Valid(value) {
+		return true, nil
+	}
+	v := New()
+	p.ValidateWith(v, value)
+	return !v.HasErrors(), v.GetErrors()
+}
+
+// ValidateWith validates value into v, so that its locale and labels apply
+func (p *Plan[T]) ValidateWith(v *Validator, value *T) bool {
+	valid, _ := p.ValidateContext(context.Background(), v, value)
+	return valid
+}
+
+// ValidateContext validates value into v, running context rules with ctx.
+// Types without context rules are only walked again when they are invalid.
+func (p *Plan[T]) ValidateContext(ctx context.Context, v *Validator, value *T) (bool, error) {
+	if !p.plan.async && p.Valid(value) {
+		return true, nil
+	}
+	if value == nil {
+		v.fail("", "required", nil)
+		return false, nil
+	}
+	return v.ValidateContext(ctx, value)
+}
+
+// plans caches compiled struct plans by type
+var plans sync.Map
+
+// checkFunc checks a field value; parent is the struct holding it
+type checkFunc func(fv, parent reflect.Value) bool
+
+// structPlan checks the fields of a struct like validateStruct
+type structPlan struct {
+	fields []fieldPlan
+	async  bool // the type or a nested type has context rules
+}
+
+type fieldPlan struct {
+	index int
+	value *valuePlan
+}
+
+// valuePlan checks one value like validateValue: its rules, then nested
+// struct fields or elements
+type valuePlan struct {
+	rules  []checkFunc
+	fields *structPlan // struct, after dereferencing pointers
+	elems  *valuePlan  // slice, array or map elements
+	// Interfaces are only known at run time and use the tag path
+	dynamic   bool
+	elemRules string
+	dive      bool
+	// The value can lead back to a struct that holds it, so values are
+	// tracked while checking to stop at cycles
+	cyclic bool
+}
+
+// check reports whether every field passes. seen holds the cyclic values
+// being checked, and is allocated when the first one is reached.
+func (p *structPlan) check(val reflect.Value, seen map[visit]bool) bool {
+	for i := range p.fields {
+		f := &p.fields[i]
+		if !f.value.check(val.Field(f.index), val, seen) {
+			return false
+		}
+	}
+	return true
+}
+
+// check reports whether the value and its nested values pass
+func (p *valuePlan) check(val, parent reflect.Value, seen map[visit]bool) bool {
+	for _, rule := range p.rules {
+		if !rule(val, parent) {
+			return false
+		}
+	}
+
+	if p.cyclic {
+		if key, ok := visitKey(val); ok {
+			if seen[key] {
+				return true
+			}
+			if seen == nil {
+				seen = make(map[visit]bool)
+			}
+			seen[key] = true
+			defer delete(seen, key)
+		}
+	}
+
+	for val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return true
+		}
+		val = val.Elem()
+	}
+
+	switch {
+	case p.dynamic:
+		scratch := New()
+		scratch.object = structFields(parent)
+		scratch.validateNested("", val, p.elemRules, p.dive)
+		return !scratch.HasErrors()
+	case p.fields != nil:
+		return p.fields.check(val, seen)
+	case p.elems != nil && val.Kind() == reflect.Map:
+		var iter reflect.MapIter
+		iter.Reset(val)
+		for iter.Next() {
+			if !p.elems.check(iter.Value(), parent, seen) {
+				return false
+			}
+		}
+	case p.elems != nil:
+		for i := 0; i < val.Len(); i++ {
+			if !p.elems.check(val.Index(i), parent, seen) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// empty reports whether the plan checks nothing
+func (p *valuePlan) empty() bool {
+	return len(p.rules) == 0 && p.fields == nil && p.elems == nil && !p.dynamic
+}
+
+// ========== COMPILER ==========
+
+// compiler builds plans, sharing struct plans so recursive types terminate
+type compiler struct {
+	structs   map[reflect.Type]*structPlan
+	compiling map[reflect.Type]bool
+	async     bool // context rules were found in the struct being compiled
+}
+
+func newCompiler() *compiler {
+	return &compiler{
+		structs:   make(map[reflect.Type]*structPlan),
+		compiling: make(map[reflect.Type]bool),
+	}
+}
+
+// compileStruct compiles the exported fields of a struct type
+func (c *compiler) compileStruct(typ reflect.Type) (*structPlan, error) {
+	if plan, ok := c.structs[typ]; ok {
+		return plan, nil
+	}
+	if cached, ok := plans.Load(typ); ok {
+		plan := cached.(*structPlan)
+		c.async = c.async || plan.async
+		return plan, nil
+	}
+
+	plan := &structPlan{}
+	c.structs[typ] = plan
+	c.compiling[typ] = true
+	outer := c.async
+	c.async = false
+	defer func() {
+		delete(c.compiling, typ)
+		plan.async = c.async
+		c.async = outer || plan.async
+	}()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag := field.Tag.Get("validate")
+		if tag == "-" {
+			continue
+		}
+		value, err := c.compileValue(field.Type, tag, typ)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		if !value.empty() {
+			plan.fields = append(plan.fields, fieldPlan{index: i, value: value})
+		}
+	}
+	return plan, nil
+}
+
+// compileValue compiles the rules of a value and how to reach its nested values
+func (c *compiler) compileValue(typ reflect.Type, tag string, parent reflect.Type) (*valuePlan, error) {
+	before, after, dive := splitDive(tag)
+	plan := &valuePlan{}
+
+	rules, err := c.compileRules(typ, before, parent)
+	if err != nil {
+		return nil, err
+	}
+	plan.rules = rules
+
+	elem := typ
+	for elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+
+	switch elem.Kind() {
+	case reflect.Interface:
+		plan.dynamic = true
+		plan.elemRules, plan.dive = after, dive
+	case reflect.Struct:
+		if elem == reflect.TypeOf(time.Time{}) {
+			break
+		}
+		fields, err := c.compileStruct(elem)
+		if err != nil {
+			return nil, err
+		}
+		// Structs still being compiled are recursive and may gain fields
+		if c.compiling[elem] {
+			plan.fields = fields
+			plan.cyclic = true
+		} else if len(fields.fields) > 0 {
+			plan.fields = fields
+		}
+	case reflect.Slice, reflect.Array, reflect.Map:
+		if elem.Elem().Kind() == reflect.Uint8 && elem.Kind() != reflect.Map {
+			break
+		}
+		if !dive {
+			after = ""
+		}
+		elems, err := c.compileValue(elem.Elem(), after, parent)
+		if err != nil {
+			return nil, err
+		}
+		if !elems.empty() {
+			plan.elems = elems
+			plan.cyclic = elems.cyclic
+		}
+	}
+	return plan, nil
+}
+
+// compileRules compiles the rules of a tag for values of typ in a struct of type parent
+func (c *compiler) compileRules(typ reflect.Type, tag string, parent reflect.Type) ([]checkFunc, error) {
+	var checks []checkFunc
+	for _, rule := range parseTag(tag) {
+		// Context rules need I/O and only run in ValidateContext
+		if _, ok := lookupContextRule(rule.name); ok {
+			c.async = true
+			continue
+		}
+		check, err := compileRule(typ, rule.name, rule.param, rule.text, parent)
+		if err != nil {
+			return nil, err
+		}
+		checks = append(checks, check)
+	}
+	return checks, nil
+}
+
+// compileRule returns a typed check where the tag path's semantics allow one,
+// and otherwise a check that runs the tag path for the single rule
+func compileRule(typ reflect.Type, name, param, rule string, parent reflect.Type) (checkFunc, error) {
+	if fn, ok := lookupRule(name); ok {
+		return func(fv, _ reflect.Value) bool {
+			if fv.Kind() == reflect.Interface {
+				fv = fv.Elem()
+			}
+			return emptyValue(fv) || fn(fv, param)
+		}, nil
+	}
+	if !isBuiltinRule(name) {
+		return nil, fmt.Errorf("unknown validation rule %q", name)
+	}
+
+	// Strings without a String method are formatted as themselves by toString
+	isString := typ.Kind() == reflect.String && !typ.Implements(stringerType)
+	// toIntValue only converts predeclared number types
+	isNumber := typ.PkgPath() == "" && numberKind(typ.Kind())
+
+	switch name {
+	case "required":
+		return func(fv, _ reflect.Value) bool { return !emptyValue(fv) }, nil
+
+	case "min", "max", "min_len", "max_len":
+		limit, ok := toInt(param)
+		if !ok {
+			return nil, fmt.Errorf("rule %q needs an integer parameter", rule)
+		}
+		switch {
+		case name == "min" && isNumber:
+			return func(fv, _ reflect.Value) bool { return intValue(fv) >= limit }, nil
+		case name == "max" && isNumber:
+			return func(fv, _ reflect.Value) bool { return intValue(fv) <= limit }, nil
+		case name == "min_len" && isString:
+			return func(fv, _ reflect.Value) bool { return utf8.RuneCountInString(fv.String()) >= limit }, nil
+		case name == "max_len" && isString:
+			return func(fv, _ reflect.Value) bool { return utf8.RuneCountInString(fv.String()) <= limit }, nil
+		}
+
+	case "regex":
+		pattern, err := regexp.Compile(param)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q has an invalid pattern: %w", rule, err)
+		}
+		if isString {
+			return stringCheck(pattern.MatchString), nil
+		}
+
+	case "in", "not_in":
+		if isString {
+			options := strings.Split(param, ",")
+			for i := range options {
+				options[i] = strings.TrimSpace(options[i])
+			}
+			want := name == "in"
+			return stringCheck(func(s string) bool { return contains(options, s) == want }), nil
+		}
+
+	case "eq_field":
+		other, found := fieldByJSONName(parent, param)
+		if !found {
+			// Missing fields are skipped by the tag path
+			return func(reflect.Value, reflect.Value) bool { return true }, nil
+		}
+		if isString && other.Type == typ {
+			slow := tagCheck(rule)
+			// Equal strings are equal however they are compared; others may
+			// still be equal as numbers or dates
+			return func(fv, parent reflect.Value) bool {
+				s, o := fv.String(), parent.FieldByIndex(other.Index).String()
+				return s == "" || o == "" || s == o || slow(fv, parent)
+			}, nil
+		}
+
+	case "equal":
+		if isString {
+			return func(fv, _ reflect.Value) bool { return fv.String() == param }, nil
+		}
+	case "not_equal":
+		if isString {
+			return func(fv, _ reflect.Value) bool { return fv.String() != param }, nil
+		}
+
+	default:
+		if format, ok := stringFormats[name]; ok && isString {
+			return stringCheck(format), nil
+		}
+	}
+	return tagCheck(rule), nil
+}
+
+// stringFormats are the rules that check the format of non-empty strings
+var stringFormats = map[string]func(string) bool{
+	"email":    isEmail,
+	"url":      isURL,
+	"alpha":    isAlpha,
+	"alphanum": isAlphaNum,
+	"numeric":  isNumeric,
+	"uuid":     isUUID,
+	"ip":       isIP,
+	"ipv4":     isIPv4,
+	"ipv6":     isIPv6,
+	"date":     func(s string) bool { _, err := time.Parse("2006-01-02", s); return err == nil },
+	"datetime": func(s string) bool { _, err := time.Parse(time.RFC3339, s); return err == nil },
+	"time":     func(s string) bool { _, err := time.Parse("15:04:05", s); return err == nil },
+}
+
+// stringCheck applies ok to non-empty strings
+func stringCheck(ok func(string) bool) checkFunc {
+	return func(fv, _ reflect.Value) bool {
+		s := fv.String()
+		return s == "" || ok(s)
+	}
+}
+
+// tagCheck runs the tag path for one rule, for types and rules without a typed check
+func tagCheck(rule string) checkFunc {
+	return func(fv, parent reflect.Value) bool {
+		scratch := New()
+		scratch.object = structFields(parent)
+		scratch.validateField("", fv.Interface(), rule)
+		return !scratch.HasErrors()
+	}
+}
+
+var stringerType = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+
+// fieldByJSONName finds the exported field that structFields keys by name
+func fieldByJSONName(typ reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.IsExported() && jsonName(field) == name {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// numberKind reports whether kind is an integer or float kind
+func numberKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// intValue converts a number like toIntValue
+func intValue(fv reflect.Value) int {
+	switch fv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(fv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int(fv.Uint())
+	}
+	return int(fv.Float())
+}
+
+// emptyValue is isEmpty without boxing the value
+func emptyValue(fv reflect.Value) bool {
+	switch fv.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.String, reflect.Array, reflect.Slice, reflect.Map:
+		return fv.Len() == 0
+	case reflect.Ptr:
+		return fv.IsNil()
+	case reflect.Interface:
+		return fv.IsNil() || emptyValue(fv.Elem())
+	case reflect.Float32, reflect.Float64:
+		return fv.Float() == 0
+	}
+	return fv.IsZero()
+}
+
+// contains reports whether options holds s
+func contains(options []string, s string) bool {
+	for _, option := range options {
+		if option == s {
+			return true
+		}
+	}
+	return false
+}
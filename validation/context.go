@@ -0,0 +1,191 @@
+package validation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Context rules check values with I/O, such as database lookups. They run
+// after the synchronous rules, concurrently and with the caller's context, and
+// only for non-empty fields whose synchronous rules passed.
+
+// DefaultConcurrency bounds the context checks a validator runs at once
+const DefaultConcurrency = 4
+
+// ContextFunc checks a value with I/O. It reports whether the value is valid;
+// an error means the check could not run.
+type ContextFunc func(ctx context.Context, value interface{}) (bool, error)
+
+// ContextCheck is a context rule and the message, a catalog code or template,
+// reported when it fails
+type ContextCheck struct {
+	Fn      ContextFunc
+	Message string
+}
+
+// Exister reports whether records matching conditions exist. *repo.Repository implements it.
+type Exister interface {
+	Exists(ctx context.Context, conditions map[string]interface{}) (bool, error)
+}
+
+// CustomContext adds a context rule to a field. Msg is a catalog code or a
+// template; it defaults to "invalid".
+func CustomContext(fn ContextFunc, msg ...string) func(*Rules) {
+	check := ContextCheck{Fn: fn, Message: "invalid"}
+	if len(msg) > 0 {
+		check.Message = msg[0]
+	}
+	return func(r *Rules) { r.ContextChecks = append(r.ContextChecks, check) }
+}
+
+// Unique requires that no record has the value in column, e.g. an email that
+// is not registered yet
+func Unique(records Exister, column string) func(*Rules) {
+	return CustomContext(func(ctx context.Context, value interface{}) (bool, error) {
+		exists, err := records.Exists(ctx, map[string]interface{}{column: value})
+		return !exists, err
+	}, "unique")
+}
+
+// Exists requires a record with the value in column, e.g. a coupon code
+func Exists(records Exister, column string) func(*Rules) {
+	return CustomContext(func(ctx context.Context, value interface{}) (bool, error) {
+		return records.Exists(ctx, map[string]interface{}{column: value})
+	}, "exists")
+}
+
+// contextRules holds context rules added with RegisterContextRule, by name
+var contextRules sync.Map
+
+// RegisterContextRule adds a tag rule backed by fn, e.g. `validate:"required|email|unique_email"`.
+// Its message code is its name.
+func RegisterContextRule(name string, fn ContextFunc, message ...string) error {
+	if err := checkRuleName(name); err != nil {
+		return err
+	}
+	contextRules.Store(name, ContextCheck{Fn: fn, Message: name})
+	if len(message) > 0 {
+		RegisterBundle(DefaultLocale, Bundle{name: message[0]})
+	}
+	return nil
+}
+
+// lookupContextRule returns a registered context rule
+func lookupContextRule(name string) (ContextCheck, bool) {
+	check, ok := contextRules.Load(name)
+	if !ok {
+		return ContextCheck{}, false
+	}
+	return check.(ContextCheck), true
+}
+
+// ========== RUNNING CHECKS ==========
+
+// pendingCheck is a context check queued during synchronous validation
+type pendingCheck struct {
+	field string
+	value interface{}
+	check ContextCheck
+}
+
+// SetConcurrency sets how many context checks run at once
+func (v *Validator) SetConcurrency(n int) *Validator {
+	v.concurrency = n
+	return v
+}
+
+// ValidateContext validates a struct like Validate, running context rules
+// with ctx. The error reports checks that could not run, e.g. because the
+// deadline passed; their fields get an "unavailable" error.
+func (v *Validator) ValidateContext(ctx context.Context, s interface{}) (bool, error) {
+	v.validateTags(s)
+	err := v.runChecks(ctx)
+	return len(v.errors) == 0, err
+}
+
+// queueChecks queues the context checks of a field if its synchronous rules
+// added no errors since it had errorCount of them
+func (v *Validator) queueChecks(field string, value interface{}, checks []ContextCheck, errorCount int) {
+	if len(checks) == 0 || isEmpty(value) || len(v.errors[field]) > errorCount {
+		return
+	}
+	for _, check := range checks {
+		v.pending = append(v.pending, pendingCheck{field: field, value: value, check: check})
+	}
+}
+
+// checkResult is the outcome of a pending check
+type checkResult struct {
+	index int
+	ok    bool
+	err   error
+}
+
+// runChecks runs the queued checks with a bounded pool of workers. Once ctx is
+// done no more checks start, and checks still running are not waited for.
+func (v *Validator) runChecks(ctx context.Context) error {
+	pending := v.pending
+	v.pending = nil
+	if len(pending) == 0 {
+		return nil
+	}
+
+	workers := v.concurrency
+	if workers <= 0 {
+		workers = DefaultConcurrency
+	}
+	if workers > len(pending) {
+		workers = len(pending)
+	}
+
+	jobs := make(chan int)
+	results := make(chan checkResult, len(pending))
+	for w := 0; w < workers; w++ {
+		go func() {
+			for i := range jobs {
+				ok, err := pending[i].check.Fn(ctx, pending[i].value)
+				results <- checkResult{index: i, ok: ok, err: err}
+			}
+		}()
+	}
+	go func() {
+		defer close(jobs)
+		for i := range pending {
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	outcomes := make([]*checkResult, len(pending))
+collect:
+	for received := 0; received < len(pending); received++ {
+		select {
+		case result := <-results:
+			outcomes[result.index] = &result
+		case <-ctx.Done():
+			break collect
+		}
+	}
+
+	// Errors are added in queue order so results do not depend on scheduling
+	var errs []error
+	for i, job := range pending {
+		result := outcomes[i]
+		switch {
+		case result == nil:
+			v.fail(job.field, "unavailable", nil)
+			errs = append(errs, fmt.Errorf("failed to check %s: %w", job.field, ctx.Err()))
+		case result.err != nil:
+			v.fail(job.field, "unavailable", nil)
+			errs = append(errs, fmt.Errorf("failed to check %s: %w", job.field, result.err))
+		case !result.ok:
+			v.customMessage(job.field, job.check.Message)
+		}
+	}
+	return errors.Join(errs...)
+}
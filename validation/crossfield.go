@@ -0,0 +1,310 @@
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldCondition matches when another field equals one of Values
+type FieldCondition struct {
+	Field  string
+	Values []interface{}
+}
+
+// matches reports whether the condition holds for obj
+func (c FieldCondition) matches(obj map[string]interface{}) bool {
+	value, ok := obj[c.Field]
+	if !ok || isEmpty(value) {
+		return false
+	}
+	str, _ := toString(value)
+	for _, v := range c.Values {
+		if fmt.Sprintf("%v", v) == str {
+			return true
+		}
+	}
+	return false
+}
+
+// String describes the condition for error messages
+func (c FieldCondition) String() string {
+	if len(c.Values) == 1 {
+		return fmt.Sprintf("%s is %v", c.Field, c.Values[0])
+	}
+	var values []string
+	for _, v := range c.Values {
+		values = append(values, fmt.Sprintf("%v", v))
+	}
+	return fmt.Sprintf("%s is one of: %s", c.Field, strings.Join(values, ", "))
+}
+
+// ObjectRule validates the whole object. Errors are reported with v.AddError
+// under the field they belong to; v.DisplayName gives localized field names.
+type ObjectRule func(obj map[string]interface{}, v *Validator)
+
+// Cross-field rule helpers
+
+// EqField requires the value to equal another field, e.g. password_confirm and password
+func EqField(field string) func(*Rules) {
+	return func(r *Rules) { r.EqField = field }
+}
+
+// NeField requires the value to differ from another field
+func NeField(field string) func(*Rules) {
+	return func(r *Rules) { r.NeField = field }
+}
+
+// GtField requires the value to be greater than, or after, another field
+func GtField(field string) func(*Rules) {
+	return func(r *Rules) { r.GtField = field }
+}
+
+// GteField requires the value to be greater than or equal to another field
+func GteField(field string) func(*Rules) {
+	return func(r *Rules) { r.GteField = field }
+}
+
+// LtField requires the value to be less than, or before, another field
+func LtField(field string) func(*Rules) {
+	return func(r *Rules) { r.LtField = field }
+}
+
+// LteField requires the value to be less than or equal to another field
+func LteField(field string) func(*Rules) {
+	return func(r *Rules) { r.LteField = field }
+}
+
+// RequiredIf makes the field required when field equals one of values.
+// Several RequiredIf rules make it required when any of them matches.
+func RequiredIf(field string, values ...interface{}) func(*Rules) {
+	return func(r *Rules) {
+		r.RequiredIf = append(r.RequiredIf, FieldCondition{Field: field, Values: values})
+	}
+}
+
+// RequiredWith makes the field required when any of fields is present
+func RequiredWith(fields ...string) func(*Rules) {
+	return func(r *Rules) { r.RequiredWith = append(r.RequiredWith, fields...) }
+}
+
+// ExcludedUnless requires the field to be empty unless field equals one of values.
+// Several ExcludedUnless rules must all match for the field to be allowed.
+func ExcludedUnless(field string, values ...interface{}) func(*Rules) {
+	return func(r *Rules) {
+		r.ExcludedUnless = append(r.ExcludedUnless, FieldCondition{Field: field, Values: values})
+	}
+}
+
+// Check adds a rule that receives the whole object, for constraints that do not
+// fit a single field
+func (s *Schema) Check(rule ObjectRule) *Schema {
+	s.checks = append(s.checks, rule)
+	return s
+}
+
+// validateRequired reports an empty field that is required, possibly
+// depending on other fields
+func (r Rules) validateRequired(v *Validator, field string, obj map[string]interface{}) {
+	if r.Required {
+		v.fail(field, "required", nil)
+		return
+	}
+	for _, cond := range r.RequiredIf {
+		if cond.matches(obj) {
+			v.fail(field, "required_if", params{"condition": v.condition(field, cond), "other": v.DisplayName(siblingPath(field, cond.Field))})
+			return
+		}
+	}
+	for _, other := range r.RequiredWith {
+		if value, ok := obj[other]; ok && !isEmpty(value) {
+			v.fail(field, "required_with", params{"other": v.DisplayName(siblingPath(field, other))})
+			return
+		}
+	}
+}
+
+// validateCrossField applies rules that compare a non-empty value with other fields
+func (r Rules) validateCrossField(v *Validator, field string, value interface{}, obj map[string]interface{}) {
+	for _, cond := range r.ExcludedUnless {
+		if !cond.matches(obj) {
+			v.fail(field, "excluded_unless", params{"condition": v.condition(field, cond), "other": v.DisplayName(siblingPath(field, cond.Field))})
+			break
+		}
+	}
+
+	if r.EqField != "" {
+		if other, ok := otherValue(obj, r.EqField); ok && !valuesEqual(value, other) {
+			v.fail(field, "eq_field", params{"other": v.DisplayName(siblingPath(field, r.EqField))})
+		}
+	}
+	if r.NeField != "" {
+		if other, ok := otherValue(obj, r.NeField); ok && valuesEqual(value, other) {
+			v.fail(field, "ne_field", params{"other": v.DisplayName(siblingPath(field, r.NeField))})
+		}
+	}
+
+	orderings := []struct {
+		other    string
+		ok       func(cmp int) bool
+		code     string
+		timeCode string
+	}{
+		{r.GtField, func(cmp int) bool { return cmp > 0 }, "gt_field", "after_field"},
+		{r.GteField, func(cmp int) bool { return cmp >= 0 }, "gte_field", "not_before_field"},
+		{r.LtField, func(cmp int) bool { return cmp < 0 }, "lt_field", "before_field"},
+		{r.LteField, func(cmp int) bool { return cmp <= 0 }, "lte_field", "not_after_field"},
+	}
+	for _, o := range orderings {
+		if o.other == "" {
+			continue
+		}
+		other, ok := otherValue(obj, o.other)
+		if !ok {
+			continue
+		}
+		p := params{"other": v.DisplayName(siblingPath(field, o.other))}
+		cmp, isTime, comparable := compareValues(value, other)
+		switch {
+		case !comparable:
+			v.fail(field, "not_comparable", p)
+		case !o.ok(cmp) && isTime:
+			v.fail(field, o.timeCode, p)
+		case !o.ok(cmp):
+			v.fail(field, o.code, p)
+		}
+	}
+}
+
+// otherValue returns another field's value; empty fields are left to their own rules
+func otherValue(obj map[string]interface{}, field string) (interface{}, bool) {
+	value, ok := obj[field]
+	if !ok || isEmpty(value) {
+		return nil, false
+	}
+	return value, true
+}
+
+// valuesEqual reports whether a and b are equal. Strings are compared as
+// written, so "0" does not equal "0000"; numbers and times compare by value.
+func valuesEqual(a, b interface{}) bool {
+	if sa, okA := stringValue(a); okA {
+		if sb, okB := stringValue(b); okB {
+			return sa == sb
+		}
+	}
+	if cmp, _, ok := compareValues(a, b); ok {
+		return cmp == 0
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// compareValues orders times, numbers and strings. Strings holding dates on
+// both sides, as in map data, are compared as times; numbers must have a
+// numeric type, so numeric strings are ordered as strings.
+func compareValues(a, b interface{}) (cmp int, isTime bool, ok bool) {
+	if ta, okA := toTime(a); okA {
+		if tb, okB := toTime(b); okB {
+			return ta.Compare(tb), true, true
+		}
+	}
+	if fa, okA := numberValue(a); okA {
+		if fb, okB := numberValue(b); okB {
+			switch {
+			case fa < fb:
+				return -1, false, true
+			case fa > fb:
+				return 1, false, true
+			}
+			return 0, false, true
+		}
+	}
+	sa, okA := stringValue(a)
+	sb, okB := stringValue(b)
+	if okA && okB {
+		return strings.Compare(sa, sb), false, true
+	}
+	return 0, false, false
+}
+
+// numberValue converts values of numeric types
+func numberValue(value interface{}) (float64, bool) {
+	rv := reflect.ValueOf(value)
+	if rv.Kind() == reflect.String {
+		return 0, false
+	}
+	return toFloat(value)
+}
+
+// stringValue returns the value of strings, including named string types
+func stringValue(value interface{}) (string, bool) {
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.String {
+		return "", false
+	}
+	return rv.String(), true
+}
+
+// toTime converts time.Time values and RFC 3339 or YYYY-MM-DD strings
+func toTime(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, true
+	case *time.Time:
+		if v != nil {
+			return *v, true
+		}
+	case string:
+		for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// toFloat converts numbers and numeric strings
+func toFloat(value interface{}) (float64, bool) {
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	case reflect.String:
+		if f, err := strconv.ParseFloat(rv.String(), 64); err == nil {
+			return f, true
+		}
+	}
+	return 0, false
+}
+
+// parseCondition parses a "field,value1,value2" tag value
+func parseCondition(value string) FieldCondition {
+	parts := splitOptions(value)
+	cond := FieldCondition{}
+	if len(parts) > 0 {
+		cond.Field = parts[0].(string)
+		cond.Values = parts[1:]
+	}
+	return cond
+}
+
+// structFields returns the exported fields of a struct keyed by json name
+func structFields(val reflect.Value) map[string]interface{} {
+	typ := val.Type()
+	obj := make(map[string]interface{}, typ.NumField())
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		obj[jsonName(field)] = val.Field(i).Interface()
+	}
+	return obj
+}
@@ -0,0 +1,206 @@
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// JSONSchema returns the JSON Schema keywords implied by the rules.
+// Required is not included because it belongs to the parent object.
+func (r Rules) JSONSchema() map[string]interface{} {
+	schema := make(map[string]interface{})
+	var patterns []string
+
+	if r.Type != "" {
+		schema["type"] = r.Type
+	}
+	if r.Label != "" {
+		schema["title"] = r.Label
+	}
+	if r.Min != nil {
+		schema["minimum"] = *r.Min
+	}
+	if r.Max != nil {
+		schema["maximum"] = *r.Max
+	}
+	if r.MinFloat != nil {
+		schema["minimum"] = *r.MinFloat
+	}
+	if r.MaxFloat != nil {
+		schema["maximum"] = *r.MaxFloat
+	}
+	if r.MinLen != nil {
+		schema["minLength"] = *r.MinLen
+	}
+	if r.MaxLen != nil {
+		schema["maxLength"] = *r.MaxLen
+	}
+
+	if r.Pattern != nil {
+		patterns = append(patterns, r.Pattern.String())
+	}
+	if r.Alpha {
+		patterns = append(patterns, alphaRegex.String())
+	}
+	if r.AlphaNum {
+		patterns = append(patterns, alphaNumRegex.String())
+	}
+	if r.Numeric {
+		patterns = append(patterns, numericRegex.String())
+	}
+
+	switch {
+	case r.Email:
+		schema["format"] = "email"
+	case r.URL:
+		schema["format"] = "uri"
+	case r.UUID:
+		schema["format"] = "uuid"
+	case r.IPv4:
+		schema["format"] = "ipv4"
+	case r.IPv6:
+		schema["format"] = "ipv6"
+	case r.IP:
+		schema["anyOf"] = []interface{}{
+			map[string]interface{}{"format": "ipv4"},
+			map[string]interface{}{"format": "ipv6"},
+		}
+	case r.DateTimeOnly:
+		schema["format"] = "date-time"
+	case r.DateOnly:
+		schema["format"] = "date"
+	case r.TimeOnly:
+		schema["format"] = "time"
+	}
+
+	if r.TimeFormat != nil {
+		schema["x-time-format"] = *r.TimeFormat
+	}
+	if r.TimeAfter != nil {
+		schema["formatMinimum"] = r.TimeAfter.Format(time.RFC3339)
+	}
+	if r.TimeBefore != nil {
+		schema["formatMaximum"] = r.TimeBefore.Format(time.RFC3339)
+	}
+
+	if len(r.OneOf) > 0 {
+		schema["enum"] = r.OneOf
+	}
+	if len(r.NotOneOf) > 0 {
+		schema["not"] = map[string]interface{}{"enum": r.NotOneOf}
+	}
+
+	// JSON Schema allows one pattern per schema; extra ones go into allOf
+	if len(patterns) > 0 {
+		schema["pattern"] = patterns[0]
+		if len(patterns) > 1 {
+			var allOf []interface{}
+			for _, p := range patterns[1:] {
+				allOf = append(allOf, map[string]interface{}{"pattern": p})
+			}
+			schema["allOf"] = allOf
+		}
+	}
+
+	if _, typed := schema["type"]; !typed {
+		if t := r.jsonType(); t != "" {
+			schema["type"] = t
+		}
+	}
+
+	return schema
+}
+
+// jsonType infers the JSON type from the kind of rules present
+func (r Rules) jsonType() string {
+	switch {
+	case r.MinLen != nil || r.MaxLen != nil || r.Pattern != nil || r.Email || r.URL ||
+		r.Alpha || r.AlphaNum || r.Numeric || r.UUID || r.IP || r.IPv4 || r.IPv6 ||
+		r.TimeFormat != nil || r.DateTimeOnly || r.DateOnly || r.TimeOnly:
+		return "string"
+	case r.MinFloat != nil || r.MaxFloat != nil:
+		return "number"
+	case r.Min != nil || r.Max != nil:
+		return "integer"
+	}
+	return ""
+}
+
+// Fields returns the names of fields with rules, sorted
+func (s *Schema) Fields() []string {
+	fields := make([]string, 0, len(s.rules))
+	for field := range s.rules {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// Rules returns the rules for a field
+func (s *Schema) Rules(field string) (Rules, bool) {
+	r, ok := s.rules[field]
+	return r, ok
+}
+
+// JSONSchema returns an object JSON Schema describing the schema fields,
+// including nested objects and arrays
+func (s *Schema) JSONSchema() map[string]interface{} {
+	properties := make(map[string]interface{}, len(s.rules))
+	var required []string
+
+	for _, field := range s.Fields() {
+		rules := s.rules[field]
+		properties[field] = rules.JSONSchema()
+		if rules.Required {
+			required = append(required, field)
+		}
+	}
+	for field, sub := range s.objects {
+		properties[field] = mergeSchema(properties[field], sub.JSONSchema())
+	}
+	for field, sub := range s.items {
+		array := map[string]interface{}{"type": "array", "items": sub.JSONSchema()}
+		properties[field] = mergeSchema(properties[field], array)
+	}
+	for field, rules := range s.values {
+		array := map[string]interface{}{"type": "array", "items": rules.JSONSchema()}
+		properties[field] = mergeSchema(properties[field], array)
+	}
+
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	if s.closed {
+		schema["additionalProperties"] = false
+	}
+	return schema
+}
+
+// MarshalJSONSchema encodes the schema as a JSON Schema draft 2020-12
+// document, which SchemaFromJSON reads back
+func (s *Schema) MarshalJSONSchema() ([]byte, error) {
+	schema := s.JSONSchema()
+	schema["$schema"] = DraftURI
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode JSON Schema: %w", err)
+	}
+	return data, nil
+}
+
+// mergeSchema adds the keywords of nested to the field schema, if any
+func mergeSchema(field interface{}, nested map[string]interface{}) map[string]interface{} {
+	if existing, ok := field.(map[string]interface{}); ok {
+		for k, v := range nested {
+			existing[k] = v
+		}
+		return existing
+	}
+	return nested
+}
@@ -0,0 +1,341 @@
+package validation
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/selanim/sego/responseutils"
+)
+
+// DefaultLocale is used when no bundle matches the requested locale
+const DefaultLocale = "en"
+
+// Bundle maps message codes to templates for one locale. Codes are rule names
+// such as "required" or "min_len". Templates may use {field} for the display
+// name of the field, {param} for the rule parameter and rule specific
+// placeholders such as {min}, {max}, {other} and {values}. Keys of the form
+// "field.<name>" give display names of fields.
+type Bundle map[string]string
+
+//go:embed locales/*.json
+var builtinLocales embed.FS
+
+// catalog holds the bundles of all locales
+var catalog = struct {
+	mu      sync.RWMutex
+	bundles map[string]Bundle
+}{bundles: make(map[string]Bundle)}
+
+func init() {
+	// English, Swahili and French ship with the package
+	if err := LoadBundles(builtinLocales, "locales"); err != nil {
+		panic(fmt.Sprintf("validation: failed to load built-in locales: %v", err))
+	}
+}
+
+// RegisterBundle adds messages for locale, overriding existing templates
+func RegisterBundle(locale string, bundle Bundle) {
+	locale = normalizeLocale(locale)
+
+	catalog.mu.Lock()
+	defer catalog.mu.Unlock()
+
+	existing, ok := catalog.bundles[locale]
+	if !ok {
+		existing = make(Bundle, len(bundle))
+		catalog.bundles[locale] = existing
+	}
+	for code, template := range bundle {
+		existing[code] = template
+	}
+}
+
+// LoadBundle registers messages for locale from a JSON object of code to template
+func LoadBundle(locale string, data []byte) error {
+	var bundle Bundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return fmt.Errorf("failed to parse %s messages: %w", locale, err)
+	}
+	RegisterBundle(locale, bundle)
+	return nil
+}
+
+// LoadBundles registers every <locale>.json file in dir of fsys, e.g. from an embed.FS
+func LoadBundles(fsys fs.FS, dir string) error {
+	files, err := fs.Glob(fsys, path.Join(dir, "*.json"))
+	if err != nil {
+		return fmt.Errorf("failed to list message files: %w", err)
+	}
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		if err := LoadBundle(strings.TrimSuffix(path.Base(file), ".json"), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Locales returns the registered locales, sorted
+func Locales() []string {
+	catalog.mu.RLock()
+	defer catalog.mu.RUnlock()
+
+	locales := make([]string, 0, len(catalog.bundles))
+	for locale := range catalog.bundles {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Message returns the template for code in locale, falling back to the base
+// language ("fr" for "fr-CA") and then DefaultLocale
+func Message(locale, code string) (string, bool) {
+	catalog.mu.RLock()
+	defer catalog.mu.RUnlock()
+
+	for _, candidate := range localeChain(locale) {
+		if template, ok := catalog.bundles[candidate][code]; ok {
+			return template, true
+		}
+	}
+	return "", false
+}
+
+// localeChain lists the locales tried for locale, most specific first
+func localeChain(locale string) []string {
+	locale = normalizeLocale(locale)
+	chain := make([]string, 0, 3)
+	if locale != "" {
+		chain = append(chain, locale)
+		if base, _, found := strings.Cut(locale, "-"); found {
+			chain = append(chain, base)
+		}
+	}
+	return append(chain, DefaultLocale)
+}
+
+// normalizeLocale lowercases a locale and uses '-' as separator, e.g. "fr_CA" becomes "fr-ca"
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
+
+// ========== LOCALE SELECTION ==========
+
+type localeKey struct{}
+
+// WithLocale returns a context carrying locale for validators created with NewWithContext
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey{}, locale)
+}
+
+// LocaleFromContext returns the locale set with WithLocale, or ""
+func LocaleFromContext(ctx context.Context) string {
+	locale, _ := ctx.Value(localeKey{}).(string)
+	return locale
+}
+
+// MatchLocale picks the registered locale that best matches an Accept-Language
+// header, or DefaultLocale when none does
+func MatchLocale(acceptLanguage string) string {
+	type weighted struct {
+		locale string
+		q      float64
+	}
+
+	var prefs []weighted
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q > 0 {
+			prefs = append(prefs, weighted{locale: normalizeLocale(tag), q: q})
+		}
+	}
+	sort.SliceStable(prefs, func(i, j int) bool { return prefs[i].q > prefs[j].q })
+
+	catalog.mu.RLock()
+	defer catalog.mu.RUnlock()
+	for _, pref := range prefs {
+		if _, ok := catalog.bundles[pref.locale]; ok {
+			return pref.locale
+		}
+		if base, _, found := strings.Cut(pref.locale, "-"); found {
+			if _, ok := catalog.bundles[base]; ok {
+				return base
+			}
+		}
+	}
+	return DefaultLocale
+}
+
+// LocaleFromRequest returns the locale from the request context, or else the
+// best match for its Accept-Language header
+func LocaleFromRequest(r *http.Request) string {
+	if locale := LocaleFromContext(r.Context()); locale != "" {
+		return locale
+	}
+	return MatchLocale(r.Header.Get("Accept-Language"))
+}
+
+// NewWithContext creates a validator using the locale from ctx
+func NewWithContext(ctx context.Context) *Validator {
+	return New().SetLocale(LocaleFromContext(ctx))
+}
+
+// NewForRequest creates a validator using the locale of the request
+func NewForRequest(r *http.Request) *Validator {
+	return New().SetLocale(LocaleFromRequest(r))
+}
+
+// ========== VALIDATOR MESSAGES ==========
+
+// params holds placeholder values for a message template
+type params map[string]interface{}
+
+// SetLocale selects the locale of error messages
+func (v *Validator) SetLocale(locale string) *Validator {
+	v.locale = locale
+	return v
+}
+
+// Locale returns the locale of error messages
+func (v *Validator) Locale() string {
+	if v.locale == "" {
+		return DefaultLocale
+	}
+	return v.locale
+}
+
+// SetLabel sets the display name used for {field} and {other} in messages.
+// Field is a name or JSON path such as "items[0].sku".
+func (v *Validator) SetLabel(field, label string) *Validator {
+	if v.labels == nil {
+		v.labels = make(map[string]string)
+	}
+	v.labels[field] = label
+	return v
+}
+
+// DisplayName returns the name of a field as shown in messages: the locale's
+// "field.<name>" message, else a label, else the field name
+func (v *Validator) DisplayName(field string) string {
+	name := leafName(field)
+	if template, ok := Message(v.locale, "field."+name); ok {
+		return template
+	}
+	if label, ok := v.labels[field]; ok {
+		return label
+	}
+	if label, ok := v.labels[name]; ok {
+		return label
+	}
+	return name
+}
+
+// fail adds the message for code to field
+func (v *Validator) fail(field, code string, p params) {
+	template, ok := Message(v.locale, code)
+	if !ok {
+		template = code
+	}
+	v.addError(field, code, v.render(template, field, p))
+}
+
+// AddErrorCode adds the localized message for a catalog code, e.g.
+// AddErrorCode("quantity", "min", map[string]interface{}{"min": 1})
+func (v *Validator) AddErrorCode(field, code string, placeholders map[string]interface{}) {
+	v.fail(field, code, placeholders)
+}
+
+// render fills the placeholders of template
+func (v *Validator) render(template, field string, p params) string {
+	if !strings.Contains(template, "{") {
+		return template
+	}
+	pairs := []string{"{field}", v.DisplayName(field)}
+	for key, value := range p {
+		pairs = append(pairs, "{"+key+"}", fmt.Sprint(value))
+	}
+	return strings.NewReplacer(pairs...).Replace(template)
+}
+
+// customMessage renders a custom rule message, which may be a message code or a template
+func (v *Validator) customMessage(field, msg string) {
+	if template, ok := Message(v.locale, msg); ok {
+		v.addError(field, msg, v.render(template, field, nil))
+		return
+	}
+	v.addError(field, "custom", v.render(msg, field, nil))
+}
+
+// condition renders a FieldCondition in the validator's locale
+func (v *Validator) condition(field string, c FieldCondition) string {
+	other := v.DisplayName(siblingPath(field, c.Field))
+	if len(c.Values) == 1 {
+		template, _ := Message(v.locale, "condition")
+		return v.render(template, field, params{"other": other, "value": c.Values[0]})
+	}
+	template, _ := Message(v.locale, "condition_in")
+	return v.render(template, field, params{"other": other, "values": joinValues(c.Values)})
+}
+
+// FieldErrors returns the errors with their codes for responseutils.ValidationErrorWithCode
+func (v *Validator) FieldErrors() []responseutils.ValidationFieldError {
+	errors := make([]responseutils.ValidationFieldError, 0, len(v.list))
+	for _, err := range v.list {
+		errors = append(errors, responseutils.ValidationFieldError{Field: err.Field, Message: err.Message, Code: err.Code})
+	}
+	return errors
+}
+
+// joinValues formats rule values for messages
+func joinValues(values []interface{}) string {
+	parts := make([]string, 0, len(values))
+	for _, value := range values {
+		parts = append(parts, fmt.Sprintf("%v", value))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// leafName returns the last field name of a JSON path, e.g. "sku" for "items[2].sku"
+func leafName(path string) string {
+	for strings.HasSuffix(path, "]") {
+		i := strings.LastIndexByte(path, '[')
+		if i < 0 {
+			break
+		}
+		path = path[:i]
+	}
+	if i := strings.LastIndexByte(path, '.'); i >= 0 {
+		return path[i+1:]
+	}
+	return path
+}
+
+// siblingPath returns the path of another field in the same object as path
+func siblingPath(path, field string) string {
+	if i := strings.LastIndexByte(path, '.'); i >= 0 {
+		return path[:i+1] + field
+	}
+	return field
+}
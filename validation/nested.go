@@ -0,0 +1,337 @@
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Nested values are validated recursively. Errors are keyed by JSON path,
+// e.g. "address.city" or "items[2].sku".
+
+// Object validates the nested object in field with sub. Add Required with
+// Field to reject a missing object.
+func (s *Schema) Object(field string, sub *Schema) *Schema {
+	if s.objects == nil {
+		s.objects = make(map[string]*Schema)
+	}
+	s.objects[field] = sub
+	return s
+}
+
+// Each validates every element of the array in field with sub
+func (s *Schema) Each(field string, sub *Schema) *Schema {
+	if s.items == nil {
+		s.items = make(map[string]*Schema)
+	}
+	s.items[field] = sub
+	return s
+}
+
+// EachValue validates every element of the array in field with rules, for
+// arrays of strings or numbers
+func (s *Schema) EachValue(field string, rules ...func(*Rules)) *Schema {
+	r := Rules{}
+	for _, ruleFunc := range rules {
+		ruleFunc(&r)
+	}
+	if s.values == nil {
+		s.values = make(map[string]Rules)
+	}
+	s.values[field] = r
+	return s
+}
+
+// DisallowUnknown rejects fields of map data that have no rules or nested schema
+func (s *Schema) DisallowUnknown() *Schema {
+	s.closed = true
+	return s
+}
+
+// known reports whether the schema describes field
+func (s *Schema) known(field string) bool {
+	_, hasRules := s.rules[field]
+	_, hasObject := s.objects[field]
+	_, hasItems := s.items[field]
+	_, hasValues := s.values[field]
+	return hasRules || hasObject || hasItems || hasValues
+}
+
+// validate validates one object, keying errors below prefix
+func (s *Schema) validate(v *Validator, prefix string, data interface{}) {
+	obj, isMap, ok := objectFields(data)
+	if !ok {
+		v.fail(prefix, "object", nil)
+		return
+	}
+
+	for field, rules := range s.rules {
+		if rules.Label != "" {
+			v.SetLabel(fieldPath(prefix, field), rules.Label)
+		}
+	}
+	for field, rules := range s.rules {
+		value, exists := obj[field]
+		// Structs are only checked for the fields they have
+		if !exists && !isMap {
+			continue
+		}
+		s.validateField(v, fieldPath(prefix, field), value, rules, obj)
+	}
+
+	for field, sub := range s.objects {
+		if value := obj[field]; !isEmpty(value) {
+			sub.validate(v, fieldPath(prefix, field), value)
+		}
+	}
+
+	for field, sub := range s.items {
+		value := obj[field]
+		if isEmpty(value) {
+			continue
+		}
+		path := fieldPath(prefix, field)
+		list := reflect.Indirect(reflect.ValueOf(value))
+		if list.Kind() != reflect.Slice && list.Kind() != reflect.Array {
+			v.fail(path, "array", nil)
+			continue
+		}
+		for i := 0; i < list.Len(); i++ {
+			sub.validate(v, indexPath(path, i), list.Index(i).Interface())
+		}
+	}
+
+	for field, rules := range s.values {
+		value := obj[field]
+		if isEmpty(value) {
+			continue
+		}
+		path := fieldPath(prefix, field)
+		list := reflect.Indirect(reflect.ValueOf(value))
+		if list.Kind() != reflect.Slice && list.Kind() != reflect.Array {
+			v.fail(path, "array", nil)
+			continue
+		}
+		for i := 0; i < list.Len(); i++ {
+			s.validateField(v, indexPath(path, i), list.Index(i).Interface(), rules, obj)
+		}
+	}
+
+	if s.closed && isMap {
+		unknown := make([]string, 0)
+		for field := range obj {
+			if !s.known(field) {
+				unknown = append(unknown, field)
+			}
+		}
+		sort.Strings(unknown)
+		for _, field := range unknown {
+			v.fail(fieldPath(prefix, field), "unknown_field", nil)
+		}
+	}
+
+	// Object rules use field names relative to the object
+	for _, check := range s.checks {
+		local := New().SetLocale(v.locale)
+		local.labels = v.labels
+		check(obj, local)
+		for _, err := range local.list {
+			v.addError(fieldPath(prefix, err.Field), err.Code, err.Message)
+		}
+	}
+}
+
+// objectFields returns the fields of a map or struct, reporting whether data was a map
+func objectFields(data interface{}) (obj map[string]interface{}, isMap bool, ok bool) {
+	if m, isObj := data.(map[string]interface{}); isObj {
+		return m, true, true
+	}
+
+	val := reflect.ValueOf(data)
+	for val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return nil, false, false
+		}
+		val = val.Elem()
+	}
+
+	switch val.Kind() {
+	case reflect.Struct:
+		return structFields(val), false, true
+	case reflect.Map:
+		if val.Type().Key().Kind() != reflect.String {
+			return nil, false, false
+		}
+		obj = make(map[string]interface{}, val.Len())
+		iter := val.MapRange()
+		for iter.Next() {
+			obj[iter.Key().String()] = iter.Value().Interface()
+		}
+		return obj, true, true
+	}
+	return nil, false, false
+}
+
+// ========== STRUCT TAGS ==========
+
+// validateStruct validates the tagged fields of a struct and recurses into
+// nested structs, slices and maps
+func (v *Validator) validateStruct(prefix string, val reflect.Value) {
+	typ := val.Type()
+
+	// Cross-field tags refer to fields of the struct being validated
+	parent := v.object
+	v.object = structFields(val)
+	defer func() { v.object = parent }()
+
+	// Labels are set first so messages about sibling fields use them
+	for i := 0; i < val.NumField(); i++ {
+		field := typ.Field(i)
+		if label := field.Tag.Get("label"); label != "" && field.IsExported() {
+			v.SetLabel(fieldPath(prefix, jsonName(field)), label)
+		}
+	}
+
+	for i := 0; i < val.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		fieldName := jsonName(field)
+
+		validateTag := field.Tag.Get("validate")
+		if validateTag == "-" {
+			continue
+		}
+		v.validateValue(fieldPath(prefix, fieldName), val.Field(i), validateTag)
+	}
+}
+
+// jsonName returns the JSON name of a struct field
+func jsonName(field reflect.StructField) string {
+	if jsonTag := field.Tag.Get("json"); jsonTag != "" && jsonTag != "-" {
+		return strings.Split(jsonTag, ",")[0]
+	}
+	return field.Name
+}
+
+// validateValue applies the rules before "dive" to the value and the rules
+// after it to each element
+func (v *Validator) validateValue(path string, val reflect.Value, rules string) {
+	rules, elemRules, dive := splitDive(rules)
+	if rules != "" {
+		v.validateField(path, val.Interface(), rules)
+	}
+	v.validateNested(path, val, elemRules, dive)
+}
+
+// validateNested recurses into structs, and into slice and map elements. With
+// dive, elemRules apply to every element.
+func (v *Validator) validateNested(path string, val reflect.Value, elemRules string, dive bool) {
+	if !v.enter(val) {
+		return
+	}
+	defer v.leave(val)
+
+	switch val.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !val.IsNil() {
+			v.validateNested(path, val.Elem(), elemRules, dive)
+		}
+	case reflect.Struct:
+		if val.Type() != reflect.TypeOf(time.Time{}) {
+			v.validateStruct(path, val)
+		}
+	case reflect.Slice, reflect.Array:
+		if val.Type().Elem().Kind() == reflect.Uint8 {
+			return
+		}
+		for i := 0; i < val.Len(); i++ {
+			v.validateElement(indexPath(path, i), val.Index(i), elemRules, dive)
+		}
+	case reflect.Map:
+		keys := val.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+		for _, key := range keys {
+			v.validateElement(fieldPath(path, fmt.Sprint(key.Interface())), val.MapIndex(key), elemRules, dive)
+		}
+	}
+}
+
+// visit identifies a pointer, slice or map being walked
+type visit struct {
+	ptr uintptr
+	typ reflect.Type
+}
+
+// visitKey returns the key of a non-nil pointer, slice or map
+func visitKey(val reflect.Value) (visit, bool) {
+	switch val.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Map:
+		if !val.IsNil() {
+			return visit{ptr: val.Pointer(), typ: val.Type()}, true
+		}
+	}
+	return visit{}, false
+}
+
+// enter marks val as being walked. It reports false when it already is, so a
+// value that contains itself is validated once.
+func (v *Validator) enter(val reflect.Value) bool {
+	key, ok := visitKey(val)
+	if !ok {
+		return true
+	}
+	if v.visiting[key] {
+		return false
+	}
+	if v.visiting == nil {
+		v.visiting = make(map[visit]bool)
+	}
+	v.visiting[key] = true
+	return true
+}
+
+// leave unmarks val once its nested values have been walked
+func (v *Validator) leave(val reflect.Value) {
+	if key, ok := visitKey(val); ok {
+		delete(v.visiting, key)
+	}
+}
+
+// validateElement validates one slice or map element
+func (v *Validator) validateElement(path string, elem reflect.Value, elemRules string, dive bool) {
+	if dive {
+		v.validateValue(path, elem, elemRules)
+		return
+	}
+	v.validateNested(path, elem, "", false)
+}
+
+// splitDive splits "required|dive|email" into the rules for the value and for its elements
+func splitDive(rules string) (before, after string, dive bool) {
+	parts := strings.Split(rules, "|")
+	for i, part := range parts {
+		if strings.TrimSpace(part) == "dive" {
+			return strings.Join(parts[:i], "|"), strings.Join(parts[i+1:], "|"), true
+		}
+	}
+	return rules, "", false
+}
+
+// ========== PATHS ==========
+
+// fieldPath appends a field name to a JSON path
+func fieldPath(prefix, field string) string {
+	if prefix == "" {
+		return field
+	}
+	return prefix + "." + field
+}
+
+// indexPath appends an array index to a JSON path
+func indexPath(prefix string, i int) string {
+	return fmt.Sprintf("%s[%d]", prefix, i)
+}
@@ -1,17 +1,24 @@
 package validation
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"math"
 	"reflect"
 	"regexp"
-	"strings"
 	"time"
 	"unicode/utf8"
 )
 
 // Schema defines validation rules for a type
 type Schema struct {
-	rules map[string]Rules
+	rules   map[string]Rules
+	checks  []ObjectRule
+	objects map[string]*Schema // nested objects, see Object
+	items   map[string]*Schema // array elements, see Each
+	values  map[string]Rules   // scalar array elements, see EachValue
+	closed  bool               // reject fields without rules, see DisallowUnknown
 }
 
 // NewSchema creates a new validation schema
@@ -44,6 +51,22 @@ func Max(max int) func(*Rules) {
 	return func(r *Rules) { r.Max = &max }
 }
 
+// MinFloat sets a minimum that is compared without truncating fractions
+func MinFloat(min float64) func(*Rules) {
+	return func(r *Rules) { r.MinFloat = &min }
+}
+
+// MaxFloat sets a maximum that is compared without truncating fractions
+func MaxFloat(max float64) func(*Rules) {
+	return func(r *Rules) { r.MaxFloat = &max }
+}
+
+// Type requires a JSON type ("string", "integer", "number", "boolean", "object"
+// or "array"); for number and boolean types 0 and false satisfy Required
+func Type(jsonType string) func(*Rules) {
+	return func(r *Rules) { r.Type = jsonType }
+}
+
 func MinLen(min int) func(*Rules) {
 	return func(r *Rules) { r.MinLen = &min }
 }
@@ -137,69 +160,82 @@ func TimeOnly() func(*Rules) {
 	return func(r *Rules) { r.TimeOnly = true }
 }
 
-// Validate validates data against the schema
+// Label sets the display name of the field in error messages
+func Label(name string) func(*Rules) {
+	return func(r *Rules) { r.Label = name }
+}
+
+// Validate validates data against the schema. Errors in nested objects and
+// arrays are keyed by JSON path, e.g. "items[2].sku".
 func (s *Schema) Validate(data interface{}) (bool, map[string][]string) {
 	validator := New()
+	s.ValidateWith(validator, data)
+	return !validator.HasErrors(), validator.GetErrors()
+}
 
-	// If data is a map
-	if m, ok := data.(map[string]interface{}); ok {
-		for field, rules := range s.rules {
-			if value, exists := m[field]; exists {
-				s.validateField(validator, field, value, rules)
-			} else if rules.Required {
-				validator.AddError(field, "This field is required")
-			}
-		}
-	} else {
-		// If data is a struct
-		val := reflect.ValueOf(data)
-		if val.Kind() == reflect.Ptr {
-			val = val.Elem()
-		}
-
-		typ := val.Type()
-		for i := 0; i < val.NumField(); i++ {
-			field := typ.Field(i)
-			value := val.Field(i).Interface()
-			fieldName := field.Name
-
-			// Check for json tag
-			if jsonTag := field.Tag.Get("json"); jsonTag != "" && jsonTag != "-" {
-				fieldName = strings.Split(jsonTag, ",")[0]
-			}
+// ValidateWith validates data into v, so that its locale and labels apply
+func (s *Schema) ValidateWith(v *Validator, data interface{}) bool {
+	valid, _ := s.ValidateWithContext(context.Background(), v, data)
+	return valid
+}
 
-			if rules, exists := s.rules[fieldName]; exists {
-				s.validateField(validator, fieldName, value, rules)
-			}
-		}
-	}
+// ValidateContext validates data, running context rules with ctx and
+// reporting messages in the locale of ctx. The error reports checks that
+// could not run; their fields get an "unavailable" error.
+func (s *Schema) ValidateContext(ctx context.Context, data interface{}) (bool, map[string][]string, error) {
+	validator := NewWithContext(ctx)
+	valid, err := s.ValidateWithContext(ctx, validator, data)
+	return valid, validator.GetErrors(), err
+}
 
-	return !validator.HasErrors(), validator.GetErrors()
+// ValidateWithContext validates data into v, running context rules with ctx
+func (s *Schema) ValidateWithContext(ctx context.Context, v *Validator, data interface{}) (bool, error) {
+	s.validate(v, "", data)
+	err := v.runChecks(ctx)
+	return !v.HasErrors(), err
 }
 
 // validateField validates a single field against rules
-func (s *Schema) validateField(v *Validator, field string, value interface{}, rules Rules) {
-	// Check required
-	if rules.Required && isEmpty(value) {
-		v.AddError(field, "This field is required")
+func (s *Schema) validateField(v *Validator, field string, value interface{}, rules Rules, obj map[string]interface{}) {
+	// Check required, including conditions on other fields; empty fields skip further validation
+	if rules.missing(value) {
+		rules.validateRequired(v, field, obj)
 		return
 	}
 
-	// Skip further validation if field is empty and not required
-	if isEmpty(value) {
+	// Other rules assume the declared type
+	if rules.Type != "" && !matchesType(value, rules.Type) {
+		v.fail(field, typeCodes[rules.Type], nil)
 		return
 	}
 
+	errorCount := len(v.errors[field])
+	defer func() { v.queueChecks(field, value, rules.ContextChecks, errorCount) }()
+
+	rules.validateCrossField(v, field, value, obj)
+
 	// Min/Max for numbers
 	if rules.Min != nil {
 		if intVal, err := toIntValue(value); err == nil && intVal < *rules.Min {
-			v.AddError(field, fmt.Sprintf("Must be at least %d", *rules.Min))
+			v.fail(field, "min", params{"min": *rules.Min})
 		}
 	}
 
 	if rules.Max != nil {
 		if intVal, err := toIntValue(value); err == nil && intVal > *rules.Max {
-			v.AddError(field, fmt.Sprintf("Must be at most %d", *rules.Max))
+			v.fail(field, "max", params{"max": *rules.Max})
+		}
+	}
+
+	if rules.MinFloat != nil {
+		if f, ok := toFloat(value); ok && f < *rules.MinFloat {
+			v.fail(field, "min", params{"min": *rules.MinFloat})
+		}
+	}
+
+	if rules.MaxFloat != nil {
+		if f, ok := toFloat(value); ok && f > *rules.MaxFloat {
+			v.fail(field, "max", params{"max": *rules.MaxFloat})
 		}
 	}
 
@@ -208,61 +244,61 @@ func (s *Schema) validateField(v *Validator, field string, value interface{}, ru
 		length := utf8.RuneCountInString(str)
 
 		if rules.MinLen != nil && length < *rules.MinLen {
-			v.AddError(field, fmt.Sprintf("Must be at least %d characters", *rules.MinLen))
+			v.fail(field, "min_len", params{"min": *rules.MinLen})
 		}
 
 		if rules.MaxLen != nil && length > *rules.MaxLen {
-			v.AddError(field, fmt.Sprintf("Must be at most %d characters", *rules.MaxLen))
+			v.fail(field, "max_len", params{"max": *rules.MaxLen})
 		}
 
 		// Pattern matching
 		if rules.Pattern != nil && !rules.Pattern.MatchString(str) {
-			v.AddError(field, "Does not match required pattern")
+			v.fail(field, "regex", nil)
 		}
 
 		// Email validation
 		if rules.Email && !isEmail(str) {
-			v.AddError(field, "Must be a valid email address")
+			v.fail(field, "email", nil)
 		}
 
 		// URL validation
 		if rules.URL && !isURL(str) {
-			v.AddError(field, "Must be a valid URL")
+			v.fail(field, "url", nil)
 		}
 
 		// Alpha validation
 		if rules.Alpha && !isAlpha(str) {
-			v.AddError(field, "Must contain only letters")
+			v.fail(field, "alpha", nil)
 		}
 
 		// AlphaNum validation
 		if rules.AlphaNum && !isAlphaNum(str) {
-			v.AddError(field, "Must contain only letters and numbers")
+			v.fail(field, "alphanum", nil)
 		}
 
 		// Numeric validation
 		if rules.Numeric && !isNumeric(str) {
-			v.AddError(field, "Must be a valid number")
+			v.fail(field, "numeric", nil)
 		}
 
 		// UUID validation
 		if rules.UUID && !isUUID(str) {
-			v.AddError(field, "Must be a valid UUID")
+			v.fail(field, "uuid", nil)
 		}
 
 		// IP validation
 		if rules.IP && !isIP(str) {
-			v.AddError(field, "Must be a valid IP address")
+			v.fail(field, "ip", nil)
 		}
 
 		// IPv4 validation
 		if rules.IPv4 && !isIPv4(str) {
-			v.AddError(field, "Must be a valid IPv4 address")
+			v.fail(field, "ipv4", nil)
 		}
 
 		// IPv6 validation
 		if rules.IPv6 && !isIPv6(str) {
-			v.AddError(field, "Must be a valid IPv6 address")
+			v.fail(field, "ipv6", nil)
 		}
 
 		// OneOf validation
@@ -275,11 +311,7 @@ func (s *Schema) validateField(v *Validator, field string, value interface{}, ru
 				}
 			}
 			if !found {
-				var options []string
-				for _, opt := range rules.OneOf {
-					options = append(options, fmt.Sprintf("%v", opt))
-				}
-				v.AddError(field, fmt.Sprintf("Must be one of: %s", strings.Join(options, ", ")))
+				v.fail(field, "in", params{"values": joinValues(rules.OneOf)})
 			}
 		}
 
@@ -287,7 +319,7 @@ func (s *Schema) validateField(v *Validator, field string, value interface{}, ru
 		if len(rules.NotOneOf) > 0 {
 			for _, opt := range rules.NotOneOf {
 				if fmt.Sprintf("%v", opt) == str {
-					v.AddError(field, fmt.Sprintf("Must not be: %v", opt))
+					v.fail(field, "not_in", params{"value": opt})
 					break
 				}
 			}
@@ -296,25 +328,25 @@ func (s *Schema) validateField(v *Validator, field string, value interface{}, ru
 		// Time validation
 		if rules.TimeFormat != nil {
 			if _, err := time.Parse(*rules.TimeFormat, str); err != nil {
-				v.AddError(field, fmt.Sprintf("Must be a valid date/time in format: %s", *rules.TimeFormat))
+				v.fail(field, "time_format", params{"format": *rules.TimeFormat})
 			}
 		}
 
 		if rules.DateTimeOnly {
 			if _, err := time.Parse(time.RFC3339, str); err != nil {
-				v.AddError(field, "Must be a valid datetime (RFC3339)")
+				v.fail(field, "datetime", nil)
 			}
 		}
 
 		if rules.DateOnly {
 			if _, err := time.Parse("2006-01-02", str); err != nil {
-				v.AddError(field, "Must be a valid date (YYYY-MM-DD)")
+				v.fail(field, "date", nil)
 			}
 		}
 
 		if rules.TimeOnly {
 			if _, err := time.Parse("15:04:05", str); err != nil {
-				v.AddError(field, "Must be a valid time (HH:MM:SS)")
+				v.fail(field, "time", nil)
 			}
 		}
 	}
@@ -322,11 +354,11 @@ func (s *Schema) validateField(v *Validator, field string, value interface{}, ru
 	// Time validation for time.Time values
 	if t, ok := value.(time.Time); ok {
 		if rules.TimeAfter != nil && !t.After(*rules.TimeAfter) {
-			v.AddError(field, fmt.Sprintf("Must be after %s", rules.TimeAfter.Format(time.RFC3339)))
+			v.fail(field, "after", params{"param": rules.TimeAfter.Format(time.RFC3339)})
 		}
 
 		if rules.TimeBefore != nil && !t.Before(*rules.TimeBefore) {
-			v.AddError(field, fmt.Sprintf("Must be before %s", rules.TimeBefore.Format(time.RFC3339)))
+			v.fail(field, "before", params{"param": rules.TimeBefore.Format(time.RFC3339)})
 		}
 	}
 
@@ -334,14 +366,77 @@ func (s *Schema) validateField(v *Validator, field string, value interface{}, ru
 	for _, customFn := range rules.Custom {
 		if err := customFn(value); err != nil {
 			if rules.CustomMsg != "" {
-				v.AddError(field, rules.CustomMsg)
+				v.customMessage(field, rules.CustomMsg)
 			} else {
-				v.AddError(field, err.Error())
+				v.addError(field, "custom", err.Error())
 			}
 		}
 	}
 }
 
+// typeCodes maps JSON types to the message code used when a value has another type
+var typeCodes = map[string]string{
+	"string":  "string",
+	"integer": "integer",
+	"number":  "numeric",
+	"boolean": "boolean",
+	"object":  "object",
+	"array":   "array",
+}
+
+// missing reports whether value is absent for Required. Zero numbers and
+// false are values when the rules declare a number or boolean type.
+func (r Rules) missing(value interface{}) bool {
+	switch r.Type {
+	case "integer", "number", "boolean":
+		if value == nil {
+			return true
+		}
+		rv := reflect.ValueOf(value)
+		return rv.Kind() == reflect.Ptr && rv.IsNil()
+	}
+	return isEmpty(value)
+}
+
+// matchesType reports whether value has the JSON type jsonType. Whole floats
+// such as those decoded from JSON are integers.
+func matchesType(value interface{}, jsonType string) bool {
+	if n, ok := value.(json.Number); ok {
+		switch jsonType {
+		case "integer":
+			_, err := n.Int64()
+			return err == nil
+		case "number":
+			_, err := n.Float64()
+			return err == nil
+		}
+		return false
+	}
+
+	rv := reflect.Indirect(reflect.ValueOf(value))
+	switch jsonType {
+	case "string":
+		return rv.Kind() == reflect.String
+	case "boolean":
+		return rv.Kind() == reflect.Bool
+	case "integer", "number":
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return true
+		case reflect.Float32, reflect.Float64:
+			f := rv.Float()
+			return jsonType == "number" || (f == math.Trunc(f) && !math.IsInf(f, 0))
+		}
+		return false
+	case "object":
+		return rv.Kind() == reflect.Struct || (rv.Kind() == reflect.Map && rv.Type().Key().Kind() == reflect.String)
+	case "array":
+		return rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array
+	}
+	return true
+}
+
 // ValidateStruct validates a struct against the schema
 func (s *Schema) ValidateStruct(data interface{}) (bool, map[string][]string) {
 	return s.Validate(data)
@@ -0,0 +1,572 @@
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DraftURI identifies the JSON Schema dialect written by MarshalJSONSchema
+const DraftURI = "https://json-schema.org/draft/2020-12/schema"
+
+// annotations are keywords that describe a schema without constraining values
+var annotations = map[string]bool{
+	"$schema":     true,
+	"$id":         true,
+	"$comment":    true,
+	"$defs":       true,
+	"definitions": true,
+	"description": true,
+	"default":     true,
+	"examples":    true,
+	"deprecated":  true,
+	"readOnly":    true,
+	"writeOnly":   true,
+}
+
+// SchemaFromJSON builds a Schema from a JSON Schema (draft 2020-12) document
+// describing an object. It supports type, required, properties,
+// additionalProperties: false, items, minLength, maxLength, pattern, minimum,
+// maximum, enum, const, not enum, format and local $ref; other keywords are
+// rejected so that no constraint is silently dropped.
+func SchemaFromJSON(data []byte) (*Schema, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON Schema: %w", err)
+	}
+	return schemaFromDocument(doc)
+}
+
+// SchemaFromMap builds a Schema from a JSON Schema document held in a map,
+// such as the result of Schema.JSONSchema, see SchemaFromJSON
+func SchemaFromMap(doc map[string]interface{}) (*Schema, error) {
+	// Re-encode so Go values such as []string and int get their decoded JSON types
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode JSON Schema: %w", err)
+	}
+	return SchemaFromJSON(data)
+}
+
+// schemaFromDocument builds a Schema from a decoded JSON document
+func schemaFromDocument(doc map[string]interface{}) (*Schema, error) {
+	imp := &schemaImporter{root: doc, active: make(map[string]bool)}
+	return imp.object("#", doc)
+}
+
+// schemaImporter converts JSON Schema nodes, tracking the $refs being
+// expanded so that recursive schemas are reported instead of looping
+type schemaImporter struct {
+	root   map[string]interface{}
+	active map[string]bool
+}
+
+// importError reports a problem at the JSON pointer path of a node
+func importError(path, format string, args ...interface{}) error {
+	return fmt.Errorf("failed to import JSON Schema: %s: %s", path, fmt.Sprintf(format, args...))
+}
+
+// object converts an object schema
+func (imp *schemaImporter) object(path string, node map[string]interface{}) (*Schema, error) {
+	node, release, err := imp.resolve(path, node)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	if t, err := schemaType(path, node); err != nil {
+		return nil, err
+	} else if t != "" && t != "object" {
+		return nil, importError(path, "expected an object schema, got type %q", t)
+	}
+
+	s := NewSchema()
+	required := make(map[string]bool)
+	if list, ok := node["required"]; ok {
+		names, ok := list.([]interface{})
+		if !ok {
+			return nil, importError(path+"/required", "must be an array of names")
+		}
+		for _, name := range names {
+			field, ok := name.(string)
+			if !ok {
+				return nil, importError(path+"/required", "must be an array of names")
+			}
+			required[field] = true
+		}
+	}
+
+	for _, key := range sortedKeys(node) {
+		switch key {
+		case "type", "required", "title":
+		case "properties":
+			properties, ok := node[key].(map[string]interface{})
+			if !ok {
+				return nil, importError(path+"/properties", "must be an object")
+			}
+			for _, field := range sortedKeys(properties) {
+				prop, ok := properties[field].(map[string]interface{})
+				if !ok {
+					return nil, importError(path+"/properties/"+field, "must be an object")
+				}
+				if err := imp.property(s, path+"/properties/"+field, field, prop, required[field]); err != nil {
+					return nil, err
+				}
+			}
+		case "additionalProperties":
+			switch node[key] {
+			case false:
+				s.DisallowUnknown()
+			case true:
+			default:
+				return nil, importError(path, "unsupported keyword %q", key)
+			}
+		default:
+			if !annotations[key] {
+				return nil, importError(path, "unsupported keyword %q", key)
+			}
+		}
+	}
+
+	// Required fields need not be described by properties
+	for field := range required {
+		if !s.known(field) {
+			s.Field(field, Required())
+		}
+	}
+	return s, nil
+}
+
+// property adds a field of an object schema to s
+func (imp *schemaImporter) property(s *Schema, path, field string, node map[string]interface{}, required bool) error {
+	node, release, err := imp.resolve(path, node)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	t, err := schemaType(path, node)
+	if err != nil {
+		return err
+	}
+	if t == "" && node["properties"] != nil {
+		t = "object"
+	}
+	if t == "" && node["items"] != nil {
+		t = "array"
+	}
+
+	// Objects and arrays check their own type; the field keeps required and the label
+	fieldRules := Rules{Required: required}
+	if title, ok := node["title"].(string); ok {
+		fieldRules.Label = title
+	}
+
+	switch t {
+	case "object":
+		sub, err := imp.object(path, node)
+		if err != nil {
+			return err
+		}
+		s.Object(field, sub)
+	case "array":
+		for _, key := range sortedKeys(node) {
+			if key != "type" && key != "items" && key != "title" && !annotations[key] {
+				return importError(path, "unsupported keyword %q", key)
+			}
+		}
+		items, ok := node["items"]
+		if !ok {
+			fieldRules.Type = "array"
+			break
+		}
+		itemNode, ok := items.(map[string]interface{})
+		if !ok {
+			return importError(path+"/items", "must be a schema object")
+		}
+		if err := imp.items(s, path+"/items", field, itemNode); err != nil {
+			return err
+		}
+	default:
+		rules, err := imp.rules(path, node)
+		if err != nil {
+			return err
+		}
+		rules.Required = required
+		s.rules[field] = rules
+		return nil
+	}
+
+	if fieldRules.Required || fieldRules.Label != "" || fieldRules.Type != "" {
+		s.rules[field] = fieldRules
+	}
+	return nil
+}
+
+// items adds the element schema of an array field to s
+func (imp *schemaImporter) items(s *Schema, path, field string, node map[string]interface{}) error {
+	resolved, release, err := imp.resolve(path, node)
+	if err != nil {
+		return err
+	}
+	t, err := schemaType(path, resolved)
+	release()
+	if err != nil {
+		return err
+	}
+
+	if t == "object" || (t == "" && resolved["properties"] != nil) {
+		sub, err := imp.object(path, node)
+		if err != nil {
+			return err
+		}
+		s.Each(field, sub)
+		return nil
+	}
+	if t == "array" {
+		return importError(path, "nested arrays are not supported")
+	}
+
+	rules, err := imp.rules(path, node)
+	if err != nil {
+		return err
+	}
+	if s.values == nil {
+		s.values = make(map[string]Rules)
+	}
+	s.values[field] = rules
+	return nil
+}
+
+// rules converts the schema of a scalar value
+func (imp *schemaImporter) rules(path string, node map[string]interface{}) (Rules, error) {
+	var r Rules
+	err := imp.applyRules(&r, path, node)
+	return r, err
+}
+
+// applyRules adds the constraints of node to r. It is called again for the
+// subschemas of allOf, which refine the same value.
+func (imp *schemaImporter) applyRules(r *Rules, path string, node map[string]interface{}) error {
+	node, release, err := imp.resolve(path, node)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	for _, key := range sortedKeys(node) {
+		value := node[key]
+		switch key {
+		case "type":
+			t, err := schemaType(path, node)
+			if err != nil {
+				return err
+			}
+			if t == "object" || t == "array" {
+				return importError(path, "type %q is not supported here", t)
+			}
+			r.Type = t
+		case "title":
+			if title, ok := value.(string); ok {
+				r.Label = title
+			}
+		case "minLength", "maxLength":
+			n, err := nonNegativeInt(path, key, value)
+			if err != nil {
+				return err
+			}
+			if key == "minLength" {
+				r.MinLen = &n
+			} else {
+				r.MaxLen = &n
+			}
+		case "minimum", "maximum":
+			f, ok := value.(float64)
+			if !ok {
+				return importError(path+"/"+key, "must be a number")
+			}
+			if key == "minimum" {
+				r.MinFloat = &f
+			} else {
+				r.MaxFloat = &f
+			}
+		case "pattern":
+			pattern, ok := value.(string)
+			if !ok {
+				return importError(path+"/pattern", "must be a string")
+			}
+			if err := addPattern(r, path, pattern); err != nil {
+				return err
+			}
+		case "format":
+			format, ok := value.(string)
+			if !ok {
+				return importError(path+"/format", "must be a string")
+			}
+			applyFormat(r, format)
+		case "enum":
+			values, ok := value.([]interface{})
+			if !ok || len(values) == 0 {
+				return importError(path+"/enum", "must be a non-empty array")
+			}
+			r.OneOf = values
+		case "const":
+			r.OneOf = []interface{}{value}
+		case "not":
+			not, ok := value.(map[string]interface{})
+			values, isEnum := not["enum"].([]interface{})
+			if !ok || len(not) != 1 || !isEnum {
+				return importError(path+"/not", "only {\"enum\": [...]} is supported")
+			}
+			r.NotOneOf = values
+		case "anyOf":
+			if !isIPAnyOf(value) {
+				return importError(path+"/anyOf", "only ipv4 or ipv6 formats are supported")
+			}
+			r.IP = true
+		case "allOf":
+			subschemas, ok := value.([]interface{})
+			if !ok {
+				return importError(path+"/allOf", "must be an array")
+			}
+			for i, sub := range subschemas {
+				subNode, ok := sub.(map[string]interface{})
+				if !ok {
+					return importError(fmt.Sprintf("%s/allOf/%d", path, i), "must be a schema object")
+				}
+				if err := imp.applyRules(r, fmt.Sprintf("%s/allOf/%d", path, i), subNode); err != nil {
+					return err
+				}
+			}
+		case "x-time-format":
+			format, ok := value.(string)
+			if !ok {
+				return importError(path+"/x-time-format", "must be a string")
+			}
+			r.TimeFormat = &format
+		case "formatMinimum", "formatMaximum":
+			s, _ := value.(string)
+			t, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				return importError(path+"/"+key, "must be an RFC 3339 date-time")
+			}
+			if key == "formatMinimum" {
+				r.TimeAfter = &t
+			} else {
+				r.TimeBefore = &t
+			}
+		default:
+			if !annotations[key] {
+				return importError(path, "unsupported keyword %q", key)
+			}
+		}
+	}
+	return nil
+}
+
+// addPattern adds a pattern to r. The patterns exported for Alpha, AlphaNum and
+// Numeric map back to those rules; a second pattern becomes a custom rule.
+func addPattern(r *Rules, path, pattern string) error {
+	switch pattern {
+	case alphaRegex.String():
+		r.Alpha = true
+		return nil
+	case alphaNumRegex.String():
+		r.AlphaNum = true
+		return nil
+	case numericRegex.String():
+		r.Numeric = true
+		return nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return importError(path+"/pattern", "invalid pattern: %v", err)
+	}
+	if r.Pattern == nil {
+		r.Pattern = re
+		return nil
+	}
+	r.Custom = append(r.Custom, func(value interface{}) error {
+		if str, ok := value.(string); ok && !re.MatchString(str) {
+			return fmt.Errorf("does not match %s", pattern)
+		}
+		return nil
+	})
+	r.CustomMsg = "regex"
+	return nil
+}
+
+// applyFormat sets the rule for a format. Unknown formats are annotations in
+// draft 2020-12 and are ignored.
+func applyFormat(r *Rules, format string) {
+	switch format {
+	case "email":
+		r.Email = true
+	case "uri":
+		r.URL = true
+	case "uuid":
+		r.UUID = true
+	case "ipv4":
+		r.IPv4 = true
+	case "ipv6":
+		r.IPv6 = true
+	case "date-time":
+		r.DateTimeOnly = true
+	case "date":
+		r.DateOnly = true
+	case "time":
+		r.TimeOnly = true
+	}
+}
+
+// isIPAnyOf reports whether an anyOf value lists only ipv4 and ipv6 formats, as exported for IP
+func isIPAnyOf(value interface{}) bool {
+	options, ok := value.([]interface{})
+	if !ok || len(options) == 0 {
+		return false
+	}
+	for _, option := range options {
+		node, ok := option.(map[string]interface{})
+		if !ok || len(node) != 1 || (node["format"] != "ipv4" && node["format"] != "ipv6") {
+			return false
+		}
+	}
+	return true
+}
+
+// schemaType returns the type of a node, allowing "null" alongside one other
+// type; "" means the node has no type
+func schemaType(path string, node map[string]interface{}) (string, error) {
+	switch t := node["type"].(type) {
+	case nil:
+		return "", nil
+	case string:
+		return checkType(path, t)
+	case []interface{}:
+		var found string
+		for _, item := range t {
+			name, ok := item.(string)
+			if !ok {
+				return "", importError(path+"/type", "must be a string or an array of strings")
+			}
+			if name == "null" {
+				continue
+			}
+			if found != "" {
+				return "", importError(path+"/type", "only one type besides \"null\" is supported")
+			}
+			found = name
+		}
+		return checkType(path, found)
+	}
+	return "", importError(path+"/type", "must be a string or an array of strings")
+}
+
+// checkType rejects unknown type names
+func checkType(path, t string) (string, error) {
+	if _, ok := typeCodes[t]; !ok && t != "" && t != "null" {
+		return "", importError(path+"/type", "unknown type %q", t)
+	}
+	if t == "null" {
+		return "", nil
+	}
+	return t, nil
+}
+
+// nonNegativeInt returns a non-negative integer keyword such as minLength
+func nonNegativeInt(path, key string, value interface{}) (int, error) {
+	f, ok := value.(float64)
+	if !ok || f < 0 || f != math.Trunc(f) {
+		return 0, importError(path+"/"+key, "must be a non-negative integer")
+	}
+	return int(f), nil
+}
+
+// resolve follows the local $ref of node, if any. Keywords next to $ref
+// override those of the target. The returned func must be called once the
+// node has been converted.
+func (imp *schemaImporter) resolve(path string, node map[string]interface{}) (map[string]interface{}, func(), error) {
+	var refs []string
+	release := func() {
+		for _, ref := range refs {
+			delete(imp.active, ref)
+		}
+	}
+
+	for {
+		ref, ok := node["$ref"]
+		if !ok {
+			return node, release, nil
+		}
+		pointer, ok := ref.(string)
+		if !ok {
+			release()
+			return nil, nil, importError(path+"/$ref", "must be a string")
+		}
+		if imp.active[pointer] {
+			release()
+			return nil, nil, importError(path, "recursive $ref %q is not supported", pointer)
+		}
+		target, err := imp.lookup(path, pointer)
+		if err != nil {
+			release()
+			return nil, nil, err
+		}
+		imp.active[pointer] = true
+		refs = append(refs, pointer)
+
+		merged := make(map[string]interface{}, len(target)+len(node))
+		for k, v := range target {
+			merged[k] = v
+		}
+		for k, v := range node {
+			if k != "$ref" {
+				merged[k] = v
+			}
+		}
+		if _, own := target["$ref"]; own {
+			merged["$ref"] = target["$ref"]
+		}
+		node = merged
+	}
+}
+
+// lookup returns the node a local JSON pointer such as "#/$defs/address" points to
+func (imp *schemaImporter) lookup(path, pointer string) (map[string]interface{}, error) {
+	if !strings.HasPrefix(pointer, "#") {
+		return nil, importError(path, "only local $ref values are supported, got %q", pointer)
+	}
+
+	var current interface{} = imp.root
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "#"), "/")[1:] {
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+		node, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, importError(path, "$ref %q not found", pointer)
+		}
+		if current, ok = node[token]; !ok {
+			return nil, importError(path, "$ref %q not found", pointer)
+		}
+	}
+
+	node, ok := current.(map[string]interface{})
+	if !ok {
+		return nil, importError(path, "$ref %q does not point to a schema", pointer)
+	}
+	return node, nil
+}
+
+// sortedKeys returns the keys of a JSON object in order, so errors are deterministic
+func sortedKeys(node map[string]interface{}) []string {
+	keys := make([]string, 0, len(node))
+	for key := range node {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
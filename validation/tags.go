@@ -0,0 +1,126 @@
+package validation
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Tags are parsed in one place: the tag path, compiled plans and RulesFromTag
+// all read `validate` tags with parseTag and share the tagRules vocabulary, so
+// JSON Schema and OpenAPI output describe the rules the validator enforces.
+
+// tagRule is one rule of a validate tag, e.g. "min_len:3"
+type tagRule struct {
+	name  string
+	param string
+	text  string // the rule as written
+}
+
+// parseTag splits a validate tag such as "required|min_len:3" into rules
+func parseTag(tag string) []tagRule {
+	var rules []tagRule
+	for _, text := range strings.Split(tag, "|") {
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+		name, param, _ := strings.Cut(text, ":")
+		rules = append(rules, tagRule{name: name, param: param, text: text})
+	}
+	return rules
+}
+
+// tagRules are the built-in tag rules. Each records its rule in Rules;
+// Validator.applyRule enforces every one of them.
+var tagRules = map[string]func(r *Rules, param string){
+	"required": func(r *Rules, _ string) { r.Required = true },
+	"min": func(r *Rules, param string) {
+		if n, ok := toInt(param); ok {
+			r.Min = &n
+		}
+	},
+	"max": func(r *Rules, param string) {
+		if n, ok := toInt(param); ok {
+			r.Max = &n
+		}
+	},
+	"min_len": func(r *Rules, param string) {
+		if n, ok := toInt(param); ok {
+			r.MinLen = &n
+		}
+	},
+	"max_len": func(r *Rules, param string) {
+		if n, ok := toInt(param); ok {
+			r.MaxLen = &n
+		}
+	},
+	"email":    func(r *Rules, _ string) { r.Email = true },
+	"url":      func(r *Rules, _ string) { r.URL = true },
+	"alpha":    func(r *Rules, _ string) { r.Alpha = true },
+	"alphanum": func(r *Rules, _ string) { r.AlphaNum = true },
+	"numeric":  func(r *Rules, _ string) { r.Numeric = true },
+	"uuid":     func(r *Rules, _ string) { r.UUID = true },
+	"ip":       func(r *Rules, _ string) { r.IP = true },
+	"ipv4":     func(r *Rules, _ string) { r.IPv4 = true },
+	"ipv6":     func(r *Rules, _ string) { r.IPv6 = true },
+	"regex": func(r *Rules, param string) {
+		if re, err := regexp.Compile(param); err == nil {
+			r.Pattern = re
+		}
+	},
+	"in":        func(r *Rules, param string) { r.OneOf = splitOptions(param) },
+	"not_in":    func(r *Rules, param string) { r.NotOneOf = splitOptions(param) },
+	"date":      func(r *Rules, _ string) { r.DateOnly = true },
+	"datetime":  func(r *Rules, _ string) { r.DateTimeOnly = true },
+	"time":      func(r *Rules, _ string) { r.TimeOnly = true },
+	"equal":     func(r *Rules, param string) { r.OneOf = []interface{}{param} },
+	"not_equal": func(r *Rules, param string) { r.NotOneOf = []interface{}{param} },
+	"eq_field":  func(r *Rules, param string) { r.EqField = param },
+	"ne_field":  func(r *Rules, param string) { r.NeField = param },
+	"gt_field":  func(r *Rules, param string) { r.GtField = param },
+	"gte_field": func(r *Rules, param string) { r.GteField = param },
+	"lt_field":  func(r *Rules, param string) { r.LtField = param },
+	"lte_field": func(r *Rules, param string) { r.LteField = param },
+	"required_if": func(r *Rules, param string) {
+		r.RequiredIf = append(r.RequiredIf, parseCondition(param))
+	},
+	"required_with": func(r *Rules, param string) {
+		for _, field := range splitOptions(param) {
+			r.RequiredWith = append(r.RequiredWith, field.(string))
+		}
+	},
+	"excluded_unless": func(r *Rules, param string) {
+		r.ExcludedUnless = append(r.ExcludedUnless, parseCondition(param))
+	},
+}
+
+// isBuiltinRule reports whether name is a built-in tag rule
+func isBuiltinRule(name string) bool {
+	_, ok := tagRules[name]
+	return ok
+}
+
+// RulesFromTag converts a validate struct tag such as "required|min_len:3|email" to Rules.
+// Cross-field rules take field names, e.g. "gt_field:start_date|required_if:country,DE,FR".
+func RulesFromTag(tag string) Rules {
+	var r Rules
+
+	// Rules after "dive" apply to elements, not to the field itself
+	tag, _, _ = splitDive(tag)
+	for _, rule := range parseTag(tag) {
+		if set, ok := tagRules[rule.name]; ok {
+			set(&r, rule.param)
+		}
+	}
+
+	return r
+}
+
+// splitOptions splits a comma-separated tag value into trimmed options
+func splitOptions(value string) []interface{} {
+	var options []interface{}
+	for _, opt := range strings.Split(value, ",") {
+		options = append(options, strings.TrimSpace(opt))
+	}
+	return options
+}
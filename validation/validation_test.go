@@ -1,8 +1,15 @@
 package validation
 
 import (
+	"context"
+	stderrors "errors" // errors is a common variable name in these tests
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -427,3 +434,972 @@ func TestErrorHandling(t *testing.T) {
 		t.Error("Expected no errors after clear")
 	}
 }
+
+func TestSchemaJSONSchema(t *testing.T) {
+	schema := NewSchema().
+		Field("username", Required(), MinLen(3), MaxLen(20), AlphaNum()).
+		Field("email", Required(), Email()).
+		Field("age", Min(18), Max(120)).
+		Field("role", OneOf("admin", "user")).
+		Field("code", Pattern(`^[A-Z]{3}$`), Alpha())
+
+	js := schema.JSONSchema()
+	if js["type"] != "object" {
+		t.Errorf("Expected type object, got %v", js["type"])
+	}
+
+	required := js["required"].([]string)
+	if len(required) != 2 || required[0] != "email" || required[1] != "username" {
+		t.Errorf("Expected required [email username], got %v", required)
+	}
+
+	props := js["properties"].(map[string]interface{})
+
+	username := props["username"].(map[string]interface{})
+	if username["minLength"] != 3 || username["maxLength"] != 20 || username["type"] != "string" {
+		t.Errorf("Unexpected username schema: %v", username)
+	}
+
+	if email := props["email"].(map[string]interface{}); email["format"] != "email" {
+		t.Errorf("Expected email format, got %v", email["format"])
+	}
+
+	age := props["age"].(map[string]interface{})
+	if age["minimum"] != 18 || age["maximum"] != 120 || age["type"] != "integer" {
+		t.Errorf("Unexpected age schema: %v", age)
+	}
+
+	if role := props["role"].(map[string]interface{}); len(role["enum"].([]interface{})) != 2 {
+		t.Errorf("Expected role enum, got %v", role["enum"])
+	}
+
+	code := props["code"].(map[string]interface{})
+	if code["pattern"] != `^[A-Z]{3}$` {
+		t.Errorf("Expected code pattern, got %v", code["pattern"])
+	}
+	if allOf, ok := code["allOf"].([]interface{}); !ok || len(allOf) != 1 {
+		t.Errorf("Expected extra pattern in allOf, got %v", code["allOf"])
+	}
+
+	rules := RulesFromTag("required|min_len:8|in:a, b")
+	if !rules.Required || rules.MinLen == nil || *rules.MinLen != 8 || len(rules.OneOf) != 2 || rules.OneOf[1] != "b" {
+		t.Errorf("Unexpected rules from tag: %+v", rules)
+	}
+}
+
+func TestTagVocabulary(t *testing.T) {
+	// Every rule RulesFromTag knows is enforced by the tag path and accepted by the compiler
+	for name := range tagRules {
+		if !New().applyRule("field", "", tagRule{name: name, text: name}) {
+			t.Errorf("Expected tag path to enforce %q", name)
+		}
+		if err := checkRuleName(name); err == nil {
+			t.Errorf("Expected %q to be reserved as a built-in rule", name)
+		}
+	}
+	if New().applyRule("field", "", tagRule{name: "no_such_rule"}) {
+		t.Error("Expected unknown rule to be reported")
+	}
+
+	rules := RulesFromTag("equal:yes|not_equal:no")
+	if len(rules.OneOf) != 1 || rules.OneOf[0] != "yes" || len(rules.NotOneOf) != 1 || rules.NotOneOf[0] != "no" {
+		t.Errorf("Expected equal and not_equal in rules, got %+v", rules)
+	}
+}
+
+func TestCrossFieldRules(t *testing.T) {
+	schema := NewSchema().
+		Field("password", Required(), MinLen(8)).
+		Field("password_confirm", Required(), EqField("password")).
+		Field("start_date", Required(), DateOnly()).
+		Field("end_date", Required(), GtField("start_date")).
+		Field("country", Required()).
+		Field("vat_id", RequiredIf("country", "DE", "FR", "IT")).
+		Field("state", ExcludedUnless("country", "US")).
+		Field("phone", RequiredWith("sms_opt_in"))
+
+	valid := map[string]interface{}{
+		"password":         "secret123",
+		"password_confirm": "secret123",
+		"start_date":       "2024-01-01",
+		"end_date":         "2024-02-01",
+		"country":          "DE",
+		"vat_id":           "DE123456789",
+	}
+	if isValid, errors := schema.Validate(valid); !isValid {
+		t.Errorf("Expected valid data to pass, got errors: %v", errors)
+	}
+
+	invalid := map[string]interface{}{
+		"password":         "secret123",
+		"password_confirm": "secret124",
+		"start_date":       "2024-02-01",
+		"end_date":         "2024-01-01",
+		"country":          "FR",
+		"state":            "CA",
+		"sms_opt_in":       true,
+	}
+	isValid, errors := schema.Validate(invalid)
+	if isValid {
+		t.Fatal("Expected invalid data to fail validation")
+	}
+
+	expected := map[string]string{
+		"password_confirm": "Must match password",
+		"end_date":         "Must be after start_date",
+		"vat_id":           "This field is required when country is one of: DE, FR, IT",
+		"state":            "This field must be empty unless country is US",
+		"phone":            "This field is required when sms_opt_in is present",
+	}
+	for field, msg := range expected {
+		if len(errors[field]) != 1 || errors[field][0] != msg {
+			t.Errorf("Expected %s error %q, got %v", field, msg, errors[field])
+		}
+	}
+	if _, exists := errors["start_date"]; exists {
+		t.Errorf("Expected errors keyed to the dependent field only, got %v", errors["start_date"])
+	}
+}
+
+func TestCrossFieldStrings(t *testing.T) {
+	schema := NewSchema().
+		Field("pin_confirm", EqField("pin")).
+		Field("code_confirm", EqField("code")).
+		Field("alias", NeField("code"))
+
+	// Numeric-looking strings are compared as written
+	isValid, errors := schema.Validate(map[string]interface{}{
+		"pin":          "0000",
+		"pin_confirm":  "0",
+		"code":         "1000",
+		"code_confirm": "1e3",
+		"alias":        "1e3",
+	})
+	if isValid || len(errors["pin_confirm"]) != 1 || len(errors["code_confirm"]) != 1 {
+		t.Errorf("Expected 0 and 1e3 to differ from 0000 and 1000, got %v", errors)
+	}
+	if _, exists := errors["alias"]; exists {
+		t.Errorf("Expected 1e3 to be a different string from 1000, got %v", errors["alias"])
+	}
+
+	if isValid, errors := schema.Validate(map[string]interface{}{"pin": "0000", "pin_confirm": "0000"}); !isValid {
+		t.Errorf("Expected matching strings to pass, got %v", errors)
+	}
+}
+
+func TestCrossFieldNumbersAndTimes(t *testing.T) {
+	type booking struct {
+		MinGuests int       `json:"min_guests"`
+		MaxGuests int       `json:"max_guests" validate:"gte_field:min_guests"`
+		CheckIn   time.Time `json:"check_in"`
+		CheckOut  time.Time `json:"check_out" validate:"gt_field:check_in"`
+		Coupon    string    `json:"coupon" validate:"excluded_unless:min_guests,1,2"`
+	}
+
+	now := time.Now()
+	v := New()
+	if !v.Validate(booking{MinGuests: 2, MaxGuests: 4, CheckIn: now, CheckOut: now.Add(time.Hour), Coupon: "X"}) {
+		t.Errorf("Expected valid booking, got %v", v.GetErrors())
+	}
+
+	v = New()
+	v.Validate(booking{MinGuests: 5, MaxGuests: 4, CheckIn: now, CheckOut: now, Coupon: "X"})
+	errors := v.GetErrors()
+	if len(errors["max_guests"]) != 1 || errors["max_guests"][0] != "Must be greater than or equal to min_guests" {
+		t.Errorf("Unexpected max_guests errors: %v", errors["max_guests"])
+	}
+	if len(errors["check_out"]) != 1 || errors["check_out"][0] != "Must be after check_in" {
+		t.Errorf("Unexpected check_out errors: %v", errors["check_out"])
+	}
+	if len(errors["coupon"]) != 1 {
+		t.Errorf("Expected coupon to be excluded, got %v", errors["coupon"])
+	}
+
+	rules := RulesFromTag("required_if:country,DE,FR|required_with:a, b|lt_field:end")
+	if len(rules.RequiredIf) != 1 || rules.RequiredIf[0].Field != "country" || len(rules.RequiredIf[0].Values) != 2 {
+		t.Errorf("Unexpected required_if rules: %+v", rules.RequiredIf)
+	}
+	if len(rules.RequiredWith) != 2 || rules.RequiredWith[1] != "b" || rules.LtField != "end" {
+		t.Errorf("Unexpected cross-field rules from tag: %+v", rules)
+	}
+}
+
+func TestSchemaCheck(t *testing.T) {
+	type order struct {
+		Items    int     `json:"items"`
+		Discount float64 `json:"discount"`
+		Total    float64 `json:"total"`
+	}
+
+	schema := NewSchema().
+		Field("items", Required(), Min(1)).
+		Check(func(obj map[string]interface{}, v *Validator) {
+			if obj["discount"].(float64) > obj["total"].(float64) {
+				v.AddError("discount", "Cannot exceed the order total")
+			}
+		})
+
+	if isValid, errors := schema.Validate(order{Items: 1, Discount: 5, Total: 10}); !isValid {
+		t.Errorf("Expected valid order, got %v", errors)
+	}
+
+	isValid, errors := schema.Validate(&order{Items: 1, Discount: 15, Total: 10})
+	if isValid {
+		t.Fatal("Expected schema check to fail")
+	}
+	if len(errors["discount"]) != 1 || errors["discount"][0] != "Cannot exceed the order total" {
+		t.Errorf("Expected discount error from check, got %v", errors)
+	}
+}
+
+func TestNestedValidation(t *testing.T) {
+	type address struct {
+		Street string `json:"street" validate:"required"`
+		City   string `json:"city" validate:"required|min_len:2"`
+	}
+	type item struct {
+		SKU      string `json:"sku" validate:"required|alphanum"`
+		Quantity int    `json:"quantity" validate:"min:1"`
+	}
+	type order struct {
+		Address  address           `json:"address"`
+		Billing  *address          `json:"billing"`
+		Items    []item            `json:"items" validate:"required"`
+		Emails   []string          `json:"emails" validate:"dive|email"`
+		Tags     map[string]string `json:"tags" validate:"dive|max_len:5"`
+		Matrix   [][]int           `json:"matrix" validate:"dive|dive|max:9"`
+		Internal []item            `json:"-"`
+	}
+
+	v := New()
+	valid := order{
+		Address: address{Street: "Main St", City: "Oslo"},
+		Items:   []item{{SKU: "A1", Quantity: 1}},
+		Emails:  []string{"a@example.com"},
+		Tags:    map[string]string{"env": "prod"},
+		Matrix:  [][]int{{1, 2}},
+	}
+	if !v.Validate(valid) {
+		t.Errorf("Expected valid order, got %v", v.GetErrors())
+	}
+
+	v = New()
+	v.Validate(&order{
+		Address: address{Street: "Main St", City: "O"},
+		Billing: &address{City: "Bergen"},
+		Items:   []item{{SKU: "A1", Quantity: 1}, {SKU: "A2", Quantity: 1}, {SKU: "", Quantity: 0}},
+		Emails:  []string{"a@example.com", "invalid"},
+		Tags:    map[string]string{"env": "production"},
+		Matrix:  [][]int{{1}, {2, 10}},
+	})
+	errors := v.GetErrors()
+	for _, path := range []string{"address.city", "billing.street", "items[2].sku", "items[2].quantity", "emails[1]", "tags.env", "matrix[1][1]"} {
+		if _, exists := errors[path]; !exists {
+			t.Errorf("Expected error at %s, got %v", path, errors)
+		}
+	}
+	if len(errors) != 7 {
+		t.Errorf("Expected 7 error paths, got %v", errors)
+	}
+}
+
+// cyclicNode can point back to itself
+type cyclicNode struct {
+	Name     string        `json:"name" validate:"required"`
+	Next     *cyclicNode   `json:"next"`
+	Children []*cyclicNode `json:"children"`
+	Any      interface{}   `json:"any"`
+}
+
+func TestNestedValidationCycles(t *testing.T) {
+	root := &cyclicNode{Name: "root"}
+	child := &cyclicNode{Next: root}
+	root.Next = root
+	root.Children = []*cyclicNode{child, root}
+	root.Any = child
+
+	done := make(chan map[string][]string, 1)
+	go func() {
+		v := New()
+		v.Validate(root)
+		plan := MustCompile[cyclicNode]()
+		if plan.Valid(root) {
+			t.Error("Expected compiled plan to reject the unnamed child")
+		}
+		done <- v.GetErrors()
+	}()
+
+	select {
+	case errors := <-done:
+		// The child is reported where it is first reached; the root is not walked again
+		if _, exists := errors["children[0].name"]; !exists {
+			t.Errorf("Expected error at children[0].name, got %v", errors)
+		}
+		if _, exists := errors["name"]; exists {
+			t.Errorf("Expected no error for the named root, got %v", errors)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected validation of a cyclic value to return")
+	}
+}
+
+func TestNestedSchema(t *testing.T) {
+	addressSchema := NewSchema().
+		Field("city", Required()).
+		Field("zip", Pattern(`^\d{5}$`))
+	itemSchema := NewSchema().
+		Field("sku", Required()).
+		Field("quantity", Required(), Min(1)).
+		Check(func(obj map[string]interface{}, v *Validator) {
+			if obj["sku"] == "GIFT" && obj["quantity"] != 1 {
+				v.AddError("quantity", "Gift cards are sold one at a time")
+			}
+		})
+	schema := NewSchema().
+		Field("address", Required()).
+		Object("address", addressSchema).
+		Field("items", Required()).
+		Each("items", itemSchema)
+
+	// Data as decoded from a JSON body
+	data := map[string]interface{}{
+		"address": map[string]interface{}{"zip": "123"},
+		"items": []interface{}{
+			map[string]interface{}{"sku": "A1", "quantity": 2},
+			map[string]interface{}{"quantity": 0},
+			map[string]interface{}{"sku": "GIFT", "quantity": 3},
+			"not an object",
+		},
+	}
+
+	isValid, errors := schema.Validate(data)
+	if isValid {
+		t.Fatal("Expected nested errors")
+	}
+	expected := map[string]string{
+		"address.city":      "This field is required",
+		"address.zip":       "Does not match required pattern",
+		"items[1].sku":      "This field is required",
+		"items[1].quantity": "This field is required",
+		"items[2].quantity": "Gift cards are sold one at a time",
+		"items[3]":          "Must be an object",
+	}
+	for path, msg := range expected {
+		if len(errors[path]) != 1 || errors[path][0] != msg {
+			t.Errorf("Expected %q at %s, got %v", msg, path, errors[path])
+		}
+	}
+	if len(errors) != len(expected) {
+		t.Errorf("Expected %d error paths, got %v", len(expected), errors)
+	}
+
+	if isValid, errors := schema.Validate(map[string]interface{}{}); isValid || len(errors["address"]) != 1 {
+		t.Errorf("Expected missing address to be required, got %v", errors)
+	}
+
+	js := schema.JSONSchema()
+	props := js["properties"].(map[string]interface{})
+	if items := props["items"].(map[string]interface{}); items["type"] != "array" || items["items"] == nil {
+		t.Errorf("Expected array schema for items, got %v", items)
+	}
+	if address := props["address"].(map[string]interface{}); address["type"] != "object" {
+		t.Errorf("Expected object schema for address, got %v", address)
+	}
+}
+
+func TestLocalizedMessages(t *testing.T) {
+	type signup struct {
+		Email    string `json:"email" validate:"required|email"`
+		Password string `json:"password" label:"Password" validate:"min_len:8"`
+		Confirm  string `json:"confirm" validate:"eq_field:password"`
+	}
+	input := signup{Email: "invalid", Password: "short", Confirm: "other"}
+
+	tests := []struct {
+		locale   string
+		expected map[string]string
+	}{
+		{"en", map[string]string{
+			"email":    "Must be a valid email address",
+			"password": "Must be at least 8 characters",
+			"confirm":  "Must match Password",
+		}},
+		{"sw", map[string]string{
+			"email":    "Lazima iwe anwani halali ya barua pepe",
+			"password": "Lazima iwe na angalau herufi 8",
+			"confirm":  "Lazima ilingane na Password",
+		}},
+		{"fr-CA", map[string]string{
+			"email":    "Doit être une adresse e-mail valide",
+			"password": "Doit contenir au moins 8 caractères",
+			"confirm":  "Doit correspondre à Password",
+		}},
+	}
+	for _, tt := range tests {
+		v := New().SetLocale(tt.locale)
+		v.Validate(input)
+		errors := v.GetErrors()
+		for field, msg := range tt.expected {
+			if len(errors[field]) != 1 || errors[field][0] != msg {
+				t.Errorf("%s: Expected %q for %s, got %v", tt.locale, msg, field, errors[field])
+			}
+		}
+	}
+
+	// Codes are kept for API clients
+	v := New()
+	v.Validate(input)
+	codes := make(map[string]string)
+	for _, err := range v.FieldErrors() {
+		codes[err.Field] = err.Code
+	}
+	if codes["email"] != "email" || codes["password"] != "min_len" || codes["confirm"] != "eq_field" {
+		t.Errorf("Expected rule codes, got %v", codes)
+	}
+}
+
+func TestCustomBundleAndLabels(t *testing.T) {
+	err := LoadBundle("en-test", []byte(`{
+		"required": "{field} is required",
+		"min": "{field} must be {min} or more",
+		"field.email": "E-mail address",
+		"too_young": "{field} is too young"
+	}`))
+	if err != nil {
+		t.Fatalf("Expected bundle to load, got %v", err)
+	}
+	if err := LoadBundle("broken", []byte(`{`)); err == nil {
+		t.Error("Expected error for invalid JSON")
+	}
+
+	schema := NewSchema().
+		Field("email", Required()).
+		Field("age", Min(18), Label("Age")).
+		Field("birth_year", Custom(func(interface{}) error { return fmt.Errorf("invalid") }, "too_young")).
+		Field("nickname", Custom(func(interface{}) error { return fmt.Errorf("invalid") }, "{field} is taken"))
+
+	v := New().SetLocale("en-test").SetLabel("nickname", "Nickname")
+	if schema.ValidateWith(v, map[string]interface{}{"age": 12, "birth_year": 2020, "nickname": "bob"}) {
+		t.Fatal("Expected errors")
+	}
+	expected := map[string]string{
+		"email":      "E-mail address is required",
+		"age":        "Age must be 18 or more",
+		"birth_year": "birth_year is too young",
+		"nickname":   "Nickname is taken",
+	}
+	errors := v.GetErrors()
+	for field, msg := range expected {
+		if len(errors[field]) != 1 || errors[field][0] != msg {
+			t.Errorf("Expected %q for %s, got %v", msg, field, errors[field])
+		}
+	}
+
+	// Codes missing from the bundle fall back to English
+	v = New().SetLocale("en-test")
+	v.ValidateField("email", "invalid", "email")
+	if msgs := v.GetErrors()["email"]; len(msgs) != 1 || msgs[0] != "Must be a valid email address" {
+		t.Errorf("Expected English fallback, got %v", msgs)
+	}
+}
+
+func TestLocaleSelection(t *testing.T) {
+	tests := []struct {
+		header   string
+		expected string
+	}{
+		{"fr-CH, fr;q=0.9, en;q=0.8", "fr"},
+		{"en;q=0.5, sw;q=0.9", "sw"},
+		{"de-DE, de;q=0.9", DefaultLocale},
+		{"", DefaultLocale},
+	}
+	for _, tt := range tests {
+		if got := MatchLocale(tt.header); got != tt.expected {
+			t.Errorf("MatchLocale(%q): Expected %s, got %s", tt.header, tt.expected, got)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("Accept-Language", "sw")
+	if v := NewForRequest(req); v.Locale() != "sw" {
+		t.Errorf("Expected sw from Accept-Language, got %s", v.Locale())
+	}
+
+	req = req.WithContext(WithLocale(req.Context(), "fr"))
+	if v := NewForRequest(req); v.Locale() != "fr" {
+		t.Errorf("Expected fr from context, got %s", v.Locale())
+	}
+
+	v := NewWithContext(WithLocale(context.Background(), "sw"))
+	v.ValidateField("name", "", "required")
+	if msgs := v.GetErrors()["name"]; len(msgs) != 1 || msgs[0] != "Sehemu hii inahitajika" {
+		t.Errorf("Expected Swahili message, got %v", msgs)
+	}
+}
+
+type compiledLine struct {
+	SKU      string  `json:"sku" validate:"required|sku"`
+	Quantity int     `json:"quantity" validate:"min:1|max:1000"`
+	Price    float64 `json:"price" validate:"min:0"`
+}
+
+type compiledOrder struct {
+	ID       string            `json:"id" validate:"required|uuid"`
+	Email    string            `json:"email" validate:"required|email|max_len:100"`
+	Status   string            `json:"status" validate:"in:new,paid,shipped"`
+	Country  string            `json:"country" validate:"min_len:2|max_len:2|alpha"`
+	Placed   string            `json:"placed" validate:"datetime"`
+	Lines    []compiledLine    `json:"lines" validate:"required"`
+	Tags     []string          `json:"tags" validate:"dive|alphanum"`
+	Meta     map[string]string `json:"meta" validate:"dive|max_len:10"`
+	Parent   *compiledOrder    `json:"parent"`
+	Password string            `json:"password" validate:"min_len:8"`
+	Confirm  string            `json:"confirm" validate:"eq_field:password"`
+}
+
+var skuPattern = regexp.MustCompile(`^[A-Z]{3}-\d{4}$`)
+
+func init() {
+	RegisterRule("sku", func(value reflect.Value, _ string) bool {
+		return value.Kind() == reflect.String && skuPattern.MatchString(value.String())
+	}, "Must be a valid SKU")
+}
+
+func validCompiledOrder() compiledOrder {
+	return compiledOrder{
+		ID:       "123e4567-e89b-12d3-a456-426614174000",
+		Email:    "buyer@example.com",
+		Status:   "paid",
+		Country:  "NO",
+		Placed:   "2024-05-01T10:00:00Z",
+		Lines:    []compiledLine{{SKU: "ABC-1234", Quantity: 2, Price: 9.5}},
+		Tags:     []string{"vip", "b2b"},
+		Meta:     map[string]string{"source": "web"},
+		Password: "correct-horse",
+		Confirm:  "correct-horse",
+	}
+}
+
+func TestCompiledPlan(t *testing.T) {
+	plan, err := Compile[compiledOrder]()
+	if err != nil {
+		t.Fatalf("Expected plan to compile, got %v", err)
+	}
+
+	mutations := map[string]func(o *compiledOrder){
+		"valid":          func(o *compiledOrder) {},
+		"missing id":     func(o *compiledOrder) { o.ID = "" },
+		"bad email":      func(o *compiledOrder) { o.Email = "invalid" },
+		"bad status":     func(o *compiledOrder) { o.Status = "lost" },
+		"empty status":   func(o *compiledOrder) { o.Status = "" },
+		"bad country":    func(o *compiledOrder) { o.Country = "N0" },
+		"bad date":       func(o *compiledOrder) { o.Placed = "yesterday" },
+		"no lines":       func(o *compiledOrder) { o.Lines = nil },
+		"bad sku":        func(o *compiledOrder) { o.Lines[0].SKU = "abc" },
+		"zero quantity":  func(o *compiledOrder) { o.Lines[0].Quantity = 0 },
+		"bad tag":        func(o *compiledOrder) { o.Tags = []string{"ok", "not ok"} },
+		"long meta":      func(o *compiledOrder) { o.Meta["source"] = "a very long source" },
+		"invalid parent": func(o *compiledOrder) { o.Parent = &compiledOrder{} },
+		"mismatch":       func(o *compiledOrder) { o.Confirm = "other" },
+	}
+	for name, mutate := range mutations {
+		order := validCompiledOrder()
+		mutate(&order)
+
+		tagValidator := New()
+		tagValid := tagValidator.Validate(order)
+		valid, errors := plan.Validate(&order)
+		if valid != tagValid || plan.Valid(&order) != tagValid {
+			t.Errorf("%s: Expected compiled result %v to match tag path %v", name, valid, tagValid)
+		}
+		if !valid && !reflect.DeepEqual(errors, tagValidator.GetErrors()) {
+			t.Errorf("%s: Expected errors %v, got %v", name, tagValidator.GetErrors(), errors)
+		}
+		// Format rules skip empty values
+		expected := name == "valid" || name == "empty status"
+		if valid != expected {
+			t.Errorf("%s: Expected valid=%v, got %v (%v)", name, expected, valid, errors)
+		}
+	}
+
+	order := validCompiledOrder()
+	order.Lines[0].SKU = "abc"
+	if _, errors := plan.Validate(&order); len(errors["lines[0].sku"]) != 1 || errors["lines[0].sku"][0] != "Must be a valid SKU" {
+		t.Errorf("Expected registered rule message, got %v", errors)
+	}
+
+	if again := MustCompile[compiledOrder](); again.plan != plan.plan {
+		t.Error("Expected compiled plans to be cached per type")
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	type unknownRule struct {
+		Name string `validate:"required|shiny"`
+	}
+	type badParam struct {
+		Name string `validate:"min_len:many"`
+	}
+	if _, err := Compile[unknownRule](); err == nil || !strings.Contains(err.Error(), "shiny") {
+		t.Errorf("Expected unknown rule error, got %v", err)
+	}
+	if _, err := Compile[badParam](); err == nil {
+		t.Error("Expected error for non-integer parameter")
+	}
+	if _, err := Compile[string](); err == nil {
+		t.Error("Expected error for non-struct type")
+	}
+	if err := RegisterRule("email", func(reflect.Value, string) bool { return true }); err == nil {
+		t.Error("Expected error when overriding a built-in rule")
+	}
+}
+
+func TestCompiledPlanAllocations(t *testing.T) {
+	plan := MustCompile[compiledOrder]()
+	order := validCompiledOrder()
+	compiled := testing.AllocsPerRun(100, func() {
+		if !plan.Valid(&order) {
+			t.Fatal("Expected valid order")
+		}
+	})
+	tags := testing.AllocsPerRun(100, func() {
+		New().Validate(&order)
+	})
+	// Reading map values through reflection is the only allocation left
+	if compiled*20 > tags {
+		t.Errorf("Expected far fewer allocations than the tag path (%.0f), got %.0f", tags, compiled)
+	}
+}
+
+func BenchmarkValidateTags(b *testing.B) {
+	order := validCompiledOrder()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		New().Validate(&order)
+	}
+}
+
+func BenchmarkValidateCompiled(b *testing.B) {
+	plan := MustCompile[compiledOrder]()
+	order := validCompiledOrder()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		plan.Validate(&order)
+	}
+}
+
+// fakeRecords is an Exister over an in-memory set of column values
+type fakeRecords struct {
+	mu       sync.Mutex
+	values   map[string]bool
+	delay    time.Duration
+	calls    int
+	inFlight int
+	maxSeen  int
+}
+
+func (f *fakeRecords) Exists(ctx context.Context, conditions map[string]interface{}) (bool, error) {
+	f.mu.Lock()
+	f.calls++
+	f.inFlight++
+	if f.inFlight > f.maxSeen {
+		f.maxSeen = f.inFlight
+	}
+	f.mu.Unlock()
+	defer func() {
+		f.mu.Lock()
+		f.inFlight--
+		f.mu.Unlock()
+	}()
+
+	select {
+	case <-time.After(f.delay):
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+	for _, value := range conditions {
+		if f.values[fmt.Sprint(value)] {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestContextRules(t *testing.T) {
+	users := &fakeRecords{values: map[string]bool{"taken@example.com": true}}
+	coupons := &fakeRecords{values: map[string]bool{"SPRING": true}}
+	schema := NewSchema().
+		Field("email", Required(), Email(), Unique(users, "email")).
+		Field("coupon", Exists(coupons, "code"))
+
+	valid, errors, err := schema.ValidateContext(context.Background(), map[string]interface{}{
+		"email":  "taken@example.com",
+		"coupon": "WINTER",
+	})
+	if valid || err != nil {
+		t.Fatalf("Expected invalid data without error, got valid=%v err=%v", valid, err)
+	}
+	if msgs := errors["email"]; len(msgs) != 1 || msgs[0] != "Is already taken" {
+		t.Errorf("Expected unique error, got %v", msgs)
+	}
+	if msgs := errors["coupon"]; len(msgs) != 1 || msgs[0] != "Does not exist" {
+		t.Errorf("Expected exists error, got %v", msgs)
+	}
+
+	// Checks are skipped when synchronous rules fail or the field is empty
+	users.calls = 0
+	_, errors, _ = schema.ValidateContext(context.Background(), map[string]interface{}{"email": "invalid"})
+	if users.calls != 0 || len(errors["email"]) != 1 {
+		t.Errorf("Expected only the email format error and no lookup, got %v after %d calls", errors, users.calls)
+	}
+
+	ctx := WithLocale(context.Background(), "sw")
+	if valid, _, err := schema.ValidateContext(ctx, map[string]interface{}{"email": "new@example.com", "coupon": "SPRING"}); !valid || err != nil {
+		t.Errorf("Expected valid data, got valid=%v err=%v", valid, err)
+	}
+}
+
+func TestContextRulesDeadlineAndConcurrency(t *testing.T) {
+	slow := &fakeRecords{values: map[string]bool{}, delay: time.Second}
+	schema := NewSchema().Field("email", Unique(slow, "email"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	valid, errors, err := schema.ValidateContext(ctx, map[string]interface{}{"email": "a@example.com"})
+	if valid || !stderrors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline error, got valid=%v err=%v", valid, err)
+	}
+	if msgs := errors["email"]; len(msgs) != 1 || msgs[0] != "Could not be checked, please try again" {
+		t.Errorf("Expected unavailable error, got %v", msgs)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected validation to stop at the deadline, took %v", elapsed)
+	}
+
+	records := &fakeRecords{values: map[string]bool{}, delay: 10 * time.Millisecond}
+	itemSchema := NewSchema().Field("code", Exists(records, "code"))
+	listSchema := NewSchema().Each("items", itemSchema)
+	items := make([]interface{}, 8)
+	for i := range items {
+		items[i] = map[string]interface{}{"code": fmt.Sprintf("C%d", i)}
+	}
+	v := New().SetConcurrency(2)
+	if valid, err := listSchema.ValidateWithContext(context.Background(), v, map[string]interface{}{"items": items}); valid || err != nil {
+		t.Fatalf("Expected every item to fail, got valid=%v err=%v", valid, err)
+	}
+	if records.calls != 8 || records.maxSeen > 2 {
+		t.Errorf("Expected 8 calls at most 2 at a time, got %d calls with %d at once", records.calls, records.maxSeen)
+	}
+	if list := v.GetErrorList(); len(list) != 8 || list[0].Field != "items[0].code" || list[7].Field != "items[7].code" {
+		t.Errorf("Expected errors in item order, got %v", list)
+	}
+}
+
+func TestContextTagRules(t *testing.T) {
+	users := &fakeRecords{values: map[string]bool{"taken@example.com": true}}
+	err := RegisterContextRule("unique_test_email", func(ctx context.Context, value interface{}) (bool, error) {
+		exists, err := users.Exists(ctx, map[string]interface{}{"email": value})
+		return !exists, err
+	}, "This email is already registered")
+	if err != nil {
+		t.Fatalf("Expected rule to register, got %v", err)
+	}
+
+	type signup struct {
+		Email string `json:"email" validate:"required|email|unique_test_email"`
+	}
+	v := New()
+	if valid, err := v.ValidateContext(context.Background(), signup{Email: "taken@example.com"}); valid || err != nil {
+		t.Errorf("Expected taken email to fail, got valid=%v err=%v", valid, err)
+	}
+	if msgs := v.GetErrors()["email"]; len(msgs) != 1 || msgs[0] != "This email is already registered" {
+		t.Errorf("Expected registered message, got %v", msgs)
+	}
+
+	plan := MustCompile[signup]()
+	if valid, errors := plan.Validate(&signup{Email: "taken@example.com"}); valid || len(errors["email"]) != 1 {
+		t.Errorf("Expected compiled plan to run context rules, got %v", errors)
+	}
+	if valid, _ := plan.Validate(&signup{Email: "new@example.com"}); !valid {
+		t.Error("Expected new email to pass")
+	}
+}
+
+const orderJSONSchema = `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"$id": "https://example.com/order.json",
+	"type": "object",
+	"required": ["id", "email", "status", "quantity", "gift", "shipping", "items"],
+	"additionalProperties": false,
+	"properties": {
+		"id": {"type": "string", "format": "uuid"},
+		"email": {"type": "string", "format": "email", "title": "Email address"},
+		"website": {"type": ["string", "null"], "format": "uri"},
+		"status": {"type": "string", "enum": ["pending", "paid"]},
+		"quantity": {"type": "integer", "minimum": 0, "maximum": 100},
+		"gift": {"type": "boolean"},
+		"placed_at": {"type": "string", "format": "date-time"},
+		"client_ip": {"type": "string", "format": "ipv4"},
+		"shipping": {"$ref": "#/$defs/address"},
+		"items": {
+			"type": "array",
+			"items": {
+				"type": "object",
+				"required": ["sku"],
+				"properties": {
+					"sku": {"type": "string", "minLength": 3, "pattern": "^[A-Z0-9-]+$"},
+					"price": {"type": "number", "minimum": 0.5}
+				}
+			}
+		},
+		"tags": {"type": "array", "items": {"type": "string", "maxLength": 5}}
+	},
+	"$defs": {
+		"address": {
+			"type": "object",
+			"required": ["city"],
+			"properties": {
+				"city": {"type": "string", "minLength": 2},
+				"zip": {"type": "string", "pattern": "^[0-9]{5}$"}
+			}
+		}
+	}
+}`
+
+func TestSchemaFromJSON(t *testing.T) {
+	schema, err := SchemaFromJSON([]byte(orderJSONSchema))
+	if err != nil {
+		t.Fatalf("Expected schema to import, got %v", err)
+	}
+
+	valid := map[string]interface{}{
+		"id":        "123e4567-e89b-12d3-a456-426614174000",
+		"email":     "buyer@example.com",
+		"website":   nil,
+		"status":    "paid",
+		"quantity":  float64(0),
+		"gift":      false,
+		"placed_at": "2024-05-01T10:00:00Z",
+		"client_ip": "10.0.0.1",
+		"shipping":  map[string]interface{}{"city": "Arusha", "zip": "23101"},
+		"items":     []interface{}{map[string]interface{}{"sku": "AB-1", "price": 9.99}},
+		"tags":      []interface{}{"new"},
+	}
+	if ok, errors := schema.ValidateMap(valid); !ok {
+		t.Errorf("Expected valid payload to pass, got %v", errors)
+	}
+
+	invalid := map[string]interface{}{
+		"id":        "not-a-uuid",
+		"email":     "buyer",
+		"status":    "shipped",
+		"quantity":  2.5,
+		"gift":      "yes",
+		"placed_at": "yesterday",
+		"client_ip": "::1",
+		"shipping":  map[string]interface{}{"zip": "ABC"},
+		"items":     []interface{}{map[string]interface{}{"sku": "ab", "price": 0.1}},
+		"tags":      []interface{}{"ok", "too-long"},
+		"coupon":    "FREE",
+	}
+	ok, errors := schema.ValidateMap(invalid)
+	if ok {
+		t.Fatal("Expected invalid payload to fail")
+	}
+	expected := map[string]string{
+		"id":             "Must be a valid UUID",
+		"email":          "Must be a valid email address",
+		"status":         "Must be one of: pending, paid",
+		"quantity":       "Must be a valid integer",
+		"gift":           "Must be true or false",
+		"placed_at":      "Must be a valid datetime (RFC3339)",
+		"client_ip":      "Must be a valid IPv4 address",
+		"shipping.city":  "This field is required",
+		"shipping.zip":   "Does not match required pattern",
+		"items[0].price": "Must be at least 0.5",
+		"tags[1]":        "Must be at most 5 characters",
+		"coupon":         "Is not an allowed field",
+	}
+	for field, msg := range expected {
+		if msgs := errors[field]; len(msgs) == 0 || msgs[0] != msg {
+			t.Errorf("Expected %s error %q, got %v", field, msg, msgs)
+		}
+	}
+	if msgs := errors["items[0].sku"]; len(msgs) != 2 {
+		t.Errorf("Expected min length and pattern errors for sku, got %v", msgs)
+	}
+
+	// Types are checked before other rules
+	ok, errors = schema.ValidateMap(map[string]interface{}{"email": 42})
+	if ok || errors["email"][0] != "Must be a string" {
+		t.Errorf("Expected type error for email, got %v", errors["email"])
+	}
+	if msgs := errors["quantity"]; len(msgs) != 1 || msgs[0] != "This field is required" {
+		t.Errorf("Expected missing quantity to be required, got %v", msgs)
+	}
+}
+
+func TestSchemaJSONSchemaRoundTrip(t *testing.T) {
+	schema, err := SchemaFromJSON([]byte(orderJSONSchema))
+	if err != nil {
+		t.Fatalf("Expected schema to import, got %v", err)
+	}
+	data, err := schema.MarshalJSONSchema()
+	if err != nil {
+		t.Fatalf("Expected schema to export, got %v", err)
+	}
+	if !strings.Contains(string(data), `"$schema": "https://json-schema.org/draft/2020-12/schema"`) {
+		t.Errorf("Expected draft 2020-12 $schema, got %s", data)
+	}
+
+	again, err := SchemaFromJSON(data)
+	if err != nil {
+		t.Fatalf("Expected exported schema to import, got %v", err)
+	}
+	if !reflect.DeepEqual(schema.JSONSchema(), again.JSONSchema()) {
+		t.Errorf("Expected round trip to keep the schema:\n%v\n%v", schema.JSONSchema(), again.JSONSchema())
+	}
+
+	// Schemas built in Go export rules that import back to the same checks
+	built := NewSchema().
+		Field("name", Required(), MinLen(2), Alpha(), Label("Name")).
+		Field("ip", IP()).
+		Field("code", Pattern(`^[a-z]+$`), AlphaNum()).
+		Field("role", NotOneOf("root"))
+	imported, err := SchemaFromMap(built.JSONSchema())
+	if err != nil {
+		t.Fatalf("Expected built schema to import, got %v", err)
+	}
+	payload := map[string]interface{}{"name": "A1", "ip": "nope", "code": "ABC", "role": "root"}
+	_, want := built.ValidateMap(payload)
+	_, got := imported.ValidateMap(payload)
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected imported schema to report %v, got %v", want, got)
+	}
+}
+
+func TestSchemaFromJSONErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		want   string
+	}{
+		{"unsupported keyword", `{"type": "object", "properties": {"tags": {"type": "array", "minItems": 1}}}`, `#/properties/tags: unsupported keyword "minItems"`},
+		{"bad pattern", `{"properties": {"code": {"type": "string", "pattern": "([a-z"}}}`, `#/properties/code/pattern: invalid pattern`},
+		{"not an object", `{"type": "string"}`, `expected an object schema`},
+		{"missing ref", `{"properties": {"a": {"$ref": "#/$defs/missing"}}}`, `$ref "#/$defs/missing" not found`},
+		{"recursive ref", `{"$defs": {"node": {"type": "object", "properties": {"child": {"$ref": "#/$defs/node"}}}}, "properties": {"root": {"$ref": "#/$defs/node"}}}`, `recursive $ref`},
+		{"remote ref", `{"properties": {"a": {"$ref": "https://example.com/a.json"}}}`, `only local $ref`},
+		{"invalid JSON", `{`, `failed to parse JSON Schema`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := SchemaFromJSON([]byte(tt.schema))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
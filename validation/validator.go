@@ -1,6 +1,7 @@
 package validation
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 	"regexp"
@@ -12,6 +13,15 @@ import (
 // Validator represents a validation instance
 type Validator struct {
 	errors map[string][]string
+	list   []Error                // errors in the order they were added, with codes
+	object map[string]interface{} // fields of the struct being validated, for cross-field tags
+	locale string
+	labels map[string]string // display names by field name or path
+	// Context checks queued by validateField, see runChecks
+	pending     []pendingCheck
+	concurrency int
+	// Pointers, slices and maps being walked, so cyclic values terminate
+	visiting map[visit]bool
 }
 
 // New creates a new validator instance
@@ -25,6 +35,8 @@ func New() *Validator {
 type Error struct {
 	Field   string `json:"field"`
 	Message string `json:"message"`
+	// Code is the rule that failed, e.g. "min_len"; empty for errors added with AddError
+	Code string `json:"code,omitempty"`
 }
 
 // Errors represents multiple validation errors
@@ -70,45 +82,63 @@ type Rules struct {
 	DateTimeOnly bool
 	DateOnly     bool
 	TimeOnly     bool
+	Label        string // display name in error messages
+	Type         string // JSON type, see Type
+
+	// Cross-field rules name other fields of the same object
+	EqField        string
+	NeField        string
+	GtField        string
+	GteField       string
+	LtField        string
+	LteField       string
+	RequiredIf     []FieldCondition
+	RequiredWith   []string
+	ExcludedUnless []FieldCondition
+
+	// Context rules run after the synchronous rules pass, see ValidateContext
+	ContextChecks []ContextCheck
 }
 
-// Validate validates a struct based on field tags
+// Validate validates a struct based on field tags. Nested structs, slices and
+// maps are validated recursively; "dive" applies the rules after it to each
+// element, e.g. `validate:"required|dive|email"`. Nested errors are keyed by
+// JSON path, e.g. "items[2].sku". Context rules run with context.Background;
+// use ValidateContext to pass a deadline.
 func (v *Validator) Validate(s interface{}) bool {
+	valid, _ := v.ValidateContext(context.Background(), s)
+	return valid
+}
+
+// validateTags applies the synchronous tag rules of a struct
+func (v *Validator) validateTags(s interface{}) {
 	val := reflect.ValueOf(s)
 	if val.Kind() == reflect.Ptr {
+		// Marked so a field pointing back to the root stops there
+		v.enter(val)
+		defer v.leave(val)
 		val = val.Elem()
 	}
 
-	typ := val.Type()
-
-	for i := 0; i < val.NumField(); i++ {
-		field := typ.Field(i)
-		value := val.Field(i)
-		fieldName := field.Name
-		jsonTag := field.Tag.Get("json")
-		if jsonTag != "" && jsonTag != "-" {
-			fieldName = strings.Split(jsonTag, ",")[0]
-		}
-
-		// Get validation tag
-		validateTag := field.Tag.Get("validate")
-		if validateTag != "" {
-			v.validateField(fieldName, value.Interface(), validateTag)
-		}
-	}
-
-	return len(v.errors) == 0
+	v.validateStruct("", val)
 }
 
 // ValidateField validates a single field with rules
 func (v *Validator) ValidateField(field string, value interface{}, rules string) bool {
 	v.validateField(field, value, rules)
+	v.runChecks(context.Background())
 	return len(v.errors[field]) == 0
 }
 
 // AddError adds a custom error for a field
 func (v *Validator) AddError(field, message string) {
+	v.addError(field, "", message)
+}
+
+// addError records an error with the code of the rule that failed
+func (v *Validator) addError(field, code, message string) {
 	v.errors[field] = append(v.errors[field], message)
+	v.list = append(v.list, Error{Field: field, Message: message, Code: code})
 }
 
 // HasErrors checks if there are any validation errors
@@ -130,181 +160,194 @@ func (v *Validator) GetErrorMap() map[string]interface{} {
 	return errMap
 }
 
-// GetErrorList returns errors as a list
+// GetErrorList returns errors as a list in the order they were found
 func (v *Validator) GetErrorList() []Error {
-	var errors []Error
-	for field, messages := range v.errors {
-		for _, message := range messages {
-			errors = append(errors, Error{Field: field, Message: message})
-		}
-	}
-	return errors
+	return append([]Error(nil), v.list...)
 }
 
 // Clear clears all validation errors
 func (v *Validator) Clear() {
 	v.errors = make(map[string][]string)
+	v.list = nil
 }
 
 // validateField validates a field based on validation tag
 func (v *Validator) validateField(field string, value interface{}, rules string) {
-	ruleList := strings.Split(rules, "|")
-	for _, rule := range ruleList {
-		rule = strings.TrimSpace(rule)
-		if rule == "" {
+	errorCount := len(v.errors[field])
+	var checks []ContextCheck
+	defer func() { v.queueChecks(field, value, checks, errorCount) }()
+
+	for _, rule := range parseTag(rules) {
+		if check, ok := lookupContextRule(rule.name); ok {
+			checks = append(checks, check)
 			continue
 		}
-
-		parts := strings.SplitN(rule, ":", 2)
-		ruleName := parts[0]
-		var ruleValue string
-		if len(parts) > 1 {
-			ruleValue = parts[1]
+		if !v.applyRule(field, value, rule) {
+			v.validateCustomRule(field, value, rule.name, rule.param)
 		}
+	}
+}
 
-		switch ruleName {
-		case "required":
-			if isEmpty(value) {
-				v.AddError(field, "This field is required")
-			}
-		case "min":
-			if num, ok := toInt(ruleValue); ok {
-				if intVal, err := toIntValue(value); err == nil && intVal < num {
-					v.AddError(field, fmt.Sprintf("Must be at least %d", num))
-				}
-			}
-		case "max":
-			if num, ok := toInt(ruleValue); ok {
-				if intVal, err := toIntValue(value); err == nil && intVal > num {
-					v.AddError(field, fmt.Sprintf("Must be at most %d", num))
-				}
+// applyRule applies a built-in tag rule, reporting false for other rule names
+func (v *Validator) applyRule(field string, value interface{}, rule tagRule) bool {
+	ruleValue := rule.param
+	switch rule.name {
+	case "required":
+		if isEmpty(value) {
+			v.fail(field, "required", nil)
+		}
+	case "min":
+		if num, ok := toInt(ruleValue); ok {
+			if intVal, err := toIntValue(value); err == nil && intVal < num {
+				v.fail(field, "min", params{"min": num, "param": num})
 			}
-		case "min_len":
-			if length, ok := toInt(ruleValue); ok {
-				if str, isString := toString(value); isString && utf8.RuneCountInString(str) < length {
-					v.AddError(field, fmt.Sprintf("Must be at least %d characters", length))
-				}
+		}
+	case "max":
+		if num, ok := toInt(ruleValue); ok {
+			if intVal, err := toIntValue(value); err == nil && intVal > num {
+				v.fail(field, "max", params{"max": num, "param": num})
 			}
-		case "max_len":
-			if length, ok := toInt(ruleValue); ok {
-				if str, isString := toString(value); isString && utf8.RuneCountInString(str) > length {
-					v.AddError(field, fmt.Sprintf("Must be at most %d characters", length))
-				}
+		}
+	case "min_len":
+		if length, ok := toInt(ruleValue); ok {
+			if str, isString := toString(value); isString && utf8.RuneCountInString(str) < length {
+				v.fail(field, "min_len", params{"min": length, "param": length})
 			}
-		case "email":
-			if str, isString := toString(value); isString && str != "" {
-				if !isEmail(str) {
-					v.AddError(field, "Must be a valid email address")
-				}
+		}
+	case "max_len":
+		if length, ok := toInt(ruleValue); ok {
+			if str, isString := toString(value); isString && utf8.RuneCountInString(str) > length {
+				v.fail(field, "max_len", params{"max": length, "param": length})
 			}
-		case "url":
-			if str, isString := toString(value); isString && str != "" {
-				if !isURL(str) {
-					v.AddError(field, "Must be a valid URL")
-				}
+		}
+	case "email":
+		if str, isString := toString(value); isString && str != "" {
+			if !isEmail(str) {
+				v.fail(field, "email", nil)
 			}
-		case "alpha":
-			if str, isString := toString(value); isString && str != "" {
-				if !isAlpha(str) {
-					v.AddError(field, "Must contain only letters")
-				}
+		}
+	case "url":
+		if str, isString := toString(value); isString && str != "" {
+			if !isURL(str) {
+				v.fail(field, "url", nil)
 			}
-		case "alphanum":
-			if str, isString := toString(value); isString && str != "" {
-				if !isAlphaNum(str) {
-					v.AddError(field, "Must contain only letters and numbers")
-				}
+		}
+	case "alpha":
+		if str, isString := toString(value); isString && str != "" {
+			if !isAlpha(str) {
+				v.fail(field, "alpha", nil)
 			}
-		case "numeric":
-			if str, isString := toString(value); isString && str != "" {
-				if !isNumeric(str) {
-					v.AddError(field, "Must be a valid number")
-				}
+		}
+	case "alphanum":
+		if str, isString := toString(value); isString && str != "" {
+			if !isAlphaNum(str) {
+				v.fail(field, "alphanum", nil)
 			}
-		case "uuid":
-			if str, isString := toString(value); isString && str != "" {
-				if !isUUID(str) {
-					v.AddError(field, "Must be a valid UUID")
-				}
+		}
+	case "numeric":
+		if str, isString := toString(value); isString && str != "" {
+			if !isNumeric(str) {
+				v.fail(field, "numeric", nil)
 			}
-		case "ip":
-			if str, isString := toString(value); isString && str != "" {
-				if !isIP(str) {
-					v.AddError(field, "Must be a valid IP address")
-				}
+		}
+	case "uuid":
+		if str, isString := toString(value); isString && str != "" {
+			if !isUUID(str) {
+				v.fail(field, "uuid", nil)
 			}
-		case "ipv4":
-			if str, isString := toString(value); isString && str != "" {
-				if !isIPv4(str) {
-					v.AddError(field, "Must be a valid IPv4 address")
-				}
+		}
+	case "ip":
+		if str, isString := toString(value); isString && str != "" {
+			if !isIP(str) {
+				v.fail(field, "ip", nil)
 			}
-		case "ipv6":
-			if str, isString := toString(value); isString && str != "" {
-				if !isIPv6(str) {
-					v.AddError(field, "Must be a valid IPv6 address")
-				}
+		}
+	case "ipv4":
+		if str, isString := toString(value); isString && str != "" {
+			if !isIPv4(str) {
+				v.fail(field, "ipv4", nil)
 			}
-		case "regex":
-			if pattern, err := regexp.Compile(ruleValue); err == nil {
-				if str, isString := toString(value); isString && str != "" {
-					if !pattern.MatchString(str) {
-						v.AddError(field, "Does not match required pattern")
-					}
-				}
+		}
+	case "ipv6":
+		if str, isString := toString(value); isString && str != "" {
+			if !isIPv6(str) {
+				v.fail(field, "ipv6", nil)
 			}
-		case "in":
+		}
+	case "regex":
+		if pattern, err := regexp.Compile(ruleValue); err == nil {
 			if str, isString := toString(value); isString && str != "" {
-				options := strings.Split(ruleValue, ",")
-				found := false
-				for _, opt := range options {
-					if strings.TrimSpace(opt) == str {
-						found = true
-						break
-					}
-				}
-				if !found {
-					v.AddError(field, fmt.Sprintf("Must be one of: %s", ruleValue))
+				if !pattern.MatchString(str) {
+					v.fail(field, "regex", params{"param": ruleValue})
 				}
 			}
-		case "not_in":
-			if str, isString := toString(value); isString && str != "" {
-				options := strings.Split(ruleValue, ",")
-				for _, opt := range options {
-					if strings.TrimSpace(opt) == str {
-						v.AddError(field, fmt.Sprintf("Must not be: %s", str))
-						break
-					}
+		}
+	case "in":
+		if str, isString := toString(value); isString && str != "" {
+			options := strings.Split(ruleValue, ",")
+			found := false
+			for _, opt := range options {
+				if strings.TrimSpace(opt) == str {
+					found = true
+					break
 				}
 			}
-		case "date":
-			if str, isString := toString(value); isString && str != "" {
-				if _, err := time.Parse("2006-01-02", str); err != nil {
-					v.AddError(field, "Must be a valid date (YYYY-MM-DD)")
-				}
+			if !found {
+				v.fail(field, "in", params{"values": ruleValue, "param": ruleValue})
 			}
-		case "datetime":
-			if str, isString := toString(value); isString && str != "" {
-				if _, err := time.Parse(time.RFC3339, str); err != nil {
-					v.AddError(field, "Must be a valid datetime (RFC3339)")
+		}
+	case "not_in":
+		if str, isString := toString(value); isString && str != "" {
+			options := strings.Split(ruleValue, ",")
+			for _, opt := range options {
+				if strings.TrimSpace(opt) == str {
+					v.fail(field, "not_in", params{"value": str, "param": ruleValue})
+					break
 				}
 			}
-		case "time":
-			if str, isString := toString(value); isString && str != "" {
-				if _, err := time.Parse("15:04:05", str); err != nil {
-					v.AddError(field, "Must be a valid time (HH:MM:SS)")
-				}
+		}
+	case "date":
+		if str, isString := toString(value); isString && str != "" {
+			if _, err := time.Parse("2006-01-02", str); err != nil {
+				v.fail(field, "date", nil)
 			}
-		case "equal":
-			if str, isString := toString(value); isString && str != ruleValue {
-				v.AddError(field, fmt.Sprintf("Must be equal to %s", ruleValue))
+		}
+	case "datetime":
+		if str, isString := toString(value); isString && str != "" {
+			if _, err := time.Parse(time.RFC3339, str); err != nil {
+				v.fail(field, "datetime", nil)
 			}
-		case "not_equal":
-			if str, isString := toString(value); isString && str == ruleValue {
-				v.AddError(field, fmt.Sprintf("Must not be equal to %s", ruleValue))
+		}
+	case "time":
+		if str, isString := toString(value); isString && str != "" {
+			if _, err := time.Parse("15:04:05", str); err != nil {
+				v.fail(field, "time", nil)
 			}
 		}
+	case "equal":
+		if str, isString := toString(value); isString && str != ruleValue {
+			v.fail(field, "equal", params{"param": ruleValue})
+		}
+	case "not_equal":
+		if str, isString := toString(value); isString && str == ruleValue {
+			v.fail(field, "not_equal", params{"param": ruleValue})
+		}
+	case "eq_field", "ne_field", "gt_field", "gte_field", "lt_field", "lte_field",
+		"required_if", "required_with", "excluded_unless":
+		v.validateCrossFieldTag(field, value, RulesFromTag(rule.text))
+	default:
+		return false
+	}
+	return true
+}
+
+// validateCrossFieldTag applies a cross-field tag rule against the struct being validated
+func (v *Validator) validateCrossFieldTag(field string, value interface{}, rules Rules) {
+	if isEmpty(value) {
+		rules.validateRequired(v, field, v.object)
+		return
 	}
+	rules.validateCrossField(v, field, value, v.object)
 }
 
 // Helper functions